@@ -19,6 +19,10 @@ var Config = &struct {
 	KitchenAppSecret string       `json:"kitchen_app_secret"`
 	FileUploadPath   string       `json:"file_upload_path"`
 	FileBaseUrl      string       `json:"file_base_url"`
+	OverTolerance    float64      `json:"over_tolerance"`
+	UnderTolerance   float64      `json:"under_tolerance"`
+	CostMethod       uint8        `json:"cost_method"`
+	WriteOffLimit    float64      `json:"write_off_limit"`
 }{}
 
 func LoadConfig(configPath string) error {
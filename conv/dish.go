@@ -2,6 +2,7 @@ package conv
 
 import (
 	"encoding/json"
+	"fmt"
 	"github.com/canteen_management/enum"
 	"time"
 
@@ -14,6 +15,35 @@ const (
 	dishConvertLogTag = "DishConvert"
 )
 
+func GetDish(dishMap map[uint32]*model.Dish, dishID uint32) *model.Dish {
+	dish, ok := dishMap[dishID]
+	if ok {
+		return dish
+	}
+	logger.Warn(dishConvertLogTag, "Dish Not Found|DishID:%v", dishID)
+	return &model.Dish{ID: dishID, DishName: fmt.Sprintf("已删除菜品(%v)", dishID), IsDelete: true}
+}
+
+// GetDishTypeName 菜品类别可能已删除，或菜品本身已删除(类别为0)，缺失时返回占位名称
+func GetDishTypeName(dishTypeMap map[uint32]*model.DishType, dishTypeID uint32) string {
+	dishType, ok := dishTypeMap[dishTypeID]
+	if ok {
+		return dishType.DishTypeName
+	}
+	if dishTypeID == 0 {
+		return "未分类"
+	}
+	return fmt.Sprintf("已删除类别(%v)", dishTypeID)
+}
+
+func GetMasterDishType(dishTypeMap map[uint32]*model.DishType, dishTypeID uint32) uint32 {
+	dishType, ok := dishTypeMap[dishTypeID]
+	if !ok {
+		return 0
+	}
+	return dishType.MasterType
+}
+
 func ConvertToDishTypeInfoList(daoList []*model.DishType) []*dto.DishTypeInfo {
 	retList := make([]*dto.DishTypeInfo, 0, len(daoList))
 	for _, dao := range daoList {
@@ -35,9 +65,53 @@ func ConvertToDishInfoList(daoList []*model.Dish, dishTypeMap map[uint32]*model.
 	return retList
 }
 
+func ConvertToDishInfoListWithRecipe(daoList []*model.Dish, dishTypeMap map[uint32]*model.DishType,
+	recipeMap map[uint32]*model.RecipeNutrition) []*dto.DishInfo {
+	retList := ConvertToDishInfoList(daoList, dishTypeMap)
+	for _, dishInfo := range retList {
+		recipeNutrition, ok := recipeMap[dishInfo.DishID]
+		if !ok {
+			continue
+		}
+		dishInfo.RecipeNutrition = ConvertToNutritionInfo(recipeNutrition.Nutrition)
+		dishInfo.RecipeAllergenList = enum.GetAllergenList(recipeNutrition.Allergen)
+	}
+	return retList
+}
+
+func ConvertToNutritionInfo(nutrition *model.Nutrition) *dto.NutritionInfo {
+	if nutrition == nil {
+		return &dto.NutritionInfo{}
+	}
+	return &dto.NutritionInfo{Energy: nutrition.Energy, Protein: nutrition.Protein, Fat: nutrition.Fat,
+		Carbs: nutrition.Carbs, Sodium: nutrition.Sodium}
+}
+
+func ConvertFromNutritionInfo(info *dto.NutritionInfo) *model.Nutrition {
+	if info == nil {
+		return &model.Nutrition{}
+	}
+	return &model.Nutrition{Energy: info.Energy, Protein: info.Protein, Fat: info.Fat,
+		Carbs: info.Carbs, Sodium: info.Sodium}
+}
+
+func SumDishNutrition(dishList []*model.Dish) (*model.Nutrition, uint32) {
+	total, allergen := &model.Nutrition{}, uint32(0)
+	for _, dish := range dishList {
+		total.Add(dish.ToNutrition(), 1)
+		allergen |= dish.Allergen
+	}
+	return total, allergen
+}
+
 func ConvertToDishInfo(dao *model.Dish, dishTypeMap map[uint32]*model.DishType) *dto.DishInfo {
 	dishInfo := &dto.DishInfo{DishID: dao.ID, DishName: dao.DishName, Picture: dao.Picture,
-		Material: dao.Material, Price: dao.Price}
+		Material: dao.Material, Price: dao.Price, Status: dao.Status, SeasonStart: dao.SeasonStart,
+		SeasonEnd: dao.SeasonEnd, IsDelete: dao.IsDelete, Nutrition: ConvertToNutritionInfo(dao.ToNutrition()),
+		AllergenList: enum.GetAllergenList(dao.Allergen), Recipe: make([]*dto.RecipeItem, 0)}
+	for goodsID, quantity := range dao.ToRecipe() {
+		dishInfo.Recipe = append(dishInfo.Recipe, &dto.RecipeItem{GoodsID: goodsID, Quantity: quantity})
+	}
 	typeInfo, ok := dishTypeMap[dao.DishType]
 	if ok {
 		dishInfo.DishTypeID = typeInfo.ID
@@ -53,8 +127,19 @@ func ConvertToDishInfo(dao *model.Dish, dishTypeMap map[uint32]*model.DishType)
 }
 
 func ConvertFromDishInfo(info *dto.DishInfo) *model.Dish {
-	return &model.Dish{ID: info.DishID, DishName: info.DishName, DishType: info.DishTypeID,
-		Picture: info.Picture, Price: info.Price, Material: info.Material}
+	dish := &model.Dish{ID: info.DishID, DishName: info.DishName, DishType: info.DishTypeID,
+		Picture: info.Picture, Price: info.Price, Material: info.Material, Status: info.Status,
+		SeasonStart: info.SeasonStart, SeasonEnd: info.SeasonEnd, Allergen: enum.GetAllergenMask(info.AllergenList)}
+	dish.FromNutrition(ConvertFromNutritionInfo(info.Nutrition))
+	recipe := make(map[uint32]float64, len(info.Recipe))
+	for _, item := range info.Recipe {
+		if item.GoodsID == 0 || item.Quantity <= 0 {
+			continue
+		}
+		recipe[item.GoodsID] += item.Quantity
+	}
+	dish.FromRecipe(recipe)
+	return dish
 }
 
 func ConvertFromDishList(infoList []*dto.DishInfo) []*model.Dish {
@@ -91,7 +176,7 @@ func ConvertToWeekMenuList(daoList []*model.WeekMenu, dishList map[uint32]*model
 		}
 		retList = append(retList, &dto.WeekMenuInfo{WeekMenuID: dao.ID, MenuType: dao.MenuTypeID,
 			MenuStartDate: dao.MenuStartDate.Unix(), MenuEndDate: dao.MenuStartDate.Add(time.Hour * 24 * 7).Unix(),
-			MenuContent: mealStrList})
+			Status: dao.Status, Version: dao.Version, Online: dao.IsOnline(), MenuContent: mealStrList})
 	}
 	return retList
 }
@@ -109,10 +194,25 @@ func ConvertToWeekMenuDetail(dao *model.WeekMenu, dishList map[uint32]*model.Dis
 		MenuType:      dao.MenuTypeID,
 		MenuStartDate: dao.MenuStartDate.Unix(),
 		MenuEndDate:   dao.MenuStartDate.Add(time.Hour * 24 * 7).Unix(),
+		Status:        dao.Status,
+		Version:       dao.Version,
 		MenuList:      menuList,
 	}, nil
 }
 
+func ConvertToWeekMenuHistoryList(daoList []*model.WeekMenuHistory, dishMap map[uint32]*model.Dish) []*dto.WeekMenuHistoryInfo {
+	retList := make([]*dto.WeekMenuHistoryInfo, 0, len(daoList))
+	for _, dao := range daoList {
+		mealStrList, err := ConvertFromWeekMenuContent(dao.MenuContent, dishMap)
+		if err != nil {
+			continue
+		}
+		retList = append(retList, &dto.WeekMenuHistoryInfo{Version: dao.Version, Operator: dao.Operator,
+			MenuContent: mealStrList, CreateTime: dao.CreateAt.Unix()})
+	}
+	return retList
+}
+
 func ConvertFromWeekMenuContent(content string, dishMap map[uint32]*model.Dish) ([]string, error) {
 	contentMap := make([]map[uint8][]uint32, 0)
 	err := json.Unmarshal([]byte(content), &contentMap)
@@ -126,7 +226,7 @@ func ConvertFromWeekMenuContent(content string, dishMap map[uint32]*model.Dish)
 		for _, dishContent := range dayMenu {
 			mealStr := ""
 			for _, dishID := range dishContent {
-				mealStr += dishMap[dishID].DishName + ","
+				mealStr += GetDish(dishMap, dishID).DishName + ","
 			}
 			mealStrList = append(mealStrList, mealStr[:len(mealStr)-1])
 		}
@@ -179,10 +279,15 @@ func ConvertFromMenuContent2(mealMap map[uint8][]uint32, dishMap map[uint32]*mod
 			MealType: mealType,
 			DishList: make([]*dto.DishInfo, 0),
 		}
+		mealDishList := make([]*model.Dish, 0, len(dishContent))
 		for _, dishID := range dishContent {
-			dishInfo := ConvertToDishInfo(dishMap[dishID], dishTypeMap)
-			mealInfo.DishList = append(mealInfo.DishList, dishInfo)
+			dish := GetDish(dishMap, dishID)
+			mealInfo.DishList = append(mealInfo.DishList, ConvertToDishInfo(dish, dishTypeMap))
+			mealDishList = append(mealDishList, dish)
 		}
+		nutrition, allergen := SumDishNutrition(mealDishList)
+		mealInfo.Nutrition = ConvertToNutritionInfo(nutrition)
+		mealInfo.AllergenList = enum.GetAllergenList(allergen)
 		mealList = append(mealList, mealInfo)
 	}
 	return mealList, nil
@@ -3,9 +3,11 @@ package conv
 import (
 	"fmt"
 	"github.com/canteen_management/dto"
+	"github.com/canteen_management/enum"
 	"github.com/canteen_management/model"
 	"strconv"
 	"strings"
+	"time"
 )
 
 func ConvertFromStoreTypeInfo(info *dto.StoreTypeInfo) *model.StorehouseType {
@@ -33,17 +35,25 @@ func ConvertToGoodsTypeInfoList(daoList []*model.GoodsType) []*dto.GoodsTypeInfo
 }
 
 func ConvertFromGoodsInfo(info *dto.GoodsInfo) *model.Goods {
-	return &model.Goods{ID: info.GoodsID, Name: info.GoodsName, GoodsTypeID: info.GoodsType, StoreTypeID: info.StoreType,
+	goods := &model.Goods{ID: info.GoodsID, Name: info.GoodsName, Barcode: strings.TrimSpace(info.Barcode),
+		GoodsTypeID: info.GoodsType, StoreTypeID: info.StoreType,
 		Picture: info.Picture, BatchSize: info.BatchSize, BatchUnit: info.BatchUnit, AveragePrice: info.Price,
-		Price: info.Price, Quantity: info.Quantity}
+		Price: info.Price, Quantity: info.Quantity, MinQuantity: info.MinQuantity, ReorderPoint: info.ReorderPoint,
+		MaxQuantity: info.MaxQuantity, Allergen: enum.GetAllergenMask(info.AllergenList)}
+	goods.FromNutrition(ConvertFromNutritionInfo(info.Nutrition))
+	return goods
 }
 
 func ConvertToGoodsInfoList(daoList []*model.Goods) []*dto.GoodsInfo {
 	retList := make([]*dto.GoodsInfo, 0, len(daoList))
 	for _, dao := range daoList {
-		retList = append(retList, &dto.GoodsInfo{GoodsID: dao.ID, GoodsName: dao.Name, GoodsType: dao.GoodsTypeID,
+		retList = append(retList, &dto.GoodsInfo{GoodsID: dao.ID, GoodsName: dao.Name, Barcode: dao.Barcode,
+			Code: dao.GetCode(), GoodsType: dao.GoodsTypeID,
 			StoreType: dao.StoreTypeID, Picture: dao.Picture, BatchSize: dao.BatchSize, BatchUnit: dao.BatchUnit,
-			Price: dao.Price, Quantity: dao.Quantity})
+			Price: dao.Price, Quantity: dao.Quantity, Reserved: dao.Reserved, Available: dao.GetAvailable(),
+			MinQuantity: dao.MinQuantity, ReorderPoint: dao.ReorderPoint,
+			MaxQuantity: dao.MaxQuantity, StockLevel: dao.GetStockLevel(),
+			Nutrition: ConvertToNutritionInfo(dao.ToNutrition()), AllergenList: enum.GetAllergenList(dao.Allergen)})
 	}
 	return retList
 }
@@ -98,7 +108,8 @@ func ConvertGoodsListToGoodsNode(goodsMap map[uint32]*model.Goods, goodsTypes []
 				Name:      goods.Name,
 				Picture:   goods.Picture,
 				GoodsID:   goods.ID,
-				Left:      goods.Quantity,
+				Left:      goods.GetAvailable(),
+				Reserved:  goods.Reserved,
 				BatchSize: goods.BatchSize,
 				BatchUnit: goods.BatchUnit,
 			}
@@ -119,9 +130,13 @@ func ConvertToGoodsHistoryInfoList(historyList []*model.GoodsHistory) []*dto.Goo
 		retInfo := &dto.GoodsHistoryInfo{
 			ID:             history.ID,
 			GoodsID:        history.GoodsID,
+			StoreTypeID:    history.StoreTypeID,
 			ChangeQuantity: history.ChangeQuantity,
 			BeforeQuantity: history.BeforeQuantity,
 			AfterQuantity:  history.AfterQuantity,
+			UnitCost:       history.UnitCost,
+			ChangeValue:    history.ChangeValue,
+			AfterValue:     history.AfterValue,
 			ChangeType:     history.ChangeType,
 			RefID:          history.RefID,
 			CreateAt:       history.CreateAt.Unix(),
@@ -130,3 +145,269 @@ func ConvertToGoodsHistoryInfoList(historyList []*model.GoodsHistory) []*dto.Goo
 	}
 	return retList
 }
+
+func ConvertToGoodsPriceHistoryList(historyList []*model.GoodsPriceHistory,
+	supplierMap map[uint32]*model.Supplier) []*dto.GoodsPriceHistoryInfo {
+	retList := make([]*dto.GoodsPriceHistoryInfo, 0, len(historyList))
+	for _, history := range historyList {
+		retInfo := &dto.GoodsPriceHistoryInfo{
+			ID:          history.ID,
+			GoodsID:     history.GoodsID,
+			BeforePrice: history.BeforePrice,
+			AfterPrice:  history.AfterPrice,
+			QuotePrice:  history.QuotePrice,
+			ChangeType:  history.ChangeType,
+			SupplierID:  history.SupplierID,
+			RefID:       history.RefID,
+			Operator:    history.Operator,
+			CreateAt:    history.CreateAt.Unix(),
+		}
+		if supplier, ok := supplierMap[history.SupplierID]; ok {
+			retInfo.SupplierName = supplier.Name
+		}
+		retList = append(retList, retInfo)
+	}
+	return retList
+}
+
+func ConvertToStockLotList(lotList []*model.StockLot, goodsMap map[uint32]*model.Goods) []*dto.StockLotInfo {
+	now := time.Now()
+	retList := make([]*dto.StockLotInfo, 0, len(lotList))
+	for _, lot := range lotList {
+		retInfo := &dto.StockLotInfo{
+			LotID:          lot.ID,
+			LotCode:        lot.GetCode(),
+			GoodsID:        lot.GoodsID,
+			PurchaseID:     lot.PurchaseID,
+			BatchNo:        lot.BatchNo,
+			ProductionDate: lot.ProductionDate.Unix(),
+			ExpiryDate:     lot.ExpiryDate.Unix(),
+			InitQuantity:   lot.InitQuantity,
+			Quantity:       lot.Quantity,
+			RemainDays:     int64(lot.ExpiryDate.Sub(now).Hours() / 24),
+			Expired:        lot.ExpiryDate.Before(now),
+		}
+		if goods, ok := goodsMap[lot.GoodsID]; ok {
+			retInfo.GoodsName = goods.Name
+		}
+		retList = append(retList, retInfo)
+	}
+	return retList
+}
+
+func ConvertToGoodsStockList(stockList []*model.GoodsStock, goodsMap map[uint32]*model.Goods,
+	storeTypeMap map[uint32]*model.StorehouseType) []*dto.GoodsStockInfo {
+	retList := make([]*dto.GoodsStockInfo, 0, len(stockList))
+	for _, stock := range stockList {
+		retInfo := &dto.GoodsStockInfo{
+			GoodsID:     stock.GoodsID,
+			StoreTypeID: stock.StoreTypeID,
+			Quantity:    stock.Quantity,
+		}
+		if goods, ok := goodsMap[stock.GoodsID]; ok {
+			retInfo.GoodsName = goods.Name
+		}
+		if storeType, ok := storeTypeMap[stock.StoreTypeID]; ok {
+			retInfo.StoreTypeName = storeType.StoreTypeName
+		}
+		retList = append(retList, retInfo)
+	}
+	return retList
+}
+
+func ConvertFromTransferGoods(goodsList []*dto.TransferGoodsInfo) []*model.TransferItem {
+	retList := make([]*model.TransferItem, 0, len(goodsList))
+	for _, goods := range goodsList {
+		retList = append(retList, &model.TransferItem{GoodsID: goods.GoodsID, TransferNumber: goods.TransferNumber})
+	}
+	return retList
+}
+
+func ConvertToTransferList(transferList []*model.TransferOrder, goodsMap map[uint32]*model.Goods,
+	storeTypeMap map[uint32]*model.StorehouseType, adminMap map[uint32]*model.AdminUser) []*dto.TransferOrderInfo {
+	retList := make([]*dto.TransferOrderInfo, 0, len(transferList))
+	for _, transfer := range transferList {
+		retInfo := &dto.TransferOrderInfo{
+			TransferID: transfer.ID,
+			FromStore:  transfer.FromStore,
+			ToStore:    transfer.ToStore,
+			Remark:     transfer.Remark,
+			GoodsList:  make([]*dto.TransferGoodsInfo, 0),
+			CreateTime: transfer.CreateAt.Unix(),
+		}
+		if storeType, ok := storeTypeMap[transfer.FromStore]; ok {
+			retInfo.FromStoreName = storeType.StoreTypeName
+		}
+		if storeType, ok := storeTypeMap[transfer.ToStore]; ok {
+			retInfo.ToStoreName = storeType.StoreTypeName
+		}
+		if creator, ok := adminMap[transfer.Creator]; ok {
+			retInfo.Creator = creator.NickName
+		}
+		for _, item := range transfer.ToItems() {
+			goodsInfo := &dto.TransferGoodsInfo{GoodsID: item.GoodsID, TransferNumber: item.TransferNumber}
+			if goods, ok := goodsMap[item.GoodsID]; ok {
+				goodsInfo.GoodsName = goods.Name
+			}
+			retInfo.GoodsList = append(retInfo.GoodsList, goodsInfo)
+		}
+		retList = append(retList, retInfo)
+	}
+	return retList
+}
+
+func ConvertToStockAlertList(alertList []*model.StockAlert, goodsMap map[uint32]*model.Goods) []*dto.StockAlertInfo {
+	retList := make([]*dto.StockAlertInfo, 0, len(alertList))
+	for _, alert := range alertList {
+		retInfo := &dto.StockAlertInfo{
+			AlertID:      alert.ID,
+			GoodsID:      alert.GoodsID,
+			Level:        alert.Level,
+			Quantity:     alert.Quantity,
+			ReorderPoint: alert.ReorderPoint,
+			MinQuantity:  alert.MinQuantity,
+			Status:       alert.Status,
+			CreateTime:   alert.CreateAt.Unix(),
+		}
+		if goods, ok := goodsMap[alert.GoodsID]; ok {
+			retInfo.GoodsName = goods.Name
+			retInfo.BatchUnit = goods.BatchUnit
+			retInfo.Quantity = goods.Quantity
+			retInfo.ReorderNumber = goods.GetReorderNumber()
+		}
+		retList = append(retList, retInfo)
+	}
+	return retList
+}
+
+func ConvertToStockValuationList(goodsList []*model.Goods) ([]*dto.StockValuationInfo, float64) {
+	retList, totalValue := make([]*dto.StockValuationInfo, 0, len(goodsList)), 0.0
+	for _, goods := range goodsList {
+		retInfo := &dto.StockValuationInfo{
+			GoodsID:     goods.ID,
+			GoodsName:   goods.Name,
+			GoodsTypeID: goods.GoodsTypeID,
+			BatchUnit:   goods.BatchUnit,
+			Quantity:    goods.Quantity,
+			StockValue:  goods.StockValue,
+		}
+		if goods.Quantity > 0 {
+			retInfo.UnitCost = goods.StockValue / goods.Quantity
+		}
+		totalValue += goods.StockValue
+		retList = append(retList, retInfo)
+	}
+	return retList, totalValue
+}
+
+func ConvertToDocumentLogList(logList []*model.DocumentLog, adminMap map[uint32]*model.AdminUser) []*dto.DocumentLogInfo {
+	retList := make([]*dto.DocumentLogInfo, 0, len(logList))
+	for _, docLog := range logList {
+		retInfo := &dto.DocumentLogInfo{
+			ID:       docLog.ID,
+			DocType:  docLog.DocType,
+			DocID:    docLog.DocID,
+			Action:   docLog.Action,
+			Reason:   docLog.Reason,
+			CreateAt: docLog.CreateAt.Unix(),
+		}
+		if operator, ok := adminMap[docLog.Operator]; ok {
+			retInfo.Operator = operator.NickName
+		}
+		retList = append(retList, retInfo)
+	}
+	return retList
+}
+
+func ConvertToGoodsUnitList(unitList []*model.GoodsUnit) []*dto.GoodsUnitInfo {
+	retList := make([]*dto.GoodsUnitInfo, 0, len(unitList))
+	for _, unit := range unitList {
+		retList = append(retList, &dto.GoodsUnitInfo{UnitID: unit.ID, GoodsID: unit.GoodsID, UnitName: unit.UnitName,
+			UnitType: unit.UnitType, Factor: unit.Factor})
+	}
+	return retList
+}
+
+func ConvertFromGoodsUnitInfo(info *dto.GoodsUnitInfo) *model.GoodsUnit {
+	return &model.GoodsUnit{ID: info.UnitID, GoodsID: info.GoodsID, UnitName: info.UnitName,
+		UnitType: info.UnitType, Factor: info.Factor}
+}
+
+// ConvertToStockQuantity 按商品已定义的单位换算为库存单位数量，未指定单位时视为库存单位
+func ConvertToStockQuantity(unitMap model.GoodsUnitMap, goodsID uint32, unitName string,
+	quantity float64) (float64, error) {
+	if unitName == "" {
+		return quantity, nil
+	}
+	if goodsID == 0 {
+		return 0, fmt.Errorf("按单位%v录入时请指定商品", unitName)
+	}
+	unit := unitMap.GetUnit(goodsID, unitName)
+	if unit == nil {
+		return 0, fmt.Errorf("商品未定义单位%v|GoodsID:%v", unitName, goodsID)
+	}
+	return unit.ToStock(quantity), nil
+}
+
+func NormalizePurchaseGoodsUnit(unitMap model.GoodsUnitMap, goodsList []*dto.PurchaseGoodsInfo) error {
+	var err error
+	for _, goods := range goodsList {
+		if goods.Unit == "" {
+			continue
+		}
+		for _, number := range []*float64{&goods.ExpectNumber, &goods.ReceiveNumber, &goods.ReturnNumber} {
+			*number, err = ConvertToStockQuantity(unitMap, goods.GoodsID, goods.Unit, *number)
+			if err != nil {
+				return err
+			}
+		}
+		goods.Unit = ""
+	}
+	return nil
+}
+
+func NormalizeOutboundGoodsUnit(unitMap model.GoodsUnitMap, goodsList []*dto.OutboundGoodsInfo) error {
+	var err error
+	for _, goods := range goodsList {
+		goods.ExpectNumber, err = ConvertToStockQuantity(unitMap, goods.GoodsID, goods.Unit, goods.ExpectNumber)
+		if err != nil {
+			return err
+		}
+		goods.Unit = ""
+	}
+	return nil
+}
+
+func NormalizeReturnGoodsUnit(unitMap model.GoodsUnitMap, goodsList []*dto.ReturnGoodsInfo) error {
+	var err error
+	for _, goods := range goodsList {
+		goods.ReturnNumber, err = ConvertToStockQuantity(unitMap, goods.GoodsID, goods.Unit, goods.ReturnNumber)
+		if err != nil {
+			return err
+		}
+		goods.Unit = ""
+	}
+	return nil
+}
+
+func NormalizeInventoryGoodsUnit(unitMap model.GoodsUnitMap, goods *dto.InventoryGoodsNode) error {
+	var err error
+	goods.RealNumber, err = ConvertToStockQuantity(unitMap, goods.GoodsID, goods.Unit, goods.RealNumber)
+	if err != nil {
+		return err
+	}
+	goods.Unit = ""
+	return nil
+}
+
+func NormalizeRecipeUnit(unitMap model.GoodsUnitMap, recipe []*dto.RecipeItem) error {
+	var err error
+	for _, item := range recipe {
+		item.Quantity, err = ConvertToStockQuantity(unitMap, item.GoodsID, item.Unit, item.Quantity)
+		if err != nil {
+			return err
+		}
+		item.Unit = ""
+	}
+	return nil
+}
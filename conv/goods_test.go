@@ -0,0 +1,42 @@
+package conv
+
+import (
+	"math"
+	"testing"
+
+	"github.com/canteen_management/model"
+)
+
+func TestConvertToStockQuantity(t *testing.T) {
+	unitMap := model.GoodsUnitMap{
+		1: {"箱": {GoodsID: 1, UnitName: "箱", Factor: 12}},
+		2: {"克": {GoodsID: 2, UnitName: "克", Factor: 0.001}},
+	}
+	testList := []struct {
+		name     string
+		goodsID  uint32
+		unitName string
+		quantity float64
+		want     float64
+		wantErr  bool
+	}{
+		{"stock unit", 1, "", 3, 3, false},
+		{"stock unit without goods", 0, "", 3, 3, false},
+		{"purchase unit", 1, "箱", 2.5, 30, false},
+		{"recipe unit", 2, "克", 250, 0.25, false},
+		{"unit without goods", 0, "箱", 1, 0, true},
+		{"undefined unit", 1, "袋", 1, 0, true},
+		{"unknown goods", 9, "箱", 1, 0, true},
+	}
+	for _, tt := range testList {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ConvertToStockQuantity(unitMap, tt.goodsID, tt.unitName, tt.quantity)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ConvertToStockQuantity Err Mismatch|Err:%v|WantErr:%v", err, tt.wantErr)
+			}
+			if math.Abs(got-tt.want) > 0.000001 {
+				t.Fatalf("ConvertToStockQuantity Mismatch|Got:%v|Want:%v", got, tt.want)
+			}
+		})
+	}
+}
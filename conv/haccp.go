@@ -0,0 +1,154 @@
+package conv
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/canteen_management/dto"
+	"github.com/canteen_management/enum"
+	"github.com/canteen_management/model"
+)
+
+var (
+	haccpSheetHead = []string{"记录时间", "控制点", "类型", "库房/菜品类别", "读数", "控制范围", "来源", "记录人",
+		"是否超限", "处理人", "纠偏措施", "处理时间"}
+)
+
+func ConvertToHaccpPointList(pointList []*model.HaccpPoint, storeTypeMap map[uint32]*model.StorehouseType,
+	dishTypeMap map[uint32]*model.DishType) []*dto.HaccpPointInfo {
+	retList := make([]*dto.HaccpPointInfo, 0, len(pointList))
+	for _, point := range pointList {
+		info := &dto.HaccpPointInfo{
+			PointID:     point.ID,
+			Name:        point.Name,
+			PointType:   point.PointType,
+			StoreTypeID: point.StoreTypeID,
+			DishTypeID:  point.DishTypeID,
+			MinValue:    point.MinValue,
+			MaxValue:    point.MaxValue,
+			Unit:        point.Unit,
+			DeviceKey:   point.DeviceKey,
+			Enable:      point.Enable,
+		}
+		if storeType, ok := storeTypeMap[point.StoreTypeID]; ok {
+			info.StoreTypeName = storeType.StoreTypeName
+		}
+		if dishType, ok := dishTypeMap[point.DishTypeID]; ok {
+			info.DishTypeName = dishType.DishTypeName
+		}
+		retList = append(retList, info)
+	}
+	return retList
+}
+
+func ConvertFromHaccpPointInfo(info *dto.HaccpPointInfo) *model.HaccpPoint {
+	return &model.HaccpPoint{
+		ID:          info.PointID,
+		Name:        info.Name,
+		PointType:   info.PointType,
+		StoreTypeID: info.StoreTypeID,
+		DishTypeID:  info.DishTypeID,
+		MinValue:    info.MinValue,
+		MaxValue:    info.MaxValue,
+		Unit:        info.Unit,
+		DeviceKey:   info.DeviceKey,
+		Enable:      info.Enable,
+	}
+}
+
+func ConvertFromHaccpReading(req *dto.RecordHaccpReadingReq, recorder uint32) *model.HaccpReading {
+	reading := &model.HaccpReading{
+		Value:    req.Value,
+		Recorder: recorder,
+		DishID:   req.DishID,
+		Remark:   req.Remark,
+	}
+	if req.ReadAt > 0 {
+		reading.ReadAt = time.Unix(req.ReadAt, 0)
+	}
+	return reading
+}
+
+func ConvertToHaccpReadingList(readingList []*model.HaccpReading, pointMap map[uint32]*model.HaccpPoint,
+	adminMap map[uint32]*model.AdminUser) []*dto.HaccpReadingInfo {
+	retList := make([]*dto.HaccpReadingInfo, 0, len(readingList))
+	for _, reading := range readingList {
+		info := &dto.HaccpReadingInfo{
+			ReadingID:   reading.ID,
+			PointID:     reading.PointID,
+			Value:       reading.Value,
+			MinValue:    reading.MinValue,
+			MaxValue:    reading.MaxValue,
+			Source:      reading.Source,
+			DishID:      reading.DishID,
+			Remark:      reading.Remark,
+			AlertStatus: reading.AlertStatus,
+			Action:      reading.Action,
+			ReadAt:      reading.ReadAt.Unix(),
+		}
+		if point, ok := pointMap[reading.PointID]; ok {
+			info.PointName, info.PointType, info.Unit = point.Name, point.PointType, point.Unit
+		}
+		if recorder, ok := adminMap[reading.Recorder]; ok {
+			info.Recorder = recorder.NickName
+		}
+		if reading.AlertStatus == enum.HaccpAlertResolved {
+			info.HandleAt = reading.HandleAt.Unix()
+			if handler, ok := adminMap[reading.Handler]; ok {
+				info.Handler = handler.NickName
+			}
+		}
+		retList = append(retList, info)
+	}
+	return retList
+}
+
+func GenerateHaccpLogSheet(readingList []*model.HaccpReading, pointMap map[uint32]*model.HaccpPoint,
+	storeTypeMap map[uint32]*model.StorehouseType, dishTypeMap map[uint32]*model.DishType,
+	adminMap map[uint32]*model.AdminUser) [][]string {
+	rows := make([][]string, 0, len(readingList)+1)
+	rows = append(rows, haccpSheetHead)
+	for _, info := range ConvertToHaccpReadingList(readingList, pointMap, adminMap) {
+		typeName, ownerName := "", ""
+		if point, ok := pointMap[info.PointID]; ok {
+			switch point.PointType {
+			case enum.HaccpPointStorage:
+				typeName = "库房"
+				if storeType, ok := storeTypeMap[point.StoreTypeID]; ok {
+					ownerName = storeType.StoreTypeName
+				}
+			case enum.HaccpPointCooking:
+				typeName = "烹饪"
+				if dishType, ok := dishTypeMap[point.DishTypeID]; ok {
+					ownerName = dishType.DishTypeName
+				}
+			}
+		}
+		source := "手工"
+		if info.Source == enum.HaccpSourceSensor {
+			source = "传感器"
+		}
+		outOfRange, handleAt := "否", ""
+		if info.AlertStatus != enum.HaccpAlertNone {
+			outOfRange = "是"
+		}
+		if info.HandleAt > 0 {
+			handleAt = time.Unix(info.HandleAt, 0).Format("2006-01-02 15:04:05")
+		}
+		rows = append(rows, []string{
+			time.Unix(info.ReadAt, 0).Format("2006-01-02 15:04:05"),
+			info.PointName,
+			typeName,
+			ownerName,
+			fmt.Sprintf("%v%v", info.Value, info.Unit),
+			fmt.Sprintf("%v~%v", info.MinValue, info.MaxValue),
+			source,
+			info.Recorder,
+			outOfRange,
+			info.Handler,
+			info.Action,
+			handleAt,
+		})
+	}
+	return rows
+}
@@ -1,6 +1,9 @@
 package conv
 
 import (
+	"sort"
+	"time"
+
 	"github.com/canteen_management/dto"
 	"github.com/canteen_management/enum"
 	"github.com/canteen_management/model"
@@ -11,11 +14,15 @@ func ConvertToInventoryInfoList(inventoryList []*model.InventoryOrder, detailMap
 	retList := make([]*dto.InventoryOrderInfo, 0, len(inventoryList))
 	for _, inventory := range inventoryList {
 		retInfo := &dto.InventoryOrderInfo{
-			ID:        inventory.ID,
-			GoodsList: make([]*dto.InventoryGoodsNode, 0),
-			Status:    inventory.Status,
-			StartTime: inventory.CreateAt.Unix(),
-			EndTime:   inventory.FinishAt.Unix(),
+			ID:          inventory.ID,
+			PlanID:      inventory.PlanID,
+			GoodsTypeID: inventory.GoodsTypeID,
+			StoreTypeID: inventory.StoreTypeID,
+			AbcClass:    inventory.AbcClass,
+			GoodsList:   make([]*dto.InventoryGoodsNode, 0),
+			Status:      inventory.Status,
+			StartTime:   inventory.CreateAt.Unix(),
+			EndTime:     inventory.FinishAt.Unix(),
 		}
 		if creator, ok := adminMap[inventory.Creator]; ok {
 			retInfo.Creator = creator.NickName
@@ -23,6 +30,9 @@ func ConvertToInventoryInfoList(inventoryList []*model.InventoryOrder, detailMap
 		if partner, ok := adminMap[inventory.Partner]; ok {
 			retInfo.Partner = partner.NickName
 		}
+		if approver, ok := adminMap[inventory.Approver]; ok {
+			retInfo.Approver = approver.NickName
+		}
 		details, ok := detailMap[inventory.ID]
 		if !ok {
 			continue
@@ -38,14 +48,23 @@ func ConvertToInventoryInfoList(inventoryList []*model.InventoryOrder, detailMap
 					GoodsTypeID:  detail.GoodsType,
 					ExpectNumber: detail.ExpectNumber,
 				},
-				RealNumber: detail.RealNumber,
-				Tag:        detail.Tag,
-				Status:     detail.Status,
+				StoreTypeID:   detail.StoreTypeID,
+				LotID:         detail.LotID,
+				BatchNo:       detail.BatchNo,
+				RealNumber:    detail.RealNumber,
+				Reason:        detail.Reason,
+				VarianceValue: detail.VarianceValue,
+				Tag:           detail.Tag,
+				Status:        detail.Status,
 			}
 			totalCount++
 			if detail.Status == enum.InventoryNeedFix {
 				exceptionCount++
-				exceptionAmount += (detail.RealNumber - detail.ExpectNumber) * goodsMap[detail.GoodsID].Price
+				if detail.VarianceValue != 0 {
+					exceptionAmount += detail.VarianceValue
+				} else {
+					exceptionAmount += (detail.RealNumber - detail.ExpectNumber) * goodsMap[detail.GoodsID].Price
+				}
 			}
 			retInfo.GoodsList = append(retInfo.GoodsList, inventoryGoods)
 		}
@@ -61,6 +80,7 @@ func ConvertFromApplyInventory(inventory *dto.InventoryGoodsNode) *model.Invento
 	return &model.InventoryDetail{
 		ID:         inventory.ID,
 		RealNumber: inventory.RealNumber,
+		Reason:     inventory.Reason,
 		Tag:        inventory.Tag,
 		Status:     inventory.Status,
 	}
@@ -70,9 +90,13 @@ func ConvertFromApplyInventory(inventory *dto.InventoryGoodsNode) *model.Invento
 func ConvertGoodsListToInventoryNode(inventory *model.InventoryOrder, details []*model.InventoryDetail,
 	goodsMap map[uint32]*model.Goods, goodsTypes []*model.GoodsType) *dto.InventoryOrderInfo {
 	retInfo := &dto.InventoryOrderInfo{
-		ID:        inventory.ID,
-		GoodsList: make([]*dto.InventoryGoodsNode, 0),
-		Status:    inventory.Status,
+		ID:          inventory.ID,
+		PlanID:      inventory.PlanID,
+		GoodsTypeID: inventory.GoodsTypeID,
+		StoreTypeID: inventory.StoreTypeID,
+		AbcClass:    inventory.AbcClass,
+		GoodsList:   make([]*dto.InventoryGoodsNode, 0),
+		Status:      inventory.Status,
 	}
 	goodsTypeMap := make(map[uint32][]*model.InventoryDetail)
 	for _, goods := range details {
@@ -99,11 +123,16 @@ func ConvertGoodsListToInventoryNode(inventory *model.InventoryOrder, details []
 					GoodsTypeID:  detail.GoodsType,
 					ExpectNumber: detail.ExpectNumber,
 				},
-				BatchSize:  goodsMap[detail.GoodsID].BatchSize,
-				BatchUnit:  goodsMap[detail.GoodsID].BatchUnit,
-				RealNumber: detail.RealNumber,
-				Tag:        detail.Tag,
-				Status:     detail.Status,
+				StoreTypeID:   detail.StoreTypeID,
+				LotID:         detail.LotID,
+				BatchNo:       detail.BatchNo,
+				BatchSize:     goodsMap[detail.GoodsID].BatchSize,
+				BatchUnit:     goodsMap[detail.GoodsID].BatchUnit,
+				RealNumber:    detail.RealNumber,
+				Reason:        detail.Reason,
+				VarianceValue: detail.VarianceValue,
+				Tag:           detail.Tag,
+				Status:        detail.Status,
 			}
 			typeNode.Children = append(typeNode.Children, inventoryGoods)
 		}
@@ -111,3 +140,95 @@ func ConvertGoodsListToInventoryNode(inventory *model.InventoryOrder, details []
 	}
 	return retInfo
 }
+
+func ConvertToCycleCountPlanList(planList []*model.CycleCountPlan) []*dto.CycleCountPlanInfo {
+	retList := make([]*dto.CycleCountPlanInfo, 0, len(planList))
+	for _, plan := range planList {
+		retList = append(retList, &dto.CycleCountPlanInfo{
+			PlanID:       plan.ID,
+			Name:         plan.Name,
+			GoodsTypeID:  plan.GoodsTypeID,
+			StoreTypeID:  plan.StoreTypeID,
+			AbcClass:     plan.AbcClass,
+			IntervalDays: plan.IntervalDays,
+			Counter:      plan.Counter,
+			NextCountAt:  plan.NextCountAt.Unix(),
+			Enable:       plan.Enable,
+		})
+	}
+	return retList
+}
+
+func ConvertFromCycleCountPlanInfo(info *dto.CycleCountPlanInfo) *model.CycleCountPlan {
+	plan := &model.CycleCountPlan{
+		ID:           info.PlanID,
+		Name:         info.Name,
+		GoodsTypeID:  info.GoodsTypeID,
+		StoreTypeID:  info.StoreTypeID,
+		AbcClass:     info.AbcClass,
+		IntervalDays: info.IntervalDays,
+		Counter:      info.Counter,
+		Enable:       info.Enable,
+	}
+	if info.NextCountAt > 0 {
+		plan.NextCountAt = time.Unix(info.NextCountAt, 0)
+	}
+	return plan
+}
+
+func ConvertToVarianceReport(details []*model.InventoryDetail, goodsTypeMap map[uint32]*model.GoodsType) *dto.InventoryVarianceRes {
+	retData := &dto.InventoryVarianceRes{VarianceList: make([]*dto.InventoryVarianceInfo, 0)}
+	infoMap := make(map[uint64]*dto.InventoryVarianceInfo)
+	for _, detail := range details {
+		key := uint64(detail.GoodsType)<<8 | uint64(detail.Reason)
+		info, ok := infoMap[key]
+		if !ok {
+			info = &dto.InventoryVarianceInfo{GoodsTypeID: detail.GoodsType, Reason: detail.Reason}
+			if goodsType, ok := goodsTypeMap[detail.GoodsType]; ok {
+				info.GoodsTypeName = goodsType.GoodsTypeName
+			}
+			infoMap[key] = info
+			retData.VarianceList = append(retData.VarianceList, info)
+		}
+		info.LineCount++
+		if detail.VarianceValue > 0 {
+			info.GainValue += detail.VarianceValue
+			retData.TotalGainValue += detail.VarianceValue
+		} else {
+			info.LossValue -= detail.VarianceValue
+			retData.TotalLossValue -= detail.VarianceValue
+		}
+		info.NetValue += detail.VarianceValue
+	}
+	sort.Slice(retData.VarianceList, func(i, j int) bool {
+		if retData.VarianceList[i].GoodsTypeID != retData.VarianceList[j].GoodsTypeID {
+			return retData.VarianceList[i].GoodsTypeID < retData.VarianceList[j].GoodsTypeID
+		}
+		return retData.VarianceList[i].Reason < retData.VarianceList[j].Reason
+	})
+	return retData
+}
+
+func ConvertToScanInventoryNode(detail *model.InventoryDetail, goods *model.Goods) *dto.InventoryGoodsNode {
+	return &dto.InventoryGoodsNode{
+		PurchaseGoodsBase: dto.PurchaseGoodsBase{
+			ID:           detail.ID,
+			GoodsID:      detail.GoodsID,
+			Name:         goods.Name,
+			Picture:      goods.Picture,
+			GoodsTypeID:  detail.GoodsType,
+			ExpectNumber: detail.ExpectNumber,
+			Code:         goods.GetCode(),
+		},
+		StoreTypeID:   detail.StoreTypeID,
+		LotID:         detail.LotID,
+		BatchNo:       detail.BatchNo,
+		BatchSize:     goods.BatchSize,
+		BatchUnit:     goods.BatchUnit,
+		RealNumber:    detail.RealNumber,
+		Reason:        detail.Reason,
+		VarianceValue: detail.VarianceValue,
+		Tag:           detail.Tag,
+		Status:        detail.Status,
+	}
+}
@@ -2,6 +2,7 @@ package conv
 
 import (
 	"fmt"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
@@ -10,6 +11,7 @@ import (
 	"github.com/canteen_management/enum"
 	"github.com/canteen_management/logger"
 	"github.com/canteen_management/model"
+	"github.com/canteen_management/utils"
 )
 
 const (
@@ -17,7 +19,8 @@ const (
 )
 
 func ConvertMenuToOrderNode(menuDate int64, dayMenu map[uint8][]uint32, dishMap map[uint32]*model.Dish,
-	typeMap map[uint32]*model.DishType, dishQuantityMap map[string]float64, includeAll bool) []*dto.OrderNode {
+	typeMap map[uint32]*model.DishType, dishQuantityMap map[string]float64, includeAll bool,
+	excludeAllergen uint32) []*dto.OrderNode {
 	retData := make([]*dto.OrderNode, 0)
 	for mealType := enum.MealUnknown + 1; mealType < enum.MealALL; mealType++ {
 		totalDishList, ok := dayMenu[mealType]
@@ -29,35 +32,51 @@ func ConvertMenuToOrderNode(menuDate int64, dayMenu map[uint8][]uint32, dishMap
 		retMeal := &dto.OrderNode{ID: fmt.Sprintf("%v_%v", menuDate, mealType), Name: mealName}
 		dishListByType, maxTypeID := make(map[uint32][]*model.Dish), uint32(0)
 		for _, dishID := range totalDishList {
-			dishType := dishMap[dishID].DishType
+			dish := GetDish(dishMap, dishID)
+			// 已下架、已删除或不在供应季的菜品不展示，避免下单时才被拒绝
+			if !dish.IsAvailable(time.Unix(menuDate, 0)) {
+				continue
+			}
+			dishType := dish.DishType
 			if dishType > maxTypeID {
 				maxTypeID = dishType
 			}
 			if _, ok := dishListByType[dishType]; ok == false {
 				dishListByType[dishType] = make([]*model.Dish, 0)
 			}
-			dishListByType[dishType] = append(dishListByType[dishType], dishMap[dishID])
+			dishListByType[dishType] = append(dishListByType[dishType], dish)
 		}
 
 		retMeal.Children = make([]*dto.OrderNode, 0, len(dishListByType))
-		mealSelected := int32(0)
+		mealSelected, mealDishList := int32(0), make([]*model.Dish, 0, len(totalDishList))
 		for dishType := uint32(1); dishType <= maxTypeID; dishType++ {
 			dishList, ok := dishListByType[dishType]
 			if !ok {
 				continue
 			}
-			retListByType := &dto.OrderNode{ID: fmt.Sprintf("%v", dishType), Name: typeMap[dishType].DishTypeName}
+			retListByType := &dto.OrderNode{ID: fmt.Sprintf("%v", dishType), Name: GetDishTypeName(typeMap, dishType)}
 			retListByType.Children = make([]*dto.OrderNode, 0, len(dishList))
 			for index, dish := range dishList {
+				if dish.Allergen&excludeAllergen > 0 {
+					continue
+				}
+				mealDishList = append(mealDishList, dish)
 				retDish := &dto.OrderNode{ID: fmt.Sprintf("%v_%v_%v", retMeal.ID, dish.ID, index),
-					DishID: dish.ID, Name: dish.DishName, Picture: dish.Picture, Price: dish.Price}
+					DishID: dish.ID, Name: dish.DishName, Picture: dish.Picture, Price: dish.Price,
+					Nutrition: ConvertToNutritionInfo(dish.ToNutrition()), AllergenList: enum.GetAllergenList(dish.Allergen)}
 				retListByType.Children = append(retListByType.Children, retDish)
 				if quantity, ok := dishQuantityMap[retDish.ID]; (ok && quantity > 0) || includeAll {
 					mealSelected += int32(quantity)
 				}
 			}
+			if len(retListByType.Children) == 0 {
+				continue
+			}
 			retMeal.Children = append(retMeal.Children, retListByType)
 		}
+		mealNutrition, mealAllergen := SumDishNutrition(mealDishList)
+		retMeal.Nutrition = ConvertToNutritionInfo(mealNutrition)
+		retMeal.AllergenList = enum.GetAllergenList(mealAllergen)
 		retMeal.SelectedNumber = mealSelected
 		retData = append(retData, retMeal)
 	}
@@ -125,9 +144,10 @@ func ConvertToOrderInfoList(orderList []*model.OrderDao, detailMap map[uint32][]
 			orderItems := make([]*dto.ApplyItem, 0, len(details))
 			for _, detail := range details {
 				item := &dto.ApplyItem{
+					DetailID: detail.ID,
 					DishID:   detail.DishID,
-					DishName: dishMap[detail.DishID].DishName,
-					Picture:  dishMap[detail.DishID].Picture,
+					DishName: GetDish(dishMap, detail.DishID).DishName,
+					Picture:  GetDish(dishMap, detail.DishID).Picture,
 					Price:    detail.Price,
 					Quantity: detail.Quantity,
 				}
@@ -152,3 +172,157 @@ func ConvertDishID(itemID string) (uint32, error) {
 	}
 	return uint32(goodsID), nil
 }
+
+func ConvertToDishRatingList(itemList []*dto.DishRatingItem) []*model.DishRating {
+	retList := make([]*model.DishRating, 0, len(itemList))
+	for _, item := range itemList {
+		retList = append(retList, &model.DishRating{OrderDetailID: item.OrderDetailID, Score: item.Score,
+			Comment: item.Comment, Picture: item.Picture})
+	}
+	return retList
+}
+
+func ConvertToDishRatingInfoList(daoList []*model.DishRating, dishMap map[uint32]*model.Dish) []*dto.DishRatingInfo {
+	retList := make([]*dto.DishRatingInfo, 0, len(daoList))
+	for _, dao := range daoList {
+		retInfo := &dto.DishRatingInfo{ID: dao.ID, OrderID: dao.OrderID, DishID: dao.DishID, Uid: dao.Uid,
+			Score: dao.Score, Comment: dao.Comment, Picture: dao.Picture, CreateTime: dao.CreateAt.Unix()}
+		if dish, ok := dishMap[dao.DishID]; ok {
+			retInfo.DishName = dish.DishName
+		}
+		retList = append(retList, retInfo)
+	}
+	return retList
+}
+
+func ConvertToDishRatingSummaryList(summaryList []*model.DishRatingSummary,
+	dishMap map[uint32]*model.Dish) []*dto.DishRatingSummaryInfo {
+	retList := make([]*dto.DishRatingSummaryInfo, 0, len(summaryList))
+	for _, summary := range summaryList {
+		retInfo := &dto.DishRatingSummaryInfo{DishID: summary.DishID, RatingNumber: summary.RatingNumber,
+			AverageScore: summary.AverageScore}
+		if dish, ok := dishMap[summary.DishID]; ok {
+			retInfo.DishName = dish.DishName
+			retInfo.DishType = dish.DishType
+		}
+		retList = append(retList, retInfo)
+	}
+	return retList
+}
+
+func ConvertFromWasteItems(items []*dto.WasteItem) []*model.WasteLog {
+	retList := make([]*model.WasteLog, 0, len(items))
+	for _, item := range items {
+		retList = append(retList, &model.WasteLog{WasteType: item.WasteType, DishID: item.DishID, GoodsID: item.GoodsID,
+			StoreTypeID: item.StoreTypeID, Quantity: item.Quantity, Weight: item.Weight, Remark: item.Remark})
+	}
+	return retList
+}
+
+func ConvertToWasteLogList(wasteList []*model.WasteLog, dishMap map[uint32]*model.Dish,
+	goodsMap map[uint32]*model.Goods) []*dto.WasteLogInfo {
+	retList := make([]*dto.WasteLogInfo, 0, len(wasteList))
+	for _, waste := range wasteList {
+		retInfo := &dto.WasteLogInfo{
+			ID:          waste.ID,
+			MealDate:    waste.MealDate.Unix(),
+			MealType:    waste.MealType,
+			WasteType:   waste.WasteType,
+			DishID:      waste.DishID,
+			GoodsID:     waste.GoodsID,
+			StoreTypeID: waste.StoreTypeID,
+			Quantity:    waste.Quantity,
+			Weight:      waste.Weight,
+			Value:       waste.Value,
+			Remark:      waste.Remark,
+			CreateAt:    waste.CreateAt.Unix(),
+		}
+		if waste.DishID > 0 {
+			retInfo.DishName = GetDish(dishMap, waste.DishID).DishName
+		}
+		if goods, ok := goodsMap[waste.GoodsID]; ok {
+			retInfo.GoodsName = goods.Name
+		}
+		retList = append(retList, retInfo)
+	}
+	return retList
+}
+
+// ConvertToWasteReport 按就餐日期、餐次、菜品对比点餐份数与浪费份数，备餐损耗按商品汇总
+func ConvertToWasteReport(orderList []*model.OrderDao, detailMap map[uint32][]*model.OrderDetail,
+	wasteList []*model.WasteLog, dishMap map[uint32]*model.Dish, goodsMap map[uint32]*model.Goods) *dto.WasteReportRes {
+	retData := &dto.WasteReportRes{DishWasteList: make([]*dto.DishWasteInfo, 0),
+		PrepWasteList: make([]*dto.PrepWasteInfo, 0)}
+	dishWasteMap, prepWasteMap := make(map[string]*dto.DishWasteInfo), make(map[string]*dto.PrepWasteInfo)
+	getDishWaste := func(mealDate int64, mealType uint8, dishID uint32) *dto.DishWasteInfo {
+		key := fmt.Sprintf("%v_%v_%v", mealDate, mealType, dishID)
+		info, ok := dishWasteMap[key]
+		if !ok {
+			info = &dto.DishWasteInfo{MealDate: mealDate, MealType: mealType, DishID: dishID,
+				DishName: GetDish(dishMap, dishID).DishName}
+			dishWasteMap[key] = info
+			retData.DishWasteList = append(retData.DishWasteList, info)
+		}
+		return info
+	}
+
+	for _, order := range orderList {
+		if order.Status != enum.OrderPaid && order.Status != enum.OrderReady && order.Status != enum.OrderFinish {
+			continue
+		}
+		mealDate := utils.GetZeroTime(order.OrderDate.Unix())
+		for _, detail := range detailMap[order.ID] {
+			info := getDishWaste(mealDate, order.MealType, detail.DishID)
+			info.OrderQuantity += detail.Quantity
+			retData.OrderQuantity += detail.Quantity
+		}
+	}
+	for _, waste := range wasteList {
+		mealDate := waste.MealDate.Unix()
+		switch waste.WasteType {
+		case enum.WastePlate, enum.WasteUnserved:
+			info := getDishWaste(mealDate, waste.MealType, waste.DishID)
+			if waste.WasteType == enum.WastePlate {
+				info.PlateWaste += waste.Quantity
+			} else {
+				info.UnservedWaste += waste.Quantity
+			}
+			info.WasteWeight += waste.Weight
+			retData.WasteQuantity += waste.Quantity
+		case enum.WastePrep:
+			key := fmt.Sprintf("%v_%v_%v", mealDate, waste.MealType, waste.GoodsID)
+			info, ok := prepWasteMap[key]
+			if !ok {
+				info = &dto.PrepWasteInfo{MealDate: mealDate, MealType: waste.MealType, GoodsID: waste.GoodsID}
+				if goods, ok := goodsMap[waste.GoodsID]; ok {
+					info.GoodsName = goods.Name
+				}
+				prepWasteMap[key] = info
+				retData.PrepWasteList = append(retData.PrepWasteList, info)
+			}
+			info.Quantity += waste.Quantity
+			info.Value += waste.Value
+			retData.PrepWasteValue += waste.Value
+		}
+	}
+
+	for _, info := range retData.DishWasteList {
+		if info.OrderQuantity > 0 {
+			info.WasteRate = (info.PlateWaste + info.UnservedWaste) / float64(info.OrderQuantity)
+		}
+	}
+	if retData.OrderQuantity > 0 {
+		retData.WasteRate = retData.WasteQuantity / float64(retData.OrderQuantity)
+	}
+	sort.Slice(retData.DishWasteList, func(i, j int) bool {
+		left, right := retData.DishWasteList[i], retData.DishWasteList[j]
+		if left.MealDate != right.MealDate {
+			return left.MealDate < right.MealDate
+		}
+		if left.MealType != right.MealType {
+			return left.MealType < right.MealType
+		}
+		return left.DishID < right.DishID
+	})
+	return retData
+}
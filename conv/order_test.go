@@ -0,0 +1,29 @@
+package conv
+
+import (
+	"testing"
+	"time"
+
+	"github.com/canteen_management/enum"
+	"github.com/canteen_management/model"
+)
+
+func TestConvertMenuToOrderNodeSkipUnavailable(t *testing.T) {
+	menuDate := time.Date(2026, 7, 1, 0, 0, 0, 0, time.Local).Unix()
+	dishMap := map[uint32]*model.Dish{
+		1: {ID: 1, DishName: "active", DishType: 1},
+		2: {ID: 2, DishName: "retired", DishType: 1, Status: enum.DishRetired},
+		3: {ID: 3, DishName: "deleted", DishType: 1, IsDelete: true},
+		4: {ID: 4, DishName: "winter", DishType: 1, SeasonStart: 1101, SeasonEnd: 228},
+	}
+	dayMenu := map[uint8][]uint32{enum.MealLunch: {1, 2, 3, 4, 5}}
+
+	nodeList := ConvertMenuToOrderNode(menuDate, dayMenu, dishMap, nil, nil, true, 0)
+	if len(nodeList) != 1 || len(nodeList[0].Children) != 1 {
+		t.Fatalf("ConvertMenuToOrderNode Node Mismatch|NodeList:%+v", nodeList)
+	}
+	dishNodeList := nodeList[0].Children[0].Children
+	if len(dishNodeList) != 1 || dishNodeList[0].DishID != 1 {
+		t.Fatalf("ConvertMenuToOrderNode Dish Mismatch|DishList:%+v", dishNodeList)
+	}
+}
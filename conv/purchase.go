@@ -1,6 +1,9 @@
 package conv
 
 import (
+	"sort"
+	"time"
+
 	"github.com/canteen_management/dto"
 	"github.com/canteen_management/enum"
 	"github.com/canteen_management/model"
@@ -28,6 +31,34 @@ func ConvertFromSupplierInfo(supplierInfo *dto.SupplierInfo) *model.Supplier {
 		IDNumber: supplierInfo.IDNumber, Location: supplierInfo.Location}
 }
 
+func ConvertToSourcingList(sourcingList []*model.SupplierSourcing, supplierMap map[uint32]*model.Supplier,
+	goodsMap map[uint32]*model.Goods, goodsTypeMap map[uint32]*model.GoodsType) []*dto.SourcingInfo {
+	retList := make([]*dto.SourcingInfo, 0, len(sourcingList))
+	for _, sourcing := range sourcingList {
+		retInfo := &dto.SourcingInfo{
+			SourcingID:  sourcing.ID,
+			SupplierID:  sourcing.SupplierID,
+			GoodsTypeID: sourcing.GoodsType,
+			GoodsID:     sourcing.GoodsID,
+		}
+		if supplier, ok := supplierMap[sourcing.SupplierID]; ok {
+			retInfo.SupplierName = supplier.Name
+		}
+		if goods, ok := goodsMap[sourcing.GoodsID]; ok && sourcing.GoodsID > 0 {
+			retInfo.TargetName = goods.Name
+		} else if goodsType, ok := goodsTypeMap[sourcing.GoodsType]; ok {
+			retInfo.TargetName = goodsType.GoodsTypeName
+		}
+		retList = append(retList, retInfo)
+	}
+	return retList
+}
+
+func ConvertFromSourcingInfo(info *dto.SourcingInfo) *model.SupplierSourcing {
+	return &model.SupplierSourcing{ID: info.SourcingID, SupplierID: info.SupplierID, GoodsType: info.GoodsTypeID,
+		GoodsID: info.GoodsID}
+}
+
 func ConvertFromApplyPurchase(goodsList []*dto.PurchaseGoodsInfo, goodsMap map[uint32]*model.Goods) []*model.PurchaseDetail {
 	detailList := make([]*model.PurchaseDetail, 0, len(goodsList))
 	for _, purchaseGoods := range goodsList {
@@ -48,18 +79,35 @@ func ConvertFromApplyPurchase(goodsList []*dto.PurchaseGoodsInfo, goodsMap map[u
 	return detailList
 }
 
+func ConvertFromReceivePurchase(goodsList []*dto.PurchaseGoodsInfo) []*model.ReceiptItem {
+	itemList := make([]*model.ReceiptItem, 0, len(goodsList))
+	for _, purchaseGoods := range goodsList {
+		itemList = append(itemList, &model.ReceiptItem{
+			DetailID:       purchaseGoods.ID,
+			GoodsID:        purchaseGoods.GoodsID,
+			ReceiveNumber:  purchaseGoods.ReceiveNumber,
+			BatchNo:        purchaseGoods.BatchNo,
+			ProductionDate: purchaseGoods.ProductionDate,
+			ExpiryDate:     purchaseGoods.ExpiryDate,
+		})
+	}
+	return itemList
+}
+
 func ConvertToPurchaseInfoList(purchaseList []*model.PurchaseOrder, detailMap map[uint32][]*model.PurchaseDetail,
 	goodsMap map[uint32]*model.Goods, supplierMap map[uint32]*model.Supplier, adminMap map[uint32]*model.AdminUser) []*dto.PurchaseOrderInfo {
 	retList := make([]*dto.PurchaseOrderInfo, 0, len(purchaseList))
 	for _, purchase := range purchaseList {
 		retInfo := &dto.PurchaseOrderInfo{
 			ID:            purchase.ID,
+			ParentID:      purchase.ParentID,
 			Supplier:      purchase.Supplier,
 			SupplierName:  supplierMap[purchase.Supplier].Name,
 			GoodsList:     make([]*dto.PurchaseGoodsInfo, 0),
 			TotalAmount:   purchase.TotalAmount,
 			PaymentAmount: purchase.PayAmount,
 			Status:        uint8(purchase.Status),
+			ApproveStep:   purchase.ApproveStep,
 			CreateTime:    purchase.CreateAt.Unix(),
 			ReceiveTime:   purchase.ReceiveAt.Unix(),
 			SignPicture:   purchase.SignPicture,
@@ -92,6 +140,7 @@ func ConvertToPurchaseInfoList(purchaseList []*model.PurchaseOrder, detailMap ma
 					ExpectNumber: detail.ExpectNumber,
 				},
 				ReceiveNumber: detail.ReceiveNumber,
+				ReturnNumber:  detail.ReturnNumber,
 				Price:         detail.Price,
 			}
 			retInfo.GoodsList = append(retInfo.GoodsList, purchaseGoods)
@@ -101,13 +150,105 @@ func ConvertToPurchaseInfoList(purchaseList []*model.PurchaseOrder, detailMap ma
 	return retList
 }
 
+func ConvertToPurchaseReceiptList(receiptList []*model.PurchaseReceipt, goodsMap map[uint32]*model.Goods,
+	adminMap map[uint32]*model.AdminUser) []*dto.PurchaseReceiptInfo {
+	retList := make([]*dto.PurchaseReceiptInfo, 0, len(receiptList))
+	for _, receipt := range receiptList {
+		retInfo := &dto.PurchaseReceiptInfo{
+			ReceiptID:   receipt.ID,
+			SignPicture: receipt.SignPicture,
+			Amount:      receipt.Amount,
+			ReceiveTime: receipt.CreateAt.Unix(),
+			GoodsList:   make([]*dto.PurchaseGoodsInfo, 0),
+		}
+		if receiver, ok := adminMap[receipt.Receiver]; ok {
+			retInfo.Receiver = receiver.NickName
+		}
+		for _, item := range receipt.ToItems() {
+			receiptGoods := &dto.PurchaseGoodsInfo{
+				PurchaseGoodsBase: dto.PurchaseGoodsBase{
+					ID:      item.DetailID,
+					GoodsID: item.GoodsID,
+				},
+				ReceiveNumber:  item.ReceiveNumber,
+				Price:          item.Price,
+				BatchNo:        item.BatchNo,
+				ProductionDate: item.ProductionDate,
+				ExpiryDate:     item.ExpiryDate,
+			}
+			if goods, ok := goodsMap[item.GoodsID]; ok {
+				receiptGoods.Name = goods.Name
+				receiptGoods.Picture = goods.Picture
+				receiptGoods.GoodsTypeID = goods.GoodsTypeID
+			}
+			retInfo.GoodsList = append(retInfo.GoodsList, receiptGoods)
+		}
+		retList = append(retList, retInfo)
+	}
+	return retList
+}
+
+func ConvertToPurchaseReturnList(returnList []*model.PurchaseReturn, goodsMap map[uint32]*model.Goods,
+	supplierMap map[uint32]*model.Supplier, adminMap map[uint32]*model.AdminUser) []*dto.PurchaseReturnInfo {
+	retList := make([]*dto.PurchaseReturnInfo, 0, len(returnList))
+	for _, purchaseReturn := range returnList {
+		retInfo := &dto.PurchaseReturnInfo{
+			ReturnID:   purchaseReturn.ID,
+			PurchaseID: purchaseReturn.PurchaseID,
+			Supplier:   purchaseReturn.Supplier,
+			Reason:     purchaseReturn.Reason,
+			ReasonName: enum.GetReturnReasonName(purchaseReturn.Reason),
+			Remark:     purchaseReturn.Remark,
+			Pictures:   purchaseReturn.ToPictures(),
+			Amount:     purchaseReturn.Amount,
+			CreateTime: purchaseReturn.CreateAt.Unix(),
+			GoodsList:  make([]*dto.ReturnGoodsInfo, 0),
+		}
+		if supplier, ok := supplierMap[purchaseReturn.Supplier]; ok {
+			retInfo.SupplierName = supplier.Name
+		}
+		if creator, ok := adminMap[purchaseReturn.Creator]; ok {
+			retInfo.Creator = creator.NickName
+		}
+		for _, item := range purchaseReturn.ToItems() {
+			returnGoods := &dto.ReturnGoodsInfo{
+				ID:           item.DetailID,
+				GoodsID:      item.GoodsID,
+				ReturnNumber: item.ReturnNumber,
+				Price:        item.Price,
+			}
+			if goods, ok := goodsMap[item.GoodsID]; ok {
+				returnGoods.Name = goods.Name
+				returnGoods.Picture = goods.Picture
+			}
+			retInfo.GoodsList = append(retInfo.GoodsList, returnGoods)
+		}
+		retList = append(retList, retInfo)
+	}
+	return retList
+}
+
+func ConvertFromReturnGoods(goodsList []*dto.ReturnGoodsInfo) []*model.ReturnItem {
+	retList := make([]*model.ReturnItem, 0, len(goodsList))
+	for _, goods := range goodsList {
+		retList = append(retList, &model.ReturnItem{DetailID: goods.ID, GoodsID: goods.GoodsID,
+			ReturnNumber: goods.ReturnNumber})
+	}
+	return retList
+}
+
 func ConvertFromApplyOutbound(goodsList []*dto.OutboundGoodsInfo, goodsMap map[uint32]*model.Goods) []*model.OutboundDetail {
-	detailList := make([]*model.OutboundDetail, 0, len(goodsList))
+	detailList, detailMap := make([]*model.OutboundDetail, 0, len(goodsList)), make(map[uint32]*model.OutboundDetail)
 	for _, outboundGoods := range goodsList {
 		goods, ok := goodsMap[outboundGoods.GoodsID]
 		if ok == false {
 			continue
 		}
+		// 扫码出库时同一商品会多次提交，合并为一条明细
+		if detail, ok := detailMap[goods.ID]; ok {
+			detail.OutNumber += outboundGoods.ExpectNumber
+			continue
+		}
 		detail := &model.OutboundDetail{
 			ID:        outboundGoods.ID,
 			GoodsID:   goods.ID,
@@ -115,6 +256,7 @@ func ConvertFromApplyOutbound(goodsList []*dto.OutboundGoodsInfo, goodsMap map[u
 			OutNumber: outboundGoods.ExpectNumber,
 			Price:     goods.Price,
 		}
+		detailMap[goods.ID] = detail
 		detailList = append(detailList, detail)
 	}
 	return detailList
@@ -126,8 +268,10 @@ func ConvertToOutboundInfoList(outboundList []*model.OutboundOrder, detailMap ma
 	for _, outbound := range outboundList {
 		retInfo := &dto.OutboundOrderInfo{
 			ID:          outbound.ID,
+			StoreTypeID: outbound.StoreTypeID,
 			GoodsList:   make([]*dto.OutboundGoodsInfo, 0),
 			TotalAmount: outbound.TotalAmount,
+			CostAmount:  outbound.CostAmount,
 			CreateTime:  outbound.CreateAt.Unix(),
 			Status:      outbound.Status,
 		}
@@ -167,3 +311,264 @@ func ConvertToOutboundInfoList(outboundList []*model.OutboundOrder, detailMap ma
 	}
 	return retList
 }
+
+func ConvertToQuotationInfo(quotation *model.Quotation, items []*model.QuotationItem, goodsMap map[uint32]*model.Goods,
+	supplierMap map[uint32]*model.Supplier, adminMap map[uint32]*model.AdminUser) *dto.QuotationInfo {
+	retInfo := &dto.QuotationInfo{
+		QuotationID: quotation.ID,
+		Title:       quotation.Title,
+		Status:      quotation.Status,
+		Deadline:    quotation.Deadline.Unix(),
+		CreateTime:  quotation.CreateAt.Unix(),
+		GoodsList:   make([]*dto.QuotationGoodsInfo, 0),
+	}
+	if quotation.Status != enum.QuotationOpen {
+		retInfo.CloseTime = quotation.CloseAt.Unix()
+	}
+	if creator, ok := adminMap[quotation.Creator]; ok {
+		retInfo.Creator = creator.NickName
+	}
+
+	goodsInfoMap := make(map[uint32]*dto.QuotationGoodsInfo)
+	for _, goodsID := range quotation.ToGoodsList() {
+		goodsInfo := &dto.QuotationGoodsInfo{GoodsID: goodsID, QuoteList: make([]*dto.QuoteInfo, 0)}
+		if goods, ok := goodsMap[goodsID]; ok {
+			goodsInfo.GoodsName = goods.Name
+			goodsInfo.Picture = goods.Picture
+			goodsInfo.CurrentPrice = goods.Price
+		}
+		goodsInfoMap[goodsID] = goodsInfo
+		retInfo.GoodsList = append(retInfo.GoodsList, goodsInfo)
+	}
+	for _, item := range items {
+		goodsInfo, ok := goodsInfoMap[item.GoodsID]
+		if !ok {
+			continue
+		}
+		quote := &dto.QuoteInfo{SupplierID: item.SupplierID, Price: item.Price, Remark: item.Remark,
+			IsAward: item.IsAward}
+		if supplier, ok := supplierMap[item.SupplierID]; ok {
+			quote.SupplierName = supplier.Name
+		}
+		if len(goodsInfo.QuoteList) == 0 || item.Price < goodsInfo.LowestPrice {
+			goodsInfo.LowestPrice = item.Price
+		}
+		goodsInfo.QuoteList = append(goodsInfo.QuoteList, quote)
+	}
+	for _, goodsInfo := range retInfo.GoodsList {
+		for _, quote := range goodsInfo.QuoteList {
+			quote.IsLowest = quote.Price == goodsInfo.LowestPrice
+		}
+	}
+	return retInfo
+}
+
+func ConvertToQuotationInfoList(quotationList []*model.Quotation, itemMap map[uint32][]*model.QuotationItem,
+	goodsMap map[uint32]*model.Goods, supplierMap map[uint32]*model.Supplier,
+	adminMap map[uint32]*model.AdminUser) []*dto.QuotationInfo {
+	retList := make([]*dto.QuotationInfo, 0, len(quotationList))
+	for _, quotation := range quotationList {
+		retList = append(retList, ConvertToQuotationInfo(quotation, itemMap[quotation.ID], goodsMap, supplierMap, adminMap))
+	}
+	return retList
+}
+
+func ConvertFromQuoteItems(quoteList []*dto.QuoteItem) []*model.QuotationItem {
+	retList := make([]*model.QuotationItem, 0, len(quoteList))
+	for _, quote := range quoteList {
+		retList = append(retList, &model.QuotationItem{GoodsID: quote.GoodsID, Price: quote.Price, Remark: quote.Remark})
+	}
+	return retList
+}
+
+func ConvertToStatementInfo(statement *model.SupplierStatement, supplierMap map[uint32]*model.Supplier,
+	adminMap map[uint32]*model.AdminUser) *dto.StatementInfo {
+	retInfo := &dto.StatementInfo{
+		StatementID:   statement.ID,
+		SupplierID:    statement.SupplierID,
+		PeriodStart:   statement.PeriodStart.Unix(),
+		PeriodEnd:     statement.PeriodEnd.Unix(),
+		OrderCount:    statement.OrderCount,
+		TotalAmount:   statement.TotalAmount,
+		PaidAmount:    statement.PaidAmount,
+		Outstanding:   statement.GetOutstanding(),
+		Status:        statement.Status,
+		DisputeReason: statement.DisputeReason,
+		CreateTime:    statement.CreateAt.Unix(),
+	}
+	if supplier, ok := supplierMap[statement.SupplierID]; ok {
+		retInfo.SupplierName = supplier.Name
+	}
+	if creator, ok := adminMap[statement.Creator]; ok {
+		retInfo.Creator = creator.NickName
+	}
+	return retInfo
+}
+
+func ConvertToStatementList(statementList []*model.SupplierStatement, supplierMap map[uint32]*model.Supplier,
+	adminMap map[uint32]*model.AdminUser) []*dto.StatementInfo {
+	retList := make([]*dto.StatementInfo, 0, len(statementList))
+	for _, statement := range statementList {
+		retList = append(retList, ConvertToStatementInfo(statement, supplierMap, adminMap))
+	}
+	return retList
+}
+
+func ConvertToStatementOrderList(orderList []*model.PurchaseOrder) []*dto.StatementOrderInfo {
+	retList := make([]*dto.StatementOrderInfo, 0, len(orderList))
+	for _, order := range orderList {
+		retList = append(retList, &dto.StatementOrderInfo{
+			PurchaseID:  order.ID,
+			TotalAmount: order.TotalAmount,
+			PayAmount:   order.PayAmount,
+			ReceiveTime: order.ReceiveAt.Unix(),
+		})
+	}
+	return retList
+}
+
+func ConvertToPaymentList(paymentList []*model.SupplierPayment, adminMap map[uint32]*model.AdminUser) []*dto.PaymentInfo {
+	retList := make([]*dto.PaymentInfo, 0, len(paymentList))
+	for _, payment := range paymentList {
+		retInfo := &dto.PaymentInfo{
+			PaymentID: payment.ID,
+			Amount:    payment.Amount,
+			Voucher:   payment.Voucher,
+			Remark:    payment.Remark,
+			PayTime:   payment.PayAt.Unix(),
+		}
+		if operator, ok := adminMap[payment.Operator]; ok {
+			retInfo.Operator = operator.NickName
+		}
+		retList = append(retList, retInfo)
+	}
+	return retList
+}
+
+func ConvertToPayableBalanceList(balanceMap map[uint32]*model.PayableBalance,
+	supplierMap map[uint32]*model.Supplier) ([]*dto.PayableBalanceInfo, float64) {
+	retList, totalOutstanding := make([]*dto.PayableBalanceInfo, 0, len(balanceMap)), 0.0
+	for _, balance := range balanceMap {
+		retInfo := &dto.PayableBalanceInfo{
+			SupplierID:     balance.SupplierID,
+			UnbilledAmount: balance.UnbilledAmount,
+			BilledAmount:   balance.BilledAmount,
+			PaidAmount:     balance.PaidAmount,
+			Outstanding:    balance.GetOutstanding(),
+			StatementCount: balance.StatementCount,
+			DisputedCount:  balance.DisputedCount,
+		}
+		if supplier, ok := supplierMap[balance.SupplierID]; ok {
+			retInfo.SupplierName = supplier.Name
+		}
+		totalOutstanding += retInfo.Outstanding
+		retList = append(retList, retInfo)
+	}
+	sort.Slice(retList, func(i, j int) bool {
+		return retList[i].Outstanding > retList[j].Outstanding
+	})
+	return retList, totalOutstanding
+}
+
+func ConvertToSupplierScoreList(scoreList []*model.SupplierScore, supplierMap map[uint32]*model.Supplier) []*dto.SupplierScoreInfo {
+	retList := make([]*dto.SupplierScoreInfo, 0, len(scoreList))
+	for _, score := range scoreList {
+		retInfo := &dto.SupplierScoreInfo{
+			SupplierID:       score.SupplierID,
+			OrderCount:       score.OrderCount,
+			FinishCount:      score.FinishCount,
+			AcceptHours:      score.AcceptHours,
+			LeadHours:        score.LeadHours,
+			OnTimeRate:       score.OnTimeRate,
+			QuantityAccuracy: score.QuantityAccuracy,
+			ReturnRate:       score.ReturnRate,
+			PriceChangeRate:  score.PriceChangeRate,
+			Score:            score.Score,
+			Rank:             score.Rank,
+		}
+		if supplier, ok := supplierMap[score.SupplierID]; ok {
+			retInfo.SupplierName = supplier.Name
+		}
+		retList = append(retList, retInfo)
+	}
+	return retList
+}
+
+func ConvertToQualificationList(qualificationList []*model.SupplierQualification,
+	supplierMap map[uint32]*model.Supplier) []*dto.QualificationInfo {
+	retList := make([]*dto.QualificationInfo, 0, len(qualificationList))
+	now := time.Now()
+	for _, qualification := range qualificationList {
+		retInfo := &dto.QualificationInfo{
+			QualificationID: qualification.ID,
+			SupplierID:      qualification.SupplierID,
+			DocType:         qualification.DocType,
+			DocTypeName:     enum.GetQualificationName(qualification.DocType),
+			DocNumber:       qualification.DocNumber,
+			FileUrl:         qualification.FileUrl,
+			IssueTime:       qualification.IssueAt.Unix(),
+			ExpireTime:      qualification.ExpireAt.Unix(),
+			Remark:          qualification.Remark,
+			IsMandatory:     enum.IsMandatoryQualification(qualification.DocType),
+			RemainDays:      int32(qualification.ExpireAt.Sub(now).Hours() / 24),
+		}
+		if supplier, ok := supplierMap[qualification.SupplierID]; ok {
+			retInfo.SupplierName = supplier.Name
+		}
+		retList = append(retList, retInfo)
+	}
+	return retList
+}
+
+func ConvertFromQualificationInfo(info *dto.QualificationInfo) *model.SupplierQualification {
+	return &model.SupplierQualification{
+		ID:         info.QualificationID,
+		SupplierID: info.SupplierID,
+		DocType:    info.DocType,
+		DocNumber:  info.DocNumber,
+		FileUrl:    info.FileUrl,
+		IssueAt:    time.Unix(info.IssueTime, 0),
+		ExpireAt:   time.Unix(info.ExpireTime, 0),
+		Remark:     info.Remark,
+	}
+}
+
+func ConvertToApprovalList(approvalList []*model.PurchaseApproval, adminMap map[uint32]*model.AdminUser) []*dto.ApprovalInfo {
+	retList := make([]*dto.ApprovalInfo, 0, len(approvalList))
+	for _, approval := range approvalList {
+		retInfo := &dto.ApprovalInfo{
+			ApprovalID:  approval.ID,
+			Step:        approval.Step,
+			Pass:        approval.Pass,
+			Comment:     approval.Comment,
+			ApproveTime: approval.CreateAt.Unix(),
+		}
+		if approver, ok := adminMap[approval.Approver]; ok {
+			retInfo.Approver = approver.NickName
+		}
+		retList = append(retList, retInfo)
+	}
+	return retList
+}
+
+func ConvertToApprovalChainList(chainList []*model.ApprovalChain) []*dto.ApprovalChainInfo {
+	retList := make([]*dto.ApprovalChainInfo, 0, len(chainList))
+	for _, chain := range chainList {
+		retList = append(retList, &dto.ApprovalChainInfo{
+			ChainID:   chain.ID,
+			Step:      chain.Step,
+			MinAmount: chain.MinAmount,
+			Role:      chain.Role,
+		})
+	}
+	return retList
+}
+
+func ConvertFromApprovalChainInfo(info *dto.ApprovalChainInfo) *model.ApprovalChain {
+	return &model.ApprovalChain{
+		ID:        info.ChainID,
+		Step:      info.Step,
+		MinAmount: info.MinAmount,
+		Role:      info.Role,
+	}
+}
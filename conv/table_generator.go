@@ -20,12 +20,20 @@ const (
 	IndexMenuTypeName = "MenuTypeName"
 	IndexDish         = "Dish"
 	IndexDishType     = "DishType"
+	IndexNutrition    = "Nutrition"
+	IndexStatus       = "Status"
 
 	IndexDelimiter = "_"
 
 	TableGeneratorLogTag = "TableGenerator"
 
 	DayRowFixed = 4
+
+	SheetMealColumn     = "餐次"
+	SheetDishTypeColumn = "菜品类型"
+	NutritionColumnName = "营养合计"
+
+	sheetDayColumnStart = 2
 )
 
 var (
@@ -48,6 +56,24 @@ func GenerateMealMainDishTypeIndex(mealType enum.MealType, mainTypeID uint32, in
 	return fmt.Sprintf("%v%v%v%v%v", enum.GetMealKey(mealType), IndexDelimiter, mainTypeID, IndexDelimiter, index)
 }
 
+func GenerateMealNutritionIndex(mealType enum.MealType) string {
+	return enum.GetMealKey(mealType) + IndexDelimiter + IndexNutrition
+}
+
+func GenerateNutritionValue(nutrition *model.Nutrition, allergen uint32) string {
+	value := fmt.Sprintf("能量:%.1fkcal\n蛋白质:%.1fg\n脂肪:%.1fg\n碳水:%.1fg\n钠:%.1fmg",
+		nutrition.Energy, nutrition.Protein, nutrition.Fat, nutrition.Carbs, nutrition.Sodium)
+	allergenList := enum.GetAllergenList(allergen)
+	if len(allergenList) == 0 {
+		return value
+	}
+	allergenStr := ""
+	for _, allergenType := range allergenList {
+		allergenStr += "," + enum.GetAllergenName(allergenType)
+	}
+	return value + "\n过敏原:" + allergenStr[1:]
+}
+
 func GenerateWeekDayValue(date time.Time) string {
 	return fmt.Sprintf("%v\n(%v月%v日)", weekDays[(date.Weekday()+6)%7], int(date.Month()), date.Day())
 }
@@ -77,7 +103,7 @@ func GenerateStaffMenuListTableData(menuList []*model.Menu, dishIDMap map[uint32
 		for mealType, dishList := range dishMap {
 			dishStr := ""
 			for _, dishID := range dishList {
-				dishStr += "," + dishIDMap[dishID].DishName
+				dishStr += "," + GetDish(dishIDMap, dishID).DishName
 			}
 			if len(dishList) == 0 {
 				dishStr = ","
@@ -118,7 +144,7 @@ func GenerateStaffDetailTableData(menu *model.Menu, dishIDMap map[uint32]*model.
 		}
 
 		for _, dishID := range dishList {
-			dish := dishIDMap[dishID]
+			dish := GetDish(dishIDMap, dishID)
 			if _, ok := dishByType[dish.DishType]; ok == false {
 				dishByType[dish.DishType] = make([]*model.Dish, 0)
 			}
@@ -144,7 +170,7 @@ func GenerateStaffDetailTableData(menu *model.Menu, dishIDMap map[uint32]*model.
 				rows[curIndex][enum.GetMealKey(mealType)] = &dto.TableRowColumnInfo{
 					ID: uint32(mealType), Value: fmt.Sprintf("%v(%v)", enum.GetMealName(mealType), mealDishNumber[mealType])}
 				rows[curIndex][GenerateDishTypeIndex(mealType)] = &dto.TableRowColumnInfo{
-					ID: dishType, Value: fmt.Sprintf("%v(%v)", dishTypeMap[dishType].DishTypeName, len(dishList))}
+					ID: dishType, Value: fmt.Sprintf("%v(%v)", GetDishTypeName(dishTypeMap, dishType), len(dishList))}
 				rows[curIndex][GenerateDishIndex(mealType)] = &dto.TableRowColumnInfo{
 					ID: dish.ID, Value: dish.DishName}
 				extraTimes := 0
@@ -227,7 +253,7 @@ func GenerateMenuTypeListTableData(menuTypeList []*model.MenuType, dishTypeMap m
 		for mealType, mealContent := range menuContentMap {
 			content := ""
 			for dishType, dishNumber := range mealContent {
-				content += fmt.Sprintf(",%v:%v", dishTypeMap[dishType].DishTypeName, dishNumber)
+				content += fmt.Sprintf(",%v:%v", GetDishTypeName(dishTypeMap, dishType), dishNumber)
 			}
 			if content == "" {
 				content = ","
@@ -253,8 +279,8 @@ func GenerateMenuTypeDetailTableHead(menuType *model.MenuType, dishTypeMap map[u
 		mealHead := &dto.TableColumnInfo{Name: enum.GetMealName(mealType), DataIndex: enum.GetMealKey(mealType),
 			Hide: false, Children: make([]*dto.TableColumnInfo, 0)}
 		for dishType := range mealContent {
-			dishHead := &dto.TableColumnInfo{Name: dishTypeMap[dishType].DishTypeName, MergeRow: true,
-				DataIndex: enum.GetMealKey(mealType) + fmt.Sprintf("_%v", dishTypeMap[dishType].ID), Hide: false}
+			dishHead := &dto.TableColumnInfo{Name: GetDishTypeName(dishTypeMap, dishType), MergeRow: true,
+				DataIndex: enum.GetMealKey(mealType) + fmt.Sprintf("_%v", dishType), Hide: false}
 			mealHead.Children = append(mealHead.Children, dishHead)
 		}
 		head = append(head, mealHead)
@@ -287,8 +313,8 @@ func GenerateMenuTypeDetailTableData(menuType *model.MenuType, dishTypeMap map[u
 			if ok == false {
 				continue
 			}
-			row[enum.GetMealKey(mealType)+fmt.Sprintf("_%v", dishTypeMap[dishType].ID)] =
-				&dto.TableRowColumnInfo{ID: dishTypeMap[dishType].ID, Value: fmt.Sprintf("%v", dishNumber)}
+			row[enum.GetMealKey(mealType)+fmt.Sprintf("_%v", dishType)] =
+				&dto.TableRowColumnInfo{ID: dishType, Value: fmt.Sprintf("%v", dishNumber)}
 		}
 	}
 
@@ -327,6 +353,7 @@ func GenerateWeekMenuListTableHead() []*dto.TableColumnInfo {
 	head := make([]*dto.TableColumnInfo, 0)
 	head = append(head, &dto.TableColumnInfo{Name: "菜单ID", DataIndex: IndexID, Hide: false})
 	head = append(head, &dto.TableColumnInfo{Name: "菜单日期", DataIndex: IndexMenuDate, Hide: false})
+	head = append(head, &dto.TableColumnInfo{Name: "状态", DataIndex: IndexStatus, Hide: false})
 	for index, dayName := range weekDays {
 		for i := enum.MealUnknown + 1; i < enum.MealALL; i++ {
 			head = append(head, &dto.TableColumnInfo{Name: dayName + enum.GetMealName(i),
@@ -345,6 +372,8 @@ func GenerateWeekMenuListTableData(menuList []*model.WeekMenu, dishIDMap map[uin
 		startDate := menu.MenuStartDate.Format("01-02")
 		endDate := menu.MenuStartDate.Add(time.Hour * 24 * 7).Format("01-02")
 		row[IndexMenuDate] = &dto.TableRowColumnInfo{Value: fmt.Sprintf("%v~%v", startDate, endDate)}
+		row[IndexStatus] = &dto.TableRowColumnInfo{ID: uint32(menu.Status),
+			Value: enum.GetWeekMenuStatusName(menu.Status)}
 		dataList = append(dataList, row)
 
 		menuConf := menu.ToWeekMenuConfig()
@@ -359,7 +388,7 @@ func GenerateWeekMenuListTableData(menuList []*model.WeekMenu, dishIDMap map[uin
 				}
 				mealStr := ""
 				for _, dishID := range dishList {
-					mealStr += dishIDMap[dishID].DishName + ","
+					mealStr += GetDish(dishIDMap, dishID).DishName + ","
 				}
 				if len(dishList) == 0 {
 					mealStr = ","
@@ -386,21 +415,25 @@ func GenerateWeekMenuDetailTable(menu *model.WeekMenu, dishIDMap map[uint32]*mod
 	}
 
 	typeDishList := make([]map[uint8]map[uint32]*list.List, 0)
+	dayNutritionList := make([]map[uint8]string, 0)
 
 	columnNumber := make(map[uint8]map[uint32]int32)
 	menuConf := menu.ToWeekMenuConfig()
 	for _, dayMenu := range menuConf {
 		dayTypeDish := make(map[uint8]map[uint32]*list.List)
+		dayNutrition := make(map[uint8]string)
 		for mealType, dishList := range dayMenu {
 			if _, ok := columnNumber[mealType]; !ok {
 				columnNumber[mealType] = make(map[uint32]int32)
 			}
 			dayTypeDish[mealType] = make(map[uint32]*list.List)
 			typeNumber := make(map[uint32]int32)
+			mealDishList := make([]*model.Dish, 0, len(dishList))
 			for _, dishID := range dishList {
-				dish := dishIDMap[dishID]
+				dish := GetDish(dishIDMap, dishID)
+				mealDishList = append(mealDishList, dish)
 				dishType := dish.DishType
-				mainType := dishTypeMap[dishType].MasterType
+				mainType := GetMasterDishType(dishTypeMap, dishType)
 				if mainType == 0 {
 					mainType = dishType
 				}
@@ -413,6 +446,8 @@ func GenerateWeekMenuDetailTable(menu *model.WeekMenu, dishIDMap map[uint32]*mod
 				}
 				dayTypeDish[mealType][mainType].PushBack(dish)
 			}
+			nutrition, allergen := SumDishNutrition(mealDishList)
+			dayNutrition[mealType] = GenerateNutritionValue(nutrition, allergen)
 
 			for mainType, dishNumber := range typeNumber {
 				if _, ok := columnNumber[mealType][mainType]; !ok {
@@ -428,6 +463,7 @@ func GenerateWeekMenuDetailTable(menu *model.WeekMenu, dishIDMap map[uint32]*mod
 			}
 		}
 		typeDishList = append(typeDishList, dayTypeDish)
+		dayNutritionList = append(dayNutritionList, dayNutrition)
 	}
 
 	for mealType := enum.MealUnknown + 1; mealType < enum.MealALL; mealType++ {
@@ -448,15 +484,20 @@ func GenerateWeekMenuDetailTable(menu *model.WeekMenu, dishIDMap map[uint32]*mod
 					DataIndex: GenerateMealMainDishTypeIndex(mealType, mainType.ID, i), Hide: false})
 			}
 		}
+		children = append(children, &dto.TableColumnInfo{Name: NutritionColumnName, MergeRow: true,
+			DataIndex: GenerateMealNutritionIndex(mealType), Hide: false})
 		mealColumn.Children = children
 		head = append(head, mealColumn)
 	}
 
 	startDate := menu.MenuStartDate
-	for _, dayDishMap := range typeDishList {
+	for dayIndex, dayDishMap := range typeDishList {
 		for rowIndex := 0; rowIndex < DayRowFixed; rowIndex++ {
 			row := dto.TableRowInfo{}
 			row[IndexMenuDate] = &dto.TableRowColumnInfo{Value: GenerateWeekDayValue(startDate)}
+			for mealType, nutritionValue := range dayNutritionList[dayIndex] {
+				row[GenerateMealNutritionIndex(mealType)] = &dto.TableRowColumnInfo{Value: nutritionValue}
+			}
 			for mealType, dishTypeList := range dayDishMap {
 				if _, ok := columnNumber[mealType]; ok == false {
 					continue
@@ -520,3 +561,122 @@ func ParseWeekMenuDetail(rowList []*dto.TableRowInfo, menuID, menuTypeID uint32,
 	weekMenu.FromWeekMenuConfig(menuConf)
 	return weekMenu
 }
+
+func GenerateWeekMenuSheet(head []*dto.TableColumnInfo, dataList []dto.TableRowInfo) [][]string {
+	dayNumber := len(dataList) / DayRowFixed
+	headRow := []string{SheetMealColumn, SheetDishTypeColumn}
+	for dayIndex := 0; dayIndex < dayNumber; dayIndex++ {
+		headRow = append(headRow, dataList[dayIndex*DayRowFixed][IndexMenuDate].Value)
+	}
+
+	rows := [][]string{headRow}
+	for _, mealColumn := range head {
+		for _, column := range mealColumn.Children {
+			rowNumber := DayRowFixed
+			if column.MergeRow {
+				rowNumber = 1
+			}
+			for rowIndex := 0; rowIndex < rowNumber; rowIndex++ {
+				row := []string{mealColumn.Name, column.Name}
+				empty := true
+				for dayIndex := 0; dayIndex < dayNumber; dayIndex++ {
+					value := ""
+					if cell, ok := dataList[dayIndex*DayRowFixed+rowIndex][column.DataIndex]; ok && cell != nil {
+						value = cell.Value
+					}
+					if value != "" {
+						empty = false
+					}
+					row = append(row, value)
+				}
+				if !empty {
+					rows = append(rows, row)
+				}
+			}
+		}
+	}
+	return rows
+}
+
+func splitSheetDishNames(value string) []string {
+	nameList := make([]string, 0)
+	for _, name := range strings.FieldsFunc(value, func(r rune) bool {
+		return r == ',' || r == '，' || r == '、' || r == '\n'
+	}) {
+		name = strings.TrimSpace(name)
+		if name != "" {
+			nameList = append(nameList, name)
+		}
+	}
+	return nameList
+}
+
+// ParseWeekMenuSheet 解析导入的周菜单，菜品类型列为主类型名称，菜品需归属该主类型
+func ParseWeekMenuSheet(rows [][]string, dishNameMap map[string]*model.Dish, dishTypeMap map[uint32]*model.DishType,
+	menuTypeID uint32, menuDate int64) (*model.WeekMenu, []*dto.SheetErrorInfo) {
+	weekMenu := &model.WeekMenu{MenuTypeID: menuTypeID, MenuStartDate: time.Unix(utils.GetFirstDateOfWeek(menuDate), 0)}
+	errorList := make([]*dto.SheetErrorInfo, 0)
+	menuConf := make([]map[uint8][]uint32, len(weekDays))
+	for dayIndex := range menuConf {
+		menuConf[dayIndex] = make(map[uint8][]uint32)
+	}
+	mainTypeNameMap := make(map[string]uint32)
+	for _, dishType := range dishTypeMap {
+		if dishType.MasterType == 0 {
+			mainTypeNameMap[dishType.DishTypeName] = dishType.ID
+		}
+	}
+
+	for rowIndex, row := range rows {
+		if rowIndex == 0 || len(row) <= sheetDayColumnStart {
+			continue
+		}
+		mealName, typeName := strings.TrimSpace(row[0]), strings.TrimSpace(row[1])
+		if mealName == "" || typeName == NutritionColumnName {
+			continue
+		}
+		mealType := enum.GetMealTypeByName(mealName)
+		if mealType == enum.MealUnknown {
+			errorList = append(errorList, &dto.SheetErrorInfo{Row: rowIndex + 1, Column: utils.GetColumnName(0),
+				Value: mealName, Reason: "未知餐次"})
+			continue
+		}
+		mainType, ok := mainTypeNameMap[typeName]
+		if !ok {
+			errorList = append(errorList, &dto.SheetErrorInfo{Row: rowIndex + 1, Column: utils.GetColumnName(1),
+				Value: typeName, Reason: "未知菜品类型"})
+			continue
+		}
+		for column := sheetDayColumnStart; column < len(row); column++ {
+			dayIndex := column - sheetDayColumnStart
+			if dayIndex >= len(weekDays) {
+				break
+			}
+			for _, dishName := range splitSheetDishNames(row[column]) {
+				dish, ok := dishNameMap[dishName]
+				if !ok {
+					errorList = append(errorList, &dto.SheetErrorInfo{Row: rowIndex + 1,
+						Column: utils.GetColumnName(column), Value: dishName, Reason: "未知菜品"})
+					continue
+				}
+				if !dish.IsAvailable(weekMenu.MenuStartDate.AddDate(0, 0, dayIndex)) {
+					errorList = append(errorList, &dto.SheetErrorInfo{Row: rowIndex + 1,
+						Column: utils.GetColumnName(column), Value: dishName, Reason: "菜品已下架或不在供应季"})
+					continue
+				}
+				dishMainType := GetMasterDishType(dishTypeMap, dish.DishType)
+				if dishMainType == 0 {
+					dishMainType = dish.DishType
+				}
+				if dishMainType != mainType {
+					errorList = append(errorList, &dto.SheetErrorInfo{Row: rowIndex + 1,
+						Column: utils.GetColumnName(column), Value: dishName, Reason: "菜品不属于" + typeName})
+					continue
+				}
+				menuConf[dayIndex][mealType] = append(menuConf[dayIndex][mealType], dish.ID)
+			}
+		}
+	}
+	weekMenu.FromWeekMenuConfig(menuConf)
+	return weekMenu, errorList
+}
@@ -1,6 +1,9 @@
 package dto
 
 import (
+	"fmt"
+	"strings"
+
 	"github.com/canteen_management/config"
 	"github.com/canteen_management/enum"
 	"github.com/canteen_management/model"
@@ -20,6 +23,19 @@ type Response struct {
 	NotEscapeHtml bool `json:"-"`
 }
 
+// DocumentOperateReq 单据取消、冲销的公共参数，原因必填用于审计
+type DocumentOperateReq struct {
+	Uid    uint32 `json:"uid"`
+	Reason string `json:"reason"`
+}
+
+func (dor *DocumentOperateReq) CheckParams() error {
+	if strings.TrimSpace(dor.Reason) == "" {
+		return fmt.Errorf("请填写原因")
+	}
+	return nil
+}
+
 type PaginationQ interface {
 	FixPagination()
 }
@@ -75,14 +91,23 @@ type TableRowColumnInfo struct {
 
 type TableRowInfo = map[string]*TableRowColumnInfo
 
+type SheetErrorInfo struct {
+	Row    int    `json:"row"`
+	Column string `json:"column"`
+	Value  string `json:"value"`
+	Reason string `json:"reason"`
+}
+
 type OrderNode struct {
-	ID             string       `json:"id"`
-	Price          float64      `json:"price,omitempty"`
-	Name           string       `json:"name"`
-	DishID         uint32       `json:"dish_id,omitempty"`
-	Picture        string       `json:"picture,omitempty"`
-	SelectedNumber int32        `json:"selected_number"`
-	Children       []*OrderNode `json:"children,omitempty"`
+	ID             string         `json:"id"`
+	Price          float64        `json:"price,omitempty"`
+	Name           string         `json:"name"`
+	DishID         uint32         `json:"dish_id,omitempty"`
+	Picture        string         `json:"picture,omitempty"`
+	Nutrition      *NutritionInfo `json:"nutrition,omitempty"`
+	AllergenList   []uint8        `json:"allergen_list,omitempty"`
+	SelectedNumber int32          `json:"selected_number"`
+	Children       []*OrderNode   `json:"children,omitempty"`
 }
 
 type GoodsNode struct {
@@ -93,6 +118,7 @@ type GoodsNode struct {
 	GoodsID        uint32       `json:"goods_id,omitempty"`
 	SelectedNumber int32        `json:"selected_number"`
 	Left           float64      `json:"left"`
+	Reserved       float64      `json:"reserved"`
 	BatchSize      float64      `json:"batch_size"`
 	BatchUnit      string       `json:"batch_unit"`
 	Children       []*GoodsNode `json:"children,omitempty"`
@@ -123,6 +149,8 @@ type PurchaseGoodsBase struct {
 	Picture      string  `json:"picture"`
 	GoodsTypeID  uint32  `json:"goods_type_id"`
 	ExpectNumber float64 `json:"expect_number"`
+	Unit         string  `json:"unit,omitempty"`
+	Code         string  `json:"code,omitempty"`
 }
 
 type UploadBase64Req struct {
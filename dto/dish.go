@@ -31,15 +31,39 @@ type DishListReq struct {
 	PaginationReq
 }
 
+type NutritionInfo struct {
+	Energy  float64 `json:"energy"`
+	Protein float64 `json:"protein"`
+	Fat     float64 `json:"fat"`
+	Carbs   float64 `json:"carbs"`
+	Sodium  float64 `json:"sodium"`
+}
+
+type RecipeItem struct {
+	GoodsID  uint32  `json:"goods_id"`
+	Quantity float64 `json:"quantity"`
+	Unit     string  `json:"unit,omitempty"`
+}
+
 type DishInfo struct {
-	DishID         uint32  `json:"dish_id"`
-	DishName       string  `json:"dish_name"`
-	DishTypeID     uint32  `json:"dish_type_id"`
-	DishTypeName   string  `json:"dish_type_name"`
-	MasterTypeName string  `json:"master_type_name"`
-	Picture        string  `json:"picture"`
-	Material       string  `json:"material"`
-	Price          float64 `json:"price"`
+	DishID         uint32         `json:"dish_id"`
+	DishName       string         `json:"dish_name"`
+	DishTypeID     uint32         `json:"dish_type_id"`
+	DishTypeName   string         `json:"dish_type_name"`
+	MasterTypeName string         `json:"master_type_name"`
+	Picture        string         `json:"picture"`
+	Material       string         `json:"material"`
+	Price          float64        `json:"price"`
+	Status         int8           `json:"status"`
+	SeasonStart    uint32         `json:"season_start"`
+	SeasonEnd      uint32         `json:"season_end"`
+	IsDelete       bool           `json:"is_delete"`
+	Nutrition      *NutritionInfo `json:"nutrition"`
+	AllergenList   []uint8        `json:"allergen_list"`
+	Recipe         []*RecipeItem  `json:"recipe"`
+
+	RecipeNutrition    *NutritionInfo `json:"recipe_nutrition,omitempty"` // 按配方推算，只读
+	RecipeAllergenList []uint8        `json:"recipe_allergen_list,omitempty"`
 }
 
 type DishListRes struct {
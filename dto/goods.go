@@ -33,15 +33,25 @@ type GoodsListReq struct {
 }
 
 type GoodsInfo struct {
-	GoodsID   uint32  `json:"goods_id"`
-	GoodsName string  `json:"goods_name"`
-	GoodsType uint32  `json:"goods_type"`
-	StoreType uint32  `json:"store_type"`
-	Picture   string  `json:"picture"`
-	BatchSize float64 `json:"batch_size"`
-	BatchUnit string  `json:"batch_unit"`
-	Price     float64 `json:"price"`
-	Quantity  float64 `json:"quantity"`
+	GoodsID      uint32         `json:"goods_id"`
+	GoodsName    string         `json:"goods_name"`
+	Barcode      string         `json:"barcode"`
+	Code         string         `json:"code"`
+	GoodsType    uint32         `json:"goods_type"`
+	StoreType    uint32         `json:"store_type"`
+	Picture      string         `json:"picture"`
+	BatchSize    float64        `json:"batch_size"`
+	BatchUnit    string         `json:"batch_unit"`
+	Price        float64        `json:"price"`
+	Quantity     float64        `json:"quantity"`
+	Reserved     float64        `json:"reserved"`
+	Available    float64        `json:"available"`
+	MinQuantity  float64        `json:"min_quantity"`
+	ReorderPoint float64        `json:"reorder_point"`
+	MaxQuantity  float64        `json:"max_quantity"`
+	StockLevel   uint8          `json:"stock_level"`
+	Nutrition    *NutritionInfo `json:"nutrition"`
+	AllergenList []uint8        `json:"allergen_list"`
 }
 
 type GoodsListRes struct {
@@ -54,6 +64,27 @@ type ModifyGoodsInfoReq struct {
 	Goods   *GoodsInfo       `json:"goods"`
 }
 
+type GoodsUnitInfo struct {
+	UnitID   uint32  `json:"unit_id"`
+	GoodsID  uint32  `json:"goods_id"`
+	UnitName string  `json:"unit_name"`
+	UnitType uint8   `json:"unit_type"`
+	Factor   float64 `json:"factor"`
+}
+
+type GoodsUnitListReq struct {
+	GoodsID uint32 `json:"goods_id"`
+}
+
+type GoodsUnitListRes struct {
+	UnitList []*GoodsUnitInfo `json:"unit_list"`
+}
+
+type ModifyGoodsUnitReq struct {
+	Operate enum.OperateType `json:"operate"`
+	Unit    *GoodsUnitInfo   `json:"unit"`
+}
+
 type ModifyGoodsQuantityReq struct {
 	Operate enum.OperateType `json:"operate"`
 	Goods   *GoodsInfo       `json:"goods"`
@@ -80,6 +111,7 @@ type GoodsPriceListRes struct {
 }
 
 type ModifyGoodsPriceReq struct {
+	Uid      uint32            `json:"uid"`
 	GoodsID  uint32            `json:"goods_id"`
 	PriceMap map[uint8]float64 `json:"price_map"`
 }
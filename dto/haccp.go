@@ -0,0 +1,123 @@
+package dto
+
+import (
+	"fmt"
+
+	"github.com/canteen_management/enum"
+	"github.com/canteen_management/utils"
+)
+
+type HaccpPointInfo struct {
+	PointID       uint32  `json:"point_id"`
+	Name          string  `json:"name"`
+	PointType     uint8   `json:"point_type"`
+	StoreTypeID   uint32  `json:"store_type_id"`
+	StoreTypeName string  `json:"store_type_name"`
+	DishTypeID    uint32  `json:"dish_type_id"`
+	DishTypeName  string  `json:"dish_type_name"`
+	MinValue      float64 `json:"min_value"`
+	MaxValue      float64 `json:"max_value"`
+	Unit          string  `json:"unit"`
+	DeviceKey     string  `json:"device_key"`
+	Enable        bool    `json:"enable"`
+}
+
+type HaccpPointListReq struct {
+	PointType uint8 `json:"point_type"`
+}
+
+type HaccpPointListRes struct {
+	PointList []*HaccpPointInfo `json:"point_list"`
+}
+
+type ModifyHaccpPointReq struct {
+	Operate     enum.OperateType `json:"operate"`
+	Point       *HaccpPointInfo  `json:"point"`
+	ResetSecret bool             `json:"reset_secret"`
+}
+
+// ModifyHaccpPointRes 新生成的设备密钥仅在此返回一次，需配置到传感器上
+type ModifyHaccpPointRes struct {
+	DeviceSecret string `json:"device_secret"`
+}
+
+type RecordHaccpReadingReq struct {
+	PointID uint32  `json:"point_id"`
+	Value   float64 `json:"value"`
+	DishID  uint32  `json:"dish_id"`
+	Remark  string  `json:"remark"`
+	ReadAt  int64   `json:"read_at"`
+}
+
+type HaccpIngestReq struct {
+	DeviceKey string  `json:"device_key"`
+	Value     float64 `json:"value"`
+	ReadAt    int64   `json:"read_at"`
+	Sign      string  `json:"sign"`
+}
+
+type HaccpIngestRes struct {
+	ReadingID   uint32 `json:"reading_id"`
+	AlertStatus int8   `json:"alert_status"`
+}
+
+type HaccpReadingListReq struct {
+	PaginationReq
+	PointID     uint32 `json:"point_id"`
+	PointType   uint8  `json:"point_type"`
+	AlertStatus int8   `json:"alert_status"`
+	StartTime   int64  `json:"start_time"`
+	EndTime     int64  `json:"end_time"`
+}
+
+type HaccpReadingInfo struct {
+	ReadingID   uint32  `json:"reading_id"`
+	PointID     uint32  `json:"point_id"`
+	PointName   string  `json:"point_name"`
+	PointType   uint8   `json:"point_type"`
+	Value       float64 `json:"value"`
+	MinValue    float64 `json:"min_value"`
+	MaxValue    float64 `json:"max_value"`
+	Unit        string  `json:"unit"`
+	Source      uint8   `json:"source"`
+	Recorder    string  `json:"recorder"`
+	DishID      uint32  `json:"dish_id"`
+	Remark      string  `json:"remark"`
+	AlertStatus int8    `json:"alert_status"`
+	Handler     string  `json:"handler"`
+	Action      string  `json:"action"`
+	ReadAt      int64   `json:"read_at"`
+	HandleAt    int64   `json:"handle_at"`
+}
+
+type HaccpReadingListRes struct {
+	PaginationRes
+	ReadingList []*HaccpReadingInfo `json:"reading_list"`
+}
+
+type ResolveHaccpAlertReq struct {
+	ReadingID uint32 `json:"reading_id"`
+	Action    string `json:"action"`
+}
+
+type ExportHaccpLogReq struct {
+	PointType uint8  `json:"point_type"`
+	StartTime int64  `json:"start_time"`
+	EndTime   int64  `json:"end_time"`
+	FileType  string `json:"file_type"`
+}
+
+func (ehl *ExportHaccpLogReq) CheckParams() error {
+	if ehl.FileType != utils.SheetTypeXlsx && ehl.FileType != utils.SheetTypeCsv {
+		return fmt.Errorf("文件格式错误")
+	}
+	if ehl.StartTime <= 0 || ehl.EndTime <= ehl.StartTime {
+		return fmt.Errorf("请选择检查周期")
+	}
+	return nil
+}
+
+type ExportHaccpLogRes struct {
+	FileName string `json:"file_name"`
+	Url      string `json:"url"`
+}
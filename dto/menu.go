@@ -1,11 +1,18 @@
 package dto
 
-import "github.com/canteen_management/enum"
+import (
+	"fmt"
+
+	"github.com/canteen_management/enum"
+	"github.com/canteen_management/utils"
+)
 
 type MealInfo struct {
-	MealName string      `json:"meal_name"`
-	MealType uint8       `json:"meal_type"`
-	DishList []*DishInfo `json:"dish_list"`
+	MealName     string         `json:"meal_name"`
+	MealType     uint8          `json:"meal_type"`
+	DishList     []*DishInfo    `json:"dish_list"`
+	Nutrition    *NutritionInfo `json:"nutrition"`
+	AllergenList []uint8        `json:"allergen_list"`
 }
 
 type MenuInfo struct {
@@ -27,6 +34,9 @@ type WeekMenuInfo struct {
 	MenuType      uint32   `json:"menu_type"`
 	MenuStartDate int64    `json:"menu_start_time"`
 	MenuEndDate   int64    `json:"menu_end_time"`
+	Status        int8     `json:"status"`
+	Version       uint32   `json:"version"`
+	Online        bool     `json:"online"` // 是否有已发布版本在供点餐
 	MenuContent   []string `json:"menu_content"`
 }
 
@@ -44,6 +54,8 @@ type WeekMenuDetailInfo struct {
 	MenuType      uint32      `json:"menu_type"`
 	MenuStartDate int64       `json:"menu_start_time"`
 	MenuEndDate   int64       `json:"menu_end_time"`
+	Status        int8        `json:"status"`
+	Version       uint32      `json:"version"`
 	MenuList      []*MenuInfo `json:"menu_list"`
 }
 
@@ -59,12 +71,82 @@ type WeekMenuDetailTableRes struct {
 
 type ModifyWeekMenuDetailReq struct {
 	Operate      enum.OperateType `json:"operate"`
+	Uid          uint32           `json:"uid"`
 	WeekMenuID   uint32           `json:"week_menu_id"`
 	MenuTypeID   uint32           `json:"menu_type_id"`
 	MenuDate     int64            `json:"menu_date"`
 	WeekMenuRows []*TableRowInfo  `json:"week_menu_rows"`
 }
 
+type SubmitWeekMenuReq struct {
+	WeekMenuID uint32 `json:"week_menu_id"`
+}
+
+type ReviewWeekMenuReq struct {
+	WeekMenuID uint32 `json:"week_menu_id"`
+	Pass       bool   `json:"pass"`
+}
+
+type ArchiveWeekMenuReq = SubmitWeekMenuReq
+
+type WeekMenuHistoryReq struct {
+	PaginationReq
+	WeekMenuID uint32 `json:"week_menu_id"`
+}
+
+type WeekMenuHistoryInfo struct {
+	Version     uint32   `json:"version"`
+	Operator    uint32   `json:"operator"`
+	MenuContent []string `json:"menu_content"`
+	CreateTime  int64    `json:"create_time"`
+}
+
+type WeekMenuHistoryRes struct {
+	PaginationRes
+	List []*WeekMenuHistoryInfo `json:"list"`
+}
+
+type ExportWeekMenuReq struct {
+	WeekMenuID uint32 `json:"week_menu_id"`
+	FileType   string `json:"file_type"`
+}
+
+func (ewm *ExportWeekMenuReq) CheckParams() error {
+	if ewm.FileType != utils.SheetTypeXlsx && ewm.FileType != utils.SheetTypeCsv {
+		return fmt.Errorf("文件格式错误")
+	}
+	return nil
+}
+
+type ExportWeekMenuRes struct {
+	FileName string `json:"file_name"`
+	Url      string `json:"url"`
+}
+
+type ImportWeekMenuReq struct {
+	Uid       uint32 `json:"uid"`
+	MenuType  uint32 `json:"menu_type"`
+	TimeStart int64  `json:"time_start"`
+	FileName  string `json:"file_name"`
+	FileData  string `json:"file_data"`
+}
+
+func (iwm *ImportWeekMenuReq) CheckParams() error {
+	if iwm.MenuType == 0 || iwm.TimeStart == 0 {
+		return fmt.Errorf("菜单类型和日期不能为空")
+	}
+	fileType := utils.GetSheetType(iwm.FileName)
+	if fileType != utils.SheetTypeXlsx && fileType != utils.SheetTypeCsv {
+		return fmt.Errorf("文件格式错误")
+	}
+	return nil
+}
+
+type ImportWeekMenuRes struct {
+	WeekMenuID uint32            `json:"week_menu_id"`
+	ErrorList  []*SheetErrorInfo `json:"error_list"`
+}
+
 type ModifyWeekMenuReq struct {
 	Operate  enum.OperateType   `json:"operate"`
 	WeekMenu WeekMenuDetailInfo `json:"week_menu"`
@@ -80,8 +162,9 @@ type GenerateStaffMenuRes struct {
 }
 
 type GenerateWeekMenuReq struct {
-	MenuType  uint32 `json:"menu_type"`
-	TimeStart int64  `json:"time_start"`
+	MenuType     uint32 `json:"menu_type"`
+	TimeStart    int64  `json:"time_start"`
+	RatingWeight bool   `json:"rating_weight"`
 }
 
 type GenerateWeekMenuRes = GenerateStaffMenuRes
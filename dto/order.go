@@ -7,7 +7,8 @@ import (
 )
 
 type OrderMenuReq struct {
-	Uid uint32 `json:"uid"`
+	Uid                 uint32  `json:"uid"`
+	ExcludeAllergenList []uint8 `json:"exclude_allergen_list"`
 }
 
 type OrderMenuRes struct {
@@ -20,6 +21,7 @@ type OrderMenuRes struct {
 
 type ApplyItem struct {
 	ID       string  `json:"id"`
+	DetailID uint32  `json:"detail_id,omitempty"`
 	DishID   uint32  `json:"dish_id"`
 	DishName string  `json:"dish_name"`
 	Price    float64 `json:"price"`
@@ -153,6 +155,72 @@ type OrderDishAnalysisRes struct {
 	Summary []*OrderDishSummaryInfo `json:"summary"`
 }
 
+type DishRatingItem struct {
+	OrderDetailID uint32 `json:"order_detail_id"`
+	Score         uint8  `json:"score"`
+	Comment       string `json:"comment"`
+	Picture       string `json:"picture"`
+}
+
+type RateOrderReq struct {
+	OrderID    uint32            `json:"order_id"`
+	RatingList []*DishRatingItem `json:"rating_list"`
+}
+
+func (ror *RateOrderReq) CheckParams() error {
+	if len(ror.RatingList) == 0 {
+		return fmt.Errorf("请选择要评价的菜品")
+	}
+	for _, rating := range ror.RatingList {
+		if rating.Score < 1 || rating.Score > 5 {
+			return fmt.Errorf("评分范围为1-5分")
+		}
+	}
+	return nil
+}
+
+type DishRatingListReq struct {
+	PaginationReq
+	DishID    uint32 `json:"dish_id"`
+	StartTime int64  `json:"start_time"`
+	EndTime   int64  `json:"end_time"`
+}
+
+type DishRatingInfo struct {
+	ID         uint32 `json:"id"`
+	OrderID    uint32 `json:"order_id"`
+	DishID     uint32 `json:"dish_id"`
+	DishName   string `json:"dish_name"`
+	Uid        uint32 `json:"uid"`
+	Score      uint8  `json:"score"`
+	Comment    string `json:"comment"`
+	Picture    string `json:"picture"`
+	CreateTime int64  `json:"create_time"`
+}
+
+type DishRatingListRes struct {
+	PaginationRes
+	List []*DishRatingInfo `json:"list"`
+}
+
+type DishRatingSummaryReq struct {
+	DishID    uint32 `json:"dish_id"`
+	StartTime int64  `json:"start_time"`
+	EndTime   int64  `json:"end_time"`
+}
+
+type DishRatingSummaryInfo struct {
+	DishID       uint32  `json:"dish_id"`
+	DishName     string  `json:"dish_name"`
+	DishType     uint32  `json:"dish_type"`
+	RatingNumber int32   `json:"rating_number"`
+	AverageScore float64 `json:"average_score"`
+}
+
+type DishRatingSummaryRes struct {
+	Summary []*DishRatingSummaryInfo `json:"summary"`
+}
+
 type OrderListReq struct {
 	PaginationReq
 	OrderStatus int8   `json:"order_status"`
@@ -255,3 +323,93 @@ type OrderCartMeal struct {
 type GetOrderCartRes struct {
 	MealList []*OrderCartMeal `json:"meal_list"`
 }
+
+type WasteItem struct {
+	WasteType   uint8   `json:"waste_type"`
+	DishID      uint32  `json:"dish_id"`
+	GoodsID     uint32  `json:"goods_id"`
+	StoreTypeID uint32  `json:"store_type_id"`
+	Quantity    float64 `json:"quantity"`
+	Weight      float64 `json:"weight"`
+	Remark      string  `json:"remark"`
+}
+
+type RecordWasteReq struct {
+	Uid       uint32       `json:"uid"`
+	MealDate  int64        `json:"meal_date"`
+	MealType  uint8        `json:"meal_type"`
+	WasteList []*WasteItem `json:"waste_list"`
+}
+
+func (rwr *RecordWasteReq) CheckParams() error {
+	if rwr.MealDate == 0 || len(rwr.WasteList) == 0 {
+		return fmt.Errorf("请填写就餐日期及浪费记录")
+	}
+	return nil
+}
+
+type WasteListReq struct {
+	MealType  uint8  `json:"meal_type"`
+	WasteType uint8  `json:"waste_type"`
+	DishID    uint32 `json:"dish_id"`
+	StartTime int64  `json:"start_time"`
+	EndTime   int64  `json:"end_time"`
+}
+
+type WasteLogInfo struct {
+	ID          uint32  `json:"id"`
+	MealDate    int64   `json:"meal_date"`
+	MealType    uint8   `json:"meal_type"`
+	WasteType   uint8   `json:"waste_type"`
+	DishID      uint32  `json:"dish_id"`
+	DishName    string  `json:"dish_name"`
+	GoodsID     uint32  `json:"goods_id"`
+	GoodsName   string  `json:"goods_name"`
+	StoreTypeID uint32  `json:"store_type_id"`
+	Quantity    float64 `json:"quantity"`
+	Weight      float64 `json:"weight"`
+	Value       float64 `json:"value"`
+	Remark      string  `json:"remark"`
+	CreateAt    int64   `json:"created_at"`
+}
+
+type WasteListRes struct {
+	WasteList []*WasteLogInfo `json:"waste_list"`
+}
+
+type WasteReportReq struct {
+	MealType  uint8  `json:"meal_type"`
+	DishID    uint32 `json:"dish_id"`
+	StartTime int64  `json:"start_time"`
+	EndTime   int64  `json:"end_time"`
+}
+
+type DishWasteInfo struct {
+	MealDate      int64   `json:"meal_date"`
+	MealType      uint8   `json:"meal_type"`
+	DishID        uint32  `json:"dish_id"`
+	DishName      string  `json:"dish_name"`
+	OrderQuantity int32   `json:"order_quantity"`
+	PlateWaste    float64 `json:"plate_waste"`
+	UnservedWaste float64 `json:"unserved_waste"`
+	WasteWeight   float64 `json:"waste_weight"`
+	WasteRate     float64 `json:"waste_rate"`
+}
+
+type PrepWasteInfo struct {
+	MealDate  int64   `json:"meal_date"`
+	MealType  uint8   `json:"meal_type"`
+	GoodsID   uint32  `json:"goods_id"`
+	GoodsName string  `json:"goods_name"`
+	Quantity  float64 `json:"quantity"`
+	Value     float64 `json:"value"`
+}
+
+type WasteReportRes struct {
+	DishWasteList  []*DishWasteInfo `json:"dish_waste_list"`
+	PrepWasteList  []*PrepWasteInfo `json:"prep_waste_list"`
+	OrderQuantity  int32            `json:"order_quantity"`
+	WasteQuantity  float64          `json:"waste_quantity"`
+	WasteRate      float64          `json:"waste_rate"`
+	PrepWasteValue float64          `json:"prep_waste_value"`
+}
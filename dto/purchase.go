@@ -12,13 +12,15 @@ type SupplierListReq struct {
 }
 
 type SupplierInfo struct {
-	SupplierID       uint32 `json:"supplier_id"`
-	Name             string `json:"name"`
-	PhoneNumber      string `json:"phone_number"`
-	IDNumber         string `json:"id_number"`
-	Location         string `json:"location"`
-	ValidityDeadline int64  `json:"validity_deadline"`
-	OpenID           string `json:"open_id"`
+	SupplierID            uint32   `json:"supplier_id"`
+	Name                  string   `json:"name"`
+	PhoneNumber           string   `json:"phone_number"`
+	IDNumber              string   `json:"id_number"`
+	Location              string   `json:"location"`
+	ValidityDeadline      int64    `json:"validity_deadline"`
+	OpenID                string   `json:"open_id"`
+	Lapsed                bool     `json:"lapsed"`
+	MissingQualifications []string `json:"missing_qualifications"`
 }
 
 type SupplierListRes struct {
@@ -41,20 +43,86 @@ type RenewSupplierReq struct {
 	EndTime    int64  `json:"end_time"`
 }
 
+type QualificationInfo struct {
+	QualificationID uint32 `json:"qualification_id"`
+	SupplierID      uint32 `json:"supplier_id"`
+	SupplierName    string `json:"supplier_name"`
+	DocType         uint8  `json:"doc_type"`
+	DocTypeName     string `json:"doc_type_name"`
+	DocNumber       string `json:"doc_number"`
+	FileUrl         string `json:"file_url"`
+	IssueTime       int64  `json:"issue_time"`
+	ExpireTime      int64  `json:"expire_time"`
+	Remark          string `json:"remark"`
+	IsMandatory     bool   `json:"is_mandatory"`
+	RemainDays      int32  `json:"remain_days"`
+}
+
+type QualificationListReq struct {
+	Uid        uint32 `json:"uid"`
+	SupplierID uint32 `json:"supplier_id"`
+}
+
+type QualificationListRes struct {
+	QualificationList []*QualificationInfo `json:"qualification_list"`
+}
+
+type ModifyQualificationReq struct {
+	Uid           uint32             `json:"uid"`
+	Operate       enum.OperateType   `json:"operate"`
+	Qualification *QualificationInfo `json:"qualification"`
+}
+
+type QualificationAlertReq struct {
+	Days int `json:"days"`
+}
+
+type QualificationAlertRes struct {
+	AlertList []*QualificationInfo `json:"alert_list"`
+}
+
+type SourcingInfo struct {
+	SourcingID   uint32 `json:"sourcing_id"`
+	SupplierID   uint32 `json:"supplier_id"`
+	SupplierName string `json:"supplier_name"`
+	GoodsTypeID  uint32 `json:"goods_type_id"`
+	GoodsID      uint32 `json:"goods_id"`
+	TargetName   string `json:"target_name"`
+}
+
+type SourcingListReq struct {
+	SupplierID uint32 `json:"supplier_id"`
+}
+
+type SourcingListRes struct {
+	SourcingList []*SourcingInfo `json:"sourcing_list"`
+}
+
+type ModifySourcingReq struct {
+	Operate  enum.OperateType `json:"operate"`
+	Sourcing *SourcingInfo    `json:"sourcing"`
+}
+
 type PurchaseGoodsInfo struct {
 	PurchaseGoodsBase
-	ReceiveNumber float64 `json:"receive_number"`
-	Price         float64 `json:"price"`
+	ReceiveNumber  float64 `json:"receive_number"`
+	ReturnNumber   float64 `json:"return_number"`
+	Price          float64 `json:"price"`
+	BatchNo        string  `json:"batch_no,omitempty"`
+	ProductionDate int64   `json:"production_date,omitempty"`
+	ExpiryDate     int64   `json:"expiry_date,omitempty"`
 }
 
 type PurchaseOrderInfo struct {
 	ID            uint32               `json:"id"`
+	ParentID      uint32               `json:"parent_id"`
 	Supplier      uint32               `json:"supplier"`
 	SupplierName  string               `json:"supplier_name"`
 	GoodsList     []*PurchaseGoodsInfo `json:"goods_list"`
 	TotalAmount   float64              `json:"total_amount"`
 	PaymentAmount float64              `json:"payment_amount"`
 	Status        uint8                `json:"status"`
+	ApproveStep   uint8                `json:"approve_step"`
 	Creator       string               `json:"creator"`
 	CreateTime    int64                `json:"create_time"`
 	ReceiveTime   int64                `json:"receive_time"`
@@ -67,6 +135,7 @@ type PurchaseListReq struct {
 	Status     int8   `json:"status"`
 	Uid        uint32 `json:"uid"`
 	PurchaseID uint32 `json:"purchase_id"`
+	SupplierID uint32 `json:"supplier_id"`
 	StartTime  int64  `json:"start_time"`
 	EndTime    int64  `json:"end_time"`
 }
@@ -81,17 +150,72 @@ type ApplyPurchaseReq struct {
 	GoodsList []*PurchaseGoodsInfo `json:"goods_list"`
 }
 
+type ApplyPurchaseRes struct {
+	PurchaseIDList []uint32 `json:"purchase_id_list"`
+}
+
+type ReorderPurchaseReq struct {
+	Uid uint32 `json:"uid"`
+}
+
 type ReviewPurchaseReq struct {
 	PurchaseID uint32 `json:"purchase_id"`
+	Uid        uint32 `json:"uid"`
+	Reject     bool   `json:"reject"`
+	Comment    string `json:"comment"`
+}
+
+type EditPurchaseReq struct {
+	Uid        uint32               `json:"uid"`
+	PurchaseID uint32               `json:"purchase_id"`
+	GoodsList  []*PurchaseGoodsInfo `json:"goods_list"`
+}
+
+type ApprovalInfo struct {
+	ApprovalID  uint32 `json:"approval_id"`
+	Step        uint8  `json:"step"`
+	Approver    string `json:"approver"`
+	Pass        bool   `json:"pass"`
+	Comment     string `json:"comment"`
+	ApproveTime int64  `json:"approve_time"`
+}
+
+type PurchaseApprovalListReq struct {
+	PurchaseID uint32 `json:"purchase_id"`
+}
+
+type PurchaseApprovalListRes struct {
+	ApprovalList []*ApprovalInfo `json:"approval_list"`
+}
+
+type ApprovalChainInfo struct {
+	ChainID   uint32  `json:"chain_id"`
+	Step      uint8   `json:"step"`
+	MinAmount float64 `json:"min_amount"`
+	Role      uint8   `json:"role"`
+}
+
+type ApprovalChainListReq struct {
+}
+
+type ApprovalChainListRes struct {
+	ChainList []*ApprovalChainInfo `json:"chain_list"`
+}
+
+type ModifyApprovalChainReq struct {
+	Operate enum.OperateType   `json:"operate"`
+	Chain   *ApprovalChainInfo `json:"chain"`
 }
 
 type ConfirmPurchaseReq struct {
 	PurchaseID uint32 `json:"purchase_id"`
+	Uid        uint32 `json:"uid"`
 }
 
 type ReceivePurchaseReq struct {
 	PurchaseID  uint32               `json:"purchase_id"`
 	Uid         uint32               `json:"uid"`
+	StoreTypeID uint32               `json:"store_type_id"`
 	GoodsList   []*PurchaseGoodsInfo `json:"goods_list"`
 	SignPicture string               `json:"sign_picture"`
 }
@@ -102,3 +226,319 @@ func (rpr *ReceivePurchaseReq) CheckParams() error {
 	}
 	return nil
 }
+
+type ClosePurchaseReq struct {
+	PurchaseID    uint32 `json:"purchase_id"`
+	Uid           uint32 `json:"uid"`
+	SkipBackorder bool   `json:"skip_backorder"`
+}
+
+type CancelPurchaseReq struct {
+	DocumentOperateReq
+	PurchaseID uint32 `json:"purchase_id"`
+}
+
+type ReversePurchaseReq struct {
+	DocumentOperateReq
+	PurchaseID uint32 `json:"purchase_id"`
+}
+
+type ClosePurchaseRes struct {
+	BackorderID uint32 `json:"backorder_id"`
+}
+
+type PurchaseReceiptListReq struct {
+	PurchaseID uint32 `json:"purchase_id"`
+}
+
+type PurchaseReceiptInfo struct {
+	ReceiptID   uint32               `json:"receipt_id"`
+	Receiver    string               `json:"receiver"`
+	SignPicture string               `json:"sign_picture"`
+	Amount      float64              `json:"amount"`
+	ReceiveTime int64                `json:"receive_time"`
+	GoodsList   []*PurchaseGoodsInfo `json:"goods_list"`
+}
+
+type PurchaseReceiptListRes struct {
+	ReceiptList []*PurchaseReceiptInfo `json:"receipt_list"`
+}
+
+type ReturnGoodsInfo struct {
+	ID           uint32  `json:"id"`
+	GoodsID      uint32  `json:"goods_id"`
+	Name         string  `json:"name"`
+	Picture      string  `json:"picture"`
+	ReturnNumber float64 `json:"return_number"`
+	Unit         string  `json:"unit,omitempty"`
+	Price        float64 `json:"price"`
+}
+
+type PurchaseReturnInfo struct {
+	ReturnID     uint32             `json:"return_id"`
+	PurchaseID   uint32             `json:"purchase_id"`
+	Supplier     uint32             `json:"supplier"`
+	SupplierName string             `json:"supplier_name"`
+	Creator      string             `json:"creator"`
+	Reason       uint8              `json:"reason"`
+	ReasonName   string             `json:"reason_name"`
+	Remark       string             `json:"remark"`
+	Pictures     []string           `json:"pictures"`
+	Amount       float64            `json:"amount"`
+	CreateTime   int64              `json:"create_time"`
+	GoodsList    []*ReturnGoodsInfo `json:"goods_list"`
+}
+
+type ApplyPurchaseReturnReq struct {
+	Uid        uint32             `json:"uid"`
+	PurchaseID uint32             `json:"purchase_id"`
+	Reason     uint8              `json:"reason"`
+	Remark     string             `json:"remark"`
+	Pictures   []string           `json:"pictures"`
+	GoodsList  []*ReturnGoodsInfo `json:"goods_list"`
+}
+
+func (aprr *ApplyPurchaseReturnReq) CheckParams() error {
+	if enum.GetReturnReasonName(aprr.Reason) == "" {
+		return fmt.Errorf("请选择退货原因")
+	}
+	if len(aprr.GoodsList) == 0 {
+		return fmt.Errorf("退货商品不能为空")
+	}
+	return nil
+}
+
+type PurchaseReturnListReq struct {
+	PaginationReq
+	Uid        uint32 `json:"uid"`
+	PurchaseID uint32 `json:"purchase_id"`
+	SupplierID uint32 `json:"supplier_id"`
+	StartTime  int64  `json:"start_time"`
+	EndTime    int64  `json:"end_time"`
+}
+
+type PurchaseReturnListRes struct {
+	PaginationRes
+	ReturnList []*PurchaseReturnInfo `json:"return_list"`
+}
+
+type QuoteInfo struct {
+	SupplierID   uint32  `json:"supplier_id"`
+	SupplierName string  `json:"supplier_name"`
+	Price        float64 `json:"price"`
+	Remark       string  `json:"remark"`
+	IsAward      bool    `json:"is_award"`
+	IsLowest     bool    `json:"is_lowest"`
+}
+
+type QuotationGoodsInfo struct {
+	GoodsID      uint32       `json:"goods_id"`
+	GoodsName    string       `json:"goods_name"`
+	Picture      string       `json:"picture"`
+	CurrentPrice float64      `json:"current_price"`
+	LowestPrice  float64      `json:"lowest_price"`
+	QuoteList    []*QuoteInfo `json:"quote_list"`
+}
+
+type QuotationInfo struct {
+	QuotationID uint32                `json:"quotation_id"`
+	Title       string                `json:"title"`
+	Status      int8                  `json:"status"`
+	Creator     string                `json:"creator"`
+	Deadline    int64                 `json:"deadline"`
+	CreateTime  int64                 `json:"create_time"`
+	CloseTime   int64                 `json:"close_time"`
+	GoodsList   []*QuotationGoodsInfo `json:"goods_list"`
+}
+
+type QuotationListReq struct {
+	PaginationReq
+	Uid    uint32 `json:"uid"`
+	Status int8   `json:"status"`
+}
+
+type QuotationListRes struct {
+	PaginationRes
+	QuotationList []*QuotationInfo `json:"quotation_list"`
+}
+
+type QuotationDetailReq struct {
+	Uid         uint32 `json:"uid"`
+	QuotationID uint32 `json:"quotation_id"`
+}
+
+type QuotationDetailRes = QuotationInfo
+
+type OpenQuotationReq struct {
+	Uid         uint32   `json:"uid"`
+	Title       string   `json:"title"`
+	Deadline    int64    `json:"deadline"`
+	GoodsIDList []uint32 `json:"goods_id_list"`
+}
+
+func (oqr *OpenQuotationReq) CheckParams() error {
+	if oqr.Title == "" || len(oqr.GoodsIDList) == 0 {
+		return fmt.Errorf("询价标题和商品不能为空")
+	}
+	return nil
+}
+
+type QuoteItem struct {
+	GoodsID uint32  `json:"goods_id"`
+	Price   float64 `json:"price"`
+	Remark  string  `json:"remark"`
+}
+
+type SubmitQuoteReq struct {
+	Uid         uint32       `json:"uid"`
+	QuotationID uint32       `json:"quotation_id"`
+	QuoteList   []*QuoteItem `json:"quote_list"`
+}
+
+type AwardItem struct {
+	GoodsID    uint32 `json:"goods_id"`
+	SupplierID uint32 `json:"supplier_id"`
+}
+
+type AwardQuotationReq struct {
+	Uid         uint32       `json:"uid"`
+	QuotationID uint32       `json:"quotation_id"`
+	AwardList   []*AwardItem `json:"award_list"`
+}
+
+type CancelQuotationReq struct {
+	QuotationID uint32 `json:"quotation_id"`
+}
+
+type StatementInfo struct {
+	StatementID   uint32  `json:"statement_id"`
+	SupplierID    uint32  `json:"supplier_id"`
+	SupplierName  string  `json:"supplier_name"`
+	PeriodStart   int64   `json:"period_start"`
+	PeriodEnd     int64   `json:"period_end"`
+	OrderCount    uint32  `json:"order_count"`
+	TotalAmount   float64 `json:"total_amount"`
+	PaidAmount    float64 `json:"paid_amount"`
+	Outstanding   float64 `json:"outstanding"`
+	Status        int8    `json:"status"`
+	DisputeReason string  `json:"dispute_reason"`
+	Creator       string  `json:"creator"`
+	CreateTime    int64   `json:"create_time"`
+}
+
+type StatementOrderInfo struct {
+	PurchaseID  uint32  `json:"purchase_id"`
+	TotalAmount float64 `json:"total_amount"`
+	PayAmount   float64 `json:"pay_amount"`
+	ReceiveTime int64   `json:"receive_time"`
+}
+
+type PaymentInfo struct {
+	PaymentID uint32  `json:"payment_id"`
+	Amount    float64 `json:"amount"`
+	Voucher   string  `json:"voucher"`
+	Remark    string  `json:"remark"`
+	Operator  string  `json:"operator"`
+	PayTime   int64   `json:"pay_time"`
+}
+
+type StatementListReq struct {
+	PaginationReq
+	Uid        uint32 `json:"uid"`
+	SupplierID uint32 `json:"supplier_id"`
+	Status     int8   `json:"status"`
+}
+
+type StatementListRes struct {
+	PaginationRes
+	StatementList []*StatementInfo `json:"statement_list"`
+}
+
+type StatementDetailReq struct {
+	Uid         uint32 `json:"uid"`
+	StatementID uint32 `json:"statement_id"`
+}
+
+type StatementDetailRes struct {
+	Statement   *StatementInfo        `json:"statement"`
+	OrderList   []*StatementOrderInfo `json:"order_list"`
+	PaymentList []*PaymentInfo        `json:"payment_list"`
+}
+
+type GenerateStatementReq struct {
+	Uid        uint32 `json:"uid"`
+	SupplierID uint32 `json:"supplier_id"`
+	StartTime  int64  `json:"start_time"`
+	EndTime    int64  `json:"end_time"`
+}
+
+type GenerateStatementRes struct {
+	StatementID uint32 `json:"statement_id"`
+}
+
+type ConfirmStatementReq struct {
+	Uid         uint32 `json:"uid"`
+	StatementID uint32 `json:"statement_id"`
+	Agree       bool   `json:"agree"`
+	Reason      string `json:"reason"`
+}
+
+type RevokeStatementReq struct {
+	StatementID uint32 `json:"statement_id"`
+}
+
+type RecordPaymentReq struct {
+	Uid         uint32  `json:"uid"`
+	StatementID uint32  `json:"statement_id"`
+	Amount      float64 `json:"amount"`
+	Voucher     string  `json:"voucher"`
+	Remark      string  `json:"remark"`
+	PayTime     int64   `json:"pay_time"`
+}
+
+type PayableBalanceReq struct {
+}
+
+type PayableBalanceInfo struct {
+	SupplierID     uint32  `json:"supplier_id"`
+	SupplierName   string  `json:"supplier_name"`
+	UnbilledAmount float64 `json:"unbilled_amount"`
+	BilledAmount   float64 `json:"billed_amount"`
+	PaidAmount     float64 `json:"paid_amount"`
+	Outstanding    float64 `json:"outstanding"`
+	StatementCount uint32  `json:"statement_count"`
+	DisputedCount  uint32  `json:"disputed_count"`
+}
+
+type PayableBalanceRes struct {
+	BalanceList      []*PayableBalanceInfo `json:"balance_list"`
+	TotalOutstanding float64               `json:"total_outstanding"`
+}
+
+type SupplierScorecardReq struct {
+	Uid         uint32  `json:"uid"`
+	StartTime   int64   `json:"start_time"`
+	EndTime     int64   `json:"end_time"`
+	OnTimeHours float64 `json:"on_time_hours"`
+}
+
+type SupplierScoreInfo struct {
+	SupplierID       uint32  `json:"supplier_id"`
+	SupplierName     string  `json:"supplier_name"`
+	OrderCount       uint32  `json:"order_count"`
+	FinishCount      uint32  `json:"finish_count"`
+	AcceptHours      float64 `json:"accept_hours"`
+	LeadHours        float64 `json:"lead_hours"`
+	OnTimeRate       float64 `json:"on_time_rate"`
+	QuantityAccuracy float64 `json:"quantity_accuracy"`
+	ReturnRate       float64 `json:"return_rate"`
+	PriceChangeRate  float64 `json:"price_change_rate"`
+	Score            float64 `json:"score"`
+	Rank             uint32  `json:"rank"`
+}
+
+type SupplierScorecardRes struct {
+	StartTime int64                `json:"start_time"`
+	EndTime   int64                `json:"end_time"`
+	ScoreList []*SupplierScoreInfo `json:"score_list"`
+}
@@ -41,8 +41,9 @@ type OutboundGoodsInfo struct {
 }
 
 type ApplyOutboundReq struct {
-	Uid       uint32               `json:"uid"`
-	GoodsList []*OutboundGoodsInfo `json:"goods_list"`
+	Uid         uint32               `json:"uid"`
+	StoreTypeID uint32               `json:"store_type_id"`
+	GoodsList   []*OutboundGoodsInfo `json:"goods_list"`
 }
 
 type ReviewOutboundReq struct {
@@ -53,6 +54,45 @@ type FinishOutboundReq struct {
 	OutboundID uint32 `json:"outbound_id"`
 }
 
+type CancelOutboundReq struct {
+	DocumentOperateReq
+	OutboundID uint32 `json:"outbound_id"`
+}
+
+type ReverseOutboundReq struct {
+	DocumentOperateReq
+	OutboundID uint32 `json:"outbound_id"`
+}
+
+type CancelInventoryReq struct {
+	DocumentOperateReq
+	InventoryID uint32 `json:"inventory_id"`
+}
+
+type ReverseInventoryReq struct {
+	DocumentOperateReq
+	InventoryID uint32 `json:"inventory_id"`
+}
+
+type DocumentLogListReq struct {
+	DocType uint8  `json:"doc_type"`
+	DocID   uint32 `json:"doc_id"`
+}
+
+type DocumentLogInfo struct {
+	ID       uint32 `json:"id"`
+	DocType  uint8  `json:"doc_type"`
+	DocID    uint32 `json:"doc_id"`
+	Action   uint8  `json:"action"`
+	Operator string `json:"operator"`
+	Reason   string `json:"reason"`
+	CreateAt int64  `json:"created_at"`
+}
+
+type DocumentLogListRes struct {
+	LogList []*DocumentLogInfo `json:"log_list"`
+}
+
 type OutboundListReq struct {
 	PaginationReq
 	Uid        uint32 `json:"uid"`
@@ -64,11 +104,13 @@ type OutboundListReq struct {
 
 type OutboundOrderInfo struct {
 	ID               uint32               `json:"id"`
+	StoreTypeID      uint32               `json:"store_type_id"`
 	TotalGoodsNumber int32                `json:"total_goods_number"`
 	TotalGoodsType   int32                `json:"total_goods_type"`
 	TotalWeight      float64              `json:"total_weight"`
 	GoodsList        []*OutboundGoodsInfo `json:"goods_list"`
 	TotalAmount      float64              `json:"total_amount"`
+	CostAmount       float64              `json:"cost_amount"`
 	CreateTime       int64                `json:"create_time"`
 	OutboundTime     int64                `json:"outbound_time"`
 	Sender           string               `json:"sender"`
@@ -91,16 +133,25 @@ type InventoryListReq struct {
 
 type InventoryGoodsNode struct {
 	PurchaseGoodsBase
-	BatchSize  float64               `json:"batch_size"`
-	BatchUnit  string                `json:"batch_unit"`
-	RealNumber float64               `json:"real_number"`
-	Tag        string                `json:"tag"`
-	Status     int8                  `json:"status"`
-	Children   []*InventoryGoodsNode `json:"children,omitempty"`
+	StoreTypeID   uint32                `json:"store_type_id"`
+	LotID         uint32                `json:"lot_id,omitempty"`
+	BatchNo       string                `json:"batch_no,omitempty"`
+	BatchSize     float64               `json:"batch_size"`
+	BatchUnit     string                `json:"batch_unit"`
+	RealNumber    float64               `json:"real_number"`
+	Reason        uint8                 `json:"reason"`
+	VarianceValue float64               `json:"variance_value"`
+	Tag           string                `json:"tag"`
+	Status        int8                  `json:"status"`
+	Children      []*InventoryGoodsNode `json:"children,omitempty"`
 }
 
 type InventoryOrderInfo struct {
 	ID              uint32                `json:"id"`
+	PlanID          uint32                `json:"plan_id"`
+	GoodsTypeID     uint32                `json:"goods_type_id"`
+	StoreTypeID     uint32                `json:"store_type_id"`
+	AbcClass        uint8                 `json:"abc_class"`
 	TotalCount      int32                 `json:"total_count"`
 	ExceptionCount  int32                 `json:"exception_count"`
 	ExceptionAmount float64               `json:"exception_amount"`
@@ -110,6 +161,7 @@ type InventoryOrderInfo struct {
 	EndTime         int64                 `json:"end_time"`
 	Creator         string                `json:"creator"`
 	Partner         string                `json:"partner"`
+	Approver        string                `json:"approver"`
 }
 
 type InventoryListRes struct {
@@ -123,8 +175,9 @@ type InventoryReq struct {
 }
 
 type ApplyInventoryReq struct {
-	Uid         uint32 `json:"uid"`
-	InventoryID uint32 `json:"inventory_id"`
+	Uid           uint32 `json:"uid"`
+	InventoryID   uint32 `json:"inventory_id"`
+	SkipUncounted bool   `json:"skip_uncounted"`
 }
 
 type ConfirmInventoryReq struct {
@@ -136,9 +189,17 @@ type ReviewInventoryReq struct {
 	InventoryID uint32 `json:"inventory_id"`
 }
 
+type ApproveWriteOffReq struct {
+	Uid         uint32 `json:"uid"`
+	InventoryID uint32 `json:"inventory_id"`
+}
+
 type StartInventoryReq struct {
-	Uid uint32 `json:"uid"`
-	New bool   `json:"new"`
+	Uid         uint32 `json:"uid"`
+	New         bool   `json:"new"`
+	GoodsTypeID uint32 `json:"goods_type_id"`
+	StoreTypeID uint32 `json:"store_type_id"`
+	AbcClass    uint8  `json:"abc_class"`
 }
 
 type StartInventoryRes struct {
@@ -156,9 +217,13 @@ type GoodsHistoryReq struct {
 type GoodsHistoryInfo struct {
 	ID             uint32  `json:"id"`
 	GoodsID        uint32  `json:"goods_id"`
+	StoreTypeID    uint32  `json:"store_type_id"`
 	ChangeQuantity float64 `json:"change_quantity"`
 	BeforeQuantity float64 `json:"before_quantity"`
 	AfterQuantity  float64 `json:"after_quantity"`
+	UnitCost       float64 `json:"unit_cost"`
+	ChangeValue    float64 `json:"change_value"`
+	AfterValue     float64 `json:"after_value"`
 	ChangeType     uint32  `json:"change_type"`
 	RefID          uint32  `json:"ref_id"`
 	CreateAt       int64   `json:"created_at"`
@@ -168,3 +233,219 @@ type GoodsHistoryRes struct {
 	PaginationRes
 	History []*GoodsHistoryInfo `json:"history"`
 }
+
+type GoodsPriceHistoryReq struct {
+	PaginationReq
+	GoodsID   uint32 `json:"goods_id"`
+	StartTime int64  `json:"start_time"`
+	EndTime   int64  `json:"end_time"`
+}
+
+type GoodsPriceHistoryInfo struct {
+	ID           uint32  `json:"id"`
+	GoodsID      uint32  `json:"goods_id"`
+	BeforePrice  float64 `json:"before_price"`
+	AfterPrice   float64 `json:"after_price"`
+	QuotePrice   float64 `json:"quote_price"`
+	ChangeType   uint32  `json:"change_type"`
+	SupplierID   uint32  `json:"supplier_id"`
+	SupplierName string  `json:"supplier_name"`
+	RefID        uint32  `json:"ref_id"`
+	Operator     uint32  `json:"operator"`
+	CreateAt     int64   `json:"created_at"`
+}
+
+type GoodsPriceHistoryRes struct {
+	PaginationRes
+	History []*GoodsPriceHistoryInfo `json:"history"`
+}
+
+type ExpiringLotReq struct {
+	GoodsID uint32 `json:"goods_id"`
+	Days    int64  `json:"days"`
+}
+
+type StockLotInfo struct {
+	LotID          uint32  `json:"lot_id"`
+	LotCode        string  `json:"lot_code"`
+	GoodsID        uint32  `json:"goods_id"`
+	GoodsName      string  `json:"goods_name"`
+	PurchaseID     uint32  `json:"purchase_id"`
+	BatchNo        string  `json:"batch_no"`
+	ProductionDate int64   `json:"production_date"`
+	ExpiryDate     int64   `json:"expiry_date"`
+	InitQuantity   float64 `json:"init_quantity"`
+	Quantity       float64 `json:"quantity"`
+	RemainDays     int64   `json:"remain_days"`
+	Expired        bool    `json:"expired"`
+}
+
+type ExpiringLotRes struct {
+	LotList []*StockLotInfo `json:"lot_list"`
+}
+
+type GoodsStockListReq struct {
+	GoodsID     uint32 `json:"goods_id"`
+	StoreTypeID uint32 `json:"store_type_id"`
+}
+
+type GoodsStockInfo struct {
+	GoodsID       uint32  `json:"goods_id"`
+	GoodsName     string  `json:"goods_name"`
+	StoreTypeID   uint32  `json:"store_type_id"`
+	StoreTypeName string  `json:"store_type_name"`
+	Quantity      float64 `json:"quantity"`
+}
+
+type GoodsStockListRes struct {
+	StockList []*GoodsStockInfo `json:"stock_list"`
+}
+
+type TransferGoodsInfo struct {
+	GoodsID        uint32  `json:"goods_id"`
+	GoodsName      string  `json:"goods_name"`
+	TransferNumber float64 `json:"transfer_number"`
+}
+
+type TransferGoodsReq struct {
+	Uid       uint32               `json:"uid"`
+	FromStore uint32               `json:"from_store"`
+	ToStore   uint32               `json:"to_store"`
+	Remark    string               `json:"remark"`
+	GoodsList []*TransferGoodsInfo `json:"goods_list"`
+}
+
+type TransferListReq struct {
+	PaginationReq
+	StoreTypeID uint32 `json:"store_type_id"`
+	StartTime   int64  `json:"start_time"`
+	EndTime     int64  `json:"end_time"`
+}
+
+type TransferOrderInfo struct {
+	TransferID    uint32               `json:"transfer_id"`
+	FromStore     uint32               `json:"from_store"`
+	FromStoreName string               `json:"from_store_name"`
+	ToStore       uint32               `json:"to_store"`
+	ToStoreName   string               `json:"to_store_name"`
+	Creator       string               `json:"creator"`
+	Remark        string               `json:"remark"`
+	GoodsList     []*TransferGoodsInfo `json:"goods_list"`
+	CreateTime    int64                `json:"create_time"`
+}
+
+type TransferListRes struct {
+	PaginationRes
+	TransferList []*TransferOrderInfo `json:"transfer_list"`
+}
+
+type StockAlertListReq struct {
+	GoodsID uint32 `json:"goods_id"`
+	Status  int8   `json:"status"`
+}
+
+type StockAlertInfo struct {
+	AlertID       uint32  `json:"alert_id"`
+	GoodsID       uint32  `json:"goods_id"`
+	GoodsName     string  `json:"goods_name"`
+	BatchUnit     string  `json:"batch_unit"`
+	Level         uint8   `json:"level"`
+	Quantity      float64 `json:"quantity"`
+	ReorderPoint  float64 `json:"reorder_point"`
+	MinQuantity   float64 `json:"min_quantity"`
+	ReorderNumber float64 `json:"reorder_number"`
+	Status        int8    `json:"status"`
+	CreateTime    int64   `json:"create_time"`
+}
+
+type StockAlertListRes struct {
+	AlertList []*StockAlertInfo `json:"alert_list"`
+}
+
+type StockValuationReq struct {
+	Date        int64  `json:"date"`
+	GoodsTypeID uint32 `json:"goods_type_id"`
+}
+
+type StockValuationInfo struct {
+	GoodsID     uint32  `json:"goods_id"`
+	GoodsName   string  `json:"goods_name"`
+	GoodsTypeID uint32  `json:"goods_type_id"`
+	BatchUnit   string  `json:"batch_unit"`
+	Quantity    float64 `json:"quantity"`
+	UnitCost    float64 `json:"unit_cost"`
+	StockValue  float64 `json:"stock_value"`
+}
+
+type StockValuationRes struct {
+	Date       int64                 `json:"date"`
+	TotalValue float64               `json:"total_value"`
+	GoodsList  []*StockValuationInfo `json:"goods_list"`
+}
+
+type CycleCountPlanInfo struct {
+	PlanID       uint32 `json:"plan_id"`
+	Name         string `json:"name"`
+	GoodsTypeID  uint32 `json:"goods_type_id"`
+	StoreTypeID  uint32 `json:"store_type_id"`
+	AbcClass     uint8  `json:"abc_class"`
+	IntervalDays uint32 `json:"interval_days"`
+	Counter      uint32 `json:"counter"`
+	NextCountAt  int64  `json:"next_count_at"`
+	Enable       bool   `json:"enable"`
+}
+
+type CycleCountPlanListReq struct {
+}
+
+type CycleCountPlanListRes struct {
+	PlanList []*CycleCountPlanInfo `json:"plan_list"`
+}
+
+type ModifyCycleCountPlanReq struct {
+	Operate enum.OperateType    `json:"operate"`
+	Plan    *CycleCountPlanInfo `json:"plan"`
+}
+
+type InventoryVarianceReq struct {
+	GoodsTypeID uint32 `json:"goods_type_id"`
+	StartTime   int64  `json:"start_time"`
+	EndTime     int64  `json:"end_time"`
+}
+
+type InventoryVarianceInfo struct {
+	GoodsTypeID   uint32  `json:"goods_type_id"`
+	GoodsTypeName string  `json:"goods_type_name"`
+	Reason        uint8   `json:"reason"`
+	LineCount     int32   `json:"line_count"`
+	GainValue     float64 `json:"gain_value"`
+	LossValue     float64 `json:"loss_value"`
+	NetValue      float64 `json:"net_value"`
+}
+
+type InventoryVarianceRes struct {
+	TotalGainValue float64                  `json:"total_gain_value"`
+	TotalLossValue float64                  `json:"total_loss_value"`
+	VarianceList   []*InventoryVarianceInfo `json:"variance_list"`
+}
+
+type ScanCodeReq struct {
+	Code string `json:"code"`
+}
+
+type ScanCodeRes struct {
+	Goods *GoodsInfo    `json:"goods"`
+	Lot   *StockLotInfo `json:"lot,omitempty"`
+}
+
+type ScanInventoryReq struct {
+	InventoryID uint32  `json:"inventory_id"`
+	Code        string  `json:"code"`
+	StoreTypeID uint32  `json:"store_type_id"`
+	Number      float64 `json:"number"`
+	Unit        string  `json:"unit"`
+}
+
+type ScanInventoryRes struct {
+	InventoryGoods *InventoryGoodsNode `json:"inventory_goods"`
+}
@@ -12,6 +12,21 @@ const (
 	MealALL
 )
 
+type WasteType = uint8
+
+const (
+	WastePlate    WasteType = iota + 1 // 餐盘剩余
+	WasteUnserved                      // 未售出份数
+	WastePrep                          // 备餐损耗，扣减库存
+)
+
+type DishStatus = int8
+
+const (
+	DishActive DishStatus = iota
+	DishRetired
+)
+
 const (
 	MealBreakfastName = "早餐"
 	MealLunchName     = "午餐"
@@ -63,3 +78,12 @@ func GetMealType(name string) MealType {
 	}
 	return MealUnknown
 }
+
+func GetMealTypeByName(name string) MealType {
+	for timeType, mealName := range mealNameMap {
+		if mealName == name {
+			return timeType
+		}
+	}
+	return MealUnknown
+}
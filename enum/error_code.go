@@ -9,6 +9,7 @@ const (
 	SqlError
 	TokenCheckFailed
 	TokenTimeout
+	PermissionDenied
 
 	OrderTimeLimit = 100
 
@@ -23,6 +24,7 @@ var (
 		SqlError:           "数据库错误",
 		TokenCheckFailed:   "token检查失败",
 		TokenTimeout:       "token已过期",
+		PermissionDenied:   "无操作权限",
 	}
 )
 
@@ -7,4 +7,55 @@ const (
 	GoodsPurchase
 	GoodsOutbound
 	GoodsInventory
+	GoodsPurchaseReturn
+	GoodsTransfer
+	GoodsReversal
+	GoodsWaste
+)
+
+type PriceChangeType = uint32
+
+const (
+	PriceChangeManual PriceChangeType = iota + 1
+	PriceChangeQuotation
+)
+
+type StockAlertLevel = uint8
+
+const (
+	StockLevelNormal StockAlertLevel = iota
+	StockLevelReorder
+	StockLevelMin
+)
+
+type StockAlertStatus = int8
+
+const (
+	StockAlertStatusAll                  = -1
+	StockAlertOpen      StockAlertStatus = iota - 1
+	StockAlertResolved
+)
+
+type CostMethod = uint8
+
+const (
+	CostMethodAverage CostMethod = iota
+	CostMethodFIFO
+)
+
+type AbcClass = uint8
+
+const (
+	AbcClassAll AbcClass = iota
+	AbcClassA
+	AbcClassB
+	AbcClassC
+)
+
+type GoodsUnitType = uint8
+
+const (
+	GoodsUnitPurchase GoodsUnitType = iota + 1
+	GoodsUnitStock
+	GoodsUnitRecipe
 )
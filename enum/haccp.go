@@ -0,0 +1,24 @@
+package enum
+
+type HaccpPointType = uint8
+
+const (
+	HaccpPointStorage HaccpPointType = iota + 1 // 库房温度，关联库房
+	HaccpPointCooking                           // 烹饪中心温度，关联菜品类别
+)
+
+type HaccpSource = uint8
+
+const (
+	HaccpSourceManual HaccpSource = iota + 1
+	HaccpSourceSensor
+)
+
+type HaccpAlertStatus = int8
+
+const (
+	HaccpAlertStatusAll                  = -1
+	HaccpAlertNone      HaccpAlertStatus = iota - 1
+	HaccpAlertOpen
+	HaccpAlertResolved
+)
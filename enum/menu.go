@@ -0,0 +1,28 @@
+package enum
+
+// OrderMenuTypeID 员工点餐使用的菜单类型
+const OrderMenuTypeID uint32 = 1
+
+type WeekMenuStatus = int8
+
+const (
+	WeekMenuDraft WeekMenuStatus = iota
+	WeekMenuReviewing
+	WeekMenuPublished
+	WeekMenuArchived
+)
+
+var weekMenuStatusNameMap = map[WeekMenuStatus]string{
+	WeekMenuDraft:     "草稿",
+	WeekMenuReviewing: "审核中",
+	WeekMenuPublished: "已发布",
+	WeekMenuArchived:  "已归档",
+}
+
+func GetWeekMenuStatusName(status WeekMenuStatus) string {
+	name, ok := weekMenuStatusNameMap[status]
+	if ok {
+		return name
+	}
+	return ""
+}
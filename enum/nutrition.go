@@ -0,0 +1,60 @@
+package enum
+
+import "fmt"
+
+type AllergenType = uint8
+
+const (
+	AllergenUnknown AllergenType = iota
+	AllergenGluten
+	AllergenCrustacean
+	AllergenEgg
+	AllergenFish
+	AllergenPeanut
+	AllergenSoy
+	AllergenMilk
+	AllergenNut
+	AllergenSesame
+	AllergenMax
+)
+
+var allergenNameMap = map[AllergenType]string{
+	AllergenGluten:     "麸质",
+	AllergenCrustacean: "甲壳类",
+	AllergenEgg:        "蛋类",
+	AllergenFish:       "鱼类",
+	AllergenPeanut:     "花生",
+	AllergenSoy:        "大豆",
+	AllergenMilk:       "乳制品",
+	AllergenNut:        "坚果",
+	AllergenSesame:     "芝麻",
+}
+
+func GetAllergenName(allergen AllergenType) string {
+	name, ok := allergenNameMap[allergen]
+	if ok {
+		return name
+	}
+	return fmt.Sprintf("Unknown Allergen:%v", allergen)
+}
+
+func GetAllergenList(allergenMask uint32) []AllergenType {
+	retList := make([]AllergenType, 0)
+	for allergen := AllergenUnknown + 1; allergen < AllergenMax; allergen++ {
+		if allergenMask&(1<<allergen) > 0 {
+			retList = append(retList, allergen)
+		}
+	}
+	return retList
+}
+
+func GetAllergenMask(allergenList []AllergenType) uint32 {
+	allergenMask := uint32(0)
+	for _, allergen := range allergenList {
+		if allergen <= AllergenUnknown || allergen >= AllergenMax {
+			continue
+		}
+		allergenMask |= 1 << allergen
+	}
+	return allergenMask
+}
@@ -25,8 +25,11 @@ const (
 	PurchaseNew PurchaseStatus = iota
 	PurchaseReviewed
 	PurchaseAccept
-	PurchaseReceived
+	PurchaseReceived // 部分收货
 	PurchaseFinish
+	PurchaseRejected
+	PurchaseCancel
+	PurchaseReversed
 )
 
 var buildingMap = map[uint32]string{
@@ -56,6 +59,8 @@ const (
 	OutboundNew OutboundStatus = iota
 	OutboundReviewed
 	OutboundFinish
+	OutboundCancel
+	OutboundReversed
 )
 
 type InventoryOrderStatus = int8
@@ -65,6 +70,8 @@ const (
 	InventoryOrderFinish
 	InventoryOrderConfirmed
 	InventoryOrderReviewed
+	InventoryOrderCancel
+	InventoryOrderReversed
 )
 
 type InventoryStatus = int8
@@ -73,4 +80,30 @@ const (
 	InventoryNew InventoryStatus = iota
 	InventoryMatch
 	InventoryNeedFix
+	InventorySkipped
+)
+
+type VarianceReason = uint8
+
+const (
+	VarianceReasonNone VarianceReason = iota
+	VarianceSpoilage
+	VarianceTheft
+	VarianceCountingError
+	VarianceTransfer
+)
+
+type DocumentType = uint8
+
+const (
+	DocumentOutbound DocumentType = iota + 1
+	DocumentPurchase
+	DocumentInventory
+)
+
+type DocumentAction = uint8
+
+const (
+	DocumentCancel DocumentAction = iota + 1
+	DocumentReverse
 )
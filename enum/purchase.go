@@ -10,3 +10,91 @@ const (
 	PurchaseOrderReceive
 	PurchaseOrderFinish
 )
+
+type QuotationStatus = int8
+
+const (
+	QuotationStatusAll                 = -1
+	QuotationOpen      QuotationStatus = iota - 1
+	QuotationAwarded
+	QuotationCancelled
+)
+
+type ReturnReason = uint8
+
+const (
+	ReturnSpoiled ReturnReason = iota + 1
+	ReturnDamaged
+	ReturnQuality
+	ReturnWrongGoods
+	ReturnExpired
+	ReturnOther
+)
+
+var returnReasonNameMap = map[ReturnReason]string{
+	ReturnSpoiled:    "变质腐烂",
+	ReturnDamaged:    "破损",
+	ReturnQuality:    "质量不合格",
+	ReturnWrongGoods: "错发货品",
+	ReturnExpired:    "临期过期",
+	ReturnOther:      "其他",
+}
+
+func GetReturnReasonName(reason ReturnReason) string {
+	name, ok := returnReasonNameMap[reason]
+	if ok {
+		return name
+	}
+	return ""
+}
+
+type StatementStatus = int8
+
+const (
+	StatementStatusAll                 = -1
+	StatementPending   StatementStatus = iota - 1
+	StatementConfirmed
+	StatementDisputed
+	StatementSettled
+)
+
+type QualificationType = uint8
+
+const (
+	QualificationLicence QualificationType = iota + 1
+	QualificationFoodPermit
+	QualificationInspection
+	QualificationContract
+	QualificationOther
+)
+
+var qualificationNameMap = map[QualificationType]string{
+	QualificationLicence:    "营业执照",
+	QualificationFoodPermit: "食品经营许可证",
+	QualificationInspection: "检验检疫报告",
+	QualificationContract:   "供货合同",
+	QualificationOther:      "其他",
+}
+
+var mandatoryQualificationList = []QualificationType{QualificationLicence, QualificationFoodPermit}
+
+func GetQualificationName(qualificationType QualificationType) string {
+	name, ok := qualificationNameMap[qualificationType]
+	if ok {
+		return name
+	}
+	return ""
+}
+
+func GetMandatoryQualificationList() []QualificationType {
+	return mandatoryQualificationList
+}
+
+func IsMandatoryQualification(qualificationType QualificationType) bool {
+	for _, mandatoryType := range mandatoryQualificationList {
+		if mandatoryType == qualificationType {
+			return true
+		}
+	}
+	return false
+}
@@ -79,7 +79,17 @@ func StartServer() {
 		logger.Warn(serverLogTag, "HandleOrderApi Failed|Err:%v", err)
 		return
 	}
+	err = HandleHaccpApi(router)
+	if err != nil {
+		logger.Warn(serverLogTag, "HandleHaccpApi Failed|Err:%v", err)
+		return
+	}
 	HandleUploadApi(router)
+	err = StartTicker()
+	if err != nil {
+		logger.Warn(serverLogTag, "StartTicker Failed|Err:%v", err)
+		return
+	}
 
 	router.Run(":8081")
 }
@@ -155,10 +165,32 @@ func HandlePurchaseApi(router *gin.Engine) error {
 		func() interface{} { return new(dto.ApplyPurchaseReq) }))
 	purchaseRouter.POST("/reviewPurchase", NewHandler(purchaseServer.RequestReviewPurchase,
 		func() interface{} { return new(dto.ReviewPurchaseReq) }))
+	purchaseRouter.POST("/reorderPurchase", NewHandler(purchaseServer.RequestReorderPurchase,
+		func() interface{} { return new(dto.ReorderPurchaseReq) }))
+	purchaseRouter.POST("/editPurchase", NewHandler(purchaseServer.RequestEditPurchase,
+		func() interface{} { return new(dto.EditPurchaseReq) }))
+	purchaseRouter.POST("/purchaseApprovalList", NewHandler(purchaseServer.RequestPurchaseApprovalList,
+		func() interface{} { return new(dto.PurchaseApprovalListReq) }))
+	purchaseRouter.POST("/approvalChainList", NewHandler(purchaseServer.RequestApprovalChainList,
+		func() interface{} { return new(dto.ApprovalChainListReq) }))
+	purchaseRouter.POST("/modifyApprovalChain", NewHandler(purchaseServer.RequestModifyApprovalChain,
+		func() interface{} { return new(dto.ModifyApprovalChainReq) }))
 	purchaseRouter.POST("/confirmPurchase", NewHandler(purchaseServer.RequestConfirmPurchase,
 		func() interface{} { return new(dto.ConfirmPurchaseReq) }))
 	purchaseRouter.POST("/receivePurchase", NewHandler(purchaseServer.RequestReceivePurchase,
 		func() interface{} { return new(dto.ReceivePurchaseReq) }))
+	purchaseRouter.POST("/closePurchase", NewHandler(purchaseServer.RequestClosePurchase,
+		func() interface{} { return new(dto.ClosePurchaseReq) }))
+	purchaseRouter.POST("/cancelPurchase", NewHandler(purchaseServer.RequestCancelPurchase,
+		func() interface{} { return new(dto.CancelPurchaseReq) }))
+	purchaseRouter.POST("/reversePurchase", NewHandler(purchaseServer.RequestReversePurchase,
+		func() interface{} { return new(dto.ReversePurchaseReq) }))
+	purchaseRouter.POST("/purchaseReceiptList", NewHandler(purchaseServer.RequestPurchaseReceiptList,
+		func() interface{} { return new(dto.PurchaseReceiptListReq) }))
+	purchaseRouter.POST("/applyPurchaseReturn", NewHandler(purchaseServer.RequestApplyPurchaseReturn,
+		func() interface{} { return new(dto.ApplyPurchaseReturnReq) }))
+	purchaseRouter.POST("/purchaseReturnList", NewHandler(purchaseServer.RequestPurchaseReturnList,
+		func() interface{} { return new(dto.PurchaseReturnListReq) }))
 
 	purchaseRouter.POST("/applyOutbound", NewHandler(purchaseServer.RequestApplyOutbound,
 		func() interface{} { return new(dto.ApplyOutboundReq) }))
@@ -166,6 +198,10 @@ func HandlePurchaseApi(router *gin.Engine) error {
 		func() interface{} { return new(dto.ReviewOutboundReq) }))
 	purchaseRouter.POST("/finishOutbound", NewHandler(purchaseServer.RequestFinishOutbound,
 		func() interface{} { return new(dto.FinishOutboundReq) }))
+	purchaseRouter.POST("/cancelOutbound", NewHandler(purchaseServer.RequestCancelOutbound,
+		func() interface{} { return new(dto.CancelOutboundReq) }))
+	purchaseRouter.POST("/reverseOutbound", NewHandler(purchaseServer.RequestReverseOutbound,
+		func() interface{} { return new(dto.ReverseOutboundReq) }))
 	purchaseRouter.POST("/outboundList", NewHandler(purchaseServer.RequestOutboundOrderList,
 		func() interface{} { return new(dto.OutboundListReq) }))
 
@@ -177,6 +213,48 @@ func HandlePurchaseApi(router *gin.Engine) error {
 		func() interface{} { return new(dto.BindSupplierReq) }))
 	purchaseRouter.POST("/renewSupplier", NewHandler(purchaseServer.RequestRenewSupplier,
 		func() interface{} { return new(dto.RenewSupplierReq) }))
+	purchaseRouter.POST("/sourcingList", NewHandler(purchaseServer.RequestSourcingList,
+		func() interface{} { return new(dto.SourcingListReq) }))
+	purchaseRouter.POST("/modifySourcing", NewHandler(purchaseServer.RequestModifySourcing,
+		func() interface{} { return new(dto.ModifySourcingReq) }))
+	purchaseRouter.POST("/qualificationList", NewHandler(purchaseServer.RequestQualificationList,
+		func() interface{} { return new(dto.QualificationListReq) }))
+	purchaseRouter.POST("/modifyQualification", NewHandler(purchaseServer.RequestModifyQualification,
+		func() interface{} { return new(dto.ModifyQualificationReq) }))
+	purchaseRouter.POST("/qualificationAlert", NewHandler(purchaseServer.RequestQualificationAlert,
+		func() interface{} { return new(dto.QualificationAlertReq) }))
+
+	purchaseRouter.POST("/quotationList", NewHandler(purchaseServer.RequestQuotationList,
+		func() interface{} { return new(dto.QuotationListReq) }))
+	purchaseRouter.POST("/quotationDetail", NewHandler(purchaseServer.RequestQuotationDetail,
+		func() interface{} { return new(dto.QuotationDetailReq) }))
+	purchaseRouter.POST("/openQuotation", NewHandler(purchaseServer.RequestOpenQuotation,
+		func() interface{} { return new(dto.OpenQuotationReq) }))
+	purchaseRouter.POST("/submitQuote", NewHandler(purchaseServer.RequestSubmitQuote,
+		func() interface{} { return new(dto.SubmitQuoteReq) }))
+	purchaseRouter.POST("/awardQuotation", NewHandler(purchaseServer.RequestAwardQuotation,
+		func() interface{} { return new(dto.AwardQuotationReq) }))
+	purchaseRouter.POST("/cancelQuotation", NewHandler(purchaseServer.RequestCancelQuotation,
+		func() interface{} { return new(dto.CancelQuotationReq) }))
+	purchaseRouter.POST("/goodsPriceHistory", NewHandler(purchaseServer.RequestGoodsPriceHistory,
+		func() interface{} { return new(dto.GoodsPriceHistoryReq) }))
+
+	purchaseRouter.POST("/statementList", NewHandler(purchaseServer.RequestStatementList,
+		func() interface{} { return new(dto.StatementListReq) }))
+	purchaseRouter.POST("/statementDetail", NewHandler(purchaseServer.RequestStatementDetail,
+		func() interface{} { return new(dto.StatementDetailReq) }))
+	purchaseRouter.POST("/generateStatement", NewHandler(purchaseServer.RequestGenerateStatement,
+		func() interface{} { return new(dto.GenerateStatementReq) }))
+	purchaseRouter.POST("/confirmStatement", NewHandler(purchaseServer.RequestConfirmStatement,
+		func() interface{} { return new(dto.ConfirmStatementReq) }))
+	purchaseRouter.POST("/revokeStatement", NewHandler(purchaseServer.RequestRevokeStatement,
+		func() interface{} { return new(dto.RevokeStatementReq) }))
+	purchaseRouter.POST("/recordPayment", NewHandler(purchaseServer.RequestRecordPayment,
+		func() interface{} { return new(dto.RecordPaymentReq) }))
+	purchaseRouter.POST("/payableBalance", NewHandler(purchaseServer.RequestPayableBalance,
+		func() interface{} { return new(dto.PayableBalanceReq) }))
+	purchaseRouter.POST("/supplierScorecard", NewHandler(purchaseServer.RequestSupplierScorecard,
+		func() interface{} { return new(dto.SupplierScorecardReq) }))
 	return nil
 }
 
@@ -204,8 +282,14 @@ func HandleStorehouseApi(router *gin.Engine) error {
 		func() interface{} { return new(dto.GoodsNodeListReq) }))
 	storeRouter.POST("/modifyGoods", NewHandler(storeServer.RequestModifyGoods,
 		func() interface{} { return new(dto.ModifyGoodsInfoReq) }))
+	storeRouter.POST("/goodsUnitList", NewHandler(storeServer.RequestGoodsUnitList,
+		func() interface{} { return new(dto.GoodsUnitListReq) }))
+	storeRouter.POST("/modifyGoodsUnit", NewHandler(storeServer.RequestModifyGoodsUnit,
+		func() interface{} { return new(dto.ModifyGoodsUnitReq) }))
 	storeRouter.POST("/goodsHistory", NewHandler(storeServer.RequestGoodsHistory,
 		func() interface{} { return new(dto.GoodsHistoryReq) }))
+	storeRouter.POST("/documentLogList", NewHandler(storeServer.RequestDocumentLogList,
+		func() interface{} { return new(dto.DocumentLogListReq) }))
 
 	storeRouter.POST("/goodsPriceList", NewHandler(storeServer.RequestGoodsPriceList,
 		func() interface{} { return new(dto.GoodsPriceListReq) }))
@@ -224,6 +308,34 @@ func HandleStorehouseApi(router *gin.Engine) error {
 		func() interface{} { return new(dto.ConfirmInventoryReq) }))
 	storeRouter.POST("/reviewInventory", NewHandler(storeServer.RequestReviewInventory,
 		func() interface{} { return new(dto.ReviewInventoryReq) }))
+	storeRouter.POST("/cancelInventory", NewHandler(storeServer.RequestCancelInventory,
+		func() interface{} { return new(dto.CancelInventoryReq) }))
+	storeRouter.POST("/reverseInventory", NewHandler(storeServer.RequestReverseInventory,
+		func() interface{} { return new(dto.ReverseInventoryReq) }))
+	storeRouter.POST("/scanCode", NewHandler(storeServer.RequestScanCode,
+		func() interface{} { return new(dto.ScanCodeReq) }))
+	storeRouter.POST("/scanInventory", NewHandler(storeServer.RequestScanInventory,
+		func() interface{} { return new(dto.ScanInventoryReq) }))
+	storeRouter.POST("/approveWriteOff", NewHandler(storeServer.RequestApproveWriteOff,
+		func() interface{} { return new(dto.ApproveWriteOffReq) }))
+	storeRouter.POST("/inventoryVariance", NewHandler(storeServer.RequestInventoryVariance,
+		func() interface{} { return new(dto.InventoryVarianceReq) }))
+	storeRouter.POST("/expiringLotList", NewHandler(storeServer.RequestExpiringLot,
+		func() interface{} { return new(dto.ExpiringLotReq) }))
+	storeRouter.POST("/stockAlertList", NewHandler(storeServer.RequestStockAlertList,
+		func() interface{} { return new(dto.StockAlertListReq) }))
+	storeRouter.POST("/stockValuation", NewHandler(storeServer.RequestStockValuation,
+		func() interface{} { return new(dto.StockValuationReq) }))
+	storeRouter.POST("/cycleCountPlanList", NewHandler(storeServer.RequestCycleCountPlanList,
+		func() interface{} { return new(dto.CycleCountPlanListReq) }))
+	storeRouter.POST("/modifyCycleCountPlan", NewHandler(storeServer.RequestModifyCycleCountPlan,
+		func() interface{} { return new(dto.ModifyCycleCountPlanReq) }))
+	storeRouter.POST("/goodsStockList", NewHandler(storeServer.RequestGoodsStockList,
+		func() interface{} { return new(dto.GoodsStockListReq) }))
+	storeRouter.POST("/transferGoods", NewHandler(storeServer.RequestTransferGoods,
+		func() interface{} { return new(dto.TransferGoodsReq) }))
+	storeRouter.POST("/transferList", NewHandler(storeServer.RequestTransferList,
+		func() interface{} { return new(dto.TransferListReq) }))
 	return nil
 }
 
@@ -257,6 +369,18 @@ func HandleMenuApi(router *gin.Engine) error {
 		func() interface{} { return new(dto.WeekMenuListReq) }))
 	menuRouter.POST("/weekMenuDetailTable", NewHandler(menuServer.RequestWeekMenuDetailTable,
 		func() interface{} { return new(dto.WeekMenuDetailReq) }))
+	menuRouter.POST("/exportWeekMenu", NewHandler(menuServer.RequestExportWeekMenu,
+		func() interface{} { return new(dto.ExportWeekMenuReq) }))
+	menuRouter.POST("/importWeekMenu", NewHandler(menuServer.RequestImportWeekMenu,
+		func() interface{} { return new(dto.ImportWeekMenuReq) }))
+	menuRouter.POST("/submitWeekMenu", NewHandler(menuServer.RequestSubmitWeekMenu,
+		func() interface{} { return new(dto.SubmitWeekMenuReq) }))
+	menuRouter.POST("/reviewWeekMenu", NewHandler(menuServer.RequestReviewWeekMenu,
+		func() interface{} { return new(dto.ReviewWeekMenuReq) }))
+	menuRouter.POST("/archiveWeekMenu", NewHandler(menuServer.RequestArchiveWeekMenu,
+		func() interface{} { return new(dto.ArchiveWeekMenuReq) }))
+	menuRouter.POST("/weekMenuHistory", NewHandler(menuServer.RequestWeekMenuHistory,
+		func() interface{} { return new(dto.WeekMenuHistoryReq) }))
 	menuRouter.POST("/modifyWeekMenu", NewHandler(menuServer.RequestModifyWeekMenu,
 		func() interface{} { return new(dto.ModifyWeekMenuDetailReq) }))
 
@@ -289,6 +413,16 @@ func HandleMenuApi(router *gin.Engine) error {
 	return nil
 }
 
+func StartTicker() error {
+	tickerServer, err := server.NewTickerServer(config.Config.MysqlConfig)
+	if err != nil {
+		logger.Warn(serverLogTag, "NewTickerServer Failed|Err:%v", err)
+		return err
+	}
+	tickerServer.Start()
+	return nil
+}
+
 func HandleStatisticApi(router *gin.Engine) error {
 	statisticRouter := router.Group("/api/statistic")
 	statisticServer, err := server.NewStatisticServer(config.Config.MysqlConfig)
@@ -340,6 +474,19 @@ func HandleOrderApi(router *gin.Engine) error {
 	orderRouter.POST("/modifyCart", NewHandler(orderServer.RequestModifyCart,
 		func() interface{} { return new(dto.ModifyCartReq) }))
 
+	orderRouter.POST("/rateOrder", NewHandler(orderServer.RequestRateOrder,
+		func() interface{} { return new(dto.RateOrderReq) }))
+	orderRouter.POST("/dishRatingList", NewHandler(orderServer.RequestDishRatingList,
+		func() interface{} { return new(dto.DishRatingListReq) }))
+	orderRouter.POST("/dishRatingSummary", NewHandler(orderServer.RequestDishRatingSummary,
+		func() interface{} { return new(dto.DishRatingSummaryReq) }))
+	orderRouter.POST("/recordWaste", NewHandler(orderServer.RequestRecordWaste,
+		func() interface{} { return new(dto.RecordWasteReq) }))
+	orderRouter.POST("/wasteList", NewHandler(orderServer.RequestWasteList,
+		func() interface{} { return new(dto.WasteListReq) }))
+	orderRouter.POST("/wasteReport", NewHandler(orderServer.RequestWasteReport,
+		func() interface{} { return new(dto.WasteReportReq) }))
+
 	orderRouter.POST("/orderDiscountList", NewHandler(orderServer.RequestDiscountList,
 		func() interface{} { return new(dto.OrderDiscountListReq) }))
 	orderRouter.POST("/modifyOrderDiscount", NewHandler(orderServer.RequestModifyDiscount,
@@ -347,6 +494,34 @@ func HandleOrderApi(router *gin.Engine) error {
 	return nil
 }
 
+func HandleHaccpApi(router *gin.Engine) error {
+	haccpRouter := router.Group("/api/haccp")
+	haccpServer, err := server.NewHaccpServer(config.Config.MysqlConfig)
+	if err != nil {
+		logger.Warn(serverLogTag, "NewHaccpServer Failed|Err:%v", err)
+		return err
+	}
+	// 传感器上报以设备编号识别、设备密钥签名鉴权，不校验登录态
+	sensorRouter := router.Group("/api/sensor")
+	sensorRouter.POST("/ingest", NewHandler(haccpServer.RequestIngestReading,
+		func() interface{} { return new(dto.HaccpIngestReq) }))
+
+	haccpRouter.Use(CheckToken)
+	haccpRouter.POST("/pointList", NewHandler(haccpServer.RequestPointList,
+		func() interface{} { return new(dto.HaccpPointListReq) }))
+	haccpRouter.POST("/modifyPoint", NewHandler(haccpServer.RequestModifyPoint,
+		func() interface{} { return new(dto.ModifyHaccpPointReq) }))
+	haccpRouter.POST("/recordReading", NewHandler(haccpServer.RequestRecordReading,
+		func() interface{} { return new(dto.RecordHaccpReadingReq) }))
+	haccpRouter.POST("/readingList", NewHandler(haccpServer.RequestReadingList,
+		func() interface{} { return new(dto.HaccpReadingListReq) }))
+	haccpRouter.POST("/resolveAlert", NewHandler(haccpServer.RequestResolveAlert,
+		func() interface{} { return new(dto.ResolveHaccpAlertReq) }))
+	haccpRouter.POST("/exportLog", NewHandler(haccpServer.RequestExportLog,
+		func() interface{} { return new(dto.ExportHaccpLogReq) }))
+	return nil
+}
+
 func HandleUploadApi(router *gin.Engine) {
 	uploadRouter := router.Group("/api/")
 
@@ -464,14 +639,14 @@ func CheckSign(c *gin.Context) {
 	paramJson := make(map[string]json.RawMessage)
 	jsonStr, err := ioutil.ReadAll(c.Request.Body)
 	if err != nil {
-		logger.Warn(serverLogTag, "Read RequestBody Failed|Err:%v")
+		logger.Warn(serverLogTag, "Read RequestBody Failed|Err:%v", err)
 		c.AbortWithError(http.StatusBadRequest, err)
 		return
 	}
 
 	err = json.Unmarshal(jsonStr, &paramJson)
 	if err != nil {
-		logger.Warn(serverLogTag, "Parse Request Json Failed|Err:%v")
+		logger.Warn(serverLogTag, "Parse Request Json Failed|Err:%v", err)
 		c.AbortWithError(http.StatusBadRequest, err)
 		return
 	}
@@ -497,7 +672,7 @@ func CheckSign(c *gin.Context) {
 		}
 	}
 
-	logger.Debug(serverLogTag, "Params:v", paramMap)
+	logger.Debug(serverLogTag, "Params:%v", paramMap)
 
 	custom := dto.GetCustomContextInfo(c)
 	custom.ParamMap = paramMap
@@ -0,0 +1,78 @@
+package model
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/canteen_management/logger"
+	"github.com/canteen_management/utils"
+)
+
+const (
+	approvalChainTable = "approval_chain"
+
+	approvalChainLogTag = "ApprovalChainModel"
+)
+
+var (
+	approvalChainUpdateTags = []string{"step", "min_amount", "role"}
+)
+
+type ApprovalChain struct {
+	ID        uint32    `json:"id"`
+	Step      uint8     `json:"step"`
+	MinAmount float64   `json:"min_amount"` // 采购金额不低于该值时需要此级审批
+	Role      uint8     `json:"role"`
+	CreateAt  time.Time `json:"created_at"`
+	UpdateAt  time.Time `json:"updated_at"`
+}
+
+type ApprovalChainModel struct {
+	sqlCli *sql.DB
+}
+
+func NewApprovalChainModelWithDB(sqlCli *sql.DB) *ApprovalChainModel {
+	return &ApprovalChainModel{
+		sqlCli: sqlCli,
+	}
+}
+
+func (acm *ApprovalChainModel) Insert(dao *ApprovalChain) error {
+	id, err := utils.SqlInsert(acm.sqlCli, approvalChainTable, dao, "id", "created_at", "updated_at")
+	if err != nil {
+		logger.Warn(approvalChainLogTag, "Insert Failed|Dao:%+v|Err:%v", dao, err)
+		return err
+	}
+	dao.ID = uint32(id)
+	return nil
+}
+
+func (acm *ApprovalChainModel) GetChainList() ([]*ApprovalChain, error) {
+	condition := " ORDER BY `step` ASC "
+	retList, err := utils.SqlQuery(acm.sqlCli, approvalChainTable, &ApprovalChain{}, condition)
+	if err != nil {
+		logger.Warn(approvalChainLogTag, "GetChainList Failed|Err:%v", err)
+		return nil, err
+	}
+	return retList.([]*ApprovalChain), nil
+}
+
+func (acm *ApprovalChainModel) UpdateChain(dao *ApprovalChain) error {
+	err := utils.SqlUpdateWithUpdateTags(acm.sqlCli, approvalChainTable, dao, "id", approvalChainUpdateTags...)
+	if err != nil {
+		logger.Warn(approvalChainLogTag, "UpdateChain Failed|Err:%v", err)
+		return err
+	}
+	return nil
+}
+
+func (acm *ApprovalChainModel) DeleteChain(id uint32) error {
+	sqlStr := fmt.Sprintf(" DELETE FROM %v WHERE `id` = ? ", approvalChainTable)
+	_, err := acm.sqlCli.Exec(sqlStr, id)
+	if err != nil {
+		logger.Warn(approvalChainLogTag, "DeleteChain Failed|Err:%v", err)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,193 @@
+package model
+
+import (
+	"database/sql"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/canteen_management/enum"
+	"github.com/canteen_management/logger"
+	"github.com/canteen_management/utils"
+)
+
+const (
+	costLayerTable = "cost_layer"
+
+	costLayerLogTag = "CostLayerModel"
+)
+
+type CostLayer struct {
+	ID           uint32    `json:"id"`
+	GoodsID      uint32    `json:"goods_id"`
+	ChangeType   uint32    `json:"change_type"`
+	RefID        uint32    `json:"ref_id"`
+	UnitCost     float64   `json:"unit_cost"`
+	InitQuantity float64   `json:"init_quantity"`
+	Quantity     float64   `json:"quantity"`
+	CreateAt     time.Time `json:"created_at"`
+	UpdateAt     time.Time `json:"updated_at"`
+}
+
+type CostLayerModel struct {
+	sqlCli     *sql.DB
+	costMethod enum.CostMethod
+}
+
+func NewCostLayerModelWithDB(sqlCli *sql.DB) *CostLayerModel {
+	return &CostLayerModel{
+		sqlCli:     sqlCli,
+		costMethod: enum.CostMethodAverage,
+	}
+}
+
+func (clm *CostLayerModel) SetCostMethod(costMethod enum.CostMethod) {
+	clm.costMethod = costMethod
+}
+
+func (clm *CostLayerModel) BatchInsertWithTx(tx *sql.Tx, layerList []*CostLayer) (err error) {
+	if len(layerList) == 0 {
+		return nil
+	}
+	if tx != nil {
+		err = utils.SqlInsertBatch(tx, costLayerTable, layerList, "id", "created_at", "updated_at")
+	} else {
+		err = utils.SqlInsertBatch(clm.sqlCli, costLayerTable, layerList, "id", "created_at", "updated_at")
+	}
+	if err != nil {
+		logger.Warn(costLayerLogTag, "BatchInsert Failed|Err:%v", err)
+		return err
+	}
+	return nil
+}
+
+func (clm *CostLayerModel) GetLayersByGoodsWithLock(tx *sql.Tx, goodsIDList []uint32) ([]*CostLayer, error) {
+	if len(goodsIDList) == 0 {
+		return make([]*CostLayer, 0), nil
+	}
+	idStr := ""
+	for _, goodsID := range goodsIDList {
+		idStr += fmt.Sprintf(",%v", goodsID)
+	}
+	condition := fmt.Sprintf(" WHERE `goods_id` in (%v) AND `quantity` > 0 ORDER BY `id` ASC ", idStr[1:])
+	retList, err := utils.SqlQueryWithLock(tx, costLayerTable, &CostLayer{}, condition)
+	if err != nil {
+		logger.Warn(costLayerLogTag, "GetLayersByGoodsWithLock Failed|Err:%v", err)
+		return nil, err
+	}
+	return retList.([]*CostLayer), nil
+}
+
+func (clm *CostLayerModel) BatchUpdateQuantityWithTx(tx *sql.Tx, layerList []*CostLayer) error {
+	if len(layerList) == 0 {
+		return nil
+	}
+	daoList := make([]interface{}, 0, len(layerList))
+	for _, layer := range layerList {
+		daoList = append(daoList, layer)
+	}
+	err := utils.SqlBatchUpdateTag(tx, costLayerTable, daoList, "id", "quantity")
+	if err != nil {
+		logger.Warn(costLayerLogTag, "BatchUpdateQuantity Failed|Err:%v", err)
+		return err
+	}
+	return nil
+}
+
+// ValueHistoryWithTx 为库存流水计算成本，goodsList 需为加锁后的商品，库存数量已按流水修改；
+// costMap 为入库单价，未指定时按当前库存单位成本入账
+func (clm *CostLayerModel) ValueHistoryWithTx(tx *sql.Tx, goodsList []*Goods, historyList []*GoodsHistory,
+	costMap map[uint32]float64) error {
+	issueIDList := make([]uint32, 0, len(historyList))
+	for _, history := range historyList {
+		if history.ChangeQuantity < 0 && history.ChangeType != enum.GoodsTransfer {
+			issueIDList = append(issueIDList, history.GoodsID)
+		}
+	}
+
+	layerMap := make(map[uint32][]*CostLayer)
+	if clm.costMethod == enum.CostMethodFIFO && len(issueIDList) > 0 {
+		layerList, err := clm.GetLayersByGoodsWithLock(tx, issueIDList)
+		if err != nil {
+			return err
+		}
+		for _, layer := range layerList {
+			layerMap[layer.GoodsID] = append(layerMap[layer.GoodsID], layer)
+		}
+	}
+
+	insertList, updateList := clm.valueHistory(goodsList, historyList, costMap, layerMap)
+	err := clm.BatchInsertWithTx(tx, insertList)
+	if err != nil {
+		return err
+	}
+	return clm.BatchUpdateQuantityWithTx(tx, updateList)
+}
+
+// valueHistory 计算流水成本并同步商品库存金额，layerMap 为先进先出计价时各商品按入库顺序排列的成本层；
+// 返回需要新增的成本层与数量被扣减的成本层
+func (clm *CostLayerModel) valueHistory(goodsList []*Goods, historyList []*GoodsHistory, costMap map[uint32]float64,
+	layerMap map[uint32][]*CostLayer) ([]*CostLayer, []*CostLayer) {
+	goodsMap := make(map[uint32]*Goods)
+	for _, goods := range goodsList {
+		goodsMap[goods.ID] = goods
+	}
+	insertList, updateList := make([]*CostLayer, 0, len(historyList)), make([]*CostLayer, 0)
+	for _, history := range historyList {
+		goods, ok := goodsMap[history.GoodsID]
+		if !ok {
+			continue
+		}
+		if history.ChangeType == enum.GoodsTransfer {
+			history.UnitCost = goods.GetUnitCost(goods.Quantity)
+			history.ChangeValue = history.UnitCost * history.ChangeQuantity
+			history.AfterValue = goods.StockValue
+			continue
+		}
+		unitCost := goods.GetUnitCost(history.BeforeQuantity)
+		if history.ChangeQuantity >= 0 {
+			if cost, ok := costMap[goods.ID]; ok {
+				unitCost = cost
+			}
+			history.UnitCost = unitCost
+			history.ChangeValue = unitCost * history.ChangeQuantity
+			goods.StockValue += history.ChangeValue
+			if clm.costMethod == enum.CostMethodFIFO && history.ChangeQuantity > 0 {
+				insertList = append(insertList, &CostLayer{GoodsID: goods.ID, ChangeType: history.ChangeType,
+					RefID: history.RefID, UnitCost: unitCost, InitQuantity: history.ChangeQuantity,
+					Quantity: history.ChangeQuantity})
+			}
+			history.AfterValue = goods.StockValue
+			continue
+		}
+
+		issueNumber, issueValue := -history.ChangeQuantity, 0.0
+		if clm.costMethod == enum.CostMethodFIFO {
+			remain := issueNumber
+			for _, layer := range layerMap[goods.ID] {
+				if remain <= stockPrecision {
+					break
+				}
+				if layer.Quantity <= stockPrecision {
+					continue
+				}
+				consume := math.Min(remain, layer.Quantity)
+				layer.Quantity -= consume
+				remain -= consume
+				issueValue += consume * layer.UnitCost
+				updateList = append(updateList, layer)
+			}
+			issueValue += math.Max(remain, 0) * unitCost
+		} else {
+			issueValue = issueNumber * unitCost
+		}
+		if history.AfterQuantity <= stockPrecision || issueValue > goods.StockValue {
+			issueValue = math.Max(goods.StockValue, 0)
+		}
+		history.UnitCost = issueValue / issueNumber
+		history.ChangeValue = -issueValue
+		goods.StockValue -= issueValue
+		history.AfterValue = goods.StockValue
+	}
+	return insertList, updateList
+}
@@ -0,0 +1,143 @@
+package model
+
+import (
+	"math"
+	"testing"
+
+	"github.com/canteen_management/enum"
+)
+
+func TestValueHistory(t *testing.T) {
+	testList := []struct {
+		name            string
+		costMethod      enum.CostMethod
+		goods           *Goods
+		history         *GoodsHistory
+		costMap         map[uint32]float64
+		layerList       []*CostLayer
+		wantUnitCost    float64
+		wantChangeValue float64
+		wantStockValue  float64
+		wantInsert      int
+		wantLayerList   []float64
+	}{
+		{
+			name:            "average receipt at purchase cost",
+			costMethod:      enum.CostMethodAverage,
+			goods:           &Goods{ID: 1, Price: 10, Quantity: 15, StockValue: 100},
+			history:         GenerateGoodsHistory(1, 10, 5, enum.GoodsPurchase, 1),
+			costMap:         map[uint32]float64{1: 16},
+			wantUnitCost:    16,
+			wantChangeValue: 80,
+			wantStockValue:  180,
+		},
+		{
+			name:            "average receipt without cost uses unit cost",
+			costMethod:      enum.CostMethodAverage,
+			goods:           &Goods{ID: 1, Price: 10, Quantity: 12, StockValue: 80},
+			history:         GenerateGoodsHistory(1, 10, 2, enum.GoodsInventory, 1),
+			wantUnitCost:    8,
+			wantChangeValue: 16,
+			wantStockValue:  96,
+		},
+		{
+			name:            "average issue",
+			costMethod:      enum.CostMethodAverage,
+			goods:           &Goods{ID: 1, Price: 10, Quantity: 6, StockValue: 120},
+			history:         GenerateGoodsHistory(1, 10, -4, enum.GoodsOutbound, 1),
+			wantUnitCost:    12,
+			wantChangeValue: -48,
+			wantStockValue:  72,
+		},
+		{
+			name:            "average issue clears remaining value",
+			costMethod:      enum.CostMethodAverage,
+			goods:           &Goods{ID: 1, Price: 10, Quantity: 0, StockValue: 95},
+			history:         GenerateGoodsHistory(1, 10, -10, enum.GoodsOutbound, 1),
+			wantUnitCost:    9.5,
+			wantChangeValue: -95,
+			wantStockValue:  0,
+		},
+		{
+			name:            "transfer keeps value",
+			costMethod:      enum.CostMethodAverage,
+			goods:           &Goods{ID: 1, Price: 10, Quantity: 10, StockValue: 50},
+			history:         GenerateGoodsHistory(1, 10, -3, enum.GoodsTransfer, 1),
+			wantUnitCost:    5,
+			wantChangeValue: -15,
+			wantStockValue:  50,
+		},
+		{
+			name:            "fifo receipt opens layer",
+			costMethod:      enum.CostMethodFIFO,
+			goods:           &Goods{ID: 1, Price: 10, Quantity: 15, StockValue: 100},
+			history:         GenerateGoodsHistory(1, 10, 5, enum.GoodsPurchase, 1),
+			costMap:         map[uint32]float64{1: 12},
+			wantUnitCost:    12,
+			wantChangeValue: 60,
+			wantStockValue:  160,
+			wantInsert:      1,
+		},
+		{
+			name:       "fifo issue across layers",
+			costMethod: enum.CostMethodFIFO,
+			goods:      &Goods{ID: 1, Price: 10, Quantity: 4, StockValue: 104},
+			history:    GenerateGoodsHistory(1, 10, -6, enum.GoodsOutbound, 1),
+			layerList: []*CostLayer{
+				{ID: 1, GoodsID: 1, UnitCost: 8, Quantity: 4},
+				{ID: 2, GoodsID: 1, UnitCost: 12, Quantity: 6},
+			},
+			wantUnitCost:    56.0 / 6,
+			wantChangeValue: -56,
+			wantStockValue:  48,
+			wantLayerList:   []float64{0, 4},
+		},
+		{
+			name:       "fifo issue beyond layers uses unit cost",
+			costMethod: enum.CostMethodFIFO,
+			goods:      &Goods{ID: 1, Price: 10, Quantity: 4, StockValue: 100},
+			history:    GenerateGoodsHistory(1, 10, -6, enum.GoodsOutbound, 1),
+			layerList: []*CostLayer{
+				{ID: 1, GoodsID: 1, UnitCost: 8, Quantity: 4},
+			},
+			wantUnitCost:    52.0 / 6,
+			wantChangeValue: -52,
+			wantStockValue:  48,
+			wantLayerList:   []float64{0},
+		},
+	}
+	for _, tt := range testList {
+		t.Run(tt.name, func(t *testing.T) {
+			clm := &CostLayerModel{costMethod: tt.costMethod}
+			layerMap := make(map[uint32][]*CostLayer)
+			for _, layer := range tt.layerList {
+				layerMap[layer.GoodsID] = append(layerMap[layer.GoodsID], layer)
+			}
+			insertList, _ := clm.valueHistory([]*Goods{tt.goods}, []*GoodsHistory{tt.history}, tt.costMap, layerMap)
+			if !floatEqual(tt.history.UnitCost, tt.wantUnitCost) {
+				t.Fatalf("UnitCost Mismatch|Got:%v|Want:%v", tt.history.UnitCost, tt.wantUnitCost)
+			}
+			if !floatEqual(tt.history.ChangeValue, tt.wantChangeValue) {
+				t.Fatalf("ChangeValue Mismatch|Got:%v|Want:%v", tt.history.ChangeValue, tt.wantChangeValue)
+			}
+			if !floatEqual(tt.goods.StockValue, tt.wantStockValue) ||
+				!floatEqual(tt.history.AfterValue, tt.wantStockValue) {
+				t.Fatalf("StockValue Mismatch|Goods:%v|History:%v|Want:%v", tt.goods.StockValue,
+					tt.history.AfterValue, tt.wantStockValue)
+			}
+			if len(insertList) != tt.wantInsert {
+				t.Fatalf("Insert Layer Mismatch|Got:%v|Want:%v", len(insertList), tt.wantInsert)
+			}
+			for i, layer := range tt.layerList {
+				if !floatEqual(layer.Quantity, tt.wantLayerList[i]) {
+					t.Fatalf("Layer Quantity Mismatch|ID:%v|Got:%v|Want:%v", layer.ID, layer.Quantity,
+						tt.wantLayerList[i])
+				}
+			}
+		})
+	}
+}
+
+func floatEqual(a, b float64) bool {
+	return math.Abs(a-b) < stockPrecision
+}
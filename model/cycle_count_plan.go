@@ -0,0 +1,96 @@
+package model
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/canteen_management/logger"
+	"github.com/canteen_management/utils"
+)
+
+const (
+	cycleCountPlanTable = "cycle_count_plan"
+
+	cycleCountPlanLogTag = "CycleCountPlanModel"
+)
+
+var (
+	cycleCountPlanUpdateTags = []string{"name", "goods_type_id", "store_type_id", "abc_class", "interval_days",
+		"counter", "next_count_at", "enable"}
+)
+
+type CycleCountPlan struct {
+	ID           uint32    `json:"id"`
+	Name         string    `json:"name"`
+	GoodsTypeID  uint32    `json:"goods_type_id"`
+	StoreTypeID  uint32    `json:"store_type_id"`
+	AbcClass     uint8     `json:"abc_class"`
+	IntervalDays uint32    `json:"interval_days"`
+	Counter      uint32    `json:"counter"`
+	NextCountAt  time.Time `json:"next_count_at"`
+	Enable       bool      `json:"enable"`
+	CreateAt     time.Time `json:"created_at"`
+	UpdateAt     time.Time `json:"updated_at"`
+}
+
+type CycleCountPlanModel struct {
+	sqlCli *sql.DB
+}
+
+func NewCycleCountPlanModelWithDB(sqlCli *sql.DB) *CycleCountPlanModel {
+	return &CycleCountPlanModel{
+		sqlCli: sqlCli,
+	}
+}
+
+func (ccpm *CycleCountPlanModel) Insert(dao *CycleCountPlan) error {
+	id, err := utils.SqlInsert(ccpm.sqlCli, cycleCountPlanTable, dao, "id", "created_at", "updated_at")
+	if err != nil {
+		logger.Warn(cycleCountPlanLogTag, "Insert Failed|Dao:%+v|Err:%v", dao, err)
+		return err
+	}
+	dao.ID = uint32(id)
+	return nil
+}
+
+func (ccpm *CycleCountPlanModel) GetPlanList() ([]*CycleCountPlan, error) {
+	retList, err := utils.SqlQuery(ccpm.sqlCli, cycleCountPlanTable, &CycleCountPlan{}, " ORDER BY `id` ASC ")
+	if err != nil {
+		logger.Warn(cycleCountPlanLogTag, "GetPlanList Failed|Err:%v", err)
+		return nil, err
+	}
+	return retList.([]*CycleCountPlan), nil
+}
+
+func (ccpm *CycleCountPlanModel) GetDuePlans(deadline time.Time) ([]*CycleCountPlan, error) {
+	condition := " WHERE `enable` = ? AND `next_count_at` <= ? "
+	retList, err := utils.SqlQuery(ccpm.sqlCli, cycleCountPlanTable, &CycleCountPlan{}, condition, true, deadline)
+	if err != nil {
+		logger.Warn(cycleCountPlanLogTag, "GetDuePlans Failed|Err:%v", err)
+		return nil, err
+	}
+	return retList.([]*CycleCountPlan), nil
+}
+
+func (ccpm *CycleCountPlanModel) UpdatePlan(dao *CycleCountPlan, updateTags ...string) error {
+	if len(updateTags) == 0 {
+		updateTags = cycleCountPlanUpdateTags
+	}
+	err := utils.SqlUpdateWithUpdateTags(ccpm.sqlCli, cycleCountPlanTable, dao, "id", updateTags...)
+	if err != nil {
+		logger.Warn(cycleCountPlanLogTag, "UpdatePlan Failed|Err:%v", err)
+		return err
+	}
+	return nil
+}
+
+func (ccpm *CycleCountPlanModel) DeletePlan(id uint32) error {
+	sqlStr := fmt.Sprintf(" DELETE FROM %v WHERE `id` = ? ", cycleCountPlanTable)
+	_, err := ccpm.sqlCli.Exec(sqlStr, id)
+	if err != nil {
+		logger.Warn(cycleCountPlanLogTag, "DeletePlan Failed|Err:%v", err)
+		return err
+	}
+	return nil
+}
@@ -2,10 +2,12 @@ package model
 
 import (
 	"database/sql"
+	"encoding/json"
 	"fmt"
 	"strings"
 	"time"
 
+	"github.com/canteen_management/enum"
 	"github.com/canteen_management/logger"
 	"github.com/canteen_management/utils"
 )
@@ -17,18 +19,82 @@ const (
 )
 
 var (
-	dishUpdateTags = []string{"picture", "dish_name", "dish_type", "price", "material"}
+	dishUpdateTags = []string{"picture", "dish_name", "dish_type", "price", "material",
+		"nutrition", "allergen", "recipe", "status", "season_start", "season_end"}
 )
 
 type Dish struct {
-	ID       uint32    `json:"id"`
-	DishName string    `json:"dish_name"`
-	DishType uint32    `json:"dish_type"`
-	Picture  string    `json:"picture"`
-	Price    float64   `json:"price"`
-	Material string    `json:"material"`
-	CreateAt time.Time `json:"created_at"`
-	UpdateAt time.Time `json:"updated_at"`
+	ID          uint32    `json:"id"`
+	DishName    string    `json:"dish_name"`
+	DishType    uint32    `json:"dish_type"`
+	Picture     string    `json:"picture"`
+	Price       float64   `json:"price"`
+	Material    string    `json:"material"`
+	Nutrition   string    `json:"nutrition"`
+	Allergen    uint32    `json:"allergen"`
+	Recipe      string    `json:"recipe"`
+	Status      int8      `json:"status"`
+	SeasonStart uint32    `json:"season_start"` // 月日(MMDD)，起止均为0表示全年供应
+	SeasonEnd   uint32    `json:"season_end"`
+	IsDelete    bool      `json:"is_delete"`
+	CreateAt    time.Time `json:"created_at"`
+	UpdateAt    time.Time `json:"updated_at"`
+}
+
+func (d *Dish) InSeason(date time.Time) bool {
+	if d.SeasonStart == 0 && d.SeasonEnd == 0 {
+		return true
+	}
+	monthDay := uint32(date.Month())*100 + uint32(date.Day())
+	if d.SeasonStart <= d.SeasonEnd {
+		return monthDay >= d.SeasonStart && monthDay <= d.SeasonEnd
+	}
+	return monthDay >= d.SeasonStart || monthDay <= d.SeasonEnd
+}
+
+func (d *Dish) IsAvailable(date time.Time) bool {
+	return !d.IsDelete && d.Status == enum.DishActive && d.InSeason(date)
+}
+
+func (d *Dish) FromNutrition(nutrition *Nutrition) error {
+	content, err := marshalNutrition(nutrition)
+	if err != nil {
+		logger.Warn(dishLogTag, "FromNutrition Failed|Err:%v", err)
+		return err
+	}
+	d.Nutrition = content
+	return nil
+}
+
+func (d *Dish) ToNutrition() *Nutrition {
+	return unmarshalNutrition(d.Nutrition)
+}
+
+func (d *Dish) FromRecipe(recipe map[uint32]float64) error {
+	if len(recipe) == 0 {
+		d.Recipe = ""
+		return nil
+	}
+	contentStr, err := json.Marshal(recipe)
+	if err != nil {
+		logger.Warn(dishLogTag, "FromRecipe Failed|Err:%v", err)
+		return err
+	}
+	d.Recipe = string(contentStr)
+	return nil
+}
+
+func (d *Dish) ToRecipe() map[uint32]float64 {
+	recipe := make(map[uint32]float64)
+	if d.Recipe == "" {
+		return recipe
+	}
+	err := json.Unmarshal([]byte(d.Recipe), &recipe)
+	if err != nil {
+		logger.Warn(dishLogTag, "ToRecipe Failed|Err:%v", err)
+		return make(map[uint32]float64)
+	}
+	return recipe
 }
 
 type DishesModel struct {
@@ -93,7 +159,7 @@ func (dm *DishesModel) GetDishCountByCondition(condition string, params ...inter
 }
 
 func (dm *DishesModel) GetDishByName(dishName string) (*Dish, error) {
-	condition := " WHERE `dish_name` = ? "
+	condition := " WHERE `dish_name` = ? AND `is_delete` = 0 "
 	dishList, err := dm.GetDishByCondition(condition, dishName)
 	if err != nil {
 		logger.Warn(dishLogTag, "GetDishByName Failed|Err:%v", err)
@@ -113,7 +179,8 @@ func (dm *DishesModel) GetDishByNameList(dishNameList []string) ([]*Dish, error)
 	for _, dishName := range dishNameList {
 		params = append(params, dishName)
 	}
-	condition := fmt.Sprintf(" WHERE `dish_name` in (%v) ", strings.Repeat(",?", len(dishNameList))[1:])
+	condition := fmt.Sprintf(" WHERE `dish_name` in (%v) AND `is_delete` = 0 ",
+		strings.Repeat(",?", len(dishNameList))[1:])
 	dishList, err := dm.GetDishByCondition(condition, params...)
 	if err != nil {
 		logger.Warn(dishLogTag, "GetDishByName Failed|Err:%v", err)
@@ -122,13 +189,16 @@ func (dm *DishesModel) GetDishByNameList(dishNameList []string) ([]*Dish, error)
 	return dishList, nil
 }
 
-func (dm *DishesModel) GetDishes(dishType uint32, page, pageSize int32) ([]*Dish, error) {
+func (dm *DishesModel) GetDishes(dishType uint32, includeDelete bool, page, pageSize int32) ([]*Dish, error) {
 	var params []interface{}
 	condition := " WHERE 1=1 "
 	if dishType > 0 {
 		condition += " AND `dish_type` = ? "
 		params = append(params, dishType)
 	}
+	if !includeDelete {
+		condition += " AND `is_delete` = 0 "
+	}
 
 	if page >= 1 {
 		condition += " ORDER BY `id` ASC LIMIT ?,? "
@@ -140,7 +210,7 @@ func (dm *DishesModel) GetDishes(dishType uint32, page, pageSize int32) ([]*Dish
 
 func (dm *DishesModel) GetDishesCount(dishType uint32) (int32, error) {
 	var params []interface{}
-	condition := " WHERE 1=1 "
+	condition := " WHERE `is_delete` = 0 "
 	if dishType > 0 {
 		condition += " AND `dish_type` = ? "
 		params = append(params, dishType)
@@ -158,7 +228,7 @@ func (dm *DishesModel) UpdateDish(dao *Dish) error {
 }
 
 func (dm *DishesModel) DeleteDish(dishID uint32) error {
-	sqlStr := fmt.Sprintf(" DELETE FROM %v WHERE `id` = ? ", dishTable)
+	sqlStr := fmt.Sprintf(" UPDATE %v SET `is_delete` = 1 WHERE `id` = ? ", dishTable)
 	_, err := dm.sqlCli.Exec(sqlStr, dishID)
 	if err != nil {
 		logger.Warn(dishLogTag, "DeleteDish Failed|Err:%v", err)
@@ -0,0 +1,128 @@
+package model
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/canteen_management/logger"
+	"github.com/canteen_management/utils"
+)
+
+const (
+	dishRatingTable = "dish_rating"
+
+	dishRatingLogTag = "DishRating"
+)
+
+type DishRating struct {
+	ID            uint32    `json:"id"`
+	OrderID       uint32    `json:"order_id"`
+	OrderDetailID uint32    `json:"order_detail_id"`
+	DishID        uint32    `json:"dish_id"`
+	Uid           uint32    `json:"uid"`
+	Score         uint8     `json:"score"`
+	Comment       string    `json:"comment"`
+	Picture       string    `json:"picture"`
+	CreateAt      time.Time `json:"created_at"`
+}
+
+type DishRatingSummary struct {
+	DishID       uint32
+	RatingNumber int32
+	AverageScore float64
+}
+
+type DishRatingModel struct {
+	sqlCli *sql.DB
+}
+
+func NewDishRatingModelWithDB(sqlCli *sql.DB) *DishRatingModel {
+	return &DishRatingModel{
+		sqlCli: sqlCli,
+	}
+}
+
+func (drm *DishRatingModel) BatchInsert(ratingList []*DishRating) error {
+	err := utils.SqlInsertBatch(drm.sqlCli, dishRatingTable, ratingList, "id", "created_at")
+	if err != nil {
+		logger.Warn(dishRatingLogTag, "BatchInsert Failed|RatingList:%+v|Err:%v", ratingList, err)
+		return err
+	}
+	return nil
+}
+
+func (drm *DishRatingModel) GetRatingByOrder(orderID uint32) ([]*DishRating, error) {
+	retList, err := utils.SqlQuery(drm.sqlCli, dishRatingTable, &DishRating{}, " WHERE `order_id` = ? ", orderID)
+	if err != nil {
+		logger.Warn(dishRatingLogTag, "GetRatingByOrder Failed|OrderID:%v|Err:%v", orderID, err)
+		return nil, err
+	}
+	return retList.([]*DishRating), nil
+}
+
+func (drm *DishRatingModel) GenerateCondition(dishID uint32, startTime, endTime int64) (string, []interface{}) {
+	var params []interface{}
+	condition := " WHERE 1=1 "
+	if dishID > 0 {
+		condition += " AND `dish_id` = ? "
+		params = append(params, dishID)
+	}
+	if startTime > 0 {
+		condition += " AND `created_at` >= ? "
+		params = append(params, time.Unix(startTime, 0))
+	}
+	if endTime > startTime {
+		condition += " AND `created_at` <= ? "
+		params = append(params, time.Unix(endTime, 0))
+	}
+	return condition, params
+}
+
+func (drm *DishRatingModel) GetRatingList(dishID uint32, startTime, endTime int64, page, pageSize int32) ([]*DishRating, error) {
+	condition, params := drm.GenerateCondition(dishID, startTime, endTime)
+	condition += " ORDER BY `id` DESC LIMIT ?,? "
+	params = append(params, (page-1)*pageSize, pageSize)
+	retList, err := utils.SqlQuery(drm.sqlCli, dishRatingTable, &DishRating{}, condition, params...)
+	if err != nil {
+		logger.Warn(dishRatingLogTag, "GetRatingList Failed|DishID:%v|Err:%v", dishID, err)
+		return nil, err
+	}
+	return retList.([]*DishRating), nil
+}
+
+func (drm *DishRatingModel) GetRatingCount(dishID uint32, startTime, endTime int64) (int32, error) {
+	condition, params := drm.GenerateCondition(dishID, startTime, endTime)
+	sqlStr := fmt.Sprintf("SELECT COUNT(*) FROM `%v` %v", dishRatingTable, condition)
+	row := drm.sqlCli.QueryRow(sqlStr, params...)
+	var count int32 = 0
+	err := row.Scan(&count)
+	if err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+func (drm *DishRatingModel) GetRatingSummary(dishID uint32, startTime, endTime int64) ([]*DishRatingSummary, error) {
+	condition, params := drm.GenerateCondition(dishID, startTime, endTime)
+	sqlStr := fmt.Sprintf("SELECT `dish_id`, COUNT(*), AVG(`score`) FROM `%v` %v GROUP BY `dish_id`",
+		dishRatingTable, condition)
+	rows, err := drm.sqlCli.Query(sqlStr, params...)
+	if err != nil {
+		logger.Warn(dishRatingLogTag, "GetRatingSummary Query Failed|DishID:%v|Err:%v", dishID, err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	summaryList := make([]*DishRatingSummary, 0)
+	for rows.Next() {
+		summary := &DishRatingSummary{}
+		err = rows.Scan(&summary.DishID, &summary.RatingNumber, &summary.AverageScore)
+		if err != nil {
+			logger.Warn(dishRatingLogTag, "GetRatingSummary Scan Failed|Err:%v", err)
+			continue
+		}
+		summaryList = append(summaryList, summary)
+	}
+	return summaryList, nil
+}
@@ -0,0 +1,71 @@
+package model
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/canteen_management/logger"
+	"github.com/canteen_management/utils"
+)
+
+const (
+	documentLogTable = "document_log"
+
+	documentLogLogTag = "DocumentLogModel"
+)
+
+// DocumentLog 单据取消、冲销审计记录，冲销流水的 ref_id 指向该记录
+type DocumentLog struct {
+	ID       uint32    `json:"id"`
+	DocType  uint8     `json:"doc_type"`
+	DocID    uint32    `json:"doc_id"`
+	Action   uint8     `json:"action"`
+	Operator uint32    `json:"operator"`
+	Reason   string    `json:"reason"`
+	CreateAt time.Time `json:"created_at"`
+}
+
+type DocumentLogModel struct {
+	sqlCli *sql.DB
+}
+
+func NewDocumentLogModelWithDB(sqlCli *sql.DB) *DocumentLogModel {
+	return &DocumentLogModel{
+		sqlCli: sqlCli,
+	}
+}
+
+func (dlm *DocumentLogModel) InsertWithTx(tx *sql.Tx, dao *DocumentLog) error {
+	id, err := int64(0), error(nil)
+	if tx != nil {
+		id, err = utils.SqlInsert(tx, documentLogTable, dao, "id", "created_at")
+	} else {
+		id, err = utils.SqlInsert(dlm.sqlCli, documentLogTable, dao, "id", "created_at")
+	}
+	if err != nil {
+		logger.Warn(documentLogLogTag, "Insert Failed|Dao:%+v|Err:%v", dao, err)
+		return err
+	}
+	dao.ID = uint32(id)
+	return nil
+}
+
+func (dlm *DocumentLogModel) GetLogList(docType uint8, docID uint32) ([]*DocumentLog, error) {
+	var params []interface{}
+	condition := " WHERE 1=1 "
+	if docType > 0 {
+		condition += " AND `doc_type` = ? "
+		params = append(params, docType)
+	}
+	if docID > 0 {
+		condition += " AND `doc_id` = ? "
+		params = append(params, docID)
+	}
+	condition += " ORDER BY `id` DESC "
+	retList, err := utils.SqlQuery(dlm.sqlCli, documentLogTable, &DocumentLog{}, condition, params...)
+	if err != nil {
+		logger.Warn(documentLogLogTag, "GetLogList Failed|Type:%v|DocID:%v|Err:%v", docType, docID, err)
+		return nil, err
+	}
+	return retList.([]*DocumentLog), nil
+}
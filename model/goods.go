@@ -4,8 +4,11 @@ import (
 	"database/sql"
 	"encoding/json"
 	"fmt"
+	"math"
+	"sort"
 	"time"
 
+	"github.com/canteen_management/enum"
 	"github.com/canteen_management/logger"
 	"github.com/canteen_management/utils"
 )
@@ -14,16 +17,19 @@ const (
 	goodsTable = "goods"
 
 	goodsLogTag = "Goods"
+
+	GoodsCodePrefix = "G"
 )
 
 var (
 	goodsUpdateTags = []string{"name", "goods_type_id", "store_type_id", "picture",
-		"batch_size", "batch_unit"}
+		"batch_size", "batch_unit", "nutrition", "allergen", "min_quantity", "reorder_point", "max_quantity", "barcode"}
 )
 
 type Goods struct {
 	ID           uint32    `json:"id"`
 	Name         string    `json:"name"`
+	Barcode      string    `json:"barcode"`
 	GoodsTypeID  uint32    `json:"goods_type_id"`
 	StoreTypeID  uint32    `json:"store_type_id"`
 	Picture      string    `json:"picture"`
@@ -33,6 +39,13 @@ type Goods struct {
 	AveragePrice float64   `json:"average_price"`
 	PriceContent string    `json:"price_content"`
 	Quantity     float64   `json:"quantity"`
+	Reserved     float64   `json:"reserved"`
+	StockValue   float64   `json:"stock_value"`
+	MinQuantity  float64   `json:"min_quantity"`
+	ReorderPoint float64   `json:"reorder_point"`
+	MaxQuantity  float64   `json:"max_quantity"`
+	Nutrition    string    `json:"nutrition"`
+	Allergen     uint32    `json:"allergen"`
 	IsDelete     bool      `json:"is_delete"`
 	CreateAt     time.Time `json:"created_at"`
 	UpdateAt     time.Time `json:"updated_at"`
@@ -72,6 +85,84 @@ func (g *Goods) ToGoodsPrice() (map[uint8]float64, float64) {
 	return retMap, average
 }
 
+func (g *Goods) FromNutrition(nutrition *Nutrition) error {
+	content, err := marshalNutrition(nutrition)
+	if err != nil {
+		logger.Warn(goodsLogTag, "FromNutrition Failed|Err:%v", err)
+		return err
+	}
+	g.Nutrition = content
+	return nil
+}
+
+func (g *Goods) ToNutrition() *Nutrition {
+	return unmarshalNutrition(g.Nutrition)
+}
+
+func (g *Goods) GetStockLevel() uint8 {
+	if g.MinQuantity > 0 && g.Quantity <= g.MinQuantity {
+		return enum.StockLevelMin
+	}
+	if g.ReorderPoint > 0 && g.Quantity <= g.ReorderPoint {
+		return enum.StockLevelReorder
+	}
+	return enum.StockLevelNormal
+}
+
+func (g *Goods) GetReorderNumber() float64 {
+	target := math.Max(g.MaxQuantity, g.ReorderPoint)
+	if g.GetStockLevel() == enum.StockLevelNormal || target <= g.Quantity {
+		return 0
+	}
+	return target - g.Quantity
+}
+
+// ClassifyGoodsAbc 按库存价值累计占比划分 ABC 类：前 80% 为 A 类，80%-95% 为 B 类，其余为 C 类
+func ClassifyGoodsAbc(goodsList []*Goods) map[uint32]uint8 {
+	sortList, totalValue := make([]*Goods, 0, len(goodsList)), 0.0
+	for _, goods := range goodsList {
+		sortList = append(sortList, goods)
+		totalValue += math.Max(goods.StockValue, 0)
+	}
+	sort.Slice(sortList, func(i, j int) bool {
+		return sortList[i].StockValue > sortList[j].StockValue
+	})
+	retMap, sumValue := make(map[uint32]uint8), 0.0
+	for _, goods := range sortList {
+		retMap[goods.ID] = enum.AbcClassC
+		if totalValue <= 0 || goods.StockValue <= 0 {
+			continue
+		}
+		if sumValue < totalValue*0.8 {
+			retMap[goods.ID] = enum.AbcClassA
+		} else if sumValue < totalValue*0.95 {
+			retMap[goods.ID] = enum.AbcClassB
+		}
+		sumValue += goods.StockValue
+	}
+	return retMap
+}
+
+// GetAvailable 可用库存为库存数量减去已申请出库的预留数量
+func (g *Goods) GetAvailable() float64 {
+	return math.Max(g.Quantity-g.Reserved, 0)
+}
+
+// GetCode 优先使用商品自带条码，未设置时使用系统编码
+func (g *Goods) GetCode() string {
+	if g.Barcode != "" {
+		return g.Barcode
+	}
+	return fmt.Sprintf("%v%08d", GoodsCodePrefix, g.ID)
+}
+
+func (g *Goods) GetUnitCost(quantity float64) float64 {
+	if quantity > stockPrecision && g.StockValue > 0 {
+		return g.StockValue / quantity
+	}
+	return g.Price
+}
+
 type GoodsModel struct {
 	sqlCli *sql.DB
 }
@@ -182,12 +273,29 @@ func (gm *GoodsModel) GetGoodsByID(id uint32) (*Goods, error) {
 	return goods, nil
 }
 
+func (gm *GoodsModel) GetGoodsByBarcode(barcode string) (*Goods, error) {
+	retList, err := utils.SqlQuery(gm.sqlCli, goodsTable, &Goods{}, " WHERE `barcode` = ? ", barcode)
+	if err != nil {
+		logger.Warn(goodsLogTag, "GetGoodsByBarcode Failed|Err:%v", err)
+		return nil, err
+	}
+	goodsList := retList.([]*Goods)
+	if len(goodsList) == 0 {
+		return nil, nil
+	}
+	return goodsList[0], nil
+}
+
 func (gm *GoodsModel) BatchUpdateQuantity(updateList []*Goods) (err error) {
 	return gm.BatchUpdateQuantityWithTx(nil, updateList)
 }
 
 func (gm *GoodsModel) BatchUpdateQuantityWithTx(tx *sql.Tx, updateList []*Goods) (err error) {
-	return gm.BatchUpdateByTagWithTx(tx, updateList, "quantity")
+	err = gm.BatchUpdateByTagWithTx(tx, updateList, "quantity")
+	if err != nil {
+		return err
+	}
+	return gm.BatchUpdateByTagWithTx(tx, updateList, "stock_value")
 }
 
 func (gm *GoodsModel) BatchUpdateByTagWithTx(tx *sql.Tx, updateList []*Goods, updateTag string) (err error) {
@@ -226,17 +334,30 @@ func (gm *GoodsModel) DeleteGoods(id uint32) error {
 	return nil
 }
 
-func (gm *GoodsModel) UpdateGoodsPriceInfo(id uint32, averagePrice, price float64, priceMap map[uint8]float64) error {
+func (gm *GoodsModel) UpdateGoodsPriceInfoWithTx(tx *sql.Tx, id uint32, averagePrice, price float64,
+	priceMap map[uint8]float64) error {
 	dao := &Goods{ID: id, AveragePrice: averagePrice, Price: price}
 	err := dao.FromGoodsPrice(priceMap)
 	if err != nil {
 		logger.Warn(goodsLogTag, "UpdatePriceInfo FromGoodsPrice  Failed|Err:%v", err)
 		return err
 	}
-	err = utils.SqlUpdateWithUpdateTags(gm.sqlCli, goodsTable, dao, "id", "price", "average_price", "price_content")
+	if tx != nil {
+		err = utils.SqlUpdateWithUpdateTags(tx, goodsTable, dao, "id", "price", "average_price", "price_content")
+	} else {
+		err = utils.SqlUpdateWithUpdateTags(gm.sqlCli, goodsTable, dao, "id", "price", "average_price", "price_content")
+	}
 	if err != nil {
 		logger.Warn(goodsLogTag, "UpdateGoodsPriceInfo Failed|Err:%v", err)
 		return err
 	}
 	return nil
 }
+
+func (gm *GoodsModel) BatchUpdatePriceWithTx(tx *sql.Tx, updateList []*Goods) error {
+	err := gm.BatchUpdateByTagWithTx(tx, updateList, "price")
+	if err != nil {
+		return err
+	}
+	return gm.BatchUpdateByTagWithTx(tx, updateList, "average_price")
+}
@@ -19,9 +19,13 @@ const (
 type GoodsHistory struct {
 	ID             uint32    `json:"id"`
 	GoodsID        uint32    `json:"goods_id"`
+	StoreTypeID    uint32    `json:"store_type_id"`
 	ChangeQuantity float64   `json:"change_quantity"`
 	BeforeQuantity float64   `json:"before_quantity"`
 	AfterQuantity  float64   `json:"after_quantity"`
+	UnitCost       float64   `json:"unit_cost"`
+	ChangeValue    float64   `json:"change_value"`
+	AfterValue     float64   `json:"after_value"`
 	ChangeType     uint32    `json:"change_type"`
 	RefID          uint32    `json:"ref_id"`
 	CreateAt       time.Time `json:"created_at"`
@@ -54,6 +58,25 @@ func GenerateOutboundGoodsHistory(goods *Goods, changeQuantity float64, outbound
 	return GenerateGoodsHistory(goods.ID, goods.Quantity, changeQuantity, enum.GoodsOutbound, outboundID)
 }
 
+func GeneratePurchaseReturnGoodsHistory(goods *Goods, changeQuantity float64, returnID uint32) *GoodsHistory {
+	return GenerateGoodsHistory(goods.ID, goods.Quantity, changeQuantity, enum.GoodsPurchaseReturn, returnID)
+}
+
+func GenerateReversalGoodsHistory(goods *Goods, changeQuantity float64, logID uint32) *GoodsHistory {
+	return GenerateGoodsHistory(goods.ID, goods.Quantity, changeQuantity, enum.GoodsReversal, logID)
+}
+
+func GenerateWasteGoodsHistory(goods *Goods, changeQuantity float64, wasteID uint32) *GoodsHistory {
+	return GenerateGoodsHistory(goods.ID, goods.Quantity, changeQuantity, enum.GoodsWaste, wasteID)
+}
+
+func GenerateTransferGoodsHistory(goodsID, storeTypeID uint32, preQuantity, changeQuantity float64,
+	transferID uint32) *GoodsHistory {
+	history := GenerateGoodsHistory(goodsID, preQuantity, changeQuantity, enum.GoodsTransfer, transferID)
+	history.StoreTypeID = storeTypeID
+	return history
+}
+
 type GoodsHistoryModel struct {
 	sqlCli *sql.DB
 }
@@ -125,6 +148,28 @@ func (ghm *GoodsHistoryModel) GetGoodsHistoryCount(goodsID, changeType uint32, s
 	return count, nil
 }
 
+// GetLatestHistoryBefore 获取每个商品在指定时间前的最后一条流水，库位调拨不影响总库存，不参与统计
+func (ghm *GoodsHistoryModel) GetLatestHistoryBefore(deadline time.Time) ([]*GoodsHistory, error) {
+	condition := fmt.Sprintf(" WHERE `id` in (SELECT MAX(`id`) FROM `%v` WHERE `created_at` <= ? "+
+		"AND `change_type` <> ? GROUP BY `goods_id`) ", goodsHistoryTable)
+	retList, err := utils.SqlQuery(ghm.sqlCli, goodsHistoryTable, &GoodsHistory{}, condition, deadline, enum.GoodsTransfer)
+	if err != nil {
+		logger.Warn(goodsHistoryLogTag, "GetLatestHistoryBefore Failed|Err:%v", err)
+		return nil, err
+	}
+	return retList.([]*GoodsHistory), nil
+}
+
+func (ghm *GoodsHistoryModel) GetHistoryByRef(changeType, refID uint32) ([]*GoodsHistory, error) {
+	condition := " WHERE `change_type` = ? AND `ref_id` = ? "
+	retList, err := utils.SqlQuery(ghm.sqlCli, goodsHistoryTable, &GoodsHistory{}, condition, changeType, refID)
+	if err != nil {
+		logger.Warn(goodsHistoryLogTag, "GetHistoryByRef Failed|Type:%v|RefID:%v|Err:%v", changeType, refID, err)
+		return nil, err
+	}
+	return retList.([]*GoodsHistory), nil
+}
+
 func (ghm *GoodsHistoryModel) GetGoodsHistoryByCondition(condition string, params ...interface{}) ([]*GoodsHistory, error) {
 	retList, err := utils.SqlQuery(ghm.sqlCli, goodsHistoryTable, &GoodsHistory{}, condition, params...)
 	if err != nil {
@@ -0,0 +1,123 @@
+package model
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/canteen_management/enum"
+	"github.com/canteen_management/logger"
+	"github.com/canteen_management/utils"
+)
+
+const (
+	goodsPriceHistoryTable = "goods_price_history"
+
+	goodsPriceHistoryLogTag = "GoodsPriceHistory"
+)
+
+type GoodsPriceHistory struct {
+	ID          uint32    `json:"id"`
+	GoodsID     uint32    `json:"goods_id"`
+	BeforePrice float64   `json:"before_price"`
+	AfterPrice  float64   `json:"after_price"`
+	QuotePrice  float64   `json:"quote_price"`
+	ChangeType  uint32    `json:"change_type"`
+	SupplierID  uint32    `json:"supplier_id"`
+	RefID       uint32    `json:"ref_id"`
+	Operator    uint32    `json:"operator"`
+	CreateAt    time.Time `json:"created_at"`
+}
+
+func GenerateManualPriceHistory(goods *Goods, quotePrice, afterPrice float64, operator uint32) *GoodsPriceHistory {
+	return &GoodsPriceHistory{GoodsID: goods.ID, BeforePrice: goods.Price, AfterPrice: afterPrice,
+		QuotePrice: quotePrice, ChangeType: enum.PriceChangeManual, Operator: operator}
+}
+
+func GenerateQuotationPriceHistory(goods *Goods, item *QuotationItem, afterPrice float64,
+	operator uint32) *GoodsPriceHistory {
+	return &GoodsPriceHistory{GoodsID: goods.ID, BeforePrice: goods.Price, AfterPrice: afterPrice,
+		QuotePrice: item.Price, ChangeType: enum.PriceChangeQuotation, SupplierID: item.SupplierID,
+		RefID: item.QuotationID, Operator: operator}
+}
+
+type GoodsPriceHistoryModel struct {
+	sqlCli *sql.DB
+}
+
+func NewGoodsPriceHistoryModelWithDB(sqlCli *sql.DB) *GoodsPriceHistoryModel {
+	return &GoodsPriceHistoryModel{
+		sqlCli: sqlCli,
+	}
+}
+
+func (gphm *GoodsPriceHistoryModel) BatchInsert(tx *sql.Tx, daoList []*GoodsPriceHistory) (err error) {
+	if len(daoList) == 0 {
+		return nil
+	}
+	if tx != nil {
+		err = utils.SqlInsertBatch(tx, goodsPriceHistoryTable, daoList, "id", "created_at")
+	} else {
+		err = utils.SqlInsertBatch(gphm.sqlCli, goodsPriceHistoryTable, daoList, "id", "created_at")
+	}
+	if err != nil {
+		logger.Warn(goodsPriceHistoryLogTag, "BatchInsert Failed|Err:%v", err)
+		return err
+	}
+	return nil
+}
+
+func (gphm *GoodsPriceHistoryModel) GenerateCondition(goodsID uint32, startTime, endTime int64) (string, []interface{}) {
+	var params []interface{}
+	condition := " WHERE 1=1 "
+	if goodsID > 0 {
+		condition += " AND `goods_id` = ? "
+		params = append(params, goodsID)
+	}
+	if startTime > 0 {
+		condition += " AND `created_at` >= ? "
+		params = append(params, time.Unix(startTime, 0))
+	}
+	if endTime > startTime {
+		condition += " AND `created_at` <= ? "
+		params = append(params, time.Unix(endTime, 0))
+	}
+	return condition, params
+}
+
+func (gphm *GoodsPriceHistoryModel) GetPriceHistory(goodsID uint32, startTime, endTime int64,
+	page, pageSize int32) ([]*GoodsPriceHistory, error) {
+	condition, params := gphm.GenerateCondition(goodsID, startTime, endTime)
+	condition += " ORDER BY `id` DESC LIMIT ?,? "
+	params = append(params, (page-1)*pageSize, pageSize)
+	retList, err := utils.SqlQuery(gphm.sqlCli, goodsPriceHistoryTable, &GoodsPriceHistory{}, condition, params...)
+	if err != nil {
+		logger.Warn(goodsPriceHistoryLogTag, "GetPriceHistory Failed|Err:%v", err)
+		return nil, err
+	}
+	return retList.([]*GoodsPriceHistory), nil
+}
+
+func (gphm *GoodsPriceHistoryModel) GetPriceHistoryCount(goodsID uint32, startTime, endTime int64) (int32, error) {
+	condition, params := gphm.GenerateCondition(goodsID, startTime, endTime)
+	sqlStr := fmt.Sprintf("SELECT COUNT(*) FROM `%v` %v", goodsPriceHistoryTable, condition)
+	row := gphm.sqlCli.QueryRow(sqlStr, params...)
+	var count int32
+	err := row.Scan(&count)
+	if err != nil {
+		logger.Warn(goodsPriceHistoryLogTag, "GetPriceHistoryCount Failed|Err:%v", err)
+		return 0, err
+	}
+	return count, nil
+}
+
+func (gphm *GoodsPriceHistoryModel) GetSupplierPriceHistory(startTime, endTime time.Time) ([]*GoodsPriceHistory, error) {
+	condition := " WHERE `supplier_id` > 0 AND `created_at` >= ? AND `created_at` < ? "
+	retList, err := utils.SqlQuery(gphm.sqlCli, goodsPriceHistoryTable, &GoodsPriceHistory{}, condition,
+		startTime, endTime)
+	if err != nil {
+		logger.Warn(goodsPriceHistoryLogTag, "GetSupplierPriceHistory Failed|Err:%v", err)
+		return nil, err
+	}
+	return retList.([]*GoodsPriceHistory), nil
+}
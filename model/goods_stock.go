@@ -0,0 +1,161 @@
+package model
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/canteen_management/logger"
+	"github.com/canteen_management/utils"
+)
+
+const (
+	goodsStockTable = "goods_stock"
+
+	goodsStockLogTag = "GoodsStockModel"
+
+	stockPrecision = 0.000001
+)
+
+type GoodsStock struct {
+	ID          uint32    `json:"id"`
+	GoodsID     uint32    `json:"goods_id"`
+	StoreTypeID uint32    `json:"store_type_id"`
+	Quantity    float64   `json:"quantity"`
+	CreateAt    time.Time `json:"created_at"`
+	UpdateAt    time.Time `json:"updated_at"`
+}
+
+func GetStockKey(goodsID, storeTypeID uint32) uint64 {
+	return uint64(goodsID)<<32 | uint64(storeTypeID)
+}
+
+// BuildStockMap 未记录在任何库位的历史库存视为存放在商品默认库位
+func BuildStockMap(goodsList []*Goods, stockList []*GoodsStock) map[uint64]*GoodsStock {
+	retMap, totalMap := make(map[uint64]*GoodsStock), make(map[uint32]float64)
+	for _, stock := range stockList {
+		retMap[GetStockKey(stock.GoodsID, stock.StoreTypeID)] = stock
+		totalMap[stock.GoodsID] += stock.Quantity
+	}
+	for _, goods := range goodsList {
+		key := GetStockKey(goods.ID, goods.StoreTypeID)
+		if _, ok := retMap[key]; ok {
+			continue
+		}
+		remain := goods.Quantity - totalMap[goods.ID]
+		if remain < stockPrecision && totalMap[goods.ID] > 0 {
+			continue
+		}
+		retMap[key] = &GoodsStock{GoodsID: goods.ID, StoreTypeID: goods.StoreTypeID, Quantity: remain}
+	}
+	return retMap
+}
+
+type GoodsStockModel struct {
+	sqlCli *sql.DB
+}
+
+func NewGoodsStockModelWithDB(sqlCli *sql.DB) *GoodsStockModel {
+	return &GoodsStockModel{
+		sqlCli: sqlCli,
+	}
+}
+
+func (gsm *GoodsStockModel) GetStockList(goodsID, storeTypeID uint32) ([]*GoodsStock, error) {
+	var params []interface{}
+	condition := " WHERE 1=1 "
+	if goodsID > 0 {
+		condition += " AND `goods_id` = ? "
+		params = append(params, goodsID)
+	}
+	if storeTypeID > 0 {
+		condition += " AND `store_type_id` = ? "
+		params = append(params, storeTypeID)
+	}
+	retList, err := utils.SqlQuery(gsm.sqlCli, goodsStockTable, &GoodsStock{}, condition, params...)
+	if err != nil {
+		logger.Warn(goodsStockLogTag, "GetStockList Failed|Err:%v", err)
+		return nil, err
+	}
+	return retList.([]*GoodsStock), nil
+}
+
+func (gsm *GoodsStockModel) GetStockByGoodsWithLock(tx *sql.Tx, goodsIDList []uint32) ([]*GoodsStock, error) {
+	if len(goodsIDList) == 0 {
+		return make([]*GoodsStock, 0), nil
+	}
+	idStr := ""
+	for _, goodsID := range goodsIDList {
+		idStr += fmt.Sprintf(",%v", goodsID)
+	}
+	condition := fmt.Sprintf(" WHERE `goods_id` in (%v) ", idStr[1:])
+	retList, err := utils.SqlQueryWithLock(tx, goodsStockTable, &GoodsStock{}, condition)
+	if err != nil {
+		logger.Warn(goodsStockLogTag, "GetStockByGoodsWithLock Failed|Err:%v", err)
+		return nil, err
+	}
+	return retList.([]*GoodsStock), nil
+}
+
+// ChangeStockWithTx goodsList 需为加锁后、修改总库存前的商品信息
+func (gsm *GoodsStockModel) ChangeStockWithTx(tx *sql.Tx, goodsList []*Goods,
+	changeList []*GoodsStock) (map[uint64]*GoodsStock, error) {
+	goodsIDList, goodsMap := make([]uint32, 0, len(goodsList)), make(map[uint32]*Goods)
+	for _, goods := range goodsList {
+		goodsIDList = append(goodsIDList, goods.ID)
+		goodsMap[goods.ID] = goods
+	}
+	stockList, err := gsm.GetStockByGoodsWithLock(tx, goodsIDList)
+	if err != nil {
+		return nil, err
+	}
+
+	stockMap := BuildStockMap(goodsList, stockList)
+	for _, change := range changeList {
+		key := GetStockKey(change.GoodsID, change.StoreTypeID)
+		stock, ok := stockMap[key]
+		if !ok {
+			stock = &GoodsStock{GoodsID: change.GoodsID, StoreTypeID: change.StoreTypeID}
+			stockMap[key] = stock
+		}
+		stock.Quantity += change.Quantity
+		if stock.Quantity < -stockPrecision {
+			name := ""
+			if goods, ok := goodsMap[change.GoodsID]; ok {
+				name = goods.Name
+			}
+			logger.Warn(goodsStockLogTag, "Stock Not Enough|Goods:%v|Store:%v|Quantity:%v",
+				change.GoodsID, change.StoreTypeID, stock.Quantity)
+			return nil, fmt.Errorf("%v库位库存不足", name)
+		}
+	}
+
+	insertList, updateList, changedMap := make([]*GoodsStock, 0), make([]interface{}, 0), make(map[uint64]bool)
+	for _, change := range changeList {
+		key := GetStockKey(change.GoodsID, change.StoreTypeID)
+		if changedMap[key] {
+			continue
+		}
+		changedMap[key] = true
+		if stock := stockMap[key]; stock.ID > 0 {
+			updateList = append(updateList, stock)
+		} else {
+			insertList = append(insertList, stock)
+		}
+	}
+	if len(insertList) > 0 {
+		err = utils.SqlInsertBatch(tx, goodsStockTable, insertList, "id", "created_at", "updated_at")
+		if err != nil {
+			logger.Warn(goodsStockLogTag, "BatchInsert Failed|Err:%v", err)
+			return nil, err
+		}
+	}
+	if len(updateList) > 0 {
+		err = utils.SqlBatchUpdateTag(tx, goodsStockTable, updateList, "id", "quantity")
+		if err != nil {
+			logger.Warn(goodsStockLogTag, "BatchUpdateQuantity Failed|Err:%v", err)
+			return nil, err
+		}
+	}
+	return stockMap, nil
+}
@@ -0,0 +1,70 @@
+package model
+
+import (
+	"testing"
+)
+
+func TestBuildStockMap(t *testing.T) {
+	testList := []struct {
+		name      string
+		goodsList []*Goods
+		stockList []*GoodsStock
+		want      map[uint64]float64
+	}{
+		{
+			name:      "legacy stock in default location",
+			goodsList: []*Goods{{ID: 1, StoreTypeID: 1, Quantity: 10}},
+			want:      map[uint64]float64{GetStockKey(1, 1): 10},
+		},
+		{
+			name:      "no stock keeps empty default location",
+			goodsList: []*Goods{{ID: 1, StoreTypeID: 1}},
+			want:      map[uint64]float64{GetStockKey(1, 1): 0},
+		},
+		{
+			name:      "fully located stock",
+			goodsList: []*Goods{{ID: 1, StoreTypeID: 1, Quantity: 10}},
+			stockList: []*GoodsStock{{GoodsID: 1, StoreTypeID: 2, Quantity: 10}},
+			want:      map[uint64]float64{GetStockKey(1, 2): 10},
+		},
+		{
+			name:      "unlocated remainder goes to default",
+			goodsList: []*Goods{{ID: 1, StoreTypeID: 1, Quantity: 10}},
+			stockList: []*GoodsStock{{GoodsID: 1, StoreTypeID: 2, Quantity: 4}},
+			want:      map[uint64]float64{GetStockKey(1, 1): 6, GetStockKey(1, 2): 4},
+		},
+		{
+			name:      "default location row wins",
+			goodsList: []*Goods{{ID: 1, StoreTypeID: 1, Quantity: 10}},
+			stockList: []*GoodsStock{{GoodsID: 1, StoreTypeID: 1, Quantity: 3}},
+			want:      map[uint64]float64{GetStockKey(1, 1): 3},
+		},
+		{
+			name: "multiple goods",
+			goodsList: []*Goods{
+				{ID: 1, StoreTypeID: 1, Quantity: 5},
+				{ID: 2, StoreTypeID: 2, Quantity: 8},
+			},
+			stockList: []*GoodsStock{{GoodsID: 2, StoreTypeID: 1, Quantity: 8}},
+			want:      map[uint64]float64{GetStockKey(1, 1): 5, GetStockKey(2, 1): 8},
+		},
+	}
+	for _, tt := range testList {
+		t.Run(tt.name, func(t *testing.T) {
+			got := BuildStockMap(tt.goodsList, tt.stockList)
+			if len(got) != len(tt.want) {
+				t.Fatalf("Length Mismatch|Got:%v|Want:%v", len(got), len(tt.want))
+			}
+			for key, quantity := range tt.want {
+				stock, ok := got[key]
+				if !ok {
+					t.Fatalf("Stock Missing|GoodsID:%v|StoreTypeID:%v", key>>32, uint32(key))
+				}
+				if stock.Quantity != quantity {
+					t.Fatalf("Quantity Mismatch|GoodsID:%v|StoreTypeID:%v|Got:%v|Want:%v", key>>32, uint32(key),
+						stock.Quantity, quantity)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,148 @@
+package model
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/canteen_management/enum"
+	"github.com/canteen_management/logger"
+	"github.com/canteen_management/utils"
+)
+
+const (
+	goodsUnitTable = "goods_unit"
+
+	goodsUnitLogTag = "GoodsUnitModel"
+)
+
+var (
+	goodsUnitUpdateTags = []string{"unit_name", "unit_type", "factor"}
+)
+
+// GoodsUnit 商品计量单位，Factor 为 1 个该单位折合的库存单位数量
+type GoodsUnit struct {
+	ID       uint32    `json:"id"`
+	GoodsID  uint32    `json:"goods_id"`
+	UnitName string    `json:"unit_name"`
+	UnitType uint8     `json:"unit_type"`
+	Factor   float64   `json:"factor"`
+	CreateAt time.Time `json:"created_at"`
+	UpdateAt time.Time `json:"updated_at"`
+}
+
+func (gu *GoodsUnit) ToStock(quantity float64) float64 {
+	return quantity * gu.Factor
+}
+
+// GoodsUnitMap 商品ID -> 单位名称 -> 单位
+type GoodsUnitMap map[uint32]map[string]*GoodsUnit
+
+func (gum GoodsUnitMap) GetUnit(goodsID uint32, unitName string) *GoodsUnit {
+	unitMap, ok := gum[goodsID]
+	if !ok {
+		return nil
+	}
+	return unitMap[unitName]
+}
+
+type GoodsUnitModel struct {
+	sqlCli *sql.DB
+}
+
+func NewGoodsUnitModelWithDB(sqlCli *sql.DB) *GoodsUnitModel {
+	return &GoodsUnitModel{
+		sqlCli: sqlCli,
+	}
+}
+
+func (gum *GoodsUnitModel) Insert(dao *GoodsUnit) error {
+	id, err := utils.SqlInsert(gum.sqlCli, goodsUnitTable, dao, "id", "created_at", "updated_at")
+	if err != nil {
+		logger.Warn(goodsUnitLogTag, "Insert Failed|Dao:%+v|Err:%v", dao, err)
+		return err
+	}
+	dao.ID = uint32(id)
+	return nil
+}
+
+func (gum *GoodsUnitModel) GetUnitList(goodsID uint32) ([]*GoodsUnit, error) {
+	var params []interface{}
+	condition := " WHERE 1=1 "
+	if goodsID > 0 {
+		condition += " AND `goods_id` = ? "
+		params = append(params, goodsID)
+	}
+	condition += " ORDER BY `goods_id` ASC, `unit_type` ASC, `id` ASC "
+	retList, err := utils.SqlQuery(gum.sqlCli, goodsUnitTable, &GoodsUnit{}, condition, params...)
+	if err != nil {
+		logger.Warn(goodsUnitLogTag, "GetUnitList Failed|GoodsID:%v|Err:%v", goodsID, err)
+		return nil, err
+	}
+	return retList.([]*GoodsUnit), nil
+}
+
+func (gum *GoodsUnitModel) GetUnit(id uint32) (*GoodsUnit, error) {
+	retList, err := utils.SqlQuery(gum.sqlCli, goodsUnitTable, &GoodsUnit{}, " WHERE `id` = ? ", id)
+	if err != nil {
+		logger.Warn(goodsUnitLogTag, "GetUnit Failed|ID:%v|Err:%v", id, err)
+		return nil, err
+	}
+	unitList := retList.([]*GoodsUnit)
+	if len(unitList) == 0 {
+		return nil, nil
+	}
+	return unitList[0], nil
+}
+
+// GetUnitMap 返回全部商品的单位，未单独定义时商品规格单位(BatchUnit)按 1/BatchSize 折算库存单位
+func (gum *GoodsUnitModel) GetUnitMap(goodsList []*Goods) (GoodsUnitMap, error) {
+	unitList, err := gum.GetUnitList(0)
+	if err != nil {
+		return nil, err
+	}
+	return BuildUnitMap(unitList, goodsList), nil
+}
+
+func BuildUnitMap(unitList []*GoodsUnit, goodsList []*Goods) GoodsUnitMap {
+	retMap := make(GoodsUnitMap)
+	for _, unit := range unitList {
+		if _, ok := retMap[unit.GoodsID]; !ok {
+			retMap[unit.GoodsID] = make(map[string]*GoodsUnit)
+		}
+		retMap[unit.GoodsID][unit.UnitName] = unit
+	}
+	for _, goods := range goodsList {
+		if goods.BatchUnit == "" || goods.BatchSize <= 0 {
+			continue
+		}
+		if _, ok := retMap[goods.ID]; !ok {
+			retMap[goods.ID] = make(map[string]*GoodsUnit)
+		}
+		if _, ok := retMap[goods.ID][goods.BatchUnit]; ok {
+			continue
+		}
+		retMap[goods.ID][goods.BatchUnit] = &GoodsUnit{GoodsID: goods.ID, UnitName: goods.BatchUnit,
+			UnitType: enum.GoodsUnitRecipe, Factor: 1 / goods.BatchSize}
+	}
+	return retMap
+}
+
+func (gum *GoodsUnitModel) UpdateUnit(dao *GoodsUnit) error {
+	err := utils.SqlUpdateWithUpdateTags(gum.sqlCli, goodsUnitTable, dao, "id", goodsUnitUpdateTags...)
+	if err != nil {
+		logger.Warn(goodsUnitLogTag, "UpdateUnit Failed|Err:%v", err)
+		return err
+	}
+	return nil
+}
+
+func (gum *GoodsUnitModel) DeleteUnit(id uint32) error {
+	sqlStr := fmt.Sprintf(" DELETE FROM %v WHERE `id` = ? ", goodsUnitTable)
+	_, err := gum.sqlCli.Exec(sqlStr, id)
+	if err != nil {
+		logger.Warn(goodsUnitLogTag, "DeleteUnit Failed|Err:%v", err)
+		return err
+	}
+	return nil
+}
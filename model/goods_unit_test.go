@@ -0,0 +1,50 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/canteen_management/enum"
+)
+
+func TestBuildUnitMap(t *testing.T) {
+	unitList := []*GoodsUnit{
+		{GoodsID: 1, UnitName: "箱", UnitType: enum.GoodsUnitPurchase, Factor: 12},
+		{GoodsID: 2, UnitName: "袋", UnitType: enum.GoodsUnitPurchase, Factor: 25},
+	}
+	goodsList := []*Goods{
+		{ID: 1, BatchUnit: "箱", BatchSize: 4},
+		{ID: 2, BatchUnit: "克", BatchSize: 1000},
+		{ID: 3, BatchUnit: "勺", BatchSize: 0},
+		{ID: 4},
+	}
+	unitMap := BuildUnitMap(unitList, goodsList)
+	testList := []struct {
+		name       string
+		goodsID    uint32
+		unitName   string
+		wantExist  bool
+		wantFactor float64
+		wantType   uint8
+	}{
+		{"defined unit kept over batch unit", 1, "箱", true, 12, enum.GoodsUnitPurchase},
+		{"defined unit", 2, "袋", true, 25, enum.GoodsUnitPurchase},
+		{"batch unit default", 2, "克", true, 0.001, enum.GoodsUnitRecipe},
+		{"zero batch size skipped", 3, "勺", false, 0, 0},
+		{"no batch unit", 4, "", false, 0, 0},
+		{"unknown goods", 9, "箱", false, 0, 0},
+	}
+	for _, tt := range testList {
+		t.Run(tt.name, func(t *testing.T) {
+			unit := unitMap.GetUnit(tt.goodsID, tt.unitName)
+			if (unit != nil) != tt.wantExist {
+				t.Fatalf("GetUnit Exist Mismatch|Got:%v|Want:%v", unit != nil, tt.wantExist)
+			}
+			if unit == nil {
+				return
+			}
+			if !floatEqual(unit.Factor, tt.wantFactor) || unit.UnitType != tt.wantType {
+				t.Fatalf("GetUnit Mismatch|Got:%+v|WantFactor:%v|WantType:%v", unit, tt.wantFactor, tt.wantType)
+			}
+		})
+	}
+}
@@ -0,0 +1,142 @@
+package model
+
+import (
+	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/canteen_management/logger"
+	"github.com/canteen_management/utils"
+)
+
+const (
+	haccpPointTable = "haccp_point"
+
+	haccpPointLogTag = "HaccpPointModel"
+)
+
+var (
+	haccpPointUpdateTags = []string{"name", "point_type", "store_type_id", "dish_type_id", "min_value", "max_value",
+		"unit", "device_key", "enable"}
+)
+
+// HaccpPoint 关键控制点，库房类关联库房，烹饪类关联菜品类别；读数超出 [MinValue, MaxValue] 时告警。
+// DeviceSecret 为传感器上报签名密钥，仅在生成时返回一次，列表接口不返回
+type HaccpPoint struct {
+	ID           uint32    `json:"id"`
+	Name         string    `json:"name"`
+	PointType    uint8     `json:"point_type"`
+	StoreTypeID  uint32    `json:"store_type_id"`
+	DishTypeID   uint32    `json:"dish_type_id"`
+	MinValue     float64   `json:"min_value"`
+	MaxValue     float64   `json:"max_value"`
+	Unit         string    `json:"unit"`
+	DeviceKey    string    `json:"device_key"`
+	DeviceSecret string    `json:"device_secret"`
+	Enable       bool      `json:"enable"`
+	CreateAt     time.Time `json:"created_at"`
+	UpdateAt     time.Time `json:"updated_at"`
+}
+
+func (hp *HaccpPoint) IsOutOfRange(value float64) bool {
+	return value < hp.MinValue || value > hp.MaxValue
+}
+
+// GetIngestSign 传感器上报签名：HMAC-SHA256(DeviceSecret, "device_key|value|read_at")，value 取最短十进制表示
+func (hp *HaccpPoint) GetIngestSign(value float64, readAt int64) string {
+	data := fmt.Sprintf("%v|%v|%v", hp.DeviceKey, strconv.FormatFloat(value, 'f', -1, 64), readAt)
+	return utils.GetHmacSha256Hex(data, hp.DeviceSecret)
+}
+
+func (hp *HaccpPoint) VerifyIngestSign(value float64, readAt int64, sign string) bool {
+	if hp.DeviceSecret == "" {
+		return false
+	}
+	return utils.CompareHmac(strings.ToLower(strings.TrimSpace(sign)), hp.GetIngestSign(value, readAt))
+}
+
+type HaccpPointModel struct {
+	sqlCli *sql.DB
+}
+
+func NewHaccpPointModelWithDB(sqlCli *sql.DB) *HaccpPointModel {
+	return &HaccpPointModel{
+		sqlCli: sqlCli,
+	}
+}
+
+func (hpm *HaccpPointModel) Insert(dao *HaccpPoint) error {
+	id, err := utils.SqlInsert(hpm.sqlCli, haccpPointTable, dao, "id", "created_at", "updated_at")
+	if err != nil {
+		logger.Warn(haccpPointLogTag, "Insert Failed|Dao:%+v|Err:%v", dao, err)
+		return err
+	}
+	dao.ID = uint32(id)
+	return nil
+}
+
+func (hpm *HaccpPointModel) GetPointList(pointType uint8) ([]*HaccpPoint, error) {
+	var params []interface{}
+	condition := " WHERE 1=1 "
+	if pointType > 0 {
+		condition += " AND `point_type` = ? "
+		params = append(params, pointType)
+	}
+	condition += " ORDER BY `id` ASC "
+	retList, err := utils.SqlQuery(hpm.sqlCli, haccpPointTable, &HaccpPoint{}, condition, params...)
+	if err != nil {
+		logger.Warn(haccpPointLogTag, "GetPointList Failed|Type:%v|Err:%v", pointType, err)
+		return nil, err
+	}
+	return retList.([]*HaccpPoint), nil
+}
+
+func (hpm *HaccpPointModel) GetPoint(id uint32) (*HaccpPoint, error) {
+	retList, err := utils.SqlQuery(hpm.sqlCli, haccpPointTable, &HaccpPoint{}, " WHERE `id` = ? ", id)
+	if err != nil {
+		logger.Warn(haccpPointLogTag, "GetPoint Failed|ID:%v|Err:%v", id, err)
+		return nil, err
+	}
+	pointList := retList.([]*HaccpPoint)
+	if len(pointList) == 0 {
+		return nil, nil
+	}
+	return pointList[0], nil
+}
+
+func (hpm *HaccpPointModel) GetPointByDeviceKey(deviceKey string) (*HaccpPoint, error) {
+	retList, err := utils.SqlQuery(hpm.sqlCli, haccpPointTable, &HaccpPoint{}, " WHERE `device_key` = ? ", deviceKey)
+	if err != nil {
+		logger.Warn(haccpPointLogTag, "GetPointByDeviceKey Failed|Err:%v", err)
+		return nil, err
+	}
+	pointList := retList.([]*HaccpPoint)
+	if len(pointList) == 0 {
+		return nil, nil
+	}
+	return pointList[0], nil
+}
+
+func (hpm *HaccpPointModel) UpdatePoint(dao *HaccpPoint, updateTags ...string) error {
+	if len(updateTags) == 0 {
+		updateTags = haccpPointUpdateTags
+	}
+	err := utils.SqlUpdateWithUpdateTags(hpm.sqlCli, haccpPointTable, dao, "id", updateTags...)
+	if err != nil {
+		logger.Warn(haccpPointLogTag, "UpdatePoint Failed|Err:%v", err)
+		return err
+	}
+	return nil
+}
+
+func (hpm *HaccpPointModel) DeletePoint(id uint32) error {
+	sqlStr := fmt.Sprintf(" DELETE FROM %v WHERE `id` = ? ", haccpPointTable)
+	_, err := hpm.sqlCli.Exec(sqlStr, id)
+	if err != nil {
+		logger.Warn(haccpPointLogTag, "DeletePoint Failed|Err:%v", err)
+		return err
+	}
+	return nil
+}
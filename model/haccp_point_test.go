@@ -0,0 +1,36 @@
+package model
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestVerifyIngestSign(t *testing.T) {
+	point := &HaccpPoint{DeviceKey: "fridge-01", DeviceSecret: "secret"}
+	sign := point.GetIngestSign(3.5, 1760000000)
+	testList := []struct {
+		name   string
+		point  *HaccpPoint
+		value  float64
+		readAt int64
+		sign   string
+		want   bool
+	}{
+		{"match", point, 3.5, 1760000000, sign, true},
+		{"upper case", point, 3.5, 1760000000, "  " + strings.ToUpper(sign), true},
+		{"value changed", point, 4.5, 1760000000, sign, false},
+		{"read at changed", point, 3.5, 1760000001, sign, false},
+		{"wrong secret", &HaccpPoint{DeviceKey: "fridge-01", DeviceSecret: "other"}, 3.5, 1760000000, sign, false},
+		{"no secret", &HaccpPoint{DeviceKey: "fridge-01"}, 3.5, 1760000000,
+			(&HaccpPoint{DeviceKey: "fridge-01"}).GetIngestSign(3.5, 1760000000), false},
+		{"empty sign", point, 3.5, 1760000000, "", false},
+	}
+	for _, tt := range testList {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.point.VerifyIngestSign(tt.value, tt.readAt, tt.sign)
+			if got != tt.want {
+				t.Fatalf("VerifyIngestSign Mismatch|Got:%v|Want:%v", got, tt.want)
+			}
+		})
+	}
+}
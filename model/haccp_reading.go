@@ -0,0 +1,147 @@
+package model
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/canteen_management/enum"
+	"github.com/canteen_management/logger"
+	"github.com/canteen_management/utils"
+)
+
+const (
+	haccpReadingTable = "haccp_reading"
+
+	haccpReadingLogTag = "HaccpReadingModel"
+)
+
+type HaccpReading struct {
+	ID          uint32    `json:"id"`
+	PointID     uint32    `json:"point_id"`
+	Value       float64   `json:"value"`
+	MinValue    float64   `json:"min_value"`
+	MaxValue    float64   `json:"max_value"`
+	Source      uint8     `json:"source"`
+	Recorder    uint32    `json:"recorder"`
+	DishID      uint32    `json:"dish_id"`
+	Remark      string    `json:"remark"`
+	AlertStatus int8      `json:"alert_status"`
+	Handler     uint32    `json:"handler"`
+	Action      string    `json:"action"`
+	ReadAt      time.Time `json:"read_at"`
+	HandleAt    time.Time `json:"handle_at"`
+	CreateAt    time.Time `json:"created_at"`
+}
+
+type HaccpReadingModel struct {
+	sqlCli *sql.DB
+}
+
+func NewHaccpReadingModelWithDB(sqlCli *sql.DB) *HaccpReadingModel {
+	return &HaccpReadingModel{
+		sqlCli: sqlCli,
+	}
+}
+
+func (hrm *HaccpReadingModel) Insert(dao *HaccpReading) error {
+	id, err := utils.SqlInsert(hrm.sqlCli, haccpReadingTable, dao, "id", "created_at")
+	if err != nil {
+		logger.Warn(haccpReadingLogTag, "Insert Failed|Dao:%+v|Err:%v", dao, err)
+		return err
+	}
+	dao.ID = uint32(id)
+	return nil
+}
+
+func (hrm *HaccpReadingModel) GenerateCondition(pointIDList []uint32, alertStatus int8, startTime,
+	endTime int64) (string, []interface{}) {
+	var params []interface{}
+	condition := " WHERE 1=1 "
+	if len(pointIDList) > 0 {
+		idStr := ""
+		for _, pointID := range pointIDList {
+			idStr += fmt.Sprintf(",%v", pointID)
+		}
+		condition += fmt.Sprintf(" AND `point_id` in (%v) ", idStr[1:])
+	}
+	if alertStatus != enum.HaccpAlertStatusAll {
+		condition += " AND `alert_status` = ? "
+		params = append(params, alertStatus)
+	}
+	if startTime > 0 {
+		condition += " AND `read_at` >= ? "
+		params = append(params, time.Unix(startTime, 0))
+	}
+	if endTime > startTime {
+		condition += " AND `read_at` <= ? "
+		params = append(params, time.Unix(endTime, 0))
+	}
+	return condition, params
+}
+
+func (hrm *HaccpReadingModel) GetReadingList(pointIDList []uint32, alertStatus int8, startTime, endTime int64,
+	page, pageSize int32) ([]*HaccpReading, error) {
+	condition, params := hrm.GenerateCondition(pointIDList, alertStatus, startTime, endTime)
+	condition += " ORDER BY `read_at` DESC, `id` DESC "
+	if pageSize > 0 {
+		condition += " LIMIT ?,? "
+		params = append(params, (page-1)*pageSize, pageSize)
+	}
+	retList, err := utils.SqlQuery(hrm.sqlCli, haccpReadingTable, &HaccpReading{}, condition, params...)
+	if err != nil {
+		logger.Warn(haccpReadingLogTag, "GetReadingList Failed|Err:%v", err)
+		return nil, err
+	}
+	return retList.([]*HaccpReading), nil
+}
+
+func (hrm *HaccpReadingModel) GetReadingCount(pointIDList []uint32, alertStatus int8, startTime,
+	endTime int64) (int32, error) {
+	condition, params := hrm.GenerateCondition(pointIDList, alertStatus, startTime, endTime)
+	sqlStr := fmt.Sprintf("SELECT COUNT(*) FROM `%v` %v", haccpReadingTable, condition)
+	row := hrm.sqlCli.QueryRow(sqlStr, params...)
+	var count int32 = 0
+	err := row.Scan(&count)
+	if err != nil {
+		logger.Warn(haccpReadingLogTag, "GetReadingCount Failed|Err:%v", err)
+		return 0, err
+	}
+	return count, nil
+}
+
+// ExistReading 同一控制点同一来源在同一时刻只保留一条读数，表上 (point_id, source, read_at) 唯一键兜底并发上报
+func (hrm *HaccpReadingModel) ExistReading(pointID uint32, source uint8, readAt time.Time) (bool, error) {
+	sqlStr := fmt.Sprintf("SELECT COUNT(*) FROM `%v` WHERE `point_id` = ? AND `source` = ? AND `read_at` = ? ",
+		haccpReadingTable)
+	row := hrm.sqlCli.QueryRow(sqlStr, pointID, source, readAt)
+	var count int32 = 0
+	err := row.Scan(&count)
+	if err != nil {
+		logger.Warn(haccpReadingLogTag, "ExistReading Failed|PointID:%v|ReadAt:%v|Err:%v", pointID, readAt, err)
+		return false, err
+	}
+	return count > 0, nil
+}
+
+func (hrm *HaccpReadingModel) GetReading(id uint32) (*HaccpReading, error) {
+	retList, err := utils.SqlQuery(hrm.sqlCli, haccpReadingTable, &HaccpReading{}, " WHERE `id` = ? ", id)
+	if err != nil {
+		logger.Warn(haccpReadingLogTag, "GetReading Failed|ID:%v|Err:%v", id, err)
+		return nil, err
+	}
+	readingList := retList.([]*HaccpReading)
+	if len(readingList) == 0 {
+		return nil, nil
+	}
+	return readingList[0], nil
+}
+
+func (hrm *HaccpReadingModel) UpdateReading(dao *HaccpReading, updateTags ...string) error {
+	err := utils.SqlUpdateWithUpdateTags(hrm.sqlCli, haccpReadingTable, dao, "id", updateTags...)
+	if err != nil {
+		logger.Warn(haccpReadingLogTag, "UpdateReading Failed|Err:%v", err)
+		return err
+	}
+	return nil
+}
@@ -4,6 +4,7 @@ import (
 	"database/sql"
 	"fmt"
 
+	"github.com/canteen_management/enum"
 	"github.com/canteen_management/logger"
 	"github.com/canteen_management/utils"
 )
@@ -14,14 +15,19 @@ const (
 )
 
 type InventoryDetail struct {
-	ID           uint32  `json:"id"`
-	InventoryID  uint32  `json:"inventory_id"`
-	GoodsID      uint32  `json:"goods_id"`
-	GoodsType    uint32  `json:"goods_type"`
-	ExpectNumber float64 `json:"expect_number"`
-	RealNumber   float64 `json:"real_number"`
-	Tag          string  `json:"tag"`
-	Status       int8    `json:"status"`
+	ID            uint32  `json:"id"`
+	InventoryID   uint32  `json:"inventory_id"`
+	GoodsID       uint32  `json:"goods_id"`
+	GoodsType     uint32  `json:"goods_type"`
+	StoreTypeID   uint32  `json:"store_type_id"`
+	LotID         uint32  `json:"lot_id"`
+	BatchNo       string  `json:"batch_no"`
+	ExpectNumber  float64 `json:"expect_number"`
+	RealNumber    float64 `json:"real_number"`
+	Reason        uint8   `json:"reason"`
+	VarianceValue float64 `json:"variance_value"`
+	Tag           string  `json:"tag"`
+	Status        int8    `json:"status"`
 }
 
 type InventoryDetailModel struct {
@@ -67,6 +73,20 @@ func (odm *InventoryDetailModel) GetDetail(orderID uint32, status int8) ([]*Inve
 	return retList.([]*InventoryDetail), nil
 }
 
+func (odm *InventoryDetailModel) GetDetailWithLock(tx *sql.Tx, orderID uint32) ([]*InventoryDetail, error) {
+	if tx == nil {
+		return nil, fmt.Errorf("tx is nil")
+	}
+	condition := " WHERE `inventory_id` = ? "
+	retList, err := utils.SqlQueryWithLock(tx, inventoryDetailTable, &InventoryDetail{}, condition, orderID)
+	if err != nil {
+		logger.Warn(inventoryDetailLogTag, "GetDetailWithLock Failed|InventoryID:%v|Err:%v", orderID, err)
+		return nil, err
+	}
+
+	return retList.([]*InventoryDetail), nil
+}
+
 func (odm *InventoryDetailModel) GetInventoryDetailByOrderList(inventoryIDList []uint32, goodsType uint32) ([]*InventoryDetail, error) {
 	if len(inventoryIDList) == 0 {
 		return nil, fmt.Errorf("inventory len zero")
@@ -91,10 +111,57 @@ func (odm *InventoryDetailModel) GetInventoryDetailByOrderList(inventoryIDList [
 
 func (odm *InventoryDetailModel) UpdateDetailByCondition(detail *InventoryDetail, conditionTag string,
 	updateTags ...string) error {
-	err := utils.SqlUpdateWithUpdateTags(odm.sqlCli, inventoryDetailTable, detail, conditionTag, updateTags...)
+	return odm.UpdateDetailByConditionWithTx(nil, detail, conditionTag, updateTags...)
+}
+
+func (odm *InventoryDetailModel) UpdateDetailByConditionWithTx(tx *sql.Tx, detail *InventoryDetail, conditionTag string,
+	updateTags ...string) (err error) {
+	if tx != nil {
+		err = utils.SqlUpdateWithUpdateTags(tx, inventoryDetailTable, detail, conditionTag, updateTags...)
+	} else {
+		err = utils.SqlUpdateWithUpdateTags(odm.sqlCli, inventoryDetailTable, detail, conditionTag, updateTags...)
+	}
 	if err != nil {
 		logger.Warn(inventoryDetailLogTag, "UpdateDetailByCondition Failed|Err:%v", err)
 		return err
 	}
 	return nil
 }
+
+func (odm *InventoryDetailModel) SkipUncountedDetail(inventoryID uint32) error {
+	sqlStr := fmt.Sprintf(" UPDATE `%v` SET `status` = ? WHERE `inventory_id` = ? AND `status` = ? ", inventoryDetailTable)
+	_, err := odm.sqlCli.Exec(sqlStr, enum.InventorySkipped, inventoryID, enum.InventoryNew)
+	if err != nil {
+		logger.Warn(inventoryDetailLogTag, "SkipUncountedDetail Failed|ID:%v|Err:%v", inventoryID, err)
+		return err
+	}
+	return nil
+}
+
+func (odm *InventoryDetailModel) BatchUpdateVarianceWithTx(tx *sql.Tx, detailList []*InventoryDetail) error {
+	daoList := make([]interface{}, 0, len(detailList))
+	for _, detail := range detailList {
+		daoList = append(daoList, detail)
+	}
+	err := utils.SqlBatchUpdateTag(tx, inventoryDetailTable, daoList, "id", "variance_value")
+	if err != nil {
+		logger.Warn(inventoryDetailLogTag, "BatchUpdateVariance Failed|Err:%v", err)
+		return err
+	}
+	return nil
+}
+
+func (odm *InventoryDetailModel) BatchUpdateExpectWithTx(tx *sql.Tx, detailList []*InventoryDetail) error {
+	daoList := make([]interface{}, 0, len(detailList))
+	for _, detail := range detailList {
+		daoList = append(daoList, detail)
+	}
+	for _, updateTag := range []string{"expect_number", "status"} {
+		err := utils.SqlBatchUpdateTag(tx, inventoryDetailTable, daoList, "id", updateTag)
+		if err != nil {
+			logger.Warn(inventoryDetailLogTag, "BatchUpdateExpect Failed|Tag:%v|Err:%v", updateTag, err)
+			return err
+		}
+	}
+	return nil
+}
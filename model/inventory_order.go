@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/canteen_management/enum"
 	"github.com/canteen_management/logger"
 	"github.com/canteen_management/utils"
 )
@@ -15,13 +16,19 @@ const (
 )
 
 type InventoryOrder struct {
-	ID       uint32    `json:"id"`
-	Creator  uint32    `json:"creator"`
-	Partner  uint32    `json:"partner"`
-	Status   int8      `json:"status"`
-	CreateAt time.Time `json:"created_at"`
-	FinishAt time.Time `json:"finish_at"`
-	UpdateAt time.Time `json:"updated_at"`
+	ID          uint32    `json:"id"`
+	Creator     uint32    `json:"creator"`
+	Partner     uint32    `json:"partner"`
+	PlanID      uint32    `json:"plan_id"`
+	GoodsTypeID uint32    `json:"goods_type_id"`
+	StoreTypeID uint32    `json:"store_type_id"`
+	AbcClass    uint8     `json:"abc_class"`
+	Status      int8      `json:"status"`
+	Approver    uint32    `json:"approver"` // 盘亏金额超过限额时的二次审批人
+	CreateAt    time.Time `json:"created_at"`
+	FinishAt    time.Time `json:"finish_at"`
+	ReviewAt    time.Time `json:"review_at"`
+	UpdateAt    time.Time `json:"updated_at"`
 }
 
 type InventoryOrderModel struct {
@@ -41,9 +48,11 @@ func (iom *InventoryOrderModel) Insert(dao *InventoryOrder) error {
 func (iom *InventoryOrderModel) InsertWithTx(tx *sql.Tx, dao *InventoryOrder) error {
 	id, err := int64(0), error(nil)
 	if tx != nil {
-		id, err = utils.SqlInsert(tx, inventoryOrderTable, dao, "id", "created_at", "updated_at", "finish_at")
+		id, err = utils.SqlInsert(tx, inventoryOrderTable, dao, "id", "created_at", "updated_at", "finish_at",
+			"review_at")
 	} else {
-		id, err = utils.SqlInsert(iom.sqlCli, inventoryOrderTable, dao, "id", "created_at", "updated_at", "finish_at")
+		id, err = utils.SqlInsert(iom.sqlCli, inventoryOrderTable, dao, "id", "created_at", "updated_at", "finish_at",
+			"review_at")
 	}
 	if err != nil {
 		logger.Warn(inventoryModelLogTag, "Insert Failed|Dao:%+v|Err:%v", dao, err)
@@ -96,6 +105,21 @@ func (iom *InventoryOrderModel) GetInventoryOrder(id uint32) (*InventoryOrder, e
 	return retInfo, nil
 }
 
+func (iom *InventoryOrderModel) GetInventoryOrderWithLock(tx *sql.Tx, id uint32) (*InventoryOrder, error) {
+	if tx == nil {
+		return nil, fmt.Errorf("tx is nil")
+	}
+	condition := " WHERE `id` = ? "
+	retInfo := &InventoryOrder{}
+	err := utils.SqlQueryRowWithLock(tx, inventoryOrderTable, retInfo, condition, id)
+	if err != nil {
+		logger.Warn(inventoryModelLogTag, "GetInventoryOrderWithLock Failed|ID:%v|Err:%v", id, err)
+		return nil, err
+	}
+
+	return retInfo, nil
+}
+
 func (iom *InventoryOrderModel) GetInventoryOrderList(id, creator uint32, status int8, startTime, endTime int64,
 	page, pageSize int32) ([]*InventoryOrder, error) {
 	condition, params := iom.GenerateCondition(id, creator, status, startTime, endTime)
@@ -123,6 +147,36 @@ func (iom *InventoryOrderModel) GetInventoryOrderCount(id, creator uint32, statu
 	return count, nil
 }
 
+func (iom *InventoryOrderModel) GetOpenOrders() ([]*InventoryOrder, error) {
+	condition := " WHERE `status` in (?,?,?) "
+	retList, err := utils.SqlQuery(iom.sqlCli, inventoryOrderTable, &InventoryOrder{}, condition,
+		enum.InventoryOrderNew, enum.InventoryOrderFinish, enum.InventoryOrderConfirmed)
+	if err != nil {
+		logger.Warn(inventoryModelLogTag, "GetOpenOrders Failed|Err:%v", err)
+		return nil, err
+	}
+	return retList.([]*InventoryOrder), nil
+}
+
+func (iom *InventoryOrderModel) GetReviewedOrders(startTime, endTime int64) ([]*InventoryOrder, error) {
+	condition := " WHERE `status` = ? "
+	params := []interface{}{enum.InventoryOrderReviewed}
+	if startTime > 0 {
+		condition += " AND `review_at` >= ? "
+		params = append(params, time.Unix(startTime, 0))
+	}
+	if endTime > startTime {
+		condition += " AND `review_at` <= ? "
+		params = append(params, time.Unix(endTime, 0))
+	}
+	retList, err := utils.SqlQuery(iom.sqlCli, inventoryOrderTable, &InventoryOrder{}, condition, params...)
+	if err != nil {
+		logger.Warn(inventoryModelLogTag, "GetReviewedOrders Failed|Err:%v", err)
+		return nil, err
+	}
+	return retList.([]*InventoryOrder), nil
+}
+
 func (iom *InventoryOrderModel) UpdateInventoryOrderByCondition(order *InventoryOrder, conditionTag string,
 	updateTags ...string) error {
 	return iom.UpdateInventoryOrderByConditionWithTx(nil, order, conditionTag, updateTags...)
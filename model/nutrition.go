@@ -0,0 +1,65 @@
+package model
+
+import (
+	"encoding/json"
+
+	"github.com/canteen_management/logger"
+)
+
+const (
+	nutritionLogTag = "Nutrition"
+)
+
+type Nutrition struct {
+	Energy  float64 `json:"energy"`
+	Protein float64 `json:"protein"`
+	Fat     float64 `json:"fat"`
+	Carbs   float64 `json:"carbs"`
+	Sodium  float64 `json:"sodium"`
+}
+
+func (n *Nutrition) IsEmpty() bool {
+	return n.Energy == 0 && n.Protein == 0 && n.Fat == 0 && n.Carbs == 0 && n.Sodium == 0
+}
+
+func (n *Nutrition) Add(other *Nutrition, times float64) {
+	if other == nil {
+		return
+	}
+	n.Energy += other.Energy * times
+	n.Protein += other.Protein * times
+	n.Fat += other.Fat * times
+	n.Carbs += other.Carbs * times
+	n.Sodium += other.Sodium * times
+}
+
+func marshalNutrition(nutrition *Nutrition) (string, error) {
+	if nutrition == nil || nutrition.IsEmpty() {
+		return "", nil
+	}
+	contentStr, err := json.Marshal(nutrition)
+	if err != nil {
+		logger.Warn(nutritionLogTag, "MarshalNutrition Failed|Err:%v", err)
+		return "", err
+	}
+	return string(contentStr), nil
+}
+
+func unmarshalNutrition(content string) *Nutrition {
+	nutrition := &Nutrition{}
+	if content == "" {
+		return nutrition
+	}
+	err := json.Unmarshal([]byte(content), nutrition)
+	if err != nil {
+		logger.Warn(nutritionLogTag, "UnmarshalNutrition Failed|Content:%v|Err:%v", content, err)
+		return &Nutrition{}
+	}
+	return nutrition
+}
+
+// RecipeNutrition 按配方原料推算出的营养与过敏原，不落库
+type RecipeNutrition struct {
+	Nutrition *Nutrition
+	Allergen  uint32
+}
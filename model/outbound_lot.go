@@ -0,0 +1,96 @@
+package model
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/canteen_management/logger"
+	"github.com/canteen_management/utils"
+)
+
+const (
+	outboundLotTable = "outbound_lot"
+
+	outboundLotLogTag = "OutboundLotModel"
+)
+
+// OutboundLot 出库时实际扣减的批次与数量，冲销时按此退回原批次
+type OutboundLot struct {
+	ID         uint32    `json:"id"`
+	OutboundID uint32    `json:"outbound_id"`
+	LotID      uint32    `json:"lot_id"`
+	GoodsID    uint32    `json:"goods_id"`
+	Quantity   float64   `json:"quantity"`
+	CreateAt   time.Time `json:"created_at"`
+}
+
+type OutboundLotModel struct {
+	sqlCli *sql.DB
+}
+
+func NewOutboundLotModelWithDB(sqlCli *sql.DB) *OutboundLotModel {
+	return &OutboundLotModel{
+		sqlCli: sqlCli,
+	}
+}
+
+func (olm *OutboundLotModel) BatchInsertWithTx(tx *sql.Tx, daoList []*OutboundLot) error {
+	if len(daoList) == 0 {
+		return nil
+	}
+	err := utils.SqlInsertBatch(tx, outboundLotTable, daoList, "id", "created_at")
+	if err != nil {
+		logger.Warn(outboundLotLogTag, "BatchInsert Failed|DaoList:%+v|Err:%v", daoList, err)
+		return err
+	}
+	return nil
+}
+
+func (olm *OutboundLotModel) GetOutboundLotsWithTx(tx *sql.Tx, outboundID uint32) ([]*OutboundLot, error) {
+	retList, err := utils.SqlQuery(tx, outboundLotTable, &OutboundLot{}, " WHERE `outbound_id` = ? ", outboundID)
+	if err != nil {
+		logger.Warn(outboundLotLogTag, "GetOutboundLots Failed|OutboundID:%v|Err:%v", outboundID, err)
+		return nil, err
+	}
+	return retList.([]*OutboundLot), nil
+}
+
+func GenerateOutboundLots(outboundID uint32, consumedList []*StockLot) []*OutboundLot {
+	retList := make([]*OutboundLot, 0, len(consumedList))
+	for _, lot := range consumedList {
+		if lot.Quantity <= 0 {
+			continue
+		}
+		retList = append(retList, &OutboundLot{OutboundID: outboundID, LotID: lot.ID, GoodsID: lot.GoodsID,
+			Quantity: lot.Quantity})
+	}
+	return retList
+}
+
+// RestoreLots 将出库扣减的数量退回原批次，lotMap 为加锁读取的批次，返回数量有变化的批次与各商品未能退回原批次的剩余数量
+func RestoreLots(outboundLots []*OutboundLot, lotMap map[uint32]*StockLot, returnMap map[uint32]float64) ([]*StockLot,
+	map[uint32]float64) {
+	remainMap := make(map[uint32]float64, len(returnMap))
+	for goodsID, number := range returnMap {
+		remainMap[goodsID] = number
+	}
+	updateList, updated := make([]*StockLot, 0, len(outboundLots)), make(map[uint32]bool)
+	for _, outboundLot := range outboundLots {
+		lot, ok := lotMap[outboundLot.LotID]
+		remain := remainMap[outboundLot.GoodsID]
+		if !ok || remain <= 0 {
+			continue
+		}
+		restore := outboundLot.Quantity
+		if restore > remain {
+			restore = remain
+		}
+		lot.Quantity += restore
+		remainMap[outboundLot.GoodsID] = remain - restore
+		if !updated[lot.ID] {
+			updated[lot.ID] = true
+			updateList = append(updateList, lot)
+		}
+	}
+	return updateList, remainMap
+}
@@ -18,7 +18,10 @@ const (
 type OutboundOrder struct {
 	ID           uint32    `json:"id"`
 	Creator      uint32    `json:"creator"`
+	StoreTypeID  uint32    `json:"store_type_id"`
 	TotalAmount  float64   `json:"total_amount"`
+	CostAmount   float64   `json:"cost_amount"`
+	Reserved     bool      `json:"reserved"`
 	Status       int8      `json:"status"`
 	OutboundTime time.Time `json:"outbound_time"`
 	CreateAt     time.Time `json:"created_at"`
@@ -103,7 +106,7 @@ func (oom *OutboundOrderModel) GetOutboundOrderWithLock(tx *sql.Tx, id uint32) (
 	}
 	condition := " WHERE `id` = ? "
 	retInfo := &OutboundOrder{}
-	err := utils.SqlQueryRowWithLock(oom.sqlCli, outboundOrderTable, retInfo, condition, id)
+	err := utils.SqlQueryRowWithLock(tx, outboundOrderTable, retInfo, condition, id)
 	if err != nil {
 		logger.Warn(outboundOrderLogTag, "GetOrderWithLock Failed|ID:%v|Err:%v", id, err)
 		return nil, err
@@ -101,7 +101,6 @@ func (pom *PayOrderModel) GenerateCondition(idList []uint32, uid, cartID uint32,
 			statusStr += fmt.Sprintf(",%v", status)
 		}
 		condition += fmt.Sprintf(" AND `status` in (%v) ", statusStr[1:])
-		params = append(params)
 	}
 	if timeStart > 0 {
 		condition += " AND `meal_time` >= ? "
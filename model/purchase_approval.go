@@ -0,0 +1,55 @@
+package model
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/canteen_management/logger"
+	"github.com/canteen_management/utils"
+)
+
+const (
+	purchaseApprovalTable = "purchase_approval"
+
+	purchaseApprovalLogTag = "PurchaseApprovalModel"
+)
+
+type PurchaseApproval struct {
+	ID         uint32    `json:"id"`
+	PurchaseID uint32    `json:"purchase_id"`
+	Step       uint8     `json:"step"`
+	Approver   uint32    `json:"approver"`
+	Pass       bool      `json:"pass"`
+	Comment    string    `json:"comment"`
+	CreateAt   time.Time `json:"created_at"`
+}
+
+type PurchaseApprovalModel struct {
+	sqlCli *sql.DB
+}
+
+func NewPurchaseApprovalModelWithDB(sqlCli *sql.DB) *PurchaseApprovalModel {
+	return &PurchaseApprovalModel{
+		sqlCli: sqlCli,
+	}
+}
+
+func (pam *PurchaseApprovalModel) InsertWithTx(tx *sql.Tx, dao *PurchaseApproval) error {
+	id, err := utils.SqlInsert(tx, purchaseApprovalTable, dao, "id", "created_at")
+	if err != nil {
+		logger.Warn(purchaseApprovalLogTag, "Insert Failed|Dao:%+v|Err:%v", dao, err)
+		return err
+	}
+	dao.ID = uint32(id)
+	return nil
+}
+
+func (pam *PurchaseApprovalModel) GetApprovalList(purchaseID uint32) ([]*PurchaseApproval, error) {
+	condition := " WHERE `purchase_id` = ? ORDER BY `id` ASC "
+	retList, err := utils.SqlQuery(pam.sqlCli, purchaseApprovalTable, &PurchaseApproval{}, condition, purchaseID)
+	if err != nil {
+		logger.Warn(purchaseApprovalLogTag, "GetApprovalList Failed|PurchaseID:%v|Err:%v", purchaseID, err)
+		return nil, err
+	}
+	return retList.([]*PurchaseApproval), nil
+}
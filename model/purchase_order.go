@@ -24,13 +24,17 @@ var (
 type PurchaseOrder struct {
 	ID          uint32    `json:"id"`
 	Supplier    uint32    `json:"supplier"`
+	ParentID    uint32    `json:"parent_id"`
+	StatementID uint32    `json:"statement_id"`
 	TotalAmount float64   `json:"total_amount"`
 	PayAmount   float64   `json:"pay_amount"`
 	Creator     uint32    `json:"creator"`
 	Status      int8      `json:"status"`
+	ApproveStep uint8     `json:"approve_step"`
 	Receiver    string    `json:"receiver"`
 	SignPicture string    `json:"sign_picture"`
 	CreateAt    time.Time `json:"created_at"`
+	AcceptAt    time.Time `json:"accept_at"`
 	ReceiveAt   time.Time `json:"receive_at"`
 	UpdateAt    time.Time `json:"updated_at"`
 }
@@ -86,9 +90,9 @@ func (pom *PurchaseOrderModel) Insert(dao *PurchaseOrder) error {
 func (pom *PurchaseOrderModel) InsertWithTx(tx *sql.Tx, dao *PurchaseOrder) error {
 	id, err := int64(0), error(nil)
 	if tx != nil {
-		id, err = utils.SqlInsert(tx, purchaseOrderTable, dao, "id", "created_at", "updated_at", "receive_at")
+		id, err = utils.SqlInsert(tx, purchaseOrderTable, dao, "id", "created_at", "updated_at", "accept_at", "receive_at")
 	} else {
-		id, err = utils.SqlInsert(pom.sqlCli, purchaseOrderTable, dao, "id", "created_at", "updated_at", "receive_at")
+		id, err = utils.SqlInsert(pom.sqlCli, purchaseOrderTable, dao, "id", "created_at", "updated_at", "accept_at", "receive_at")
 	}
 	if err != nil {
 		logger.Warn(purchaseOrderLogTag, "Insert Failed|Dao:%+v|Err:%v", dao, err)
@@ -146,6 +150,21 @@ func (pom *PurchaseOrderModel) GetPurchaseOrder(id uint32) (*PurchaseOrder, erro
 	return retInfo, nil
 }
 
+func (pom *PurchaseOrderModel) GetPurchaseOrderWithLock(tx *sql.Tx, id uint32) (*PurchaseOrder, error) {
+	if tx == nil {
+		return nil, fmt.Errorf("tx is nil")
+	}
+	condition := " WHERE `id` = ? "
+	retInfo := &PurchaseOrder{}
+	err := utils.SqlQueryRowWithLock(tx, purchaseOrderTable, retInfo, condition, id)
+	if err != nil {
+		logger.Warn(purchaseOrderLogTag, "GetOrderWithLock Failed|ID:%v|Err:%v", id, err)
+		return nil, err
+	}
+
+	return retInfo, nil
+}
+
 func (pom *PurchaseOrderModel) GetPurchaseOrderList(id uint32, status int8, supplier, creator uint32, startTime,
 	endTime int64, page, pageSize int32) ([]*PurchaseOrder, error) {
 	condition, params := pom.GenerateCondition(id, status, supplier, creator, startTime, endTime)
@@ -199,3 +218,89 @@ func (pom *PurchaseOrderModel) UpdatePurchaseStatus(dao *PurchaseOrder) error {
 	}
 	return nil
 }
+
+func (pom *PurchaseOrderModel) GetUnbilledOrdersWithLock(tx *sql.Tx, supplier uint32, startTime,
+	endTime time.Time) ([]*PurchaseOrder, error) {
+	condition := " WHERE `supplier` = ? AND `status` = ? AND `statement_id` = 0 AND `receive_at` >= ? AND `receive_at` < ? "
+	retList, err := utils.SqlQueryWithLock(tx, purchaseOrderTable, &PurchaseOrder{}, condition, supplier,
+		enum.PurchaseFinish, startTime, endTime)
+	if err != nil {
+		logger.Warn(purchaseOrderLogTag, "GetUnbilledOrders Failed|Supplier:%v|Err:%v", supplier, err)
+		return nil, err
+	}
+	return retList.([]*PurchaseOrder), nil
+}
+
+func (pom *PurchaseOrderModel) GetOrdersByStatement(statementID uint32) ([]*PurchaseOrder, error) {
+	condition := " WHERE `statement_id` = ? ORDER BY `id` ASC "
+	retList, err := utils.SqlQuery(pom.sqlCli, purchaseOrderTable, &PurchaseOrder{}, condition, statementID)
+	if err != nil {
+		logger.Warn(purchaseOrderLogTag, "GetOrdersByStatement Failed|StatementID:%v|Err:%v", statementID, err)
+		return nil, err
+	}
+	return retList.([]*PurchaseOrder), nil
+}
+
+func (pom *PurchaseOrderModel) UpdateStatementWithTx(tx *sql.Tx, idList []uint32, statementID uint32) error {
+	if len(idList) == 0 {
+		return nil
+	}
+	params := []interface{}{statementID}
+	for _, id := range idList {
+		params = append(params, id)
+	}
+	sqlStr := fmt.Sprintf("UPDATE `%v` SET `statement_id` = ? WHERE `id` in (%v)", purchaseOrderTable,
+		utils.GetSqlPlaceholder(len(idList)))
+	_, err := tx.Exec(sqlStr, params...)
+	if err != nil {
+		logger.Warn(purchaseOrderLogTag, "UpdateStatement Failed|StatementID:%v|Err:%v", statementID, err)
+		return err
+	}
+	return nil
+}
+
+func (pom *PurchaseOrderModel) GetUnbilledAmountMap() (map[uint32]float64, error) {
+	sqlStr := fmt.Sprintf("SELECT `supplier`, SUM(`pay_amount`) FROM `%v` WHERE `status` = ? AND `statement_id` = 0 "+
+		"GROUP BY `supplier`", purchaseOrderTable)
+	rows, err := pom.sqlCli.Query(sqlStr, enum.PurchaseFinish)
+	if err != nil {
+		logger.Warn(purchaseOrderLogTag, "GetUnbilledAmountMap Failed|Err:%v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	amountMap := make(map[uint32]float64)
+	for rows.Next() {
+		var supplier uint32
+		var amount float64
+		err = rows.Scan(&supplier, &amount)
+		if err != nil {
+			logger.Warn(purchaseOrderLogTag, "GetUnbilledAmountMap Scan Failed|Err:%v", err)
+			return nil, err
+		}
+		amountMap[supplier] = amount
+	}
+	return amountMap, nil
+}
+
+func (pom *PurchaseOrderModel) GetOrdersByTime(startTime, endTime time.Time) ([]*PurchaseOrder, error) {
+	condition := " WHERE `created_at` >= ? AND `created_at` < ? AND `status` >= ? "
+	retList, err := utils.SqlQuery(pom.sqlCli, purchaseOrderTable, &PurchaseOrder{}, condition, startTime, endTime,
+		enum.PurchaseAccept)
+	if err != nil {
+		logger.Warn(purchaseOrderLogTag, "GetOrdersByTime Failed|Err:%v", err)
+		return nil, err
+	}
+	return retList.([]*PurchaseOrder), nil
+}
+
+func (pom *PurchaseOrderModel) GetOpenOrders() ([]*PurchaseOrder, error) {
+	condition := " WHERE `status` in (?,?,?,?) "
+	retList, err := utils.SqlQuery(pom.sqlCli, purchaseOrderTable, &PurchaseOrder{}, condition, enum.PurchaseNew,
+		enum.PurchaseReviewed, enum.PurchaseAccept, enum.PurchaseReceived)
+	if err != nil {
+		logger.Warn(purchaseOrderLogTag, "GetOpenOrders Failed|Err:%v", err)
+		return nil, err
+	}
+	return retList.([]*PurchaseOrder), nil
+}
@@ -20,6 +20,7 @@ type PurchaseDetail struct {
 	GoodsType     uint32  `json:"goods_type"`
 	ExpectNumber  float64 `json:"expect_number"`
 	ReceiveNumber float64 `json:"receive_number"`
+	ReturnNumber  float64 `json:"return_number"`
 	Price         float64 `json:"price"`
 }
 
@@ -61,6 +62,20 @@ func (pdm *PurchaseDetailModel) GetDetail(orderID uint32) ([]*PurchaseDetail, er
 	return retList.([]*PurchaseDetail), nil
 }
 
+func (pdm *PurchaseDetailModel) GetDetailWithLock(tx *sql.Tx, purchaseID uint32) ([]*PurchaseDetail, error) {
+	if tx == nil {
+		return nil, fmt.Errorf("tx is nil")
+	}
+	condition := " WHERE `purchase_id` = ? "
+	retList, err := utils.SqlQueryWithLock(tx, purchaseDetailTable, &PurchaseDetail{}, condition, purchaseID)
+	if err != nil {
+		logger.Warn(purchaseDetailLogTag, "GetDetailWithLock Failed|PurchaseID:%v|Err:%v", purchaseID, err)
+		return nil, err
+	}
+
+	return retList.([]*PurchaseDetail), nil
+}
+
 func (pdm *PurchaseDetailModel) BatchUpdateDetailWithTx(tx *sql.Tx, detailList []*PurchaseDetail) (err error) {
 	daoList := make([]interface{}, 0)
 	for _, detail := range detailList {
@@ -103,3 +118,26 @@ func (pdm *PurchaseDetailModel) GetPurchaseDetailByOrderList(purchaseIDList []ui
 
 	return retList.([]*PurchaseDetail), nil
 }
+
+func (pdm *PurchaseDetailModel) BatchUpdateReturnWithTx(tx *sql.Tx, detailList []*PurchaseDetail) error {
+	daoList := make([]interface{}, 0)
+	for _, detail := range detailList {
+		daoList = append(daoList, detail)
+	}
+	err := utils.SqlBatchUpdateTag(tx, purchaseDetailTable, daoList, "id", "return_number")
+	if err != nil {
+		logger.Warn(purchaseDetailLogTag, "BatchUpdateReturn Failed|Err:%v", err)
+		return err
+	}
+	return nil
+}
+
+func (pdm *PurchaseDetailModel) DeleteDetailWithTx(tx *sql.Tx, purchaseID uint32) error {
+	sqlStr := fmt.Sprintf(" DELETE FROM %v WHERE `purchase_id` = ? ", purchaseDetailTable)
+	_, err := tx.Exec(sqlStr, purchaseID)
+	if err != nil {
+		logger.Warn(purchaseDetailLogTag, "DeleteDetail Failed|PurchaseID:%v|Err:%v", purchaseID, err)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,90 @@
+package model
+
+import (
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"github.com/canteen_management/logger"
+	"github.com/canteen_management/utils"
+)
+
+const (
+	purchaseReceiptTable = "purchase_receipt"
+
+	purchaseReceiptLogTag = "PurchaseReceiptModel"
+)
+
+type ReceiptItem struct {
+	DetailID       uint32  `json:"detail_id"`
+	GoodsID        uint32  `json:"goods_id"`
+	ReceiveNumber  float64 `json:"receive_number"`
+	Price          float64 `json:"price"`
+	BatchNo        string  `json:"batch_no"`
+	ProductionDate int64   `json:"production_date"`
+	ExpiryDate     int64   `json:"expiry_date"`
+}
+
+type PurchaseReceipt struct {
+	ID             uint32    `json:"id"`
+	PurchaseID     uint32    `json:"purchase_id"`
+	Receiver       uint32    `json:"receiver"`
+	StoreTypeID    uint32    `json:"store_type_id"`
+	SignPicture    string    `json:"sign_picture"`
+	ReceiveContent string    `json:"receive_content"`
+	Amount         float64   `json:"amount"`
+	CreateAt       time.Time `json:"created_at"`
+}
+
+func (pr *PurchaseReceipt) FromItems(items []*ReceiptItem) error {
+	contentStr, err := json.Marshal(items)
+	if err != nil {
+		logger.Warn(purchaseReceiptLogTag, "FromItems Failed|Err:%v", err)
+		return err
+	}
+	pr.ReceiveContent = string(contentStr)
+	return nil
+}
+
+func (pr *PurchaseReceipt) ToItems() []*ReceiptItem {
+	items := make([]*ReceiptItem, 0)
+	if pr.ReceiveContent == "" {
+		return items
+	}
+	err := json.Unmarshal([]byte(pr.ReceiveContent), &items)
+	if err != nil {
+		logger.Warn(purchaseReceiptLogTag, "ToItems Failed|Err:%v", err)
+		return make([]*ReceiptItem, 0)
+	}
+	return items
+}
+
+type PurchaseReceiptModel struct {
+	sqlCli *sql.DB
+}
+
+func NewPurchaseReceiptModelWithDB(sqlCli *sql.DB) *PurchaseReceiptModel {
+	return &PurchaseReceiptModel{
+		sqlCli: sqlCli,
+	}
+}
+
+func (prm *PurchaseReceiptModel) InsertWithTx(tx *sql.Tx, dao *PurchaseReceipt) error {
+	id, err := utils.SqlInsert(tx, purchaseReceiptTable, dao, "id", "created_at")
+	if err != nil {
+		logger.Warn(purchaseReceiptLogTag, "Insert Failed|Dao:%+v|Err:%v", dao, err)
+		return err
+	}
+	dao.ID = uint32(id)
+	return nil
+}
+
+func (prm *PurchaseReceiptModel) GetReceiptList(purchaseID uint32) ([]*PurchaseReceipt, error) {
+	condition := " WHERE `purchase_id` = ? ORDER BY `id` ASC "
+	retList, err := utils.SqlQuery(prm.sqlCli, purchaseReceiptTable, &PurchaseReceipt{}, condition, purchaseID)
+	if err != nil {
+		logger.Warn(purchaseReceiptLogTag, "GetReceiptList Failed|PurchaseID:%v|Err:%v", purchaseID, err)
+		return nil, err
+	}
+	return retList.([]*PurchaseReceipt), nil
+}
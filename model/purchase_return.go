@@ -0,0 +1,152 @@
+package model
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/canteen_management/logger"
+	"github.com/canteen_management/utils"
+)
+
+const (
+	purchaseReturnTable = "purchase_return"
+
+	purchaseReturnLogTag = "PurchaseReturnModel"
+)
+
+type ReturnItem struct {
+	DetailID     uint32  `json:"detail_id"`
+	GoodsID      uint32  `json:"goods_id"`
+	ReturnNumber float64 `json:"return_number"`
+	Price        float64 `json:"price"`
+}
+
+type PurchaseReturn struct {
+	ID            uint32    `json:"id"`
+	PurchaseID    uint32    `json:"purchase_id"`
+	Supplier      uint32    `json:"supplier"`
+	Creator       uint32    `json:"creator"`
+	Reason        uint8     `json:"reason"`
+	Remark        string    `json:"remark"`
+	Pictures      string    `json:"pictures"`
+	ReturnContent string    `json:"return_content"`
+	Amount        float64   `json:"amount"`
+	CreateAt      time.Time `json:"created_at"`
+}
+
+func (pr *PurchaseReturn) FromItems(items []*ReturnItem) error {
+	contentStr, err := json.Marshal(items)
+	if err != nil {
+		logger.Warn(purchaseReturnLogTag, "FromItems Failed|Err:%v", err)
+		return err
+	}
+	pr.ReturnContent = string(contentStr)
+	return nil
+}
+
+func (pr *PurchaseReturn) ToItems() []*ReturnItem {
+	items := make([]*ReturnItem, 0)
+	if pr.ReturnContent == "" {
+		return items
+	}
+	err := json.Unmarshal([]byte(pr.ReturnContent), &items)
+	if err != nil {
+		logger.Warn(purchaseReturnLogTag, "ToItems Failed|Err:%v", err)
+		return make([]*ReturnItem, 0)
+	}
+	return items
+}
+
+func (pr *PurchaseReturn) FromPictures(pictures []string) error {
+	pictureStr, err := json.Marshal(pictures)
+	if err != nil {
+		logger.Warn(purchaseReturnLogTag, "FromPictures Failed|Err:%v", err)
+		return err
+	}
+	pr.Pictures = string(pictureStr)
+	return nil
+}
+
+func (pr *PurchaseReturn) ToPictures() []string {
+	pictures := make([]string, 0)
+	if pr.Pictures == "" {
+		return pictures
+	}
+	err := json.Unmarshal([]byte(pr.Pictures), &pictures)
+	if err != nil {
+		logger.Warn(purchaseReturnLogTag, "ToPictures Failed|Err:%v", err)
+		return make([]string, 0)
+	}
+	return pictures
+}
+
+type PurchaseReturnModel struct {
+	sqlCli *sql.DB
+}
+
+func NewPurchaseReturnModelWithDB(sqlCli *sql.DB) *PurchaseReturnModel {
+	return &PurchaseReturnModel{
+		sqlCli: sqlCli,
+	}
+}
+
+func (prm *PurchaseReturnModel) InsertWithTx(tx *sql.Tx, dao *PurchaseReturn) error {
+	id, err := utils.SqlInsert(tx, purchaseReturnTable, dao, "id", "created_at")
+	if err != nil {
+		logger.Warn(purchaseReturnLogTag, "Insert Failed|Dao:%+v|Err:%v", dao, err)
+		return err
+	}
+	dao.ID = uint32(id)
+	return nil
+}
+
+func (prm *PurchaseReturnModel) GenerateCondition(purchaseID, supplier uint32, startTime,
+	endTime int64) (string, []interface{}) {
+	var params []interface{}
+	condition := " WHERE 1=1 "
+	if purchaseID > 0 {
+		condition += " AND `purchase_id` = ? "
+		params = append(params, purchaseID)
+	}
+	if supplier > 0 {
+		condition += " AND `supplier` = ? "
+		params = append(params, supplier)
+	}
+	if startTime > 0 {
+		condition += " AND `created_at` >= ? "
+		params = append(params, time.Unix(startTime, 0))
+	}
+	if endTime > startTime {
+		condition += " AND `created_at` <= ? "
+		params = append(params, time.Unix(endTime, 0))
+	}
+	return condition, params
+}
+
+func (prm *PurchaseReturnModel) GetReturnList(purchaseID, supplier uint32, startTime, endTime int64,
+	page, pageSize int32) ([]*PurchaseReturn, error) {
+	condition, params := prm.GenerateCondition(purchaseID, supplier, startTime, endTime)
+	condition += " ORDER BY `id` DESC LIMIT ?,? "
+	params = append(params, (page-1)*pageSize, pageSize)
+	retList, err := utils.SqlQuery(prm.sqlCli, purchaseReturnTable, &PurchaseReturn{}, condition, params...)
+	if err != nil {
+		logger.Warn(purchaseReturnLogTag, "GetReturnList Failed|Err:%v", err)
+		return nil, err
+	}
+	return retList.([]*PurchaseReturn), nil
+}
+
+func (prm *PurchaseReturnModel) GetReturnCount(purchaseID, supplier uint32, startTime, endTime int64) (int32, error) {
+	condition, params := prm.GenerateCondition(purchaseID, supplier, startTime, endTime)
+	sqlStr := fmt.Sprintf("SELECT COUNT(*) FROM `%v` %v", purchaseReturnTable, condition)
+	row := prm.sqlCli.QueryRow(sqlStr, params...)
+	var count int32
+	err := row.Scan(&count)
+	if err != nil {
+		logger.Warn(purchaseReturnLogTag, "GetReturnCount Failed|Err:%v", err)
+		return 0, err
+	}
+	return count, nil
+}
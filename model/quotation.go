@@ -0,0 +1,136 @@
+package model
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/canteen_management/enum"
+	"github.com/canteen_management/logger"
+	"github.com/canteen_management/utils"
+)
+
+const (
+	quotationTable = "quotation"
+
+	quotationLogTag = "QuotationModel"
+)
+
+type Quotation struct {
+	ID           uint32    `json:"id"`
+	Title        string    `json:"title"`
+	GoodsContent string    `json:"goods_content"`
+	Creator      uint32    `json:"creator"`
+	Status       int8      `json:"status"`
+	Deadline     time.Time `json:"deadline"`
+	CloseAt      time.Time `json:"close_at"`
+	CreateAt     time.Time `json:"created_at"`
+	UpdateAt     time.Time `json:"updated_at"`
+}
+
+func (q *Quotation) FromGoodsList(goodsList []uint32) error {
+	contentStr, err := json.Marshal(goodsList)
+	if err != nil {
+		logger.Warn(quotationLogTag, "FromGoodsList Failed|Err:%v", err)
+		return err
+	}
+	q.GoodsContent = string(contentStr)
+	return nil
+}
+
+func (q *Quotation) ToGoodsList() []uint32 {
+	goodsList := make([]uint32, 0)
+	if q.GoodsContent == "" {
+		return goodsList
+	}
+	err := json.Unmarshal([]byte(q.GoodsContent), &goodsList)
+	if err != nil {
+		logger.Warn(quotationLogTag, "ToGoodsList Failed|Err:%v", err)
+		return make([]uint32, 0)
+	}
+	return goodsList
+}
+
+func (q *Quotation) IsQuoting() bool {
+	return q.Status == enum.QuotationOpen && time.Now().Before(q.Deadline)
+}
+
+type QuotationModel struct {
+	sqlCli *sql.DB
+}
+
+func NewQuotationModelWithDB(sqlCli *sql.DB) *QuotationModel {
+	return &QuotationModel{
+		sqlCli: sqlCli,
+	}
+}
+
+func (qm *QuotationModel) Insert(dao *Quotation) error {
+	id, err := utils.SqlInsert(qm.sqlCli, quotationTable, dao, "id", "created_at", "updated_at", "close_at")
+	if err != nil {
+		logger.Warn(quotationLogTag, "Insert Failed|Dao:%+v|Err:%v", dao, err)
+		return err
+	}
+	dao.ID = uint32(id)
+	return nil
+}
+
+func (qm *QuotationModel) GetQuotation(id uint32) (*Quotation, error) {
+	retInfo := &Quotation{}
+	err := utils.SqlQueryRow(qm.sqlCli, quotationTable, retInfo, " WHERE `id` = ? ", id)
+	if err != nil {
+		logger.Warn(quotationLogTag, "GetQuotation Failed|ID:%v|Err:%v", id, err)
+		return nil, err
+	}
+	return retInfo, nil
+}
+
+func (qm *QuotationModel) GenerateCondition(status int8) (string, []interface{}) {
+	var params []interface{}
+	condition := " WHERE 1=1 "
+	if status != enum.QuotationStatusAll {
+		condition += " AND `status` = ? "
+		params = append(params, status)
+	}
+	return condition, params
+}
+
+func (qm *QuotationModel) GetQuotationList(status int8, page, pageSize int32) ([]*Quotation, error) {
+	condition, params := qm.GenerateCondition(status)
+	condition += " ORDER BY `id` DESC LIMIT ?,? "
+	params = append(params, (page-1)*pageSize, pageSize)
+	retList, err := utils.SqlQuery(qm.sqlCli, quotationTable, &Quotation{}, condition, params...)
+	if err != nil {
+		logger.Warn(quotationLogTag, "GetQuotationList Failed|Err:%v", err)
+		return nil, err
+	}
+	return retList.([]*Quotation), nil
+}
+
+func (qm *QuotationModel) GetQuotationCount(status int8) (int32, error) {
+	condition, params := qm.GenerateCondition(status)
+	sqlStr := fmt.Sprintf("SELECT COUNT(*) FROM `%v` %v", quotationTable, condition)
+	row := qm.sqlCli.QueryRow(sqlStr, params...)
+	var count int32
+	err := row.Scan(&count)
+	if err != nil {
+		logger.Warn(quotationLogTag, "GetQuotationCount Failed|Err:%v", err)
+		return 0, err
+	}
+	return count, nil
+}
+
+func (qm *QuotationModel) UpdateStatusWithTx(tx *sql.Tx, dao *Quotation) error {
+	var err error
+	if tx != nil {
+		err = utils.SqlUpdateWithUpdateTags(tx, quotationTable, dao, "id", "status", "close_at")
+	} else {
+		err = utils.SqlUpdateWithUpdateTags(qm.sqlCli, quotationTable, dao, "id", "status", "close_at")
+	}
+	if err != nil {
+		logger.Warn(quotationLogTag, "UpdateStatus Failed|ID:%v|Err:%v", dao.ID, err)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,98 @@
+package model
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/canteen_management/logger"
+	"github.com/canteen_management/utils"
+)
+
+const (
+	quotationItemTable = "quotation_item"
+
+	quotationItemLogTag = "QuotationItemModel"
+)
+
+type QuotationItem struct {
+	ID          uint32    `json:"id"`
+	QuotationID uint32    `json:"quotation_id"`
+	SupplierID  uint32    `json:"supplier_id"`
+	GoodsID     uint32    `json:"goods_id"`
+	Price       float64   `json:"price"`
+	Remark      string    `json:"remark"`
+	IsAward     bool      `json:"is_award"`
+	CreateAt    time.Time `json:"created_at"`
+	UpdateAt    time.Time `json:"updated_at"`
+}
+
+type QuotationItemModel struct {
+	sqlCli *sql.DB
+}
+
+func NewQuotationItemModelWithDB(sqlCli *sql.DB) *QuotationItemModel {
+	return &QuotationItemModel{
+		sqlCli: sqlCli,
+	}
+}
+
+func (qim *QuotationItemModel) BatchInsertWithTx(tx *sql.Tx, daoList []*QuotationItem) error {
+	if len(daoList) == 0 {
+		return nil
+	}
+	err := utils.SqlInsertBatch(tx, quotationItemTable, daoList, "id", "created_at", "updated_at")
+	if err != nil {
+		logger.Warn(quotationItemLogTag, "BatchInsert Failed|Err:%v", err)
+		return err
+	}
+	return nil
+}
+
+func (qim *QuotationItemModel) DeleteSupplierItemsWithTx(tx *sql.Tx, quotationID, supplierID uint32) error {
+	sqlStr := fmt.Sprintf(" DELETE FROM %v WHERE `quotation_id` = ? AND `supplier_id` = ? ", quotationItemTable)
+	_, err := tx.Exec(sqlStr, quotationID, supplierID)
+	if err != nil {
+		logger.Warn(quotationItemLogTag, "DeleteSupplierItems Failed|QuotationID:%v|SupplierID:%v|Err:%v",
+			quotationID, supplierID, err)
+		return err
+	}
+	return nil
+}
+
+func (qim *QuotationItemModel) GetItems(quotationIDList []uint32, supplierID uint32) ([]*QuotationItem, error) {
+	if len(quotationIDList) == 0 {
+		return make([]*QuotationItem, 0), nil
+	}
+	var params []interface{}
+	idStr := ""
+	for _, quotationID := range quotationIDList {
+		idStr += ",?"
+		params = append(params, quotationID)
+	}
+	condition := fmt.Sprintf(" WHERE `quotation_id` in (%v) ", idStr[1:])
+	if supplierID > 0 {
+		condition += " AND `supplier_id` = ? "
+		params = append(params, supplierID)
+	}
+	condition += " ORDER BY `goods_id` ASC, `price` ASC "
+	retList, err := utils.SqlQuery(qim.sqlCli, quotationItemTable, &QuotationItem{}, condition, params...)
+	if err != nil {
+		logger.Warn(quotationItemLogTag, "GetItems Failed|Err:%v", err)
+		return nil, err
+	}
+	return retList.([]*QuotationItem), nil
+}
+
+func (qim *QuotationItemModel) BatchUpdateAwardWithTx(tx *sql.Tx, daoList []*QuotationItem) error {
+	updateList := make([]interface{}, 0, len(daoList))
+	for _, dao := range daoList {
+		updateList = append(updateList, dao)
+	}
+	err := utils.SqlBatchUpdateTag(tx, quotationItemTable, updateList, "id", "is_award")
+	if err != nil {
+		logger.Warn(quotationItemLogTag, "BatchUpdateAward Failed|Err:%v", err)
+		return err
+	}
+	return nil
+}
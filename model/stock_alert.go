@@ -0,0 +1,107 @@
+package model
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/canteen_management/enum"
+	"github.com/canteen_management/logger"
+	"github.com/canteen_management/utils"
+)
+
+const (
+	stockAlertTable = "stock_alert"
+
+	stockAlertLogTag = "StockAlertModel"
+)
+
+type StockAlert struct {
+	ID           uint32    `json:"id"`
+	GoodsID      uint32    `json:"goods_id"`
+	Level        uint8     `json:"level"`
+	Quantity     float64   `json:"quantity"`
+	ReorderPoint float64   `json:"reorder_point"`
+	MinQuantity  float64   `json:"min_quantity"`
+	Status       int8      `json:"status"`
+	CreateAt     time.Time `json:"created_at"`
+	UpdateAt     time.Time `json:"updated_at"`
+}
+
+type StockAlertModel struct {
+	sqlCli *sql.DB
+}
+
+func NewStockAlertModelWithDB(sqlCli *sql.DB) *StockAlertModel {
+	return &StockAlertModel{
+		sqlCli: sqlCli,
+	}
+}
+
+func (sam *StockAlertModel) GetAlertList(goodsID uint32, status int8) ([]*StockAlert, error) {
+	var params []interface{}
+	condition := " WHERE 1=1 "
+	if goodsID > 0 {
+		condition += " AND `goods_id` = ? "
+		params = append(params, goodsID)
+	}
+	if status != enum.StockAlertStatusAll {
+		condition += " AND `status` = ? "
+		params = append(params, status)
+	}
+	condition += " ORDER BY `level` DESC, `id` DESC "
+	retList, err := utils.SqlQuery(sam.sqlCli, stockAlertTable, &StockAlert{}, condition, params...)
+	if err != nil {
+		logger.Warn(stockAlertLogTag, "GetAlertList Failed|Err:%v", err)
+		return nil, err
+	}
+	return retList.([]*StockAlert), nil
+}
+
+func (sam *StockAlertModel) CheckAlertWithTx(tx *sql.Tx, goodsList []*Goods) (err error) {
+	if len(goodsList) == 0 {
+		return nil
+	}
+	var handle utils.SqlHandle = sam.sqlCli
+	if tx != nil {
+		handle = tx
+	}
+	idStr := ""
+	for _, goods := range goodsList {
+		idStr += fmt.Sprintf(",%v", goods.ID)
+	}
+	condition := fmt.Sprintf(" WHERE `goods_id` in (%v) AND `status` = ? ", idStr[1:])
+	retList, err := utils.SqlQuery(handle, stockAlertTable, &StockAlert{}, condition, enum.StockAlertOpen)
+	if err != nil {
+		logger.Warn(stockAlertLogTag, "CheckAlert GetOpenAlert Failed|Err:%v", err)
+		return err
+	}
+	openMap := make(map[uint32]*StockAlert)
+	for _, alert := range retList.([]*StockAlert) {
+		openMap[alert.GoodsID] = alert
+	}
+
+	for _, goods := range goodsList {
+		level := goods.GetStockLevel()
+		alert, ok := openMap[goods.ID]
+		switch {
+		case !ok && level == enum.StockLevelNormal:
+			continue
+		case !ok:
+			alert = &StockAlert{GoodsID: goods.ID, Level: level, Quantity: goods.Quantity,
+				ReorderPoint: goods.ReorderPoint, MinQuantity: goods.MinQuantity, Status: enum.StockAlertOpen}
+			_, err = utils.SqlInsert(handle, stockAlertTable, alert, "id", "created_at", "updated_at")
+		case level == enum.StockLevelNormal:
+			alert.Quantity, alert.Status = goods.Quantity, enum.StockAlertResolved
+			err = utils.SqlUpdateWithUpdateTags(handle, stockAlertTable, alert, "id", "quantity", "status")
+		default:
+			alert.Quantity, alert.Level = goods.Quantity, level
+			err = utils.SqlUpdateWithUpdateTags(handle, stockAlertTable, alert, "id", "quantity", "level")
+		}
+		if err != nil {
+			logger.Warn(stockAlertLogTag, "CheckAlert Failed|GoodsID:%v|Err:%v", goods.ID, err)
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,205 @@
+package model
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/canteen_management/logger"
+	"github.com/canteen_management/utils"
+)
+
+const (
+	stockLotTable = "stock_lot"
+
+	stockLotLogTag = "StockLotModel"
+
+	LotCodePrefix = "L"
+)
+
+var (
+	NoExpiryDate = time.Date(9999, 12, 31, 0, 0, 0, 0, time.Local)
+)
+
+type StockLot struct {
+	ID             uint32    `json:"id"`
+	GoodsID        uint32    `json:"goods_id"`
+	StoreTypeID    uint32    `json:"store_type_id"`
+	PurchaseID     uint32    `json:"purchase_id"`
+	BatchNo        string    `json:"batch_no"`
+	ProductionDate time.Time `json:"production_date"`
+	ExpiryDate     time.Time `json:"expiry_date"`
+	InitQuantity   float64   `json:"init_quantity"`
+	Quantity       float64   `json:"quantity"`
+	CreateAt       time.Time `json:"created_at"`
+	UpdateAt       time.Time `json:"updated_at"`
+}
+
+func (sl *StockLot) GetCode() string {
+	return fmt.Sprintf("%v%010d", LotCodePrefix, sl.ID)
+}
+
+type StockLotModel struct {
+	sqlCli *sql.DB
+}
+
+func NewStockLotModelWithDB(sqlCli *sql.DB) *StockLotModel {
+	return &StockLotModel{
+		sqlCli: sqlCli,
+	}
+}
+
+func (slm *StockLotModel) BatchInsertWithTx(tx *sql.Tx, lotList []*StockLot) error {
+	err := utils.SqlInsertBatch(tx, stockLotTable, lotList, "id", "created_at", "updated_at")
+	if err != nil {
+		logger.Warn(stockLotLogTag, "BatchInsert Failed|LotList:%+v|Err:%v", lotList, err)
+		return err
+	}
+	return nil
+}
+
+func (slm *StockLotModel) GetLotByID(id uint32) (*StockLot, error) {
+	retList, err := utils.SqlQuery(slm.sqlCli, stockLotTable, &StockLot{}, " WHERE `id` = ? ", id)
+	if err != nil {
+		logger.Warn(stockLotLogTag, "GetLotByID Failed|Err:%v", err)
+		return nil, err
+	}
+	lotList := retList.([]*StockLot)
+	if len(lotList) == 0 {
+		return nil, nil
+	}
+	return lotList[0], nil
+}
+
+func (slm *StockLotModel) GetAvailableLots() ([]*StockLot, error) {
+	condition := " WHERE `quantity` > 0 ORDER BY `goods_id` ASC, `expiry_date` ASC, `id` ASC "
+	retList, err := utils.SqlQuery(slm.sqlCli, stockLotTable, &StockLot{}, condition)
+	if err != nil {
+		logger.Warn(stockLotLogTag, "GetAvailableLots Failed|Err:%v", err)
+		return nil, err
+	}
+	return retList.([]*StockLot), nil
+}
+
+func (slm *StockLotModel) GetLotsByGoodsWithLock(tx *sql.Tx, goodsIDList []uint32, purchaseID uint32) ([]*StockLot, error) {
+	if len(goodsIDList) == 0 {
+		return make([]*StockLot, 0), nil
+	}
+	idStr := ""
+	for _, goodsID := range goodsIDList {
+		idStr += fmt.Sprintf(",%v", goodsID)
+	}
+	condition := fmt.Sprintf(" WHERE `goods_id` in (%v) AND `quantity` > 0 ", idStr[1:])
+	var params []interface{}
+	if purchaseID > 0 {
+		condition += " AND `purchase_id` = ? "
+		params = append(params, purchaseID)
+	}
+	condition += " ORDER BY `expiry_date` ASC, `id` ASC "
+	retList, err := utils.SqlQueryWithLock(tx, stockLotTable, &StockLot{}, condition, params...)
+	if err != nil {
+		logger.Warn(stockLotLogTag, "GetLotsByGoodsWithLock Failed|Err:%v", err)
+		return nil, err
+	}
+	return retList.([]*StockLot), nil
+}
+
+func (slm *StockLotModel) GetLotsByIDListWithLock(tx *sql.Tx, lotIDList []uint32) ([]*StockLot, error) {
+	if len(lotIDList) == 0 {
+		return make([]*StockLot, 0), nil
+	}
+	idStr := ""
+	for _, lotID := range lotIDList {
+		idStr += fmt.Sprintf(",%v", lotID)
+	}
+	condition := fmt.Sprintf(" WHERE `id` in (%v) ", idStr[1:])
+	retList, err := utils.SqlQueryWithLock(tx, stockLotTable, &StockLot{}, condition)
+	if err != nil {
+		logger.Warn(stockLotLogTag, "GetLotsByIDListWithLock Failed|Err:%v", err)
+		return nil, err
+	}
+	return retList.([]*StockLot), nil
+}
+
+func (slm *StockLotModel) GetExpiringLots(goodsID uint32, deadline time.Time) ([]*StockLot, error) {
+	condition := " WHERE `quantity` > 0 AND `expiry_date` <= ? "
+	params := []interface{}{deadline}
+	if goodsID > 0 {
+		condition += " AND `goods_id` = ? "
+		params = append(params, goodsID)
+	}
+	condition += " ORDER BY `expiry_date` ASC, `id` ASC "
+	retList, err := utils.SqlQuery(slm.sqlCli, stockLotTable, &StockLot{}, condition, params...)
+	if err != nil {
+		logger.Warn(stockLotLogTag, "GetExpiringLots Failed|Err:%v", err)
+		return nil, err
+	}
+	return retList.([]*StockLot), nil
+}
+
+func (slm *StockLotModel) BatchUpdateQuantityWithTx(tx *sql.Tx, lotList []*StockLot) error {
+	if len(lotList) == 0 {
+		return nil
+	}
+	daoList := make([]interface{}, 0, len(lotList))
+	for _, lot := range lotList {
+		daoList = append(daoList, lot)
+	}
+	err := utils.SqlBatchUpdateTag(tx, stockLotTable, daoList, "id", "quantity")
+	if err != nil {
+		logger.Warn(stockLotLogTag, "BatchUpdateQuantity Failed|Err:%v", err)
+		return err
+	}
+	return nil
+}
+
+// ConsumeLotsWithTx 按保质期先到先出扣减批次, 指定采购单时不限库位
+func (slm *StockLotModel) ConsumeLotsWithTx(tx *sql.Tx, consumeList []*GoodsStock, purchaseID uint32) ([]*StockLot, error) {
+	goodsIDList, remainMap := make([]uint32, 0, len(consumeList)), make(map[uint64]float64)
+	for _, item := range consumeList {
+		goodsIDList = append(goodsIDList, item.GoodsID)
+		if purchaseID > 0 {
+			remainMap[GetStockKey(item.GoodsID, 0)] += item.Quantity
+		} else {
+			remainMap[GetStockKey(item.GoodsID, item.StoreTypeID)] += item.Quantity
+		}
+	}
+	lotList, err := slm.GetLotsByGoodsWithLock(tx, goodsIDList, purchaseID)
+	if err != nil {
+		return nil, err
+	}
+
+	updateList, consumedList := consumeLots(lotList, remainMap, purchaseID)
+	err = slm.BatchUpdateQuantityWithTx(tx, updateList)
+	if err != nil {
+		return nil, err
+	}
+	return consumedList, nil
+}
+
+// consumeLots 按 lotList 的顺序扣减批次数量, remainMap 为各商品库位待扣减数量,
+// 返回数量有变化的批次与每个批次实际扣减的数量
+func consumeLots(lotList []*StockLot, remainMap map[uint64]float64, purchaseID uint32) ([]*StockLot, []*StockLot) {
+	updateList, consumedList := make([]*StockLot, 0, len(lotList)), make([]*StockLot, 0, len(lotList))
+	for _, lot := range lotList {
+		key := GetStockKey(lot.GoodsID, lot.StoreTypeID)
+		if purchaseID > 0 {
+			key = GetStockKey(lot.GoodsID, 0)
+		}
+		remain := remainMap[key]
+		if remain <= 0 {
+			continue
+		}
+		consume := remain
+		if consume > lot.Quantity {
+			consume = lot.Quantity
+		}
+		lot.Quantity -= consume
+		remainMap[key] = remain - consume
+		updateList = append(updateList, lot)
+		consumed := *lot
+		consumed.Quantity = consume
+		consumedList = append(consumedList, &consumed)
+	}
+	return updateList, consumedList
+}
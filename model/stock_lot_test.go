@@ -0,0 +1,127 @@
+package model
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestConsumeLots(t *testing.T) {
+	newLotList := func() []*StockLot {
+		return []*StockLot{
+			{ID: 1, GoodsID: 1, StoreTypeID: 1, Quantity: 5},
+			{ID: 2, GoodsID: 1, StoreTypeID: 1, Quantity: 10},
+			{ID: 3, GoodsID: 1, StoreTypeID: 2, Quantity: 4},
+			{ID: 4, GoodsID: 2, StoreTypeID: 1, Quantity: 3},
+		}
+	}
+	testList := []struct {
+		name         string
+		remainMap    map[uint64]float64
+		purchaseID   uint32
+		wantConsumed map[uint32]float64
+		wantRemain   map[uint32]float64
+	}{
+		{
+			name:         "first lot partially",
+			remainMap:    map[uint64]float64{GetStockKey(1, 1): 3},
+			wantConsumed: map[uint32]float64{1: 3},
+			wantRemain:   map[uint32]float64{1: 2, 2: 10, 3: 4, 4: 3},
+		},
+		{
+			name:         "spans lots in order",
+			remainMap:    map[uint64]float64{GetStockKey(1, 1): 8},
+			wantConsumed: map[uint32]float64{1: 5, 2: 3},
+			wantRemain:   map[uint32]float64{1: 0, 2: 7, 3: 4, 4: 3},
+		},
+		{
+			name:         "scoped by location",
+			remainMap:    map[uint64]float64{GetStockKey(1, 2): 2, GetStockKey(2, 1): 3},
+			wantConsumed: map[uint32]float64{3: 2, 4: 3},
+			wantRemain:   map[uint32]float64{1: 5, 2: 10, 3: 2, 4: 0},
+		},
+		{
+			name:         "more than lots hold",
+			remainMap:    map[uint64]float64{GetStockKey(1, 1): 20},
+			wantConsumed: map[uint32]float64{1: 5, 2: 10},
+			wantRemain:   map[uint32]float64{1: 0, 2: 0, 3: 4, 4: 3},
+		},
+		{
+			name:         "purchase ignores location",
+			remainMap:    map[uint64]float64{GetStockKey(1, 0): 17},
+			purchaseID:   7,
+			wantConsumed: map[uint32]float64{1: 5, 2: 10, 3: 2},
+			wantRemain:   map[uint32]float64{1: 0, 2: 0, 3: 2, 4: 3},
+		},
+	}
+	for _, tt := range testList {
+		t.Run(tt.name, func(t *testing.T) {
+			lotList := newLotList()
+			updateList, consumedList := consumeLots(lotList, tt.remainMap, tt.purchaseID)
+			if len(updateList) != len(consumedList) {
+				t.Fatalf("List Length Mismatch|Update:%v|Consumed:%v", len(updateList), len(consumedList))
+			}
+			gotConsumed, gotRemain := make(map[uint32]float64), make(map[uint32]float64)
+			for _, lot := range consumedList {
+				gotConsumed[lot.ID] = lot.Quantity
+			}
+			for _, lot := range lotList {
+				gotRemain[lot.ID] = lot.Quantity
+			}
+			if !reflect.DeepEqual(gotConsumed, tt.wantConsumed) {
+				t.Fatalf("Consumed Mismatch|Got:%v|Want:%v", gotConsumed, tt.wantConsumed)
+			}
+			if !reflect.DeepEqual(gotRemain, tt.wantRemain) {
+				t.Fatalf("Remain Mismatch|Got:%v|Want:%v", gotRemain, tt.wantRemain)
+			}
+		})
+	}
+}
+
+func TestRestoreLots(t *testing.T) {
+	outboundLots := []*OutboundLot{
+		{LotID: 1, GoodsID: 1, Quantity: 5},
+		{LotID: 2, GoodsID: 1, Quantity: 3},
+		{LotID: 9, GoodsID: 2, Quantity: 4},
+	}
+	testList := []struct {
+		name       string
+		returnMap  map[uint32]float64
+		wantLots   map[uint32]float64
+		wantRemain map[uint32]float64
+	}{
+		{
+			name:       "all lots restored",
+			returnMap:  map[uint32]float64{1: 8},
+			wantLots:   map[uint32]float64{1: 5, 2: 3},
+			wantRemain: map[uint32]float64{1: 0},
+		},
+		{
+			name:       "missing lot falls back",
+			returnMap:  map[uint32]float64{1: 2, 2: 4},
+			wantLots:   map[uint32]float64{1: 2, 2: 0},
+			wantRemain: map[uint32]float64{1: 0, 2: 4},
+		},
+		{
+			name:       "more than issued",
+			returnMap:  map[uint32]float64{1: 10},
+			wantLots:   map[uint32]float64{1: 5, 2: 3},
+			wantRemain: map[uint32]float64{1: 2},
+		},
+	}
+	for _, tt := range testList {
+		t.Run(tt.name, func(t *testing.T) {
+			lotMap := map[uint32]*StockLot{1: {ID: 1, GoodsID: 1}, 2: {ID: 2, GoodsID: 1}}
+			_, remainMap := RestoreLots(outboundLots, lotMap, tt.returnMap)
+			gotLots := make(map[uint32]float64)
+			for _, lot := range lotMap {
+				gotLots[lot.ID] = lot.Quantity
+			}
+			if !reflect.DeepEqual(gotLots, tt.wantLots) {
+				t.Fatalf("Lots Mismatch|Got:%v|Want:%v", gotLots, tt.wantLots)
+			}
+			if !reflect.DeepEqual(remainMap, tt.wantRemain) {
+				t.Fatalf("Remain Mismatch|Got:%v|Want:%v", remainMap, tt.wantRemain)
+			}
+		})
+	}
+}
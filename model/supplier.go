@@ -31,6 +31,20 @@ type Supplier struct {
 	UpdateAt         time.Time `json:"updated_at"`
 }
 
+type SupplierScore struct {
+	SupplierID       uint32
+	OrderCount       uint32
+	FinishCount      uint32
+	AcceptHours      float64
+	LeadHours        float64
+	OnTimeRate       float64
+	QuantityAccuracy float64
+	ReturnRate       float64
+	PriceChangeRate  float64
+	Score            float64
+	Rank             uint32
+}
+
 type SupplierModel struct {
 	sqlCli *sql.DB
 }
@@ -92,6 +106,19 @@ func (sm *SupplierModel) GetSupplier(id uint32, name, phoneNumber, openID string
 	return retList.([]*Supplier), nil
 }
 
+func (sm *SupplierModel) GetSupplierByUid(uid uint32) (*Supplier, error) {
+	retList, err := utils.SqlQuery(sm.sqlCli, supplierTable, &Supplier{}, " WHERE `uid` = ? LIMIT 1 ", uid)
+	if err != nil {
+		logger.Warn(supplierLogTag, "GetSupplierByUid Failed|Uid:%v|Err:%v", uid, err)
+		return nil, err
+	}
+	supplierList := retList.([]*Supplier)
+	if len(supplierList) == 0 {
+		return nil, nil
+	}
+	return supplierList[0], nil
+}
+
 func (sm *SupplierModel) UpdateSupplier(dao *Supplier) error {
 	err := utils.SqlUpdateWithUpdateTags(sm.sqlCli, supplierTable, dao, "id", supplierUpdateTags...)
 	if err != nil {
@@ -142,3 +169,14 @@ func (sm *SupplierModel) GetLastValidityTime() (int64, error) {
 	}
 	return lastTime.Unix(), nil
 }
+
+func (sm *SupplierModel) GetValidSuppliers() ([]*Supplier, error) {
+	condition := " WHERE `validity_deadline` > CURRENT_TIMESTAMP() ORDER BY `validity_deadline` ASC "
+	retList, err := utils.SqlQuery(sm.sqlCli, supplierTable, &Supplier{}, condition)
+	if err != nil {
+		logger.Warn(supplierLogTag, "GetValidSuppliers Failed|Err:%v", err)
+		return nil, err
+	}
+
+	return retList.([]*Supplier), nil
+}
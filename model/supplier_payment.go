@@ -0,0 +1,57 @@
+package model
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/canteen_management/logger"
+	"github.com/canteen_management/utils"
+)
+
+const (
+	supplierPaymentTable = "supplier_payment"
+
+	supplierPaymentLogTag = "SupplierPaymentModel"
+)
+
+type SupplierPayment struct {
+	ID          uint32    `json:"id"`
+	StatementID uint32    `json:"statement_id"`
+	SupplierID  uint32    `json:"supplier_id"`
+	Amount      float64   `json:"amount"`
+	Voucher     string    `json:"voucher"`
+	Remark      string    `json:"remark"`
+	Operator    uint32    `json:"operator"`
+	PayAt       time.Time `json:"pay_at"`
+	CreateAt    time.Time `json:"created_at"`
+}
+
+type SupplierPaymentModel struct {
+	sqlCli *sql.DB
+}
+
+func NewSupplierPaymentModelWithDB(sqlCli *sql.DB) *SupplierPaymentModel {
+	return &SupplierPaymentModel{
+		sqlCli: sqlCli,
+	}
+}
+
+func (spm *SupplierPaymentModel) InsertWithTx(tx *sql.Tx, dao *SupplierPayment) error {
+	id, err := utils.SqlInsert(tx, supplierPaymentTable, dao, "id", "created_at")
+	if err != nil {
+		logger.Warn(supplierPaymentLogTag, "Insert Failed|Dao:%+v|Err:%v", dao, err)
+		return err
+	}
+	dao.ID = uint32(id)
+	return nil
+}
+
+func (spm *SupplierPaymentModel) GetPaymentList(statementID uint32) ([]*SupplierPayment, error) {
+	condition := " WHERE `statement_id` = ? ORDER BY `id` ASC "
+	retList, err := utils.SqlQuery(spm.sqlCli, supplierPaymentTable, &SupplierPayment{}, condition, statementID)
+	if err != nil {
+		logger.Warn(supplierPaymentLogTag, "GetPaymentList Failed|StatementID:%v|Err:%v", statementID, err)
+		return nil, err
+	}
+	return retList.([]*SupplierPayment), nil
+}
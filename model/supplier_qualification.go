@@ -0,0 +1,102 @@
+package model
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/canteen_management/logger"
+	"github.com/canteen_management/utils"
+)
+
+const (
+	supplierQualificationTable = "supplier_qualification"
+
+	supplierQualificationLogTag = "SupplierQualificationModel"
+)
+
+var (
+	supplierQualificationUpdateTags = []string{"doc_type", "doc_number", "file_url", "issue_at", "expire_at", "remark",
+		"operator"}
+)
+
+type SupplierQualification struct {
+	ID         uint32    `json:"id"`
+	SupplierID uint32    `json:"supplier_id"`
+	DocType    uint8     `json:"doc_type"`
+	DocNumber  string    `json:"doc_number"`
+	FileUrl    string    `json:"file_url"`
+	IssueAt    time.Time `json:"issue_at"`
+	ExpireAt   time.Time `json:"expire_at"`
+	Remark     string    `json:"remark"`
+	Operator   uint32    `json:"operator"`
+	CreateAt   time.Time `json:"created_at"`
+	UpdateAt   time.Time `json:"updated_at"`
+}
+
+type SupplierQualificationModel struct {
+	sqlCli *sql.DB
+}
+
+func NewSupplierQualificationModelWithDB(sqlCli *sql.DB) *SupplierQualificationModel {
+	return &SupplierQualificationModel{
+		sqlCli: sqlCli,
+	}
+}
+
+func (sqm *SupplierQualificationModel) Insert(dao *SupplierQualification) error {
+	id, err := utils.SqlInsert(sqm.sqlCli, supplierQualificationTable, dao, "id", "created_at", "updated_at")
+	if err != nil {
+		logger.Warn(supplierQualificationLogTag, "Insert Failed|Dao:%+v|Err:%v", dao, err)
+		return err
+	}
+	dao.ID = uint32(id)
+	return nil
+}
+
+func (sqm *SupplierQualificationModel) GetQualification(id uint32) (*SupplierQualification, error) {
+	retInfo := &SupplierQualification{}
+	err := utils.SqlQueryRow(sqm.sqlCli, supplierQualificationTable, retInfo, " WHERE `id` = ? ", id)
+	if err != nil {
+		logger.Warn(supplierQualificationLogTag, "GetQualification Failed|ID:%v|Err:%v", id, err)
+		return nil, err
+	}
+	return retInfo, nil
+}
+
+func (sqm *SupplierQualificationModel) GetQualificationList(supplierID uint32) ([]*SupplierQualification, error) {
+	var params []interface{}
+	condition := " WHERE 1=1 "
+	if supplierID > 0 {
+		condition += " AND `supplier_id` = ? "
+		params = append(params, supplierID)
+	}
+	condition += " ORDER BY `supplier_id` ASC, `doc_type` ASC, `expire_at` DESC "
+	retList, err := utils.SqlQuery(sqm.sqlCli, supplierQualificationTable, &SupplierQualification{}, condition,
+		params...)
+	if err != nil {
+		logger.Warn(supplierQualificationLogTag, "GetQualificationList Failed|Err:%v", err)
+		return nil, err
+	}
+	return retList.([]*SupplierQualification), nil
+}
+
+func (sqm *SupplierQualificationModel) UpdateQualification(dao *SupplierQualification) error {
+	err := utils.SqlUpdateWithUpdateTags(sqm.sqlCli, supplierQualificationTable, dao, "id",
+		supplierQualificationUpdateTags...)
+	if err != nil {
+		logger.Warn(supplierQualificationLogTag, "UpdateQualification Failed|Err:%v", err)
+		return err
+	}
+	return nil
+}
+
+func (sqm *SupplierQualificationModel) DeleteQualification(id uint32) error {
+	sqlStr := fmt.Sprintf(" DELETE FROM %v WHERE `id` = ? ", supplierQualificationTable)
+	_, err := sqm.sqlCli.Exec(sqlStr, id)
+	if err != nil {
+		logger.Warn(supplierQualificationLogTag, "DeleteQualification Failed|Err:%v", err)
+		return err
+	}
+	return nil
+}
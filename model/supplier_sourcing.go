@@ -0,0 +1,92 @@
+package model
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/canteen_management/logger"
+	"github.com/canteen_management/utils"
+)
+
+const (
+	supplierSourcingTable  = "supplier_sourcing"
+	supplierSourcingLogTag = "SupplierSourcingModel"
+)
+
+var (
+	supplierSourcingUpdateTags = []string{"supplier_id", "goods_type", "goods_id"}
+)
+
+type SupplierSourcing struct {
+	ID         uint32    `json:"id"`
+	SupplierID uint32    `json:"supplier_id"`
+	GoodsType  uint32    `json:"goods_type"`
+	GoodsID    uint32    `json:"goods_id"` // 不为0时按商品匹配，否则按商品类型匹配
+	CreateAt   time.Time `json:"created_at"`
+	UpdateAt   time.Time `json:"updated_at"`
+}
+
+type SupplierSourcingModel struct {
+	sqlCli *sql.DB
+}
+
+func NewSupplierSourcingModelWithDB(sqlCli *sql.DB) *SupplierSourcingModel {
+	return &SupplierSourcingModel{
+		sqlCli: sqlCli,
+	}
+}
+
+func (ssm *SupplierSourcingModel) Insert(dao *SupplierSourcing) error {
+	id, err := utils.SqlInsert(ssm.sqlCli, supplierSourcingTable, dao, "id", "created_at", "updated_at")
+	if err != nil {
+		logger.Warn(supplierSourcingLogTag, "Insert Failed|Dao:%+v|Err:%v", dao, err)
+		return err
+	}
+	dao.ID = uint32(id)
+	return nil
+}
+
+func (ssm *SupplierSourcingModel) GetSourcingList(supplierID uint32) ([]*SupplierSourcing, error) {
+	var params []interface{}
+	condition := " WHERE 1=1 "
+	if supplierID > 0 {
+		condition += " AND `supplier_id` = ? "
+		params = append(params, supplierID)
+	}
+	retList, err := utils.SqlQuery(ssm.sqlCli, supplierSourcingTable, &SupplierSourcing{}, condition, params...)
+	if err != nil {
+		logger.Warn(supplierSourcingLogTag, "GetSourcingList Failed|Err:%v", err)
+		return nil, err
+	}
+	return retList.([]*SupplierSourcing), nil
+}
+
+func (ssm *SupplierSourcingModel) GetSourcingByTarget(goodsType, goodsID uint32) ([]*SupplierSourcing, error) {
+	condition := " WHERE `goods_type` = ? AND `goods_id` = ? "
+	retList, err := utils.SqlQuery(ssm.sqlCli, supplierSourcingTable, &SupplierSourcing{}, condition, goodsType, goodsID)
+	if err != nil {
+		logger.Warn(supplierSourcingLogTag, "GetSourcingByTarget Failed|Err:%v", err)
+		return nil, err
+	}
+	return retList.([]*SupplierSourcing), nil
+}
+
+func (ssm *SupplierSourcingModel) UpdateSourcing(dao *SupplierSourcing) error {
+	err := utils.SqlUpdateWithUpdateTags(ssm.sqlCli, supplierSourcingTable, dao, "id", supplierSourcingUpdateTags...)
+	if err != nil {
+		logger.Warn(supplierSourcingLogTag, "UpdateSourcing Failed|Err:%v", err)
+		return err
+	}
+	return nil
+}
+
+func (ssm *SupplierSourcingModel) DeleteSourcing(id uint32) error {
+	sqlStr := fmt.Sprintf(" DELETE FROM %v WHERE `id` = ? ", supplierSourcingTable)
+	_, err := ssm.sqlCli.Exec(sqlStr, id)
+	if err != nil {
+		logger.Warn(supplierSourcingLogTag, "DeleteSourcing Failed|Err:%v", err)
+		return err
+	}
+	return nil
+}
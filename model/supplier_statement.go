@@ -0,0 +1,163 @@
+package model
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/canteen_management/enum"
+	"github.com/canteen_management/logger"
+	"github.com/canteen_management/utils"
+)
+
+const (
+	supplierStatementTable = "supplier_statement"
+
+	supplierStatementLogTag = "SupplierStatementModel"
+)
+
+type SupplierStatement struct {
+	ID            uint32    `json:"id"`
+	SupplierID    uint32    `json:"supplier_id"`
+	PeriodStart   time.Time `json:"period_start"`
+	PeriodEnd     time.Time `json:"period_end"`
+	OrderCount    uint32    `json:"order_count"`
+	TotalAmount   float64   `json:"total_amount"`
+	PaidAmount    float64   `json:"paid_amount"`
+	Status        int8      `json:"status"`
+	DisputeReason string    `json:"dispute_reason"`
+	Creator       uint32    `json:"creator"`
+	CreateAt      time.Time `json:"created_at"`
+	UpdateAt      time.Time `json:"updated_at"`
+}
+
+func (ss *SupplierStatement) GetOutstanding() float64 {
+	return ss.TotalAmount - ss.PaidAmount
+}
+
+type PayableBalance struct {
+	SupplierID     uint32
+	UnbilledAmount float64
+	BilledAmount   float64
+	PaidAmount     float64
+	StatementCount uint32
+	DisputedCount  uint32
+}
+
+func (pb *PayableBalance) GetOutstanding() float64 {
+	return pb.UnbilledAmount + pb.BilledAmount - pb.PaidAmount
+}
+
+type SupplierStatementModel struct {
+	sqlCli *sql.DB
+}
+
+func NewSupplierStatementModelWithDB(sqlCli *sql.DB) *SupplierStatementModel {
+	return &SupplierStatementModel{
+		sqlCli: sqlCli,
+	}
+}
+
+func (ssm *SupplierStatementModel) InsertWithTx(tx *sql.Tx, dao *SupplierStatement) error {
+	id, err := utils.SqlInsert(tx, supplierStatementTable, dao, "id", "created_at", "updated_at")
+	if err != nil {
+		logger.Warn(supplierStatementLogTag, "Insert Failed|Dao:%+v|Err:%v", dao, err)
+		return err
+	}
+	dao.ID = uint32(id)
+	return nil
+}
+
+func (ssm *SupplierStatementModel) GetStatement(id uint32) (*SupplierStatement, error) {
+	retInfo := &SupplierStatement{}
+	err := utils.SqlQueryRow(ssm.sqlCli, supplierStatementTable, retInfo, " WHERE `id` = ? ", id)
+	if err != nil {
+		logger.Warn(supplierStatementLogTag, "GetStatement Failed|ID:%v|Err:%v", id, err)
+		return nil, err
+	}
+	return retInfo, nil
+}
+
+func (ssm *SupplierStatementModel) GetStatementWithLock(tx *sql.Tx, id uint32) (*SupplierStatement, error) {
+	retInfo := &SupplierStatement{}
+	err := utils.SqlQueryRowWithLock(tx, supplierStatementTable, retInfo, " WHERE `id` = ? ", id)
+	if err != nil {
+		logger.Warn(supplierStatementLogTag, "GetStatementWithLock Failed|ID:%v|Err:%v", id, err)
+		return nil, err
+	}
+	return retInfo, nil
+}
+
+func (ssm *SupplierStatementModel) GenerateCondition(supplierID uint32, status int8) (string, []interface{}) {
+	var params []interface{}
+	condition := " WHERE 1=1 "
+	if supplierID > 0 {
+		condition += " AND `supplier_id` = ? "
+		params = append(params, supplierID)
+	}
+	if status != enum.StatementStatusAll {
+		condition += " AND `status` = ? "
+		params = append(params, status)
+	}
+	return condition, params
+}
+
+func (ssm *SupplierStatementModel) GetStatementList(supplierID uint32, status int8,
+	page, pageSize int32) ([]*SupplierStatement, error) {
+	condition, params := ssm.GenerateCondition(supplierID, status)
+	condition += " ORDER BY `id` DESC LIMIT ?,? "
+	params = append(params, (page-1)*pageSize, pageSize)
+	retList, err := utils.SqlQuery(ssm.sqlCli, supplierStatementTable, &SupplierStatement{}, condition, params...)
+	if err != nil {
+		logger.Warn(supplierStatementLogTag, "GetStatementList Failed|Err:%v", err)
+		return nil, err
+	}
+	return retList.([]*SupplierStatement), nil
+}
+
+func (ssm *SupplierStatementModel) GetStatementCount(supplierID uint32, status int8) (int32, error) {
+	condition, params := ssm.GenerateCondition(supplierID, status)
+	sqlStr := fmt.Sprintf("SELECT COUNT(*) FROM `%v` %v", supplierStatementTable, condition)
+	row := ssm.sqlCli.QueryRow(sqlStr, params...)
+	var count int32
+	err := row.Scan(&count)
+	if err != nil {
+		logger.Warn(supplierStatementLogTag, "GetStatementCount Failed|Err:%v", err)
+		return 0, err
+	}
+	return count, nil
+}
+
+func (ssm *SupplierStatementModel) GetUnsettledStatements() ([]*SupplierStatement, error) {
+	condition := " WHERE `status` != ? "
+	retList, err := utils.SqlQuery(ssm.sqlCli, supplierStatementTable, &SupplierStatement{}, condition,
+		enum.StatementSettled)
+	if err != nil {
+		logger.Warn(supplierStatementLogTag, "GetUnsettledStatements Failed|Err:%v", err)
+		return nil, err
+	}
+	return retList.([]*SupplierStatement), nil
+}
+
+func (ssm *SupplierStatementModel) UpdateStatementWithTx(tx *sql.Tx, dao *SupplierStatement, updateTags ...string) (err error) {
+	if tx != nil {
+		err = utils.SqlUpdateWithUpdateTags(tx, supplierStatementTable, dao, "id", updateTags...)
+	} else {
+		err = utils.SqlUpdateWithUpdateTags(ssm.sqlCli, supplierStatementTable, dao, "id", updateTags...)
+	}
+	if err != nil {
+		logger.Warn(supplierStatementLogTag, "UpdateStatement Failed|ID:%v|Err:%v", dao.ID, err)
+		return err
+	}
+	return nil
+}
+
+func (ssm *SupplierStatementModel) DeleteStatementWithTx(tx *sql.Tx, id uint32) error {
+	sqlStr := fmt.Sprintf("DELETE FROM `%v` WHERE `id` = ? ", supplierStatementTable)
+	_, err := tx.Exec(sqlStr, id)
+	if err != nil {
+		logger.Warn(supplierStatementLogTag, "DeleteStatement Failed|ID:%v|Err:%v", id, err)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,119 @@
+package model
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/canteen_management/logger"
+	"github.com/canteen_management/utils"
+)
+
+const (
+	transferOrderTable = "transfer_order"
+
+	transferOrderLogTag = "TransferOrderModel"
+)
+
+type TransferItem struct {
+	GoodsID        uint32  `json:"goods_id"`
+	TransferNumber float64 `json:"transfer_number"`
+}
+
+type TransferOrder struct {
+	ID              uint32    `json:"id"`
+	FromStore       uint32    `json:"from_store"`
+	ToStore         uint32    `json:"to_store"`
+	Creator         uint32    `json:"creator"`
+	Remark          string    `json:"remark"`
+	TransferContent string    `json:"transfer_content"`
+	CreateAt        time.Time `json:"created_at"`
+}
+
+func (to *TransferOrder) FromItems(items []*TransferItem) error {
+	contentStr, err := json.Marshal(items)
+	if err != nil {
+		logger.Warn(transferOrderLogTag, "FromItems Failed|Err:%v", err)
+		return err
+	}
+	to.TransferContent = string(contentStr)
+	return nil
+}
+
+func (to *TransferOrder) ToItems() []*TransferItem {
+	items := make([]*TransferItem, 0)
+	if to.TransferContent == "" {
+		return items
+	}
+	err := json.Unmarshal([]byte(to.TransferContent), &items)
+	if err != nil {
+		logger.Warn(transferOrderLogTag, "ToItems Failed|Err:%v", err)
+		return make([]*TransferItem, 0)
+	}
+	return items
+}
+
+type TransferOrderModel struct {
+	sqlCli *sql.DB
+}
+
+func NewTransferOrderModelWithDB(sqlCli *sql.DB) *TransferOrderModel {
+	return &TransferOrderModel{
+		sqlCli: sqlCli,
+	}
+}
+
+func (tom *TransferOrderModel) InsertWithTx(tx *sql.Tx, dao *TransferOrder) error {
+	id, err := utils.SqlInsert(tx, transferOrderTable, dao, "id", "created_at")
+	if err != nil {
+		logger.Warn(transferOrderLogTag, "Insert Failed|Dao:%+v|Err:%v", dao, err)
+		return err
+	}
+	dao.ID = uint32(id)
+	return nil
+}
+
+func (tom *TransferOrderModel) GenerateCondition(storeTypeID uint32, startTime, endTime int64) (string, []interface{}) {
+	var params []interface{}
+	condition := " WHERE 1=1 "
+	if storeTypeID > 0 {
+		condition += " AND (`from_store` = ? OR `to_store` = ?) "
+		params = append(params, storeTypeID, storeTypeID)
+	}
+	if startTime > 0 {
+		condition += " AND `created_at` >= ? "
+		params = append(params, time.Unix(startTime, 0))
+	}
+	if endTime > startTime {
+		condition += " AND `created_at` <= ? "
+		params = append(params, time.Unix(endTime, 0))
+	}
+	return condition, params
+}
+
+func (tom *TransferOrderModel) GetTransferList(storeTypeID uint32, startTime, endTime int64,
+	page, pageSize int32) ([]*TransferOrder, error) {
+	condition, params := tom.GenerateCondition(storeTypeID, startTime, endTime)
+	condition += " ORDER BY `id` DESC LIMIT ?,? "
+	params = append(params, (page-1)*pageSize, pageSize)
+	retList, err := utils.SqlQuery(tom.sqlCli, transferOrderTable, &TransferOrder{}, condition, params...)
+	if err != nil {
+		logger.Warn(transferOrderLogTag, "GetTransferList Failed|Err:%v", err)
+		return nil, err
+	}
+	return retList.([]*TransferOrder), nil
+}
+
+func (tom *TransferOrderModel) GetTransferCount(storeTypeID uint32, startTime, endTime int64) (int32, error) {
+	condition, params := tom.GenerateCondition(storeTypeID, startTime, endTime)
+	sqlStr := fmt.Sprintf("SELECT COUNT(*) FROM `%v` %v", transferOrderTable, condition)
+	row := tom.sqlCli.QueryRow(sqlStr, params...)
+	var count int32
+	err := row.Scan(&count)
+	if err != nil {
+		logger.Warn(transferOrderLogTag, "GetTransferCount Failed|Err:%v", err)
+		return 0, err
+	}
+	return count, nil
+}
@@ -0,0 +1,90 @@
+package model
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/canteen_management/logger"
+	"github.com/canteen_management/utils"
+)
+
+const (
+	wasteLogTable = "waste_log"
+
+	wasteLogLogTag = "WasteLogModel"
+)
+
+// WasteLog 按餐次记录的浪费，菜品浪费以份数计，备餐损耗以商品数量计并扣减库存
+type WasteLog struct {
+	ID          uint32    `json:"id"`
+	MealDate    time.Time `json:"meal_date"`
+	MealType    uint8     `json:"meal_type"`
+	WasteType   uint8     `json:"waste_type"`
+	DishID      uint32    `json:"dish_id"`
+	GoodsID     uint32    `json:"goods_id"`
+	StoreTypeID uint32    `json:"store_type_id"`
+	Quantity    float64   `json:"quantity"`
+	Weight      float64   `json:"weight"`
+	Value       float64   `json:"value"`
+	Remark      string    `json:"remark"`
+	Creator     uint32    `json:"creator"`
+	CreateAt    time.Time `json:"created_at"`
+}
+
+type WasteLogModel struct {
+	sqlCli *sql.DB
+}
+
+func NewWasteLogModelWithDB(sqlCli *sql.DB) *WasteLogModel {
+	return &WasteLogModel{
+		sqlCli: sqlCli,
+	}
+}
+
+func (wlm *WasteLogModel) InsertWithTx(tx *sql.Tx, dao *WasteLog) error {
+	id, err := int64(0), error(nil)
+	if tx != nil {
+		id, err = utils.SqlInsert(tx, wasteLogTable, dao, "id", "created_at")
+	} else {
+		id, err = utils.SqlInsert(wlm.sqlCli, wasteLogTable, dao, "id", "created_at")
+	}
+	if err != nil {
+		logger.Warn(wasteLogLogTag, "Insert Failed|Dao:%+v|Err:%v", dao, err)
+		return err
+	}
+	dao.ID = uint32(id)
+	return nil
+}
+
+func (wlm *WasteLogModel) GetWasteList(mealType, wasteType uint8, dishID uint32, startTime,
+	endTime int64) ([]*WasteLog, error) {
+	var params []interface{}
+	condition := " WHERE 1=1 "
+	if mealType > 0 {
+		condition += " AND `meal_type` = ? "
+		params = append(params, mealType)
+	}
+	if wasteType > 0 {
+		condition += " AND `waste_type` = ? "
+		params = append(params, wasteType)
+	}
+	if dishID > 0 {
+		condition += " AND `dish_id` = ? "
+		params = append(params, dishID)
+	}
+	if startTime > 0 {
+		condition += " AND `meal_date` >= ? "
+		params = append(params, time.Unix(startTime, 0))
+	}
+	if endTime > startTime {
+		condition += " AND `meal_date` <= ? "
+		params = append(params, time.Unix(endTime, 0))
+	}
+	condition += " ORDER BY `meal_date` ASC, `meal_type` ASC, `id` ASC "
+	retList, err := utils.SqlQuery(wlm.sqlCli, wasteLogTable, &WasteLog{}, condition, params...)
+	if err != nil {
+		logger.Warn(wasteLogLogTag, "GetWasteList Failed|MealType:%v|WasteType:%v|Err:%v", mealType, wasteType, err)
+		return nil, err
+	}
+	return retList.([]*WasteLog), nil
+}
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/canteen_management/enum"
 	"github.com/canteen_management/logger"
 	"github.com/canteen_management/utils"
 )
@@ -17,7 +18,8 @@ const (
 )
 
 var (
-	weekMenuUpdateTags = []string{"menu_content"}
+	weekMenuUpdateTags       = []string{"menu_content", "status", "version"}
+	weekMenuStatusUpdateTags = []string{"status", "reviewer", "publish_at", "published_content"}
 
 	ErrWeekMenuNotFound = fmt.Errorf("week menu not found")
 )
@@ -27,8 +29,14 @@ type WeekMenu struct {
 	MenuTypeID    uint32    `json:"menu_type_id"`
 	MenuContent   string    `json:"menu_content"`
 	MenuStartDate time.Time `json:"menu_start_date"`
-	CreateAt      time.Time `json:"created_at"`
-	UpdateAt      time.Time `json:"updated_at"`
+	Status        int8      `json:"status"`
+	Version       uint32    `json:"version"`
+	Reviewer      uint32    `json:"reviewer"`
+	PublishAt     time.Time `json:"publish_at"`
+	// PublishedContent 最近一次审核通过的菜单内容，点餐只使用该版本，MenuContent 为待审核的编辑稿
+	PublishedContent string    `json:"published_content"`
+	CreateAt         time.Time `json:"created_at"`
+	UpdateAt         time.Time `json:"updated_at"`
 }
 
 func (wm *WeekMenu) FromWeekMenuConfig(menuConf []map[uint8][]uint32) error {
@@ -42,8 +50,20 @@ func (wm *WeekMenu) FromWeekMenuConfig(menuConf []map[uint8][]uint32) error {
 }
 
 func (wm *WeekMenu) ToWeekMenuConfig() []map[uint8][]uint32 {
+	return unmarshalWeekMenuConfig(wm.MenuContent)
+}
+
+func (wm *WeekMenu) ToPublishedWeekMenuConfig() []map[uint8][]uint32 {
+	return unmarshalWeekMenuConfig(wm.PublishedContent)
+}
+
+func (wm *WeekMenu) IsOnline() bool {
+	return wm.PublishedContent != "" && wm.Status != enum.WeekMenuArchived
+}
+
+func unmarshalWeekMenuConfig(content string) []map[uint8][]uint32 {
 	configMap := make([]map[uint8][]uint32, 0)
-	err := json.Unmarshal([]byte(wm.MenuContent), &configMap)
+	err := json.Unmarshal([]byte(content), &configMap)
 	if err != nil {
 		logger.Warn(weekMenuLogTag, "ToWeekMenuConfig Failed|Err:%v", err)
 		return nil
@@ -113,8 +133,29 @@ func (wmm *WeekMenuModel) GetWeekMenus(menuID, menuType uint32, startTime, endTi
 	return retList.([]*WeekMenu), nil
 }
 
+func (wmm *WeekMenuModel) InsertWithTx(tx *sql.Tx, dao *WeekMenu) error {
+	if tx == nil {
+		return wmm.Insert(dao)
+	}
+	id, err := utils.SqlInsert(tx, weekMenuTable, dao, "id", "created_at", "updated_at")
+	if err != nil {
+		logger.Warn(weekMenuLogTag, "InsertWithTx Failed|Dao:%+v|Err:%v", dao, err)
+		return err
+	}
+	dao.ID = uint32(id)
+	return nil
+}
+
 func (wmm *WeekMenuModel) UpdateWeekMenu(dao *WeekMenu) error {
-	err := utils.SqlUpdateWithUpdateTags(wmm.sqlCli, weekMenuTable, dao, "id", weekMenuUpdateTags...)
+	return wmm.UpdateWeekMenuWithTx(nil, dao)
+}
+
+func (wmm *WeekMenuModel) UpdateWeekMenuWithTx(tx *sql.Tx, dao *WeekMenu) (err error) {
+	if tx != nil {
+		err = utils.SqlUpdateWithUpdateTags(tx, weekMenuTable, dao, "id", weekMenuUpdateTags...)
+	} else {
+		err = utils.SqlUpdateWithUpdateTags(wmm.sqlCli, weekMenuTable, dao, "id", weekMenuUpdateTags...)
+	}
 	if err != nil {
 		logger.Warn(weekMenuLogTag, "UpdateWeekMenu Failed|Err:%v", err)
 		return err
@@ -122,6 +163,39 @@ func (wmm *WeekMenuModel) UpdateWeekMenu(dao *WeekMenu) error {
 	return nil
 }
 
+func (wmm *WeekMenuModel) GetWeekMenuWithLock(tx *sql.Tx, id uint32) (*WeekMenu, error) {
+	if tx == nil {
+		return nil, fmt.Errorf("tx is nil")
+	}
+	retList, err := utils.SqlQueryWithLock(tx, weekMenuTable, &WeekMenu{}, " WHERE `id` = ? ", id)
+	if err != nil {
+		logger.Warn(weekMenuLogTag, "GetWeekMenuWithLock Failed|ID:%v|Err:%v", id, err)
+		return nil, err
+	}
+	menuList := retList.([]*WeekMenu)
+	if len(menuList) == 0 {
+		return nil, ErrWeekMenuNotFound
+	}
+	return menuList[0], nil
+}
+
+func (wmm *WeekMenuModel) UpdateWeekMenuStatus(dao *WeekMenu) error {
+	return wmm.UpdateWeekMenuStatusWithTx(nil, dao)
+}
+
+func (wmm *WeekMenuModel) UpdateWeekMenuStatusWithTx(tx *sql.Tx, dao *WeekMenu) (err error) {
+	if tx != nil {
+		err = utils.SqlUpdateWithUpdateTags(tx, weekMenuTable, dao, "id", weekMenuStatusUpdateTags...)
+	} else {
+		err = utils.SqlUpdateWithUpdateTags(wmm.sqlCli, weekMenuTable, dao, "id", weekMenuStatusUpdateTags...)
+	}
+	if err != nil {
+		logger.Warn(weekMenuLogTag, "UpdateWeekMenuStatus Failed|ID:%v|Status:%v|Err:%v", dao.ID, dao.Status, err)
+		return err
+	}
+	return nil
+}
+
 func (wmm *WeekMenuModel) GetWeekMenuByDate(menuDate int64, menuType uint32) (*WeekMenu, error) {
 	condition := " WHERE `menu_start_date` = ? AND `menu_type_id` = ? "
 	retList, err := utils.SqlQuery(wmm.sqlCli, weekMenuTable, &WeekMenu{}, condition, time.Unix(menuDate, 0), menuType)
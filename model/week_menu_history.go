@@ -0,0 +1,82 @@
+package model
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/canteen_management/logger"
+	"github.com/canteen_management/utils"
+)
+
+const (
+	weekMenuHistoryTable = "week_menu_history"
+
+	weekMenuHistoryLogTag = "WeekMenuHistory"
+)
+
+type WeekMenuHistory struct {
+	ID          uint32    `json:"id"`
+	WeekMenuID  uint32    `json:"week_menu_id"`
+	Version     uint32    `json:"version"`
+	MenuContent string    `json:"menu_content"`
+	Operator    uint32    `json:"operator"`
+	CreateAt    time.Time `json:"created_at"`
+}
+
+func GenerateWeekMenuHistory(weekMenu *WeekMenu, operator uint32) *WeekMenuHistory {
+	return &WeekMenuHistory{
+		WeekMenuID:  weekMenu.ID,
+		Version:     weekMenu.Version,
+		MenuContent: weekMenu.MenuContent,
+		Operator:    operator,
+	}
+}
+
+type WeekMenuHistoryModel struct {
+	sqlCli *sql.DB
+}
+
+func NewWeekMenuHistoryModelWithDB(sqlCli *sql.DB) *WeekMenuHistoryModel {
+	return &WeekMenuHistoryModel{
+		sqlCli: sqlCli,
+	}
+}
+
+func (wmhm *WeekMenuHistoryModel) InsertWithTx(tx *sql.Tx, dao *WeekMenuHistory) (err error) {
+	id := int64(0)
+	if tx != nil {
+		id, err = utils.SqlInsert(tx, weekMenuHistoryTable, dao, "id", "created_at")
+	} else {
+		id, err = utils.SqlInsert(wmhm.sqlCli, weekMenuHistoryTable, dao, "id", "created_at")
+	}
+	if err != nil {
+		logger.Warn(weekMenuHistoryLogTag, "Insert Failed|Dao:%+v|Err:%v", dao, err)
+		return err
+	}
+	dao.ID = uint32(id)
+	return nil
+}
+
+func (wmhm *WeekMenuHistoryModel) GetHistoryList(weekMenuID uint32, page, pageSize int32) ([]*WeekMenuHistory, error) {
+	condition := " WHERE `week_menu_id` = ? ORDER BY `version` DESC LIMIT ?,? "
+	retList, err := utils.SqlQuery(wmhm.sqlCli, weekMenuHistoryTable, &WeekMenuHistory{}, condition,
+		weekMenuID, (page-1)*pageSize, pageSize)
+	if err != nil {
+		logger.Warn(weekMenuHistoryLogTag, "GetHistoryList Failed|WeekMenuID:%v|Err:%v", weekMenuID, err)
+		return nil, err
+	}
+
+	return retList.([]*WeekMenuHistory), nil
+}
+
+func (wmhm *WeekMenuHistoryModel) GetHistoryCount(weekMenuID uint32) (int32, error) {
+	sqlStr := fmt.Sprintf("SELECT COUNT(*) FROM `%v` WHERE `week_menu_id` = ? ", weekMenuHistoryTable)
+	row := wmhm.sqlCli.QueryRow(sqlStr, weekMenuID)
+	var count int32 = 0
+	err := row.Scan(&count)
+	if err != nil {
+		return 0, err
+	}
+	return count, nil
+}
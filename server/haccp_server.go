@@ -0,0 +1,216 @@
+package server
+
+import (
+	"fmt"
+	"io/ioutil"
+	"time"
+
+	"github.com/canteen_management/config"
+	"github.com/canteen_management/conv"
+	"github.com/canteen_management/dto"
+	"github.com/canteen_management/enum"
+	"github.com/canteen_management/logger"
+	"github.com/canteen_management/service"
+	"github.com/canteen_management/utils"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	haccpServerLogTag = "HaccpServer"
+)
+
+type HaccpServer struct {
+	haccpService *service.HaccpService
+	userService  *service.UserService
+}
+
+func NewHaccpServer(dbConf utils.Config) (*HaccpServer, error) {
+	sqlCli, err := utils.NewMysqlClient(dbConf)
+	if err != nil {
+		logger.Warn(haccpServerLogTag, "NewHaccpServer Failed|Err:%v", err)
+		return nil, err
+	}
+	haccpService := service.NewHaccpService(sqlCli)
+	userService := service.NewUserService(sqlCli)
+	return &HaccpServer{
+		haccpService: haccpService,
+		userService:  userService,
+	}, nil
+}
+
+func (hs *HaccpServer) RequestPointList(ctx *gin.Context, rawReq interface{}, res *dto.Response) {
+	req := rawReq.(*dto.HaccpPointListReq)
+	pointList, err := hs.haccpService.GetPointList(req.PointType)
+	if err != nil {
+		res.Code = enum.SqlError
+		return
+	}
+	storeTypeMap, err := hs.haccpService.GetStoreTypeMap()
+	if err != nil {
+		res.Code = enum.SqlError
+		return
+	}
+	dishTypeMap, err := hs.haccpService.GetDishTypeMap()
+	if err != nil {
+		res.Code = enum.SqlError
+		return
+	}
+	res.Data = &dto.HaccpPointListRes{
+		PointList: conv.ConvertToHaccpPointList(pointList, storeTypeMap, dishTypeMap),
+	}
+}
+
+func (hs *HaccpServer) RequestModifyPoint(ctx *gin.Context, rawReq interface{}, res *dto.Response) {
+	req := rawReq.(*dto.ModifyHaccpPointReq)
+	if req.Point == nil {
+		res.Code = enum.ParamsError
+		return
+	}
+	switch req.Operate {
+	case enum.OperateTypeAdd:
+		secret, err := hs.haccpService.AddPoint(conv.ConvertFromHaccpPointInfo(req.Point))
+		if err != nil {
+			res.Code = enum.SqlError
+			res.Msg = err.Error()
+			return
+		}
+		res.Data = &dto.ModifyHaccpPointRes{DeviceSecret: secret}
+	case enum.OperateTypeModify:
+		secret, err := hs.haccpService.UpdatePoint(conv.ConvertFromHaccpPointInfo(req.Point), req.ResetSecret)
+		if err != nil {
+			res.Code = enum.SqlError
+			res.Msg = err.Error()
+			return
+		}
+		res.Data = &dto.ModifyHaccpPointRes{DeviceSecret: secret}
+	case enum.OperateTypeDel:
+		err := hs.haccpService.DeletePoint(req.Point.PointID)
+		if err != nil {
+			res.Code = enum.SqlError
+			res.Msg = err.Error()
+			return
+		}
+	default:
+		logger.Warn(haccpServerLogTag, "RequestModifyPoint Unknown OperateType|Type:%v", req.Operate)
+		res.Code = enum.SystemError
+	}
+}
+
+func (hs *HaccpServer) RequestRecordReading(ctx *gin.Context, rawReq interface{}, res *dto.Response) {
+	req := rawReq.(*dto.RecordHaccpReadingReq)
+	custom := dto.GetCustomContextInfo(ctx)
+	if custom.Token == nil {
+		res.Code = enum.PermissionDenied
+		return
+	}
+	err := hs.haccpService.AddManualReading(req.PointID, conv.ConvertFromHaccpReading(req, custom.Token.AdminUid))
+	if err != nil {
+		res.Code = enum.SqlError
+		res.Msg = err.Error()
+		return
+	}
+}
+
+func (hs *HaccpServer) RequestIngestReading(ctx *gin.Context, rawReq interface{}, res *dto.Response) {
+	req := rawReq.(*dto.HaccpIngestReq)
+	reading, err := hs.haccpService.IngestReading(req.DeviceKey, req.Value, req.ReadAt, req.Sign)
+	if err != nil {
+		res.Code = enum.ParamsError
+		res.Msg = err.Error()
+		return
+	}
+	res.Data = &dto.HaccpIngestRes{ReadingID: reading.ID, AlertStatus: reading.AlertStatus}
+}
+
+func (hs *HaccpServer) RequestReadingList(ctx *gin.Context, rawReq interface{}, res *dto.Response) {
+	req := rawReq.(*dto.HaccpReadingListReq)
+	readingList, count, err := hs.haccpService.GetReadingList(req.PointID, req.PointType, req.AlertStatus,
+		req.StartTime, req.EndTime, req.Page, req.PageSize)
+	if err != nil {
+		res.Code = enum.SqlError
+		return
+	}
+	pointMap, err := hs.haccpService.GetPointMap()
+	if err != nil {
+		res.Code = enum.SqlError
+		return
+	}
+	adminMap, err := hs.userService.GetAdminMap()
+	if err != nil {
+		logger.Warn(haccpServerLogTag, "GetAdminMap Failed|Err:%v", err)
+		res.Code = enum.SqlError
+		return
+	}
+	res.Data = &dto.HaccpReadingListRes{
+		PaginationRes: dto.PaginationRes{
+			Page:        req.Page,
+			PageSize:    req.PageSize,
+			TotalNumber: count,
+		},
+		ReadingList: conv.ConvertToHaccpReadingList(readingList, pointMap, adminMap),
+	}
+}
+
+func (hs *HaccpServer) RequestResolveAlert(ctx *gin.Context, rawReq interface{}, res *dto.Response) {
+	req := rawReq.(*dto.ResolveHaccpAlertReq)
+	custom := dto.GetCustomContextInfo(ctx)
+	if custom.Token == nil {
+		res.Code = enum.PermissionDenied
+		return
+	}
+	err := hs.haccpService.ResolveAlert(req.ReadingID, custom.Token.AdminUid, req.Action)
+	if err != nil {
+		res.Code = enum.SqlError
+		res.Msg = err.Error()
+		return
+	}
+}
+
+func (hs *HaccpServer) RequestExportLog(ctx *gin.Context, rawReq interface{}, res *dto.Response) {
+	req := rawReq.(*dto.ExportHaccpLogReq)
+	readingList, _, err := hs.haccpService.GetReadingList(0, req.PointType, enum.HaccpAlertStatusAll,
+		req.StartTime, req.EndTime, 0, 0)
+	if err != nil {
+		logger.Warn(haccpServerLogTag, "RequestExportLog GetReadingList Failed|Err:%v", err)
+		res.Code = enum.SqlError
+		return
+	}
+	pointMap, err := hs.haccpService.GetPointMap()
+	if err != nil {
+		res.Code = enum.SqlError
+		return
+	}
+	storeTypeMap, err := hs.haccpService.GetStoreTypeMap()
+	if err != nil {
+		res.Code = enum.SqlError
+		return
+	}
+	dishTypeMap, err := hs.haccpService.GetDishTypeMap()
+	if err != nil {
+		res.Code = enum.SqlError
+		return
+	}
+	adminMap, err := hs.userService.GetAdminMap()
+	if err != nil {
+		logger.Warn(haccpServerLogTag, "RequestExportLog GetAdminMap Failed|Err:%v", err)
+		res.Code = enum.SqlError
+		return
+	}
+
+	fileName := fmt.Sprintf("haccp_log_%v_%v%v", time.Unix(req.StartTime, 0).Format("20060102"),
+		time.Unix(req.EndTime, 0).Format("20060102"), req.FileType)
+	content, err := utils.WriteSheet(fileName, conv.GenerateHaccpLogSheet(readingList, pointMap, storeTypeMap,
+		dishTypeMap, adminMap))
+	if err != nil {
+		logger.Warn(haccpServerLogTag, "RequestExportLog WriteSheet Failed|Err:%v", err)
+		res.Code = enum.SystemError
+		return
+	}
+	err = ioutil.WriteFile(config.Config.FileUploadPath+fileName, content, 0666)
+	if err != nil {
+		logger.Warn(haccpServerLogTag, "RequestExportLog WriteFile Failed|Err:%v", err)
+		res.Code = enum.SystemError
+		return
+	}
+	res.Data = &dto.ExportHaccpLogRes{FileName: fileName, Url: config.Config.FileBaseUrl + fileName}
+}
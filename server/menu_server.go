@@ -1,8 +1,12 @@
 package server
 
 import (
+	"encoding/base64"
+	"fmt"
+	"io/ioutil"
 	"time"
 
+	"github.com/canteen_management/config"
 	"github.com/canteen_management/conv"
 	"github.com/canteen_management/dto"
 	"github.com/canteen_management/enum"
@@ -16,6 +20,8 @@ import (
 
 const (
 	menuServerLogTag = "MenuServer"
+
+	ratingWeightDays = 90
 )
 
 type MenuServer struct {
@@ -102,14 +108,14 @@ func (ms *MenuServer) RequestDishList(ctx *gin.Context, rawReq interface{}, res
 		return
 	}
 
-	dishList, dishCount, err := ms.dishService.GetDishList(req.DishType, req.Page, req.PageSize)
+	dishList, recipeMap, dishCount, err := ms.dishService.GetDishList(req.DishType, req.Page, req.PageSize)
 	if err != nil {
 		res.Code = enum.SqlError
 		return
 	}
 
 	res.Data = &dto.DishListRes{
-		DishList: conv.ConvertToDishInfoList(dishList, typeMap),
+		DishList: conv.ConvertToDishInfoListWithRecipe(dishList, typeMap, recipeMap),
 		PaginationRes: dto.PaginationRes{
 			Page:        req.Page,
 			PageSize:    req.PageSize,
@@ -123,6 +129,20 @@ func (ms *MenuServer) RequestModifyDish(ctx *gin.Context, rawReq interface{}, re
 
 	logger.Info(menuServerLogTag, "RequestModifyDish Req:%#v", req)
 
+	if len(req.DishInfo.Recipe) > 0 {
+		unitMap, err := ms.dishService.GetGoodsUnitMap()
+		if err != nil {
+			res.Code = enum.SqlError
+			return
+		}
+		err = conv.NormalizeRecipeUnit(unitMap, req.DishInfo.Recipe)
+		if err != nil {
+			res.Code = enum.ParamsError
+			res.Msg = err.Error()
+			return
+		}
+	}
+
 	switch req.Operate {
 	case enum.OperateTypeAdd:
 		err := ms.dishService.AddDish(conv.ConvertFromDishInfo(&req.DishInfo))
@@ -138,13 +158,13 @@ func (ms *MenuServer) RequestModifyDish(ctx *gin.Context, rawReq interface{}, re
 			res.Msg = err.Error()
 			return
 		}
-	//case enum.OperateTypeDel:
-	//	err := ms.dishService.DeleteDish(req.DishInfo.DishID)
-	//	if err != nil {
-	//		res.Code = enum.SqlError
-	//		res.Msg = err.Error()
-	//		return
-	//	}
+	case enum.OperateTypeDel:
+		err := ms.dishService.DeleteDish(req.DishInfo.DishID)
+		if err != nil {
+			res.Code = enum.SqlError
+			res.Msg = err.Error()
+			return
+		}
 	default:
 		logger.Warn(menuServerLogTag, "RequestModifyDish Unknown OperateType|Type:%v", req.Operate)
 		res.Code = enum.SystemError
@@ -157,6 +177,20 @@ func (ms *MenuServer) RequestBatchModifyDish(ctx *gin.Context, rawReq interface{
 
 	logger.Info(menuServerLogTag, "RequestBatchModifyDish Req:%#v", req)
 
+	unitMap, err := ms.dishService.GetGoodsUnitMap()
+	if err != nil {
+		res.Code = enum.SqlError
+		return
+	}
+	for _, dishInfo := range req.DishList {
+		err = conv.NormalizeRecipeUnit(unitMap, dishInfo.Recipe)
+		if err != nil {
+			res.Code = enum.ParamsError
+			res.Msg = err.Error()
+			return
+		}
+	}
+
 	switch req.Operate {
 	case enum.OperateTypeAdd:
 		err := ms.dishService.BatchAddDish(conv.ConvertFromDishList(req.DishList))
@@ -272,16 +306,17 @@ func (ms *MenuServer) RequestModifyWeekMenu(ctx *gin.Context, rawReq interface{}
 	weekMenuDao := conv.ParseWeekMenuDetail(req.WeekMenuRows, req.WeekMenuID, req.MenuTypeID, req.MenuDate)
 	switch req.Operate {
 	case enum.OperateTypeAdd:
-		err := ms.menuService.AddWeekMenu(weekMenuDao)
+		err := ms.menuService.AddWeekMenu(weekMenuDao, req.Uid)
 		if err != nil {
 			res.Code = enum.SqlError
 			res.Msg = err.Error()
 			return
 		}
 	case enum.OperateTypeModify:
-		err := ms.menuService.UpdateWeekMenu(weekMenuDao)
+		err := ms.menuService.UpdateWeekMenu(weekMenuDao, req.Uid)
 		if err != nil {
 			res.Code = enum.SqlError
+			res.Msg = err.Error()
 			return
 		}
 	default:
@@ -290,6 +325,165 @@ func (ms *MenuServer) RequestModifyWeekMenu(ctx *gin.Context, rawReq interface{}
 	}
 }
 
+func (ms *MenuServer) RequestExportWeekMenu(ctx *gin.Context, rawReq interface{}, res *dto.Response) {
+	req := rawReq.(*dto.ExportWeekMenuReq)
+	dishMap, err := ms.dishService.GetDishIDMap()
+	if err != nil {
+		logger.Warn(menuServerLogTag, "RequestExportWeekMenu GetDishIDMap Failed|Err:%v", err)
+		res.Code = enum.SqlError
+		return
+	}
+	dishTypeMap, err := ms.dishService.GetDishTypeMap()
+	if err != nil {
+		logger.Warn(menuServerLogTag, "RequestExportWeekMenu GetDishTypeMap Failed|Err:%v", err)
+		res.Code = enum.SqlError
+		return
+	}
+	menu, err := ms.menuService.GetWeekMenu(req.WeekMenuID)
+	if err != nil {
+		logger.Warn(menuServerLogTag, "RequestExportWeekMenu GetWeekMenu Failed|Err:%v", err)
+		res.Code = enum.SqlError
+		return
+	}
+
+	head, dataList := conv.GenerateWeekMenuDetailTable(menu, dishMap, dishTypeMap)
+	fileName := fmt.Sprintf("week_menu_%v_v%v%v", menu.ID, menu.Version, req.FileType)
+	content, err := utils.WriteSheet(fileName, conv.GenerateWeekMenuSheet(head, dataList))
+	if err != nil {
+		logger.Warn(menuServerLogTag, "RequestExportWeekMenu WriteSheet Failed|Err:%v", err)
+		res.Code = enum.SystemError
+		return
+	}
+	err = ioutil.WriteFile(config.Config.FileUploadPath+fileName, content, 0666)
+	if err != nil {
+		logger.Warn(menuServerLogTag, "RequestExportWeekMenu WriteFile Failed|Err:%v", err)
+		res.Code = enum.SystemError
+		return
+	}
+	res.Data = &dto.ExportWeekMenuRes{FileName: fileName, Url: config.Config.FileBaseUrl + fileName}
+}
+
+func (ms *MenuServer) RequestImportWeekMenu(ctx *gin.Context, rawReq interface{}, res *dto.Response) {
+	req := rawReq.(*dto.ImportWeekMenuReq)
+	fileData, err := base64.StdEncoding.DecodeString(req.FileData)
+	if err != nil {
+		logger.Warn(menuServerLogTag, "RequestImportWeekMenu DecodeFile Failed|Err:%v", err)
+		res.Code = enum.ParamsError
+		return
+	}
+	rows, err := utils.ReadSheet(req.FileName, fileData)
+	if err != nil {
+		res.Code = enum.ParamsError
+		res.Msg = err.Error()
+		return
+	}
+	dishNameMap, err := ms.dishService.GetDishNameMap()
+	if err != nil {
+		res.Code = enum.SqlError
+		return
+	}
+
+	dishTypeMap, err := ms.dishService.GetDishTypeMap()
+	if err != nil {
+		res.Code = enum.SqlError
+		return
+	}
+
+	weekMenu, errorList := conv.ParseWeekMenuSheet(rows, dishNameMap, dishTypeMap, req.MenuType, req.TimeStart)
+	if len(errorList) > 0 {
+		res.Code = enum.ParamsError
+		res.Msg = fmt.Sprintf("存在%v处无法识别的内容", len(errorList))
+		res.Data = &dto.ImportWeekMenuRes{ErrorList: errorList}
+		return
+	}
+	err = ms.menuService.ImportWeekMenu(weekMenu, req.Uid)
+	if err != nil {
+		res.Code = enum.SqlError
+		res.Msg = err.Error()
+		return
+	}
+	res.Data = &dto.ImportWeekMenuRes{WeekMenuID: weekMenu.ID, ErrorList: errorList}
+}
+
+func (ms *MenuServer) RequestSubmitWeekMenu(ctx *gin.Context, rawReq interface{}, res *dto.Response) {
+	req := rawReq.(*dto.SubmitWeekMenuReq)
+
+	custom := dto.GetCustomContextInfo(ctx)
+	if custom.Token == nil || custom.Token.AdminUid == 0 {
+		logger.Warn(menuServerLogTag, "RequestSubmitWeekMenu Permission Denied|ID:%v", req.WeekMenuID)
+		res.Code = enum.PermissionDenied
+		return
+	}
+
+	err := ms.menuService.SubmitWeekMenu(req.WeekMenuID)
+	if err != nil {
+		res.Code = enum.SqlError
+		res.Msg = err.Error()
+		return
+	}
+}
+
+func (ms *MenuServer) RequestReviewWeekMenu(ctx *gin.Context, rawReq interface{}, res *dto.Response) {
+	req := rawReq.(*dto.ReviewWeekMenuReq)
+
+	custom := dto.GetCustomContextInfo(ctx)
+	if custom.Token == nil || custom.Token.Role&(1<<enum.RoleReviewer) == 0 {
+		logger.Warn(menuServerLogTag, "RequestReviewWeekMenu Permission Denied|ID:%v", req.WeekMenuID)
+		res.Code = enum.PermissionDenied
+		return
+	}
+
+	err := ms.menuService.ReviewWeekMenu(req.WeekMenuID, custom.Token.AdminUid, req.Pass)
+	if err != nil {
+		res.Code = enum.SqlError
+		res.Msg = err.Error()
+		return
+	}
+}
+
+func (ms *MenuServer) RequestArchiveWeekMenu(ctx *gin.Context, rawReq interface{}, res *dto.Response) {
+	req := rawReq.(*dto.ArchiveWeekMenuReq)
+
+	// 归档会使菜单下线，与审核一样需要审核员角色
+	custom := dto.GetCustomContextInfo(ctx)
+	if custom.Token == nil || custom.Token.Role&(1<<enum.RoleReviewer) == 0 {
+		logger.Warn(menuServerLogTag, "RequestArchiveWeekMenu Permission Denied|ID:%v", req.WeekMenuID)
+		res.Code = enum.PermissionDenied
+		return
+	}
+
+	err := ms.menuService.ArchiveWeekMenu(req.WeekMenuID)
+	if err != nil {
+		res.Code = enum.SqlError
+		res.Msg = err.Error()
+		return
+	}
+}
+
+func (ms *MenuServer) RequestWeekMenuHistory(ctx *gin.Context, rawReq interface{}, res *dto.Response) {
+	req := rawReq.(*dto.WeekMenuHistoryReq)
+	dishIDMap, err := ms.dishService.GetDishIDMap()
+	if err != nil {
+		res.Code = enum.SqlError
+		return
+	}
+
+	historyList, count, err := ms.menuService.GetWeekMenuHistory(req.WeekMenuID, req.Page, req.PageSize)
+	if err != nil {
+		res.Code = enum.SqlError
+		return
+	}
+
+	res.Data = &dto.WeekMenuHistoryRes{
+		List: conv.ConvertToWeekMenuHistoryList(historyList, dishIDMap),
+		PaginationRes: dto.PaginationRes{
+			Page:        req.Page,
+			PageSize:    req.PageSize,
+			TotalNumber: count,
+		},
+	}
+}
+
 func (ms *MenuServer) RequestModifyMenuType(ctx *gin.Context, rawReq interface{}, res *dto.Response) {
 	req := rawReq.(*dto.ModifyMenuTypeReq)
 
@@ -351,7 +545,7 @@ func (ms *MenuServer) RequestGenerateStaffMenu(ctx *gin.Context, rawReq interfac
 	for mealType, numberConf := range confMap {
 		totalDishList := make([]uint32, 0)
 		for dishType, dishNum := range numberConf {
-			dishList := ms.dishService.RandDishIDByType(dishType, int(dishNum))
+			dishList := ms.dishService.RandDishIDByType(dishType, int(dishNum), menu.MenuDate)
 			totalDishList = append(totalDishList, dishList...)
 		}
 		menuDishMap[mealType] = totalDishList
@@ -392,6 +586,17 @@ func (ms *MenuServer) RequestGenerateWeekMenu(ctx *gin.Context, rawReq interface
 		return
 	}
 
+	scoreMap := make(map[uint32]float64)
+	if req.RatingWeight {
+		nowTime := time.Now().Unix()
+		scoreMap, err = ms.dishService.GetDishScoreMap(nowTime-3600*24*ratingWeightDays, nowTime)
+		if err != nil {
+			logger.Warn(menuServerLogTag, "GenerateWeekMenu GetDishScoreMap Failed|Err:%v", err)
+			res.Code = enum.SqlError
+			return
+		}
+	}
+
 	startTime := time.Unix(req.TimeStart, 0)
 	start := time.Date(startTime.Year(), startTime.Month(), startTime.Day(), 0, 0, 0, 0,
 		startTime.Location()).Unix()
@@ -402,7 +607,13 @@ func (ms *MenuServer) RequestGenerateWeekMenu(ctx *gin.Context, rawReq interface
 		for mealType, numberConf := range confMap {
 			totalDishList := make([]*model.Dish, 0)
 			for dishType, dishNum := range numberConf {
-				dishList := ms.dishService.RandDishByType(dishType, int(dishNum))
+				var dishList []*model.Dish
+				if req.RatingWeight {
+					dishList = ms.dishService.RandDishByTypeWithScore(dishType, int(dishNum), scoreMap,
+						time.Unix(start, 0))
+				} else {
+					dishList = ms.dishService.RandDishByType(dishType, int(dishNum), time.Unix(start, 0))
+				}
 				totalDishList = append(totalDishList, dishList...)
 			}
 			dishIDList := make([]uint32, 0, len(totalDishList))
@@ -4,6 +4,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/canteen_management/config"
 	"github.com/canteen_management/conv"
 	"github.com/canteen_management/dto"
 	"github.com/canteen_management/enum"
@@ -24,6 +25,7 @@ type OrderServer struct {
 	orderService *service.OrderService
 	userService  *service.UserService
 	cartService  *service.CartService
+	wasteService *service.WasteService
 }
 
 func NewOrderServer(dbConf utils.Config) (*OrderServer, error) {
@@ -45,6 +47,8 @@ func NewOrderServer(dbConf utils.Config) (*OrderServer, error) {
 	orderService := service.NewOrderService(sqlCli)
 	userService := service.NewUserService(sqlCli)
 	cartService := service.NewCartService(sqlCli)
+	wasteService := service.NewWasteService(sqlCli)
+	wasteService.SetCostMethod(config.Config.CostMethod)
 
 	return &OrderServer{
 		dishService:  dishService,
@@ -52,6 +56,7 @@ func NewOrderServer(dbConf utils.Config) (*OrderServer, error) {
 		orderService: orderService,
 		userService:  userService,
 		cartService:  cartService,
+		wasteService: wasteService,
 	}, nil
 }
 
@@ -71,7 +76,7 @@ func (os *OrderServer) RequestOrderMenu(ctx *gin.Context, rawReq interface{}, re
 
 	nowTime := time.Now().Unix()
 	orderDate := utils.GetZeroTime(nowTime + 3600*24)
-	dayMenu, err := os.menuService.GetWeekMenuByTime(orderDate, 1)
+	dayMenu, err := os.menuService.GetWeekMenuByTime(orderDate, enum.OrderMenuTypeID)
 	if err != nil {
 		logger.Warn(orderServerLogTag, "RequestOrderMenu GetWeekMenuByTime Failed|Err:%v", err)
 		res.Code = enum.SystemError
@@ -97,7 +102,8 @@ func (os *OrderServer) RequestOrderMenu(ctx *gin.Context, rawReq interface{}, re
 			totalGoods += detail.Quantity
 		}
 	}
-	tomorrowData := conv.ConvertMenuToOrderNode(orderDate, dayMenu, dishMap, typeMap, dishQuantityMap, true)
+	tomorrowData := conv.ConvertMenuToOrderNode(orderDate, dayMenu, dishMap, typeMap, dishQuantityMap, true,
+		enum.GetAllergenMask(req.ExcludeAllergenList))
 
 	resData := dto.OrderMenuRes{
 		Menu:       tomorrowData,
@@ -386,10 +392,11 @@ func (os *OrderServer) RequestOrderDishAnalysis(ctx *gin.Context, rawReq interfa
 	}
 	retData := &dto.OrderDishAnalysisRes{Summary: make([]*dto.OrderDishSummaryInfo, 0)}
 	for dishID, quantity := range quantityMap {
+		dish := conv.GetDish(dishMap, dishID)
 		summary := &dto.OrderDishSummaryInfo{
 			DishID:      dishID,
-			DishName:    dishMap[dishID].DishName,
-			DishType:    dishMap[dishID].DishType,
+			DishName:    dish.DishName,
+			DishType:    dish.DishType,
 			Quantity:    quantity,
 			OrderNumber: orderNumberMap[dishID],
 		}
@@ -573,3 +580,131 @@ func (os *OrderServer) RequestModifyCart(ctx *gin.Context, rawReq interface{}, r
 	}
 	res.Data = retData
 }
+
+func (os *OrderServer) RequestRateOrder(ctx *gin.Context, rawReq interface{}, res *dto.Response) {
+	req := rawReq.(*dto.RateOrderReq)
+	custom := dto.GetCustomContextInfo(ctx)
+	if custom.Token == nil || custom.Token.UID == 0 {
+		res.Code = enum.PermissionDenied
+		return
+	}
+
+	err := os.orderService.RateOrder(custom.Token.UID, req.OrderID, conv.ConvertToDishRatingList(req.RatingList))
+	if err != nil {
+		logger.Warn(orderServerLogTag, "RequestRateOrder Failed|OrderID:%v|Err:%v", req.OrderID, err)
+		res.Code = enum.SqlError
+		res.Msg = err.Error()
+		return
+	}
+}
+
+func (os *OrderServer) RequestDishRatingList(ctx *gin.Context, rawReq interface{}, res *dto.Response) {
+	req := rawReq.(*dto.DishRatingListReq)
+	dishMap, err := os.dishService.GetDishIDMap()
+	if err != nil {
+		logger.Warn(orderServerLogTag, "GetDishIDMap Failed|Err:%v", err)
+		res.Code = enum.SystemError
+		return
+	}
+
+	ratingList, count, err := os.orderService.GetDishRatingList(req.DishID, req.StartTime, req.EndTime,
+		req.Page, req.PageSize)
+	if err != nil {
+		res.Code = enum.SqlError
+		return
+	}
+
+	res.Data = &dto.DishRatingListRes{
+		List: conv.ConvertToDishRatingInfoList(ratingList, dishMap),
+		PaginationRes: dto.PaginationRes{
+			Page:        req.Page,
+			PageSize:    req.PageSize,
+			TotalNumber: count,
+		},
+	}
+}
+
+func (os *OrderServer) RequestDishRatingSummary(ctx *gin.Context, rawReq interface{}, res *dto.Response) {
+	req := rawReq.(*dto.DishRatingSummaryReq)
+	dishMap, err := os.dishService.GetDishIDMap()
+	if err != nil {
+		logger.Warn(orderServerLogTag, "GetDishIDMap Failed|Err:%v", err)
+		res.Code = enum.SystemError
+		return
+	}
+
+	summaryList, err := os.orderService.GetDishRatingSummary(req.DishID, req.StartTime, req.EndTime)
+	if err != nil {
+		res.Code = enum.SqlError
+		return
+	}
+
+	res.Data = &dto.DishRatingSummaryRes{Summary: conv.ConvertToDishRatingSummaryList(summaryList, dishMap)}
+}
+
+func (os *OrderServer) RequestRecordWaste(ctx *gin.Context, rawReq interface{}, res *dto.Response) {
+	req := rawReq.(*dto.RecordWasteReq)
+	custom := dto.GetCustomContextInfo(ctx)
+	if custom.Token == nil {
+		res.Code = enum.PermissionDenied
+		return
+	}
+
+	err := os.wasteService.RecordWaste(custom.Token.AdminUid, req.MealDate, req.MealType, conv.ConvertFromWasteItems(req.WasteList))
+	if err != nil {
+		res.Code = enum.SqlError
+		res.Msg = err.Error()
+		return
+	}
+}
+
+func (os *OrderServer) RequestWasteList(ctx *gin.Context, rawReq interface{}, res *dto.Response) {
+	req := rawReq.(*dto.WasteListReq)
+	dishMap, err := os.dishService.GetDishIDMap()
+	if err != nil {
+		logger.Warn(orderServerLogTag, "GetDishIDMap Failed|Err:%v", err)
+		res.Code = enum.SystemError
+		return
+	}
+	goodsMap, err := os.wasteService.GetGoodsMap()
+	if err != nil {
+		res.Code = enum.SystemError
+		return
+	}
+
+	wasteList, err := os.wasteService.GetWasteList(req.MealType, req.WasteType, req.DishID, req.StartTime, req.EndTime)
+	if err != nil {
+		res.Code = enum.SqlError
+		return
+	}
+	res.Data = &dto.WasteListRes{WasteList: conv.ConvertToWasteLogList(wasteList, dishMap, goodsMap)}
+}
+
+func (os *OrderServer) RequestWasteReport(ctx *gin.Context, rawReq interface{}, res *dto.Response) {
+	req := rawReq.(*dto.WasteReportReq)
+	dishMap, err := os.dishService.GetDishIDMap()
+	if err != nil {
+		logger.Warn(orderServerLogTag, "GetDishIDMap Failed|Err:%v", err)
+		res.Code = enum.SystemError
+		return
+	}
+	goodsMap, err := os.wasteService.GetGoodsMap()
+	if err != nil {
+		res.Code = enum.SystemError
+		return
+	}
+
+	orderList, detailMap, err := os.orderService.GetAllOrder(req.MealType, req.StartTime, req.EndTime, -1,
+		0, req.DishID)
+	if err != nil {
+		logger.Warn(orderServerLogTag, "GetAllOrder Failed|Err:%v", err)
+		res.Code = enum.SqlError
+		return
+	}
+	wasteList, err := os.wasteService.GetWasteList(req.MealType, 0, req.DishID, req.StartTime, req.EndTime)
+	if err != nil {
+		res.Code = enum.SqlError
+		return
+	}
+	res.Data = conv.ConvertToWasteReport(orderList, detailMap, wasteList, dishMap, goodsMap)
+}
@@ -1,6 +1,9 @@
 package server
 
 import (
+	"time"
+
+	"github.com/canteen_management/config"
 	"github.com/canteen_management/conv"
 	"github.com/canteen_management/dto"
 	"github.com/canteen_management/enum"
@@ -13,13 +16,18 @@ import (
 
 const (
 	purchaseServerLogTag = "PurchaseServer"
+
+	qualificationAlertDays = 30
 )
 
 type PurchaseServer struct {
-	storeService    *service.StoreService
-	purchaseService *service.PurchaseService
-	cartService     *service.CartService
-	userService     *service.UserService
+	storeService     *service.StoreService
+	purchaseService  *service.PurchaseService
+	quotationService *service.QuotationService
+	payableService   *service.PayableService
+	scorecardService *service.ScorecardService
+	cartService      *service.CartService
+	userService      *service.UserService
 }
 
 func NewPurchaseServer(dbConf utils.Config) (*PurchaseServer, error) {
@@ -32,11 +40,20 @@ func NewPurchaseServer(dbConf utils.Config) (*PurchaseServer, error) {
 	storeService := service.NewStoreService(sqlCli)
 	cartService := service.NewCartService(sqlCli)
 	userService := service.NewUserService(sqlCli)
+	quotationService := service.NewQuotationService(sqlCli)
+	payableService := service.NewPayableService(sqlCli)
+	scorecardService := service.NewScorecardService(sqlCli)
+	purchaseService.SetReceiveTolerance(config.Config.OverTolerance, config.Config.UnderTolerance)
+	purchaseService.SetCostMethod(config.Config.CostMethod)
+	storeService.SetCostMethod(config.Config.CostMethod)
 	return &PurchaseServer{
-		purchaseService: purchaseService,
-		storeService:    storeService,
-		cartService:     cartService,
-		userService:     userService,
+		purchaseService:  purchaseService,
+		storeService:     storeService,
+		quotationService: quotationService,
+		payableService:   payableService,
+		scorecardService: scorecardService,
+		cartService:      cartService,
+		userService:      userService,
 	}, nil
 }
 
@@ -50,10 +67,28 @@ func (ps *PurchaseServer) RequestSupplierList(ctx *gin.Context, rawReq interface
 		return
 	}
 
+	lapsedMap, err := ps.purchaseService.GetLapsedSuppliers()
+	if err != nil {
+		res.Code = enum.SqlError
+		return
+	}
+	missingMap, err := ps.purchaseService.GetMissingQualifications()
+	if err != nil {
+		res.Code = enum.SqlError
+		return
+	}
+
 	retData := &dto.SupplierListRes{
 		SupplierList:     conv.ConvertToSupplier(supplierList),
 		LastValidityTime: lastValidityTime,
 	}
+	for _, supplier := range retData.SupplierList {
+		supplier.Lapsed = lapsedMap[supplier.SupplierID]
+		supplier.MissingQualifications = make([]string, 0)
+		for _, docType := range missingMap[supplier.SupplierID] {
+			supplier.MissingQualifications = append(supplier.MissingQualifications, enum.GetQualificationName(docType))
+		}
+	}
 	res.Data = retData
 }
 
@@ -101,6 +136,145 @@ func (ps *PurchaseServer) RequestRenewSupplier(ctx *gin.Context, rawReq interfac
 	}
 }
 
+func (ps *PurchaseServer) RequestSourcingList(ctx *gin.Context, rawReq interface{}, res *dto.Response) {
+	req := rawReq.(*dto.SourcingListReq)
+	sourcingList, err := ps.purchaseService.GetSourcingList(req.SupplierID)
+	if err != nil {
+		res.Code = enum.SqlError
+		return
+	}
+	supplierMap, err := ps.purchaseService.GetSupplierMap()
+	if err != nil {
+		res.Code = enum.SqlError
+		return
+	}
+	goodsMap, err := ps.storeService.GetGoodsMap()
+	if err != nil {
+		res.Code = enum.SqlError
+		return
+	}
+	goodsTypeMap, err := ps.storeService.GetGoodsTypeMap()
+	if err != nil {
+		res.Code = enum.SqlError
+		return
+	}
+
+	res.Data = &dto.SourcingListRes{
+		SourcingList: conv.ConvertToSourcingList(sourcingList, supplierMap, goodsMap, goodsTypeMap),
+	}
+}
+
+func (ps *PurchaseServer) RequestModifySourcing(ctx *gin.Context, rawReq interface{}, res *dto.Response) {
+	req := rawReq.(*dto.ModifySourcingReq)
+	if req.Sourcing == nil {
+		res.Code = enum.ParamsError
+		return
+	}
+
+	var err error
+	switch req.Operate {
+	case enum.OperateTypeAdd:
+		err = ps.purchaseService.AddSourcing(conv.ConvertFromSourcingInfo(req.Sourcing))
+	case enum.OperateTypeModify:
+		err = ps.purchaseService.UpdateSourcing(conv.ConvertFromSourcingInfo(req.Sourcing))
+	case enum.OperateTypeDel:
+		err = ps.purchaseService.DeleteSourcing(req.Sourcing.SourcingID)
+	default:
+		logger.Warn(purchaseServerLogTag, "RequestModifySourcing Unknown OperateType|Type:%v", req.Operate)
+		res.Code = enum.SystemError
+		return
+	}
+	if err != nil {
+		res.Code = enum.SqlError
+		res.Msg = err.Error()
+		return
+	}
+}
+
+func (ps *PurchaseServer) RequestQualificationList(ctx *gin.Context, rawReq interface{}, res *dto.Response) {
+	req := rawReq.(*dto.QualificationListReq)
+	supplierID, ok := ps.getSupplierID(ctx)
+	if !ok {
+		res.Code = enum.PermissionDenied
+		return
+	}
+	if supplierID == 0 {
+		supplierID = req.SupplierID
+	}
+	supplierMap, err := ps.purchaseService.GetSupplierMap()
+	if err != nil {
+		res.Code = enum.SqlError
+		return
+	}
+
+	qualificationList, err := ps.purchaseService.GetQualificationList(supplierID)
+	if err != nil {
+		res.Code = enum.SqlError
+		return
+	}
+	res.Data = &dto.QualificationListRes{
+		QualificationList: conv.ConvertToQualificationList(qualificationList, supplierMap),
+	}
+}
+
+func (ps *PurchaseServer) RequestModifyQualification(ctx *gin.Context, rawReq interface{}, res *dto.Response) {
+	req := rawReq.(*dto.ModifyQualificationReq)
+	if req.Qualification == nil {
+		res.Code = enum.ParamsError
+		return
+	}
+	supplierID, ok := ps.getSupplierID(ctx)
+	if !ok {
+		res.Code = enum.PermissionDenied
+		return
+	}
+	qualification := conv.ConvertFromQualificationInfo(req.Qualification)
+	qualification.Operator = req.Uid
+	if supplierID > 0 {
+		qualification.SupplierID = supplierID
+	}
+
+	var err error
+	switch req.Operate {
+	case enum.OperateTypeAdd:
+		err = ps.purchaseService.AddQualification(qualification)
+	case enum.OperateTypeModify:
+		err = ps.purchaseService.UpdateQualification(qualification, supplierID)
+	case enum.OperateTypeDel:
+		err = ps.purchaseService.DeleteQualification(qualification.ID, supplierID)
+	default:
+		logger.Warn(purchaseServerLogTag, "RequestModifyQualification Unknown OperateType|Type:%v", req.Operate)
+		res.Code = enum.SystemError
+		return
+	}
+	if err != nil {
+		res.Code = enum.SqlError
+		res.Msg = err.Error()
+		return
+	}
+}
+
+func (ps *PurchaseServer) RequestQualificationAlert(ctx *gin.Context, rawReq interface{}, res *dto.Response) {
+	req := rawReq.(*dto.QualificationAlertReq)
+	if req.Days <= 0 {
+		req.Days = qualificationAlertDays
+	}
+	supplierMap, err := ps.purchaseService.GetSupplierMap()
+	if err != nil {
+		res.Code = enum.SqlError
+		return
+	}
+
+	alertList, err := ps.purchaseService.GetQualificationAlerts(req.Days)
+	if err != nil {
+		res.Code = enum.SqlError
+		return
+	}
+	res.Data = &dto.QualificationAlertRes{
+		AlertList: conv.ConvertToQualificationList(alertList, supplierMap),
+	}
+}
+
 func (ps *PurchaseServer) RequestPurchaseList(ctx *gin.Context, rawReq interface{}, res *dto.Response) {
 	req := rawReq.(*dto.PurchaseListReq)
 	goodsMap, err := ps.storeService.GetGoodsMap()
@@ -121,14 +295,17 @@ func (ps *PurchaseServer) RequestPurchaseList(ctx *gin.Context, rawReq interface
 		res.Code = enum.SystemError
 		return
 	}
-	supplierID := uint32(0)
-	for _, supplierInfo := range supplierMap {
-		if supplierInfo.Uid == req.Uid {
-			supplierID = supplierInfo.ID
+	creator, supplierID := req.Uid, req.SupplierID
+	if isSupplierRole(ctx) {
+		id, ok := ps.getSupplierID(ctx)
+		if !ok {
+			res.Code = enum.PermissionDenied
+			return
 		}
+		creator, supplierID = 0, id
 	}
 
-	purchaseList, totalNumber, detailMap, err := ps.purchaseService.GetPurchaseList(req.Status, req.Uid, req.PurchaseID,
+	purchaseList, totalNumber, detailMap, err := ps.purchaseService.GetPurchaseList(req.Status, creator, req.PurchaseID,
 		supplierID, req.StartTime, req.EndTime, req.Page, req.PageSize)
 	if err != nil {
 		logger.Warn(purchaseServerLogTag, "GetPurchaseList Failed|Err:%v", err)
@@ -152,7 +329,11 @@ func (ps *PurchaseServer) RequestPurchaseList(ctx *gin.Context, rawReq interface
 
 func (ps *PurchaseServer) RequestApplyPurchase(ctx *gin.Context, rawReq interface{}, res *dto.Response) {
 	req := rawReq.(*dto.ApplyPurchaseReq)
-	AdminUid := req.Uid
+	custom := dto.GetCustomContextInfo(ctx)
+	if custom.Token == nil {
+		res.Code = enum.PermissionDenied
+		return
+	}
 
 	goodsMap, err := ps.storeService.GetGoodsMap()
 	if err != nil {
@@ -161,33 +342,59 @@ func (ps *PurchaseServer) RequestApplyPurchase(ctx *gin.Context, rawReq interfac
 		return
 	}
 
+	unitMap, err := ps.storeService.GetGoodsUnitMap()
+	if err != nil {
+		res.Code = enum.SqlError
+		return
+	}
+	err = conv.NormalizePurchaseGoodsUnit(unitMap, req.GoodsList)
+	if err != nil {
+		res.Code = enum.ParamsError
+		res.Msg = err.Error()
+		return
+	}
 	details := conv.ConvertFromApplyPurchase(req.GoodsList, goodsMap)
-	purchaseOrder := &model.PurchaseOrder{Creator: AdminUid, Status: enum.PurchaseNew}
-	err = ps.purchaseService.ApplyPurchaseOrder(purchaseOrder, details)
+	purchaseList, err := ps.purchaseService.ApplyPurchaseOrder(custom.Token.AdminUid, details)
 	if err != nil {
 		res.Code = enum.SqlError
 		res.Msg = err.Error()
 		return
 	}
 
-	ps.cartService.ClearCart(AdminUid, enum.CartTypePurchase)
+	ps.cartService.ClearCart(custom.Token.AdminUid, enum.CartTypePurchase)
+	retData := &dto.ApplyPurchaseRes{PurchaseIDList: make([]uint32, 0, len(purchaseList))}
+	for _, purchase := range purchaseList {
+		retData.PurchaseIDList = append(retData.PurchaseIDList, purchase.ID)
+	}
+	res.Data = retData
 }
 
-func (ps *PurchaseServer) RequestReviewPurchase(ctx *gin.Context, rawReq interface{}, res *dto.Response) {
-	req := rawReq.(*dto.ReviewPurchaseReq)
-
-	err := ps.purchaseService.ReviewPurchaseOrder(req.PurchaseID)
+func (ps *PurchaseServer) RequestReorderPurchase(ctx *gin.Context, rawReq interface{}, res *dto.Response) {
+	req := rawReq.(*dto.ReorderPurchaseReq)
+	purchaseList, err := ps.purchaseService.GenerateReorderPurchase(req.Uid)
 	if err != nil {
 		res.Code = enum.SqlError
 		res.Msg = err.Error()
 		return
 	}
+
+	retData := &dto.ApplyPurchaseRes{PurchaseIDList: make([]uint32, 0, len(purchaseList))}
+	for _, purchase := range purchaseList {
+		retData.PurchaseIDList = append(retData.PurchaseIDList, purchase.ID)
+	}
+	res.Data = retData
 }
 
-func (ps *PurchaseServer) RequestConfirmPurchase(ctx *gin.Context, rawReq interface{}, res *dto.Response) {
-	req := rawReq.(*dto.ConfirmPurchaseReq)
+func (ps *PurchaseServer) RequestReviewPurchase(ctx *gin.Context, rawReq interface{}, res *dto.Response) {
+	req := rawReq.(*dto.ReviewPurchaseReq)
+	custom := dto.GetCustomContextInfo(ctx)
+	if custom.Token == nil {
+		res.Code = enum.PermissionDenied
+		return
+	}
 
-	err := ps.purchaseService.ConfirmPurchaseOrder(req.PurchaseID)
+	err := ps.purchaseService.ReviewPurchaseOrder(req.PurchaseID, custom.Token.AdminUid, custom.Token.Role,
+		!req.Reject, req.Comment)
 	if err != nil {
 		res.Code = enum.SqlError
 		res.Msg = err.Error()
@@ -195,9 +402,14 @@ func (ps *PurchaseServer) RequestConfirmPurchase(ctx *gin.Context, rawReq interf
 	}
 }
 
-func (ps *PurchaseServer) RequestReceivePurchase(ctx *gin.Context, rawReq interface{}, res *dto.Response) {
-	req := rawReq.(*dto.ReceivePurchaseReq)
-	uid := req.Uid
+func (ps *PurchaseServer) RequestEditPurchase(ctx *gin.Context, rawReq interface{}, res *dto.Response) {
+	req := rawReq.(*dto.EditPurchaseReq)
+	custom := dto.GetCustomContextInfo(ctx)
+	if custom.Token == nil {
+		res.Code = enum.PermissionDenied
+		return
+	}
+
 	goodsMap, err := ps.storeService.GetGoodsMap()
 	if err != nil {
 		res.Code = enum.SystemError
@@ -205,55 +417,182 @@ func (ps *PurchaseServer) RequestReceivePurchase(ctx *gin.Context, rawReq interf
 		return
 	}
 
+	unitMap, err := ps.storeService.GetGoodsUnitMap()
+	if err != nil {
+		res.Code = enum.SqlError
+		return
+	}
+	err = conv.NormalizePurchaseGoodsUnit(unitMap, req.GoodsList)
+	if err != nil {
+		res.Code = enum.ParamsError
+		res.Msg = err.Error()
+		return
+	}
 	details := conv.ConvertFromApplyPurchase(req.GoodsList, goodsMap)
-	err = ps.purchaseService.ReceivePurchaseOrder(req.PurchaseID, uid, req.SignPicture, details)
+	purchaseList, err := ps.purchaseService.EditPurchaseOrder(req.PurchaseID, custom.Token.AdminUid, details)
 	if err != nil {
 		res.Code = enum.SqlError
 		res.Msg = err.Error()
 		return
 	}
 
-	ps.storeService.GetStoreTypeList()
+	retData := &dto.ApplyPurchaseRes{PurchaseIDList: make([]uint32, 0, len(purchaseList))}
+	for _, purchase := range purchaseList {
+		retData.PurchaseIDList = append(retData.PurchaseIDList, purchase.ID)
+	}
+	res.Data = retData
 }
 
-func (ps *PurchaseServer) RequestApplyOutbound(ctx *gin.Context, rawReq interface{}, res *dto.Response) {
-	req := rawReq.(*dto.ApplyOutboundReq)
-	uid := req.Uid
+func (ps *PurchaseServer) RequestPurchaseApprovalList(ctx *gin.Context, rawReq interface{}, res *dto.Response) {
+	req := rawReq.(*dto.PurchaseApprovalListReq)
+	adminMap, err := ps.userService.GetAdminMap()
+	if err != nil {
+		logger.Warn(purchaseServerLogTag, "GetAdminMap Failed|Err:%v", err)
+		res.Code = enum.SystemError
+		return
+	}
 
-	goodsMap, err := ps.storeService.GetGoodsMap()
+	approvalList, err := ps.purchaseService.GetApprovalList(req.PurchaseID)
+	if err != nil {
+		res.Code = enum.SqlError
+		return
+	}
+	res.Data = &dto.PurchaseApprovalListRes{
+		ApprovalList: conv.ConvertToApprovalList(approvalList, adminMap),
+	}
+}
+
+func (ps *PurchaseServer) RequestApprovalChainList(ctx *gin.Context, rawReq interface{}, res *dto.Response) {
+	chainList, err := ps.purchaseService.GetApprovalChain()
 	if err != nil {
+		res.Code = enum.SqlError
+		return
+	}
+	res.Data = &dto.ApprovalChainListRes{
+		ChainList: conv.ConvertToApprovalChainList(chainList),
+	}
+}
+
+func (ps *PurchaseServer) RequestModifyApprovalChain(ctx *gin.Context, rawReq interface{}, res *dto.Response) {
+	req := rawReq.(*dto.ModifyApprovalChainReq)
+	if req.Chain == nil {
+		res.Code = enum.ParamsError
+		return
+	}
+	custom := dto.GetCustomContextInfo(ctx)
+	if custom.Token == nil || custom.Token.Role&(1<<enum.RoleAdmin) == 0 {
+		logger.Warn(purchaseServerLogTag, "RequestModifyApprovalChain Permission Denied")
+		res.Code = enum.PermissionDenied
+		return
+	}
+
+	var err error
+	switch req.Operate {
+	case enum.OperateTypeAdd:
+		err = ps.purchaseService.AddApprovalChain(conv.ConvertFromApprovalChainInfo(req.Chain))
+	case enum.OperateTypeModify:
+		err = ps.purchaseService.UpdateApprovalChain(conv.ConvertFromApprovalChainInfo(req.Chain))
+	case enum.OperateTypeDel:
+		err = ps.purchaseService.DeleteApprovalChain(req.Chain.ChainID)
+	default:
+		logger.Warn(purchaseServerLogTag, "RequestModifyApprovalChain Unknown OperateType|Type:%v", req.Operate)
 		res.Code = enum.SystemError
+		return
+	}
+	if err != nil {
+		res.Code = enum.SqlError
 		res.Msg = err.Error()
 		return
 	}
+}
 
-	details := conv.ConvertFromApplyOutbound(req.GoodsList, goodsMap)
-	outboundOrder := &model.OutboundOrder{Creator: uid, Status: enum.OutboundNew}
-	err = ps.storeService.ApplyOutboundOrder(outboundOrder, details)
+func (ps *PurchaseServer) RequestConfirmPurchase(ctx *gin.Context, rawReq interface{}, res *dto.Response) {
+	req := rawReq.(*dto.ConfirmPurchaseReq)
+
+	supplierID, ok := ps.getSupplierID(ctx)
+	if !ok {
+		res.Code = enum.PermissionDenied
+		return
+	}
+
+	err := ps.purchaseService.ConfirmPurchaseOrder(req.PurchaseID, supplierID)
 	if err != nil {
 		res.Code = enum.SqlError
 		res.Msg = err.Error()
 		return
 	}
+}
 
-	ps.cartService.ClearCart(uid, enum.CartTypeOutbound)
+func (ps *PurchaseServer) RequestReceivePurchase(ctx *gin.Context, rawReq interface{}, res *dto.Response) {
+	req := rawReq.(*dto.ReceivePurchaseReq)
+	uid := req.Uid
+	for _, purchaseGoods := range req.GoodsList {
+		if purchaseGoods.Code == "" {
+			continue
+		}
+		goods, _, err := ps.storeService.ResolveCode(purchaseGoods.Code)
+		if err != nil {
+			res.Code = enum.ParamsError
+			res.Msg = err.Error()
+			return
+		}
+		purchaseGoods.GoodsID = goods.ID
+		if purchaseGoods.ReceiveNumber == 0 {
+			purchaseGoods.ReceiveNumber = 1
+		}
+	}
+	unitMap, err := ps.storeService.GetGoodsUnitMap()
+	if err != nil {
+		res.Code = enum.SqlError
+		return
+	}
+	err = conv.NormalizePurchaseGoodsUnit(unitMap, req.GoodsList)
+	if err != nil {
+		res.Code = enum.ParamsError
+		res.Msg = err.Error()
+		return
+	}
+	items := conv.ConvertFromReceivePurchase(req.GoodsList)
+	err = ps.purchaseService.ReceivePurchaseOrder(req.PurchaseID, uid, req.StoreTypeID, req.SignPicture, items)
+	if err != nil {
+		res.Code = enum.SqlError
+		res.Msg = err.Error()
+		return
+	}
+
+	ps.storeService.GetStoreTypeList()
 }
 
-func (ps *PurchaseServer) RequestReviewOutbound(ctx *gin.Context, rawReq interface{}, res *dto.Response) {
-	req := rawReq.(*dto.ReviewOutboundReq)
+func (ps *PurchaseServer) RequestClosePurchase(ctx *gin.Context, rawReq interface{}, res *dto.Response) {
+	req := rawReq.(*dto.ClosePurchaseReq)
+	custom := dto.GetCustomContextInfo(ctx)
+	if custom.Token == nil || isSupplierRole(ctx) {
+		res.Code = enum.PermissionDenied
+		return
+	}
 
-	err := ps.storeService.ReviewOutboundOrder(req.OutboundID)
+	backorder, err := ps.purchaseService.ClosePurchaseOrder(req.PurchaseID, custom.Token.AdminUid, req.SkipBackorder)
 	if err != nil {
 		res.Code = enum.SqlError
 		res.Msg = err.Error()
 		return
 	}
+	retData := &dto.ClosePurchaseRes{}
+	if backorder != nil {
+		retData.BackorderID = backorder.ID
+	}
+	res.Data = retData
 }
 
-func (ps *PurchaseServer) RequestFinishOutbound(ctx *gin.Context, rawReq interface{}, res *dto.Response) {
-	req := rawReq.(*dto.FinishOutboundReq)
+func (ps *PurchaseServer) RequestCancelPurchase(ctx *gin.Context, rawReq interface{}, res *dto.Response) {
+	req := rawReq.(*dto.CancelPurchaseReq)
+	custom := dto.GetCustomContextInfo(ctx)
+	if custom.Token == nil || isSupplierRole(ctx) {
+		res.Code = enum.PermissionDenied
+		return
+	}
 
-	err := ps.storeService.FinishOutboundOrder(req.OutboundID)
+	err := ps.purchaseService.CancelPurchaseOrder(req.PurchaseID, custom.Token.AdminUid, req.Reason)
 	if err != nil {
 		res.Code = enum.SqlError
 		res.Msg = err.Error()
@@ -261,8 +600,24 @@ func (ps *PurchaseServer) RequestFinishOutbound(ctx *gin.Context, rawReq interfa
 	}
 }
 
-func (ps *PurchaseServer) RequestOutboundOrderList(ctx *gin.Context, rawReq interface{}, res *dto.Response) {
-	req := rawReq.(*dto.OutboundListReq)
+func (ps *PurchaseServer) RequestReversePurchase(ctx *gin.Context, rawReq interface{}, res *dto.Response) {
+	req := rawReq.(*dto.ReversePurchaseReq)
+	custom := dto.GetCustomContextInfo(ctx)
+	if custom.Token == nil || isSupplierRole(ctx) {
+		res.Code = enum.PermissionDenied
+		return
+	}
+
+	err := ps.purchaseService.ReversePurchaseOrder(req.PurchaseID, custom.Token.AdminUid, req.Reason)
+	if err != nil {
+		res.Code = enum.SqlError
+		res.Msg = err.Error()
+		return
+	}
+}
+
+func (ps *PurchaseServer) RequestPurchaseReceiptList(ctx *gin.Context, rawReq interface{}, res *dto.Response) {
+	req := rawReq.(*dto.PurchaseReceiptListReq)
 	goodsMap, err := ps.storeService.GetGoodsMap()
 	if err != nil {
 		logger.Warn(purchaseServerLogTag, "GetGoodsMap Failed|Err:%v", err)
@@ -276,23 +631,642 @@ func (ps *PurchaseServer) RequestOutboundOrderList(ctx *gin.Context, rawReq inte
 		return
 	}
 
-	outboundList, totalNumber, detailMap, err := ps.storeService.GetOutboundList(req.Uid, req.OutboundID,
-		req.StartTime, req.EndTime, req.Status, req.Page, req.PageSize)
+	receiptList, err := ps.purchaseService.GetReceiptList(req.PurchaseID)
 	if err != nil {
-		logger.Warn(purchaseServerLogTag, "GetOutboundList Failed|Err:%v", err)
 		res.Code = enum.SqlError
 		res.Msg = err.Error()
 		return
 	}
+	res.Data = &dto.PurchaseReceiptListRes{
+		ReceiptList: conv.ConvertToPurchaseReceiptList(receiptList, goodsMap, adminMap),
+	}
+}
 
-	orderInfoList := conv.ConvertToOutboundInfoList(outboundList, detailMap, goodsMap, adminMap)
-	resData := &dto.OutboundListRes{
-		OutboundList: orderInfoList,
-		PaginationRes: dto.PaginationRes{
-			Page:        req.Page,
-			PageSize:    req.PageSize,
-			TotalNumber: totalNumber,
-		},
+func (ps *PurchaseServer) RequestApplyPurchaseReturn(ctx *gin.Context, rawReq interface{}, res *dto.Response) {
+	req := rawReq.(*dto.ApplyPurchaseReturnReq)
+	custom := dto.GetCustomContextInfo(ctx)
+	if custom.Token == nil || isSupplierRole(ctx) {
+		res.Code = enum.PermissionDenied
+		return
 	}
-	res.Data = resData
+
+	purchaseReturn := &model.PurchaseReturn{PurchaseID: req.PurchaseID, Creator: custom.Token.AdminUid, Reason: req.Reason,
+		Remark: req.Remark}
+	err := purchaseReturn.FromPictures(req.Pictures)
+	if err != nil {
+		res.Code = enum.ParamsError
+		return
+	}
+	unitMap, err := ps.storeService.GetGoodsUnitMap()
+	if err != nil {
+		res.Code = enum.SqlError
+		return
+	}
+	err = conv.NormalizeReturnGoodsUnit(unitMap, req.GoodsList)
+	if err != nil {
+		res.Code = enum.ParamsError
+		res.Msg = err.Error()
+		return
+	}
+	err = ps.purchaseService.ApplyPurchaseReturn(purchaseReturn, conv.ConvertFromReturnGoods(req.GoodsList))
+	if err != nil {
+		res.Code = enum.SqlError
+		res.Msg = err.Error()
+		return
+	}
+}
+
+func (ps *PurchaseServer) RequestPurchaseReturnList(ctx *gin.Context, rawReq interface{}, res *dto.Response) {
+	req := rawReq.(*dto.PurchaseReturnListReq)
+	supplierID, ok := ps.getSupplierID(ctx)
+	if !ok {
+		res.Code = enum.PermissionDenied
+		return
+	}
+	if supplierID == 0 {
+		supplierID = req.SupplierID
+	}
+	goodsMap, err := ps.storeService.GetGoodsMap()
+	if err != nil {
+		logger.Warn(purchaseServerLogTag, "GetGoodsMap Failed|Err:%v", err)
+		res.Code = enum.SystemError
+		return
+	}
+	supplierMap, err := ps.purchaseService.GetSupplierMap()
+	if err != nil {
+		logger.Warn(purchaseServerLogTag, "GetSupplierMap Failed|Err:%v", err)
+		res.Code = enum.SystemError
+		return
+	}
+	adminMap, err := ps.userService.GetAdminMap()
+	if err != nil {
+		logger.Warn(purchaseServerLogTag, "GetAdminMap Failed|Err:%v", err)
+		res.Code = enum.SystemError
+		return
+	}
+
+	returnList, totalNumber, err := ps.purchaseService.GetReturnList(req.PurchaseID, supplierID, req.StartTime,
+		req.EndTime, req.Page, req.PageSize)
+	if err != nil {
+		res.Code = enum.SqlError
+		res.Msg = err.Error()
+		return
+	}
+	res.Data = &dto.PurchaseReturnListRes{
+		ReturnList: conv.ConvertToPurchaseReturnList(returnList, goodsMap, supplierMap, adminMap),
+		PaginationRes: dto.PaginationRes{
+			Page:        req.Page,
+			PageSize:    req.PageSize,
+			TotalNumber: totalNumber,
+		},
+	}
+}
+
+func (ps *PurchaseServer) RequestApplyOutbound(ctx *gin.Context, rawReq interface{}, res *dto.Response) {
+	req := rawReq.(*dto.ApplyOutboundReq)
+	uid := req.Uid
+
+	goodsMap, err := ps.storeService.GetGoodsMap()
+	if err != nil {
+		res.Code = enum.SystemError
+		res.Msg = err.Error()
+		return
+	}
+
+	for _, outboundGoods := range req.GoodsList {
+		if outboundGoods.Code == "" {
+			continue
+		}
+		goods, _, err := ps.storeService.ResolveCode(outboundGoods.Code)
+		if err != nil {
+			res.Code = enum.ParamsError
+			res.Msg = err.Error()
+			return
+		}
+		outboundGoods.GoodsID = goods.ID
+		if outboundGoods.ExpectNumber == 0 {
+			outboundGoods.ExpectNumber = 1
+		}
+	}
+	unitMap, err := ps.storeService.GetGoodsUnitMap()
+	if err != nil {
+		res.Code = enum.SqlError
+		return
+	}
+	err = conv.NormalizeOutboundGoodsUnit(unitMap, req.GoodsList)
+	if err != nil {
+		res.Code = enum.ParamsError
+		res.Msg = err.Error()
+		return
+	}
+	details := conv.ConvertFromApplyOutbound(req.GoodsList, goodsMap)
+	outboundOrder := &model.OutboundOrder{Creator: uid, StoreTypeID: req.StoreTypeID, Status: enum.OutboundNew}
+	err = ps.storeService.ApplyOutboundOrder(outboundOrder, details)
+	if err != nil {
+		res.Code = enum.SqlError
+		res.Msg = err.Error()
+		return
+	}
+
+	ps.cartService.ClearCart(uid, enum.CartTypeOutbound)
+}
+
+func (ps *PurchaseServer) RequestReviewOutbound(ctx *gin.Context, rawReq interface{}, res *dto.Response) {
+	req := rawReq.(*dto.ReviewOutboundReq)
+
+	err := ps.storeService.ReviewOutboundOrder(req.OutboundID)
+	if err != nil {
+		res.Code = enum.SqlError
+		res.Msg = err.Error()
+		return
+	}
+}
+
+func (ps *PurchaseServer) RequestFinishOutbound(ctx *gin.Context, rawReq interface{}, res *dto.Response) {
+	req := rawReq.(*dto.FinishOutboundReq)
+
+	err := ps.storeService.FinishOutboundOrder(req.OutboundID)
+	if err != nil {
+		res.Code = enum.SqlError
+		res.Msg = err.Error()
+		return
+	}
+}
+
+func (ps *PurchaseServer) RequestCancelOutbound(ctx *gin.Context, rawReq interface{}, res *dto.Response) {
+	req := rawReq.(*dto.CancelOutboundReq)
+	custom := dto.GetCustomContextInfo(ctx)
+	if custom.Token == nil {
+		res.Code = enum.PermissionDenied
+		return
+	}
+
+	err := ps.storeService.CancelOutboundOrder(req.OutboundID, custom.Token.AdminUid, req.Reason)
+	if err != nil {
+		res.Code = enum.SqlError
+		res.Msg = err.Error()
+		return
+	}
+}
+
+func (ps *PurchaseServer) RequestReverseOutbound(ctx *gin.Context, rawReq interface{}, res *dto.Response) {
+	req := rawReq.(*dto.ReverseOutboundReq)
+	custom := dto.GetCustomContextInfo(ctx)
+	if custom.Token == nil {
+		res.Code = enum.PermissionDenied
+		return
+	}
+
+	err := ps.storeService.ReverseOutboundOrder(req.OutboundID, custom.Token.AdminUid, req.Reason)
+	if err != nil {
+		res.Code = enum.SqlError
+		res.Msg = err.Error()
+		return
+	}
+}
+
+func (ps *PurchaseServer) RequestOutboundOrderList(ctx *gin.Context, rawReq interface{}, res *dto.Response) {
+	req := rawReq.(*dto.OutboundListReq)
+	goodsMap, err := ps.storeService.GetGoodsMap()
+	if err != nil {
+		logger.Warn(purchaseServerLogTag, "GetGoodsMap Failed|Err:%v", err)
+		res.Code = enum.SystemError
+		return
+	}
+	adminMap, err := ps.userService.GetAdminMap()
+	if err != nil {
+		logger.Warn(purchaseServerLogTag, "GetAdminMap Failed|Err:%v", err)
+		res.Code = enum.SystemError
+		return
+	}
+
+	outboundList, totalNumber, detailMap, err := ps.storeService.GetOutboundList(req.Uid, req.OutboundID,
+		req.StartTime, req.EndTime, req.Status, req.Page, req.PageSize)
+	if err != nil {
+		logger.Warn(purchaseServerLogTag, "GetOutboundList Failed|Err:%v", err)
+		res.Code = enum.SqlError
+		res.Msg = err.Error()
+		return
+	}
+
+	orderInfoList := conv.ConvertToOutboundInfoList(outboundList, detailMap, goodsMap, adminMap)
+	resData := &dto.OutboundListRes{
+		OutboundList: orderInfoList,
+		PaginationRes: dto.PaginationRes{
+			Page:        req.Page,
+			PageSize:    req.PageSize,
+			TotalNumber: totalNumber,
+		},
+	}
+	res.Data = resData
+}
+
+func (ps *PurchaseServer) RequestQuotationList(ctx *gin.Context, rawReq interface{}, res *dto.Response) {
+	req := rawReq.(*dto.QuotationListReq)
+	supplierID, ok := ps.getSupplierID(ctx)
+	if !ok {
+		res.Code = enum.PermissionDenied
+		return
+	}
+	goodsMap, err := ps.storeService.GetGoodsMap()
+	if err != nil {
+		logger.Warn(purchaseServerLogTag, "GetGoodsMap Failed|Err:%v", err)
+		res.Code = enum.SystemError
+		return
+	}
+	supplierMap, err := ps.purchaseService.GetSupplierMap()
+	if err != nil {
+		logger.Warn(purchaseServerLogTag, "GetSupplierMap Failed|Err:%v", err)
+		res.Code = enum.SystemError
+		return
+	}
+	adminMap, err := ps.userService.GetAdminMap()
+	if err != nil {
+		logger.Warn(purchaseServerLogTag, "GetAdminMap Failed|Err:%v", err)
+		res.Code = enum.SystemError
+		return
+	}
+
+	quotationList, totalNumber, itemMap, err := ps.quotationService.GetQuotationList(req.Status, supplierID,
+		req.Page, req.PageSize)
+	if err != nil {
+		res.Code = enum.SqlError
+		res.Msg = err.Error()
+		return
+	}
+
+	res.Data = &dto.QuotationListRes{
+		QuotationList: conv.ConvertToQuotationInfoList(quotationList, itemMap, goodsMap, supplierMap, adminMap),
+		PaginationRes: dto.PaginationRes{
+			Page:        req.Page,
+			PageSize:    req.PageSize,
+			TotalNumber: totalNumber,
+		},
+	}
+}
+
+func (ps *PurchaseServer) RequestQuotationDetail(ctx *gin.Context, rawReq interface{}, res *dto.Response) {
+	req := rawReq.(*dto.QuotationDetailReq)
+	supplierID, ok := ps.getSupplierID(ctx)
+	if !ok {
+		res.Code = enum.PermissionDenied
+		return
+	}
+	goodsMap, err := ps.storeService.GetGoodsMap()
+	if err != nil {
+		logger.Warn(purchaseServerLogTag, "GetGoodsMap Failed|Err:%v", err)
+		res.Code = enum.SystemError
+		return
+	}
+	supplierMap, err := ps.purchaseService.GetSupplierMap()
+	if err != nil {
+		logger.Warn(purchaseServerLogTag, "GetSupplierMap Failed|Err:%v", err)
+		res.Code = enum.SystemError
+		return
+	}
+	adminMap, err := ps.userService.GetAdminMap()
+	if err != nil {
+		logger.Warn(purchaseServerLogTag, "GetAdminMap Failed|Err:%v", err)
+		res.Code = enum.SystemError
+		return
+	}
+
+	quotation, items, err := ps.quotationService.GetQuotationDetail(req.QuotationID, supplierID)
+	if err != nil {
+		res.Code = enum.SqlError
+		res.Msg = err.Error()
+		return
+	}
+	res.Data = conv.ConvertToQuotationInfo(quotation, items, goodsMap, supplierMap, adminMap)
+}
+
+func (ps *PurchaseServer) RequestOpenQuotation(ctx *gin.Context, rawReq interface{}, res *dto.Response) {
+	req := rawReq.(*dto.OpenQuotationReq)
+	custom := dto.GetCustomContextInfo(ctx)
+	if custom.Token == nil || isSupplierRole(ctx) {
+		res.Code = enum.PermissionDenied
+		return
+	}
+
+	quotation := &model.Quotation{Title: req.Title, Creator: custom.Token.AdminUid, Deadline: time.Unix(req.Deadline, 0)}
+	err := ps.quotationService.OpenQuotation(quotation, req.GoodsIDList)
+	if err != nil {
+		res.Code = enum.SqlError
+		res.Msg = err.Error()
+		return
+	}
+}
+
+func (ps *PurchaseServer) RequestSubmitQuote(ctx *gin.Context, rawReq interface{}, res *dto.Response) {
+	req := rawReq.(*dto.SubmitQuoteReq)
+	if !isSupplierRole(ctx) {
+		res.Code = enum.PermissionDenied
+		return
+	}
+	supplierID, ok := ps.getSupplierID(ctx)
+	if !ok {
+		res.Code = enum.PermissionDenied
+		return
+	}
+
+	err := ps.quotationService.SubmitQuote(req.QuotationID, supplierID, conv.ConvertFromQuoteItems(req.QuoteList))
+	if err != nil {
+		res.Code = enum.SqlError
+		res.Msg = err.Error()
+		return
+	}
+}
+
+func (ps *PurchaseServer) RequestAwardQuotation(ctx *gin.Context, rawReq interface{}, res *dto.Response) {
+	req := rawReq.(*dto.AwardQuotationReq)
+	custom := dto.GetCustomContextInfo(ctx)
+	if custom.Token == nil || isSupplierRole(ctx) {
+		res.Code = enum.PermissionDenied
+		return
+	}
+
+	awardMap := make(map[uint32]uint32)
+	for _, award := range req.AwardList {
+		awardMap[award.GoodsID] = award.SupplierID
+	}
+	err := ps.quotationService.AwardQuotation(req.QuotationID, custom.Token.AdminUid, awardMap)
+	if err != nil {
+		res.Code = enum.SqlError
+		res.Msg = err.Error()
+		return
+	}
+}
+
+func (ps *PurchaseServer) RequestCancelQuotation(ctx *gin.Context, rawReq interface{}, res *dto.Response) {
+	req := rawReq.(*dto.CancelQuotationReq)
+	if isSupplierRole(ctx) {
+		res.Code = enum.PermissionDenied
+		return
+	}
+
+	err := ps.quotationService.CancelQuotation(req.QuotationID)
+	if err != nil {
+		res.Code = enum.SqlError
+		res.Msg = err.Error()
+		return
+	}
+}
+
+func (ps *PurchaseServer) RequestGoodsPriceHistory(ctx *gin.Context, rawReq interface{}, res *dto.Response) {
+	req := rawReq.(*dto.GoodsPriceHistoryReq)
+	supplierMap, err := ps.purchaseService.GetSupplierMap()
+	if err != nil {
+		logger.Warn(purchaseServerLogTag, "GetSupplierMap Failed|Err:%v", err)
+		res.Code = enum.SystemError
+		return
+	}
+
+	historyList, totalNumber, err := ps.storeService.GetGoodsPriceHistory(req.GoodsID, req.StartTime, req.EndTime,
+		req.Page, req.PageSize)
+	if err != nil {
+		res.Code = enum.SqlError
+		res.Msg = err.Error()
+		return
+	}
+
+	res.Data = &dto.GoodsPriceHistoryRes{
+		History: conv.ConvertToGoodsPriceHistoryList(historyList, supplierMap),
+		PaginationRes: dto.PaginationRes{
+			Page:        req.Page,
+			PageSize:    req.PageSize,
+			TotalNumber: totalNumber,
+		},
+	}
+}
+
+func (ps *PurchaseServer) RequestStatementList(ctx *gin.Context, rawReq interface{}, res *dto.Response) {
+	req := rawReq.(*dto.StatementListReq)
+	supplierID, ok := ps.getSupplierID(ctx)
+	if !ok {
+		res.Code = enum.PermissionDenied
+		return
+	}
+	if supplierID == 0 {
+		supplierID = req.SupplierID
+	}
+	supplierMap, err := ps.purchaseService.GetSupplierMap()
+	if err != nil {
+		logger.Warn(purchaseServerLogTag, "GetSupplierMap Failed|Err:%v", err)
+		res.Code = enum.SystemError
+		return
+	}
+	adminMap, err := ps.userService.GetAdminMap()
+	if err != nil {
+		logger.Warn(purchaseServerLogTag, "GetAdminMap Failed|Err:%v", err)
+		res.Code = enum.SystemError
+		return
+	}
+
+	statementList, totalNumber, err := ps.payableService.GetStatementList(supplierID, req.Status, req.Page,
+		req.PageSize)
+	if err != nil {
+		res.Code = enum.SqlError
+		res.Msg = err.Error()
+		return
+	}
+	res.Data = &dto.StatementListRes{
+		StatementList: conv.ConvertToStatementList(statementList, supplierMap, adminMap),
+		PaginationRes: dto.PaginationRes{
+			Page:        req.Page,
+			PageSize:    req.PageSize,
+			TotalNumber: totalNumber,
+		},
+	}
+}
+
+func (ps *PurchaseServer) RequestStatementDetail(ctx *gin.Context, rawReq interface{}, res *dto.Response) {
+	req := rawReq.(*dto.StatementDetailReq)
+	supplierID, ok := ps.getSupplierID(ctx)
+	if !ok {
+		res.Code = enum.PermissionDenied
+		return
+	}
+	supplierMap, err := ps.purchaseService.GetSupplierMap()
+	if err != nil {
+		logger.Warn(purchaseServerLogTag, "GetSupplierMap Failed|Err:%v", err)
+		res.Code = enum.SystemError
+		return
+	}
+	adminMap, err := ps.userService.GetAdminMap()
+	if err != nil {
+		logger.Warn(purchaseServerLogTag, "GetAdminMap Failed|Err:%v", err)
+		res.Code = enum.SystemError
+		return
+	}
+
+	statement, orderList, paymentList, err := ps.payableService.GetStatementDetail(req.StatementID, supplierID)
+	if err != nil {
+		res.Code = enum.SqlError
+		res.Msg = err.Error()
+		return
+	}
+	res.Data = &dto.StatementDetailRes{
+		Statement:   conv.ConvertToStatementInfo(statement, supplierMap, adminMap),
+		OrderList:   conv.ConvertToStatementOrderList(orderList),
+		PaymentList: conv.ConvertToPaymentList(paymentList, adminMap),
+	}
+}
+
+func (ps *PurchaseServer) RequestGenerateStatement(ctx *gin.Context, rawReq interface{}, res *dto.Response) {
+	req := rawReq.(*dto.GenerateStatementReq)
+	custom := dto.GetCustomContextInfo(ctx)
+	if custom.Token == nil || isSupplierRole(ctx) {
+		res.Code = enum.PermissionDenied
+		return
+	}
+
+	statement, err := ps.payableService.GenerateStatement(req.SupplierID, custom.Token.AdminUid, req.StartTime, req.EndTime)
+	if err != nil {
+		res.Code = enum.SqlError
+		res.Msg = err.Error()
+		return
+	}
+	res.Data = &dto.GenerateStatementRes{StatementID: statement.ID}
+}
+
+func (ps *PurchaseServer) RequestConfirmStatement(ctx *gin.Context, rawReq interface{}, res *dto.Response) {
+	req := rawReq.(*dto.ConfirmStatementReq)
+	if !isSupplierRole(ctx) {
+		res.Code = enum.PermissionDenied
+		return
+	}
+	supplierID, ok := ps.getSupplierID(ctx)
+	if !ok {
+		res.Code = enum.PermissionDenied
+		return
+	}
+
+	err := ps.payableService.ConfirmStatement(req.StatementID, supplierID, req.Agree, req.Reason)
+	if err != nil {
+		res.Code = enum.SqlError
+		res.Msg = err.Error()
+		return
+	}
+}
+
+func (ps *PurchaseServer) RequestRevokeStatement(ctx *gin.Context, rawReq interface{}, res *dto.Response) {
+	req := rawReq.(*dto.RevokeStatementReq)
+	if isSupplierRole(ctx) {
+		res.Code = enum.PermissionDenied
+		return
+	}
+
+	err := ps.payableService.RevokeStatement(req.StatementID)
+	if err != nil {
+		res.Code = enum.SqlError
+		res.Msg = err.Error()
+		return
+	}
+}
+
+func (ps *PurchaseServer) RequestRecordPayment(ctx *gin.Context, rawReq interface{}, res *dto.Response) {
+	req := rawReq.(*dto.RecordPaymentReq)
+	custom := dto.GetCustomContextInfo(ctx)
+	if custom.Token == nil || isSupplierRole(ctx) {
+		res.Code = enum.PermissionDenied
+		return
+	}
+
+	payment := &model.SupplierPayment{StatementID: req.StatementID, Amount: req.Amount, Voucher: req.Voucher,
+		Remark: req.Remark, Operator: custom.Token.AdminUid}
+	if req.PayTime > 0 {
+		payment.PayAt = time.Unix(req.PayTime, 0)
+	}
+	err := ps.payableService.RecordPayment(payment)
+	if err != nil {
+		res.Code = enum.SqlError
+		res.Msg = err.Error()
+		return
+	}
+}
+
+func (ps *PurchaseServer) RequestPayableBalance(ctx *gin.Context, rawReq interface{}, res *dto.Response) {
+	if isSupplierRole(ctx) {
+		res.Code = enum.PermissionDenied
+		return
+	}
+	supplierMap, err := ps.purchaseService.GetSupplierMap()
+	if err != nil {
+		logger.Warn(purchaseServerLogTag, "GetSupplierMap Failed|Err:%v", err)
+		res.Code = enum.SystemError
+		return
+	}
+
+	balanceMap, err := ps.payableService.GetPayableBalance()
+	if err != nil {
+		res.Code = enum.SqlError
+		res.Msg = err.Error()
+		return
+	}
+	balanceList, totalOutstanding := conv.ConvertToPayableBalanceList(balanceMap, supplierMap)
+	res.Data = &dto.PayableBalanceRes{
+		BalanceList:      balanceList,
+		TotalOutstanding: totalOutstanding,
+	}
+}
+
+func (ps *PurchaseServer) RequestSupplierScorecard(ctx *gin.Context, rawReq interface{}, res *dto.Response) {
+	req := rawReq.(*dto.SupplierScorecardReq)
+	supplierID, ok := ps.getSupplierID(ctx)
+	if !ok {
+		res.Code = enum.PermissionDenied
+		return
+	}
+	if req.StartTime == 0 {
+		req.StartTime = utils.GetMonthStartTime(time.Now().Unix())
+	}
+	if req.EndTime <= req.StartTime {
+		req.EndTime = time.Now().Unix()
+	}
+	supplierMap, err := ps.purchaseService.GetSupplierMap()
+	if err != nil {
+		logger.Warn(purchaseServerLogTag, "GetSupplierMap Failed|Err:%v", err)
+		res.Code = enum.SystemError
+		return
+	}
+
+	scoreList, err := ps.scorecardService.GetScorecards(req.StartTime, req.EndTime, req.OnTimeHours)
+	if err != nil {
+		res.Code = enum.SqlError
+		res.Msg = err.Error()
+		return
+	}
+	if supplierID > 0 {
+		supplierScores := make([]*model.SupplierScore, 0, 1)
+		for _, score := range scoreList {
+			if score.SupplierID == supplierID {
+				supplierScores = append(supplierScores, score)
+			}
+		}
+		scoreList = supplierScores
+	}
+	res.Data = &dto.SupplierScorecardRes{
+		StartTime: req.StartTime,
+		EndTime:   req.EndTime,
+		ScoreList: conv.ConvertToSupplierScoreList(scoreList, supplierMap),
+	}
+}
+
+// getSupplierID 供应商身份只从登录态解析，供应商绑定的是微信用户，对应 token 中的 UID
+func (ps *PurchaseServer) getSupplierID(ctx *gin.Context) (uint32, bool) {
+	if !isSupplierRole(ctx) {
+		return 0, true
+	}
+	uid := dto.GetCustomContextInfo(ctx).Token.UID
+	supplier, err := ps.purchaseService.GetSupplierByUid(uid)
+	if err != nil || supplier == nil {
+		logger.Warn(purchaseServerLogTag, "Supplier Not Found|Uid:%v|Err:%v", uid, err)
+		return 0, false
+	}
+	return supplier.ID, true
+}
+
+func isSupplierRole(ctx *gin.Context) bool {
+	custom := dto.GetCustomContextInfo(ctx)
+	return custom.Token != nil && custom.Token.Role&(1<<enum.RoleSupplier) != 0
 }
@@ -1,6 +1,7 @@
 package server
 
 import (
+	"github.com/canteen_management/config"
 	"github.com/canteen_management/conv"
 	"github.com/canteen_management/dto"
 	"github.com/canteen_management/enum"
@@ -13,6 +14,8 @@ import (
 
 const (
 	storeServerLogTag = "StoreServer"
+
+	lotExpiryAlertDays = 7
 )
 
 type StorehouseServer struct {
@@ -30,6 +33,9 @@ func NewStorehouseServer(dbConf utils.Config) (*StorehouseServer, error) {
 	storeService := service.NewStoreService(sqlCli)
 	inventoryService := service.NewInventoryService(sqlCli)
 	userService := service.NewUserService(sqlCli)
+	storeService.SetCostMethod(config.Config.CostMethod)
+	inventoryService.SetCostMethod(config.Config.CostMethod)
+	inventoryService.SetWriteOffLimit(config.Config.WriteOffLimit)
 	return &StorehouseServer{
 		storeService:     storeService,
 		inventoryService: inventoryService,
@@ -120,12 +126,14 @@ func (ss *StorehouseServer) RequestModifyGoods(ctx *gin.Context, rawReq interfac
 		err := ss.storeService.AddGoods(conv.ConvertFromGoodsInfo(req.Goods))
 		if err != nil {
 			res.Code = enum.SqlError
+			res.Msg = err.Error()
 			return
 		}
 	case enum.OperateTypeModify:
 		err := ss.storeService.UpdateGoods(conv.ConvertFromGoodsInfo(req.Goods))
 		if err != nil {
 			res.Code = enum.SqlError
+			res.Msg = err.Error()
 			return
 		}
 	case enum.OperateTypeDel:
@@ -140,6 +148,52 @@ func (ss *StorehouseServer) RequestModifyGoods(ctx *gin.Context, rawReq interfac
 	}
 }
 
+func (ss *StorehouseServer) RequestGoodsUnitList(ctx *gin.Context, rawReq interface{}, res *dto.Response) {
+	req := rawReq.(*dto.GoodsUnitListReq)
+	unitList, err := ss.storeService.GetGoodsUnitList(req.GoodsID)
+	if err != nil {
+		res.Code = enum.SqlError
+		return
+	}
+	res.Data = &dto.GoodsUnitListRes{
+		UnitList: conv.ConvertToGoodsUnitList(unitList),
+	}
+}
+
+func (ss *StorehouseServer) RequestModifyGoodsUnit(ctx *gin.Context, rawReq interface{}, res *dto.Response) {
+	req := rawReq.(*dto.ModifyGoodsUnitReq)
+	if req.Unit == nil {
+		res.Code = enum.ParamsError
+		return
+	}
+	switch req.Operate {
+	case enum.OperateTypeAdd:
+		err := ss.storeService.AddGoodsUnit(conv.ConvertFromGoodsUnitInfo(req.Unit))
+		if err != nil {
+			res.Code = enum.SqlError
+			res.Msg = err.Error()
+			return
+		}
+	case enum.OperateTypeModify:
+		err := ss.storeService.UpdateGoodsUnit(conv.ConvertFromGoodsUnitInfo(req.Unit))
+		if err != nil {
+			res.Code = enum.SqlError
+			res.Msg = err.Error()
+			return
+		}
+	case enum.OperateTypeDel:
+		err := ss.storeService.DeleteGoodsUnit(req.Unit.UnitID)
+		if err != nil {
+			res.Code = enum.SqlError
+			res.Msg = err.Error()
+			return
+		}
+	default:
+		logger.Warn(storeServerLogTag, "RequestModifyGoodsUnit Unknown OperateType|Type:%v", req.Operate)
+		res.Code = enum.SystemError
+	}
+}
+
 func (ss *StorehouseServer) RequestGoodsPriceList(ctx *gin.Context, rawReq interface{}, res *dto.Response) {
 	req := rawReq.(*dto.GoodsPriceListReq)
 	goodsTypeMap, err := ss.storeService.GetGoodsTypeMap()
@@ -167,8 +221,13 @@ func (ss *StorehouseServer) RequestGoodsPriceList(ctx *gin.Context, rawReq inter
 
 func (ss *StorehouseServer) RequestModifyGoodsPrice(ctx *gin.Context, rawReq interface{}, res *dto.Response) {
 	req := rawReq.(*dto.ModifyGoodsPriceReq)
+	custom := dto.GetCustomContextInfo(ctx)
+	if custom.Token == nil {
+		res.Code = enum.PermissionDenied
+		return
+	}
 
-	err := ss.storeService.UpdateGoodsPrice(req.GoodsID, req.PriceMap)
+	err := ss.storeService.UpdateGoodsPrice(req.GoodsID, custom.Token.AdminUid, req.PriceMap)
 	if err != nil {
 		res.Code = enum.SqlError
 		res.Msg = err.Error()
@@ -303,6 +362,11 @@ func (ss *StorehouseServer) RequestInventoryOrderList(ctx *gin.Context, rawReq i
 
 func (ss *StorehouseServer) RequestStartInventory(ctx *gin.Context, rawReq interface{}, res *dto.Response) {
 	req := rawReq.(*dto.StartInventoryReq)
+	custom := dto.GetCustomContextInfo(ctx)
+	if custom.Token == nil {
+		res.Code = enum.PermissionDenied
+		return
+	}
 	goodsMap, err := ss.storeService.GetGoodsMap()
 	if err != nil {
 		logger.Warn(storeServerLogTag, "GetGoodsMap Failed|Err:%v", err)
@@ -318,7 +382,7 @@ func (ss *StorehouseServer) RequestStartInventory(ctx *gin.Context, rawReq inter
 
 	retData := &dto.StartInventoryRes{}
 	inventoryOrders, _, inventoryDetailMap, err := ss.inventoryService.InventoryOrderList(0,
-		req.Uid, 0, 0, 0, 1, 10)
+		custom.Token.AdminUid, 0, 0, 0, 1, 10)
 	if err != nil {
 		logger.Warn(storeServerLogTag, "StartInventory GetOrder Failed|Err:%v", err)
 		res.Code = enum.SqlError
@@ -330,7 +394,9 @@ func (ss *StorehouseServer) RequestStartInventory(ctx *gin.Context, rawReq inter
 			inventoryDetailMap[inventoryOrders[0].ID], goodsMap, goodsTypeMap)
 	}
 	if req.New && len(inventoryOrders) == 0 {
-		inventoryOrder, detailList, err := ss.inventoryService.StartInventory(req.Uid)
+		inventoryOrder := &model.InventoryOrder{Creator: custom.Token.AdminUid, GoodsTypeID: req.GoodsTypeID,
+			StoreTypeID: req.StoreTypeID, AbcClass: req.AbcClass}
+		detailList, err := ss.inventoryService.StartInventory(inventoryOrder)
 		if err != nil {
 			logger.Warn(storeServerLogTag, "StartInventory Failed|Err:%v", err)
 			res.Code = enum.SqlError
@@ -345,9 +411,24 @@ func (ss *StorehouseServer) RequestStartInventory(ctx *gin.Context, rawReq inter
 
 func (ss *StorehouseServer) RequestInventory(ctx *gin.Context, rawReq interface{}, res *dto.Response) {
 	req := rawReq.(*dto.InventoryReq)
+	if req.InventoryGoodsInfo == nil {
+		res.Code = enum.ParamsError
+		return
+	}
+	unitMap, err := ss.storeService.GetGoodsUnitMap()
+	if err != nil {
+		res.Code = enum.SqlError
+		return
+	}
+	err = conv.NormalizeInventoryGoodsUnit(unitMap, req.InventoryGoodsInfo)
+	if err != nil {
+		res.Code = enum.ParamsError
+		res.Msg = err.Error()
+		return
+	}
 
 	inventoryDetail := conv.ConvertFromApplyInventory(req.InventoryGoodsInfo)
-	err := ss.inventoryService.UpdateInventory(inventoryDetail)
+	err = ss.inventoryService.UpdateInventory(inventoryDetail)
 	if err != nil {
 		logger.Warn(storeServerLogTag, "UpdateInventory Failed|Err:%v", err)
 		res.Code = enum.SqlError
@@ -359,7 +440,7 @@ func (ss *StorehouseServer) RequestInventory(ctx *gin.Context, rawReq interface{
 func (ss *StorehouseServer) RequestApplyInventory(ctx *gin.Context, rawReq interface{}, res *dto.Response) {
 	req := rawReq.(*dto.ApplyInventoryReq)
 
-	err := ss.inventoryService.ApplyInventory(req.InventoryID)
+	err := ss.inventoryService.ApplyInventory(req.InventoryID, req.SkipUncounted)
 	if err != nil {
 		logger.Warn(storeServerLogTag, "ApplyInventory Failed|Err:%v", err)
 		res.Code = enum.SqlError
@@ -370,8 +451,13 @@ func (ss *StorehouseServer) RequestApplyInventory(ctx *gin.Context, rawReq inter
 
 func (ss *StorehouseServer) RequestConfirmInventory(ctx *gin.Context, rawReq interface{}, res *dto.Response) {
 	req := rawReq.(*dto.ConfirmInventoryReq)
+	custom := dto.GetCustomContextInfo(ctx)
+	if custom.Token == nil {
+		res.Code = enum.PermissionDenied
+		return
+	}
 
-	err := ss.inventoryService.ConfirmInventory(req.InventoryID, req.Uid)
+	err := ss.inventoryService.ConfirmInventory(req.InventoryID, custom.Token.AdminUid)
 	if err != nil {
 		logger.Warn(storeServerLogTag, "ConfirmInventory Failed|Err:%v", err)
 		res.Code = enum.SqlError
@@ -380,6 +466,41 @@ func (ss *StorehouseServer) RequestConfirmInventory(ctx *gin.Context, rawReq int
 	}
 }
 
+func (ss *StorehouseServer) RequestApproveWriteOff(ctx *gin.Context, rawReq interface{}, res *dto.Response) {
+	req := rawReq.(*dto.ApproveWriteOffReq)
+	custom := dto.GetCustomContextInfo(ctx)
+	if custom.Token == nil || custom.Token.Role&(1<<enum.RoleReviewer) == 0 {
+		logger.Warn(storeServerLogTag, "RequestApproveWriteOff Permission Denied|InventoryID:%v", req.InventoryID)
+		res.Code = enum.PermissionDenied
+		return
+	}
+
+	err := ss.inventoryService.ApproveWriteOff(req.InventoryID, custom.Token.AdminUid)
+	if err != nil {
+		logger.Warn(storeServerLogTag, "ApproveWriteOff Failed|Err:%v", err)
+		res.Code = enum.SqlError
+		res.Msg = err.Error()
+		return
+	}
+}
+
+func (ss *StorehouseServer) RequestInventoryVariance(ctx *gin.Context, rawReq interface{}, res *dto.Response) {
+	req := rawReq.(*dto.InventoryVarianceReq)
+	goodsTypeMap, err := ss.storeService.GetGoodsTypeMap()
+	if err != nil {
+		res.Code = enum.SqlError
+		res.Msg = err.Error()
+		return
+	}
+	details, err := ss.inventoryService.GetVarianceDetails(req.GoodsTypeID, req.StartTime, req.EndTime)
+	if err != nil {
+		res.Code = enum.SqlError
+		res.Msg = err.Error()
+		return
+	}
+	res.Data = conv.ConvertToVarianceReport(details, goodsTypeMap)
+}
+
 func (ss *StorehouseServer) RequestReviewInventory(ctx *gin.Context, rawReq interface{}, res *dto.Response) {
 	req := rawReq.(*dto.ReviewInventoryReq)
 	err := ss.inventoryService.ReviewInventory(req.InventoryID)
@@ -391,6 +512,57 @@ func (ss *StorehouseServer) RequestReviewInventory(ctx *gin.Context, rawReq inte
 	}
 }
 
+func (ss *StorehouseServer) RequestCancelInventory(ctx *gin.Context, rawReq interface{}, res *dto.Response) {
+	req := rawReq.(*dto.CancelInventoryReq)
+	custom := dto.GetCustomContextInfo(ctx)
+	if custom.Token == nil {
+		res.Code = enum.PermissionDenied
+		return
+	}
+
+	err := ss.inventoryService.CancelInventoryOrder(req.InventoryID, custom.Token.AdminUid, req.Reason)
+	if err != nil {
+		logger.Warn(storeServerLogTag, "CancelInventory Failed|Err:%v", err)
+		res.Code = enum.SqlError
+		res.Msg = err.Error()
+		return
+	}
+}
+
+func (ss *StorehouseServer) RequestReverseInventory(ctx *gin.Context, rawReq interface{}, res *dto.Response) {
+	req := rawReq.(*dto.ReverseInventoryReq)
+	custom := dto.GetCustomContextInfo(ctx)
+	if custom.Token == nil {
+		res.Code = enum.PermissionDenied
+		return
+	}
+
+	err := ss.inventoryService.ReverseInventoryOrder(req.InventoryID, custom.Token.AdminUid, req.Reason)
+	if err != nil {
+		logger.Warn(storeServerLogTag, "ReverseInventory Failed|Err:%v", err)
+		res.Code = enum.SqlError
+		res.Msg = err.Error()
+		return
+	}
+}
+
+func (ss *StorehouseServer) RequestDocumentLogList(ctx *gin.Context, rawReq interface{}, res *dto.Response) {
+	req := rawReq.(*dto.DocumentLogListReq)
+	adminMap, err := ss.userService.GetAdminMap()
+	if err != nil {
+		logger.Warn(storeServerLogTag, "GetAdminMap Failed|Err:%v", err)
+		res.Code = enum.SystemError
+		return
+	}
+	logList, err := ss.storeService.GetDocumentLogList(req.DocType, req.DocID)
+	if err != nil {
+		res.Code = enum.SqlError
+		res.Msg = err.Error()
+		return
+	}
+	res.Data = &dto.DocumentLogListRes{LogList: conv.ConvertToDocumentLogList(logList, adminMap)}
+}
+
 func (ss *StorehouseServer) RequestGoodsHistory(ctx *gin.Context, rawReq interface{}, res *dto.Response) {
 	req := rawReq.(*dto.GoodsHistoryReq)
 	history, count, err := ss.storeService.GetGoodsHistoryList(req.GoodsID, req.ChangeType, req.StartTime, req.EndTime,
@@ -412,3 +584,243 @@ func (ss *StorehouseServer) RequestGoodsHistory(ctx *gin.Context, rawReq interfa
 	}
 	res.Data = retData
 }
+
+func (ss *StorehouseServer) RequestExpiringLot(ctx *gin.Context, rawReq interface{}, res *dto.Response) {
+	req := rawReq.(*dto.ExpiringLotReq)
+	if req.Days <= 0 {
+		req.Days = lotExpiryAlertDays
+	}
+	goodsMap, err := ss.storeService.GetGoodsMap()
+	if err != nil {
+		res.Code = enum.SystemError
+		res.Msg = err.Error()
+		return
+	}
+	lotList, err := ss.storeService.GetExpiringLots(req.GoodsID, req.Days)
+	if err != nil {
+		logger.Warn(storeServerLogTag, "RequestExpiringLot Failed|Err:%v", err)
+		res.Code = enum.SqlError
+		return
+	}
+	res.Data = &dto.ExpiringLotRes{
+		LotList: conv.ConvertToStockLotList(lotList, goodsMap),
+	}
+}
+
+func (ss *StorehouseServer) RequestGoodsStockList(ctx *gin.Context, rawReq interface{}, res *dto.Response) {
+	req := rawReq.(*dto.GoodsStockListReq)
+	goodsMap, err := ss.storeService.GetGoodsMap()
+	if err != nil {
+		res.Code = enum.SystemError
+		res.Msg = err.Error()
+		return
+	}
+	storeTypeMap, err := ss.storeService.GetStoreTypeMap()
+	if err != nil {
+		res.Code = enum.SystemError
+		res.Msg = err.Error()
+		return
+	}
+	stockList, err := ss.storeService.GetGoodsStockList(req.GoodsID, req.StoreTypeID)
+	if err != nil {
+		logger.Warn(storeServerLogTag, "RequestGoodsStockList Failed|Err:%v", err)
+		res.Code = enum.SqlError
+		return
+	}
+	res.Data = &dto.GoodsStockListRes{
+		StockList: conv.ConvertToGoodsStockList(stockList, goodsMap, storeTypeMap),
+	}
+}
+
+func (ss *StorehouseServer) RequestTransferGoods(ctx *gin.Context, rawReq interface{}, res *dto.Response) {
+	req := rawReq.(*dto.TransferGoodsReq)
+	custom := dto.GetCustomContextInfo(ctx)
+	if custom.Token == nil {
+		res.Code = enum.PermissionDenied
+		return
+	}
+
+	transfer := &model.TransferOrder{FromStore: req.FromStore, ToStore: req.ToStore, Creator: custom.Token.AdminUid,
+		Remark: req.Remark}
+	err := ss.storeService.TransferGoods(transfer, conv.ConvertFromTransferGoods(req.GoodsList))
+	if err != nil {
+		res.Code = enum.SqlError
+		res.Msg = err.Error()
+		return
+	}
+}
+
+func (ss *StorehouseServer) RequestTransferList(ctx *gin.Context, rawReq interface{}, res *dto.Response) {
+	req := rawReq.(*dto.TransferListReq)
+	goodsMap, err := ss.storeService.GetGoodsMap()
+	if err != nil {
+		res.Code = enum.SystemError
+		res.Msg = err.Error()
+		return
+	}
+	storeTypeMap, err := ss.storeService.GetStoreTypeMap()
+	if err != nil {
+		res.Code = enum.SystemError
+		res.Msg = err.Error()
+		return
+	}
+	adminMap, err := ss.userService.GetAdminMap()
+	if err != nil {
+		logger.Warn(storeServerLogTag, "GetAdminMap Failed|Err:%v", err)
+		res.Code = enum.SystemError
+		return
+	}
+
+	transferList, count, err := ss.storeService.GetTransferList(req.StoreTypeID, req.StartTime, req.EndTime,
+		req.Page, req.PageSize)
+	if err != nil {
+		res.Code = enum.SqlError
+		res.Msg = err.Error()
+		return
+	}
+	res.Data = &dto.TransferListRes{
+		TransferList: conv.ConvertToTransferList(transferList, goodsMap, storeTypeMap, adminMap),
+		PaginationRes: dto.PaginationRes{
+			Page:        req.Page,
+			PageSize:    req.PageSize,
+			TotalNumber: count,
+		},
+	}
+}
+
+func (ss *StorehouseServer) RequestStockAlertList(ctx *gin.Context, rawReq interface{}, res *dto.Response) {
+	req := rawReq.(*dto.StockAlertListReq)
+	goodsMap, err := ss.storeService.GetGoodsMap()
+	if err != nil {
+		res.Code = enum.SystemError
+		res.Msg = err.Error()
+		return
+	}
+	alertList, err := ss.storeService.GetStockAlertList(req.GoodsID, req.Status)
+	if err != nil {
+		res.Code = enum.SqlError
+		return
+	}
+	res.Data = &dto.StockAlertListRes{
+		AlertList: conv.ConvertToStockAlertList(alertList, goodsMap),
+	}
+}
+
+func (ss *StorehouseServer) RequestStockValuation(ctx *gin.Context, rawReq interface{}, res *dto.Response) {
+	req := rawReq.(*dto.StockValuationReq)
+	goodsList, err := ss.storeService.GetStockValuation(req.Date, req.GoodsTypeID)
+	if err != nil {
+		res.Code = enum.SqlError
+		res.Msg = err.Error()
+		return
+	}
+	list, totalValue := conv.ConvertToStockValuationList(goodsList)
+	res.Data = &dto.StockValuationRes{
+		Date:       req.Date,
+		TotalValue: totalValue,
+		GoodsList:  list,
+	}
+}
+
+func (ss *StorehouseServer) RequestCycleCountPlanList(ctx *gin.Context, rawReq interface{}, res *dto.Response) {
+	planList, err := ss.inventoryService.GetCycleCountPlanList()
+	if err != nil {
+		res.Code = enum.SqlError
+		res.Msg = err.Error()
+		return
+	}
+	res.Data = &dto.CycleCountPlanListRes{
+		PlanList: conv.ConvertToCycleCountPlanList(planList),
+	}
+}
+
+func (ss *StorehouseServer) RequestModifyCycleCountPlan(ctx *gin.Context, rawReq interface{}, res *dto.Response) {
+	req := rawReq.(*dto.ModifyCycleCountPlanReq)
+	if req.Plan == nil {
+		res.Code = enum.ParamsError
+		return
+	}
+	switch req.Operate {
+	case enum.OperateTypeAdd:
+		err := ss.inventoryService.AddCycleCountPlan(conv.ConvertFromCycleCountPlanInfo(req.Plan))
+		if err != nil {
+			res.Code = enum.SqlError
+			res.Msg = err.Error()
+			return
+		}
+	case enum.OperateTypeModify:
+		err := ss.inventoryService.UpdateCycleCountPlan(conv.ConvertFromCycleCountPlanInfo(req.Plan))
+		if err != nil {
+			res.Code = enum.SqlError
+			res.Msg = err.Error()
+			return
+		}
+	case enum.OperateTypeDel:
+		err := ss.inventoryService.DeleteCycleCountPlan(req.Plan.PlanID)
+		if err != nil {
+			res.Code = enum.SqlError
+			res.Msg = err.Error()
+			return
+		}
+	default:
+		logger.Warn(storeServerLogTag, "RequestModifyCycleCountPlan Unknown OperateType|Type:%v", req.Operate)
+		res.Code = enum.SystemError
+	}
+}
+
+func (ss *StorehouseServer) RequestScanCode(ctx *gin.Context, rawReq interface{}, res *dto.Response) {
+	req := rawReq.(*dto.ScanCodeReq)
+	goods, lot, err := ss.storeService.ResolveCode(req.Code)
+	if err != nil {
+		res.Code = enum.ParamsError
+		res.Msg = err.Error()
+		return
+	}
+	goodsMap := map[uint32]*model.Goods{goods.ID: goods}
+	retData := &dto.ScanCodeRes{
+		Goods: conv.ConvertToGoodsInfoList([]*model.Goods{goods})[0],
+	}
+	if lot != nil {
+		retData.Lot = conv.ConvertToStockLotList([]*model.StockLot{lot}, goodsMap)[0]
+	}
+	res.Data = retData
+}
+
+func (ss *StorehouseServer) RequestScanInventory(ctx *gin.Context, rawReq interface{}, res *dto.Response) {
+	req := rawReq.(*dto.ScanInventoryReq)
+	goods, lot, err := ss.storeService.ResolveCode(req.Code)
+	if err != nil {
+		res.Code = enum.ParamsError
+		res.Msg = err.Error()
+		return
+	}
+	lotID := uint32(0)
+	if lot != nil {
+		lotID = lot.ID
+	}
+	if req.Unit != "" {
+		unitMap, err := ss.storeService.GetGoodsUnitMap()
+		if err != nil {
+			res.Code = enum.SqlError
+			return
+		}
+		if req.Number == 0 {
+			req.Number = 1
+		}
+		req.Number, err = conv.ConvertToStockQuantity(unitMap, goods.ID, req.Unit, req.Number)
+		if err != nil {
+			res.Code = enum.ParamsError
+			res.Msg = err.Error()
+			return
+		}
+	}
+	detail, err := ss.inventoryService.ScanInventory(req.InventoryID, goods.ID, lotID, req.StoreTypeID, req.Number)
+	if err != nil {
+		res.Code = enum.SqlError
+		res.Msg = err.Error()
+		return
+	}
+	res.Data = &dto.ScanInventoryRes{
+		InventoryGoods: conv.ConvertToScanInventoryNode(detail, goods),
+	}
+}
@@ -1 +1,61 @@
 package server
+
+import (
+	"time"
+
+	"github.com/canteen_management/logger"
+	"github.com/canteen_management/service"
+	"github.com/canteen_management/utils"
+)
+
+const (
+	tickerServerLogTag = "TickerServer"
+
+	stockCheckInterval = time.Hour
+	cycleCountInterval = time.Hour
+)
+
+type TickerServer struct {
+	storeService     *service.StoreService
+	inventoryService *service.InventoryService
+}
+
+func NewTickerServer(dbConf utils.Config) (*TickerServer, error) {
+	sqlCli, err := utils.NewMysqlClient(dbConf)
+	if err != nil {
+		logger.Warn(tickerServerLogTag, "NewTickerServer Failed|Err:%v", err)
+		return nil, err
+	}
+	return &TickerServer{
+		storeService:     service.NewStoreService(sqlCli),
+		inventoryService: service.NewInventoryService(sqlCli),
+	}, nil
+}
+
+func (ts *TickerServer) Start() {
+	go ts.run(stockCheckInterval, ts.checkStockAlert)
+	go ts.run(cycleCountInterval, ts.startCycleCount)
+}
+
+func (ts *TickerServer) run(interval time.Duration, task func()) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	task()
+	for range ticker.C {
+		task()
+	}
+}
+
+func (ts *TickerServer) checkStockAlert() {
+	err := ts.storeService.CheckStockAlert()
+	if err != nil {
+		logger.Warn(tickerServerLogTag, "CheckStockAlert Failed|Err:%v", err)
+	}
+}
+
+func (ts *TickerServer) startCycleCount() {
+	err := ts.inventoryService.StartDueCycleCount()
+	if err != nil {
+		logger.Warn(tickerServerLogTag, "StartDueCycleCount Failed|Err:%v", err)
+	}
+}
@@ -3,7 +3,10 @@ package service
 import (
 	"database/sql"
 	"fmt"
+	"math"
 	"math/rand"
+	"sort"
+	"time"
 
 	"github.com/canteen_management/logger"
 	"github.com/canteen_management/model"
@@ -11,19 +14,30 @@ import (
 
 const (
 	dishServiceLogTag = "DishService"
+
+	defaultDishScore = 3.0
 )
 
 type DishService struct {
 	dishModel     *model.DishesModel
 	dishTypeModel *model.DishTypeModel
+	goodsModel    *model.GoodsModel
+	ratingModel   *model.DishRatingModel
+	unitModel     *model.GoodsUnitModel
 }
 
 func NewDishService(sqlCli *sql.DB) *DishService {
 	dishModel := model.NewDishesModelWithDB(sqlCli)
 	dishTypeModel := model.NewDishTypeModelWithDB(sqlCli)
+	goodsModel := model.NewGoodsModelWithDB(sqlCli)
+	ratingModel := model.NewDishRatingModelWithDB(sqlCli)
+	unitModel := model.NewGoodsUnitModelWithDB(sqlCli)
 	return &DishService{
 		dishModel:     dishModel,
 		dishTypeModel: dishTypeModel,
+		goodsModel:    goodsModel,
+		ratingModel:   ratingModel,
+		unitModel:     unitModel,
 	}
 }
 
@@ -32,7 +46,7 @@ func (ds *DishService) Init() error {
 }
 
 func (ds *DishService) GetDishIDMap() (map[uint32]*model.Dish, error) {
-	dishList, err := ds.dishModel.GetDishes(0, 0, 100000)
+	dishList, err := ds.getDishes(0, true, 0, 100000)
 	if err != nil {
 		logger.Warn(dishServiceLogTag, "GetDishIDMap GetDishes Failed|Err:%v", err)
 		return nil, err
@@ -44,8 +58,73 @@ func (ds *DishService) GetDishIDMap() (map[uint32]*model.Dish, error) {
 	return idMap, nil
 }
 
-func (ds *DishService) GetDishMap() map[uint32][]*model.Dish {
-	dishList, err := ds.dishModel.GetDishes(0, 0, 100000)
+// getDishes 查询菜品并以配方推算值补全营养与过敏原，仅用于点餐与菜单展示，结果不可回写
+func (ds *DishService) getDishes(dishType uint32, includeDelete bool, page, pageSize int32) ([]*model.Dish, error) {
+	dishList, err := ds.dishModel.GetDishes(dishType, includeDelete, page, pageSize)
+	if err != nil {
+		return nil, err
+	}
+	recipeMap, err := ds.getRecipeNutritionMap(dishList)
+	if err != nil {
+		return nil, err
+	}
+	for _, dish := range dishList {
+		recipeNutrition, ok := recipeMap[dish.ID]
+		if !ok {
+			continue
+		}
+		dish.Allergen |= recipeNutrition.Allergen
+		if dish.ToNutrition().IsEmpty() {
+			dish.FromNutrition(recipeNutrition.Nutrition)
+		}
+	}
+	return dishList, nil
+}
+
+func (ds *DishService) getRecipeNutritionMap(dishList []*model.Dish) (map[uint32]*model.RecipeNutrition, error) {
+	recipeMap := make(map[uint32]*model.RecipeNutrition)
+	needGoods := false
+	for _, dish := range dishList {
+		if dish.Recipe != "" {
+			needGoods = true
+			break
+		}
+	}
+	if !needGoods {
+		return recipeMap, nil
+	}
+
+	goodsList, err := ds.goodsModel.GetAllGoods()
+	if err != nil {
+		logger.Warn(dishServiceLogTag, "GetRecipeNutritionMap GetAllGoods Failed|Err:%v", err)
+		return nil, err
+	}
+	goodsMap := make(map[uint32]*model.Goods)
+	for _, goods := range goodsList {
+		goodsMap[goods.ID] = goods
+	}
+
+	for _, dish := range dishList {
+		recipe := dish.ToRecipe()
+		if len(recipe) == 0 {
+			continue
+		}
+		recipeNutrition := &model.RecipeNutrition{Nutrition: &model.Nutrition{}}
+		for goodsID, quantity := range recipe {
+			goods, ok := goodsMap[goodsID]
+			if !ok {
+				continue
+			}
+			recipeNutrition.Allergen |= goods.Allergen
+			recipeNutrition.Nutrition.Add(goods.ToNutrition(), quantity)
+		}
+		recipeMap[dish.ID] = recipeNutrition
+	}
+	return recipeMap, nil
+}
+
+func (ds *DishService) GetDishMap(menuDate time.Time) map[uint32][]*model.Dish {
+	dishList, err := ds.getDishes(0, false, 0, 100000)
 	if err != nil {
 		logger.Warn(dishServiceLogTag, "GetDishMap GetDishes Failed|Err:%v", err)
 		return nil
@@ -53,6 +132,9 @@ func (ds *DishService) GetDishMap() map[uint32][]*model.Dish {
 
 	typeMap := make(map[uint32][]*model.Dish)
 	for _, dish := range dishList {
+		if !dish.IsAvailable(menuDate) {
+			continue
+		}
 		_, ok := typeMap[dish.DishType]
 		if ok == false {
 			typeMap[dish.DishType] = make([]*model.Dish, 0)
@@ -62,19 +144,38 @@ func (ds *DishService) GetDishMap() map[uint32][]*model.Dish {
 	return typeMap
 }
 
-func (ds *DishService) GetDishList(dishType uint32, page, pageSize int32) ([]*model.Dish, int32, error) {
-	dishList, err := ds.dishModel.GetDishes(dishType, page, pageSize)
+func (ds *DishService) GetDishNameMap() (map[string]*model.Dish, error) {
+	dishList, err := ds.getDishes(0, false, 0, 100000)
+	if err != nil {
+		logger.Warn(dishServiceLogTag, "GetDishNameMap GetDishes Failed|Err:%v", err)
+		return nil, err
+	}
+	nameMap := make(map[string]*model.Dish, len(dishList))
+	for _, dish := range dishList {
+		nameMap[dish.DishName] = dish
+	}
+	return nameMap, nil
+}
+
+// GetDishList 管理端菜品列表，菜品保留录入值，配方推算值单独返回，避免编辑时被当作录入值回写
+func (ds *DishService) GetDishList(dishType uint32, page, pageSize int32) ([]*model.Dish,
+	map[uint32]*model.RecipeNutrition, int32, error) {
+	dishList, err := ds.dishModel.GetDishes(dishType, false, page, pageSize)
 	if err != nil {
 		logger.Warn(dishServiceLogTag, "GetDishList Failed|Err:%v", err)
-		return nil, 0, err
+		return nil, nil, 0, err
+	}
+	recipeMap, err := ds.getRecipeNutritionMap(dishList)
+	if err != nil {
+		return nil, nil, 0, err
 	}
 
 	dishCount, err := ds.dishModel.GetDishesCount(dishType)
 	if err != nil {
 		logger.Warn(dishServiceLogTag, "GetDishesCount Failed|Err:%v", err)
-		return nil, 0, err
+		return nil, nil, 0, err
 	}
-	return dishList, dishCount, nil
+	return dishList, recipeMap, dishCount, nil
 }
 
 func (ds *DishService) AddDish(dish *model.Dish) error {
@@ -150,6 +251,20 @@ func (ds *DishService) DeleteDish(dishID uint32) error {
 	return nil
 }
 
+func (ds *DishService) GetGoodsUnitMap() (model.GoodsUnitMap, error) {
+	goodsList, err := ds.goodsModel.GetAllGoods()
+	if err != nil {
+		logger.Warn(dishServiceLogTag, "GetGoodsUnitMap GetAllGoods Failed|Err:%v", err)
+		return nil, err
+	}
+	unitMap, err := ds.unitModel.GetUnitMap(goodsList)
+	if err != nil {
+		logger.Warn(dishServiceLogTag, "GetGoodsUnitMap Failed|Err:%v", err)
+		return nil, err
+	}
+	return unitMap, nil
+}
+
 func (ds *DishService) GetDishTypeMap() (map[uint32]*model.DishType, error) {
 	dishTypeList, err := ds.dishTypeModel.GetDishTypes()
 	if err != nil {
@@ -224,8 +339,8 @@ func (ds *DishService) DeleteDishType(dishTypeID uint32) error {
 	return nil
 }
 
-func (ds *DishService) RandDishByType(typeID uint32, number int) []*model.Dish {
-	dishTypeMap := ds.GetDishMap()
+func (ds *DishService) RandDishByType(typeID uint32, number int, menuDate time.Time) []*model.Dish {
+	dishTypeMap := ds.GetDishMap(menuDate)
 	if dishTypeMap == nil {
 		return nil
 	}
@@ -252,8 +367,8 @@ func (ds *DishService) RandDishByType(typeID uint32, number int) []*model.Dish {
 	return retList
 }
 
-func (ds *DishService) RandDishIDByType(typeID uint32, number int) []uint32 {
-	dishTypeMap := ds.GetDishMap()
+func (ds *DishService) RandDishIDByType(typeID uint32, number int, menuDate time.Time) []uint32 {
+	dishTypeMap := ds.GetDishMap(menuDate)
 	if dishTypeMap == nil {
 		return nil
 	}
@@ -279,3 +394,55 @@ func (ds *DishService) RandDishIDByType(typeID uint32, number int) []uint32 {
 
 	return retList
 }
+
+func (ds *DishService) GetDishScoreMap(startTime, endTime int64) (map[uint32]float64, error) {
+	summaryList, err := ds.ratingModel.GetRatingSummary(0, startTime, endTime)
+	if err != nil {
+		logger.Warn(dishServiceLogTag, "GetDishScoreMap Failed|Err:%v", err)
+		return nil, err
+	}
+	scoreMap := make(map[uint32]float64, len(summaryList))
+	for _, summary := range summaryList {
+		scoreMap[summary.DishID] = summary.AverageScore
+	}
+	return scoreMap, nil
+}
+
+func (ds *DishService) RandDishByTypeWithScore(typeID uint32, number int, scoreMap map[uint32]float64,
+	menuDate time.Time) []*model.Dish {
+	dishTypeMap := ds.GetDishMap(menuDate)
+	if dishTypeMap == nil {
+		return nil
+	}
+	dishList, ok := dishTypeMap[typeID]
+	if ok == false {
+		return nil
+	}
+
+	dishLen := len(dishList)
+	retList := make([]*model.Dish, 0)
+	for number > 0 {
+		keyList := make([]float64, dishLen)
+		orderList := make([]int, dishLen)
+		for index, dish := range dishList {
+			score, ok := scoreMap[dish.ID]
+			if !ok || score <= 0 {
+				score = defaultDishScore
+			}
+			keyList[index] = math.Pow(rand.Float64(), 1/score)
+			orderList[index] = index
+		}
+		sort.Slice(orderList, func(i, j int) bool {
+			return keyList[orderList[i]] > keyList[orderList[j]]
+		})
+		for _, index := range orderList {
+			retList = append(retList, dishList[index])
+			number--
+			if number <= 0 {
+				break
+			}
+		}
+	}
+
+	return retList
+}
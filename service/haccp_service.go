@@ -0,0 +1,316 @@
+package service
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/canteen_management/enum"
+	"github.com/canteen_management/logger"
+	"github.com/canteen_management/model"
+	"github.com/canteen_management/utils"
+)
+
+const (
+	haccpServiceLogTag = "HaccpService"
+
+	haccpIngestMaxSkew = 5 * time.Minute
+)
+
+type HaccpService struct {
+	sqlCli         *sql.DB
+	pointModel     *model.HaccpPointModel
+	readingModel   *model.HaccpReadingModel
+	storeTypeModel *model.StorehouseTypeModel
+	dishTypeModel  *model.DishTypeModel
+}
+
+func NewHaccpService(sqlCli *sql.DB) *HaccpService {
+	pointModel := model.NewHaccpPointModelWithDB(sqlCli)
+	readingModel := model.NewHaccpReadingModelWithDB(sqlCli)
+	storeTypeModel := model.NewStorehouseTypeModelWithDB(sqlCli)
+	dishTypeModel := model.NewDishTypeModelWithDB(sqlCli)
+	return &HaccpService{
+		sqlCli:         sqlCli,
+		pointModel:     pointModel,
+		readingModel:   readingModel,
+		storeTypeModel: storeTypeModel,
+		dishTypeModel:  dishTypeModel,
+	}
+}
+
+func (hs *HaccpService) GetStoreTypeMap() (map[uint32]*model.StorehouseType, error) {
+	typeList, err := hs.storeTypeModel.GetStorehouseTypes()
+	if err != nil {
+		logger.Warn(haccpServiceLogTag, "GetStoreTypeMap Failed|Err:%v", err)
+		return nil, err
+	}
+	typeMap := make(map[uint32]*model.StorehouseType)
+	for _, storeType := range typeList {
+		typeMap[storeType.ID] = storeType
+	}
+	return typeMap, nil
+}
+
+func (hs *HaccpService) GetDishTypeMap() (map[uint32]*model.DishType, error) {
+	typeList, err := hs.dishTypeModel.GetDishTypes()
+	if err != nil {
+		logger.Warn(haccpServiceLogTag, "GetDishTypeMap Failed|Err:%v", err)
+		return nil, err
+	}
+	typeMap := make(map[uint32]*model.DishType)
+	for _, dishType := range typeList {
+		typeMap[dishType.ID] = dishType
+	}
+	return typeMap, nil
+}
+
+func (hs *HaccpService) GetPointMap() (map[uint32]*model.HaccpPoint, error) {
+	pointList, err := hs.pointModel.GetPointList(0)
+	if err != nil {
+		return nil, err
+	}
+	pointMap := make(map[uint32]*model.HaccpPoint)
+	for _, point := range pointList {
+		pointMap[point.ID] = point
+	}
+	return pointMap, nil
+}
+
+func (hs *HaccpService) checkPoint(point *model.HaccpPoint) error {
+	if point.Name == "" {
+		return fmt.Errorf("请填写控制点名称")
+	}
+	if point.MinValue > point.MaxValue {
+		return fmt.Errorf("下限不能大于上限")
+	}
+	switch point.PointType {
+	case enum.HaccpPointStorage:
+		typeMap, err := hs.GetStoreTypeMap()
+		if err != nil {
+			return err
+		}
+		if _, ok := typeMap[point.StoreTypeID]; !ok {
+			return fmt.Errorf("库房不存在")
+		}
+		point.DishTypeID = 0
+	case enum.HaccpPointCooking:
+		typeMap, err := hs.GetDishTypeMap()
+		if err != nil {
+			return err
+		}
+		if _, ok := typeMap[point.DishTypeID]; !ok {
+			return fmt.Errorf("菜品类别不存在")
+		}
+		point.StoreTypeID = 0
+	default:
+		return fmt.Errorf("控制点类型错误")
+	}
+	point.DeviceKey = strings.TrimSpace(point.DeviceKey)
+	if point.DeviceKey != "" {
+		exist, err := hs.pointModel.GetPointByDeviceKey(point.DeviceKey)
+		if err != nil {
+			return err
+		}
+		if exist != nil && exist.ID != point.ID {
+			return fmt.Errorf("设备编号已被%v使用", exist.Name)
+		}
+	}
+	return nil
+}
+
+func (hs *HaccpService) GetPointList(pointType uint8) ([]*model.HaccpPoint, error) {
+	return hs.pointModel.GetPointList(pointType)
+}
+
+// AddPoint 新增控制点，绑定设备时生成上报密钥并返回
+func (hs *HaccpService) AddPoint(point *model.HaccpPoint) (string, error) {
+	point.ID, point.DeviceSecret = 0, ""
+	err := hs.checkPoint(point)
+	if err != nil {
+		return "", err
+	}
+	if point.DeviceKey != "" {
+		point.DeviceSecret, err = utils.GenerateSecret()
+		if err != nil {
+			logger.Warn(haccpServiceLogTag, "AddPoint GenerateSecret Failed|Err:%v", err)
+			return "", err
+		}
+	}
+	err = hs.pointModel.Insert(point)
+	if err != nil {
+		return "", err
+	}
+	return point.DeviceSecret, nil
+}
+
+// UpdatePoint 修改控制点，更换设备、首次绑定设备或要求重置时重新生成上报密钥并返回
+func (hs *HaccpService) UpdatePoint(point *model.HaccpPoint, resetSecret bool) (string, error) {
+	err := hs.checkPoint(point)
+	if err != nil {
+		return "", err
+	}
+	exist, err := hs.pointModel.GetPoint(point.ID)
+	if err != nil {
+		return "", err
+	}
+	if exist == nil {
+		return "", fmt.Errorf("控制点不存在")
+	}
+	err = hs.pointModel.UpdatePoint(point)
+	if err != nil {
+		return "", err
+	}
+	if point.DeviceKey == "" {
+		if exist.DeviceSecret == "" {
+			return "", nil
+		}
+		point.DeviceSecret = ""
+		return "", hs.pointModel.UpdatePoint(point, "device_secret")
+	}
+	if !resetSecret && point.DeviceKey == exist.DeviceKey && exist.DeviceSecret != "" {
+		return "", nil
+	}
+	point.DeviceSecret, err = utils.GenerateSecret()
+	if err != nil {
+		logger.Warn(haccpServiceLogTag, "UpdatePoint GenerateSecret Failed|Err:%v", err)
+		return "", err
+	}
+	err = hs.pointModel.UpdatePoint(point, "device_secret")
+	if err != nil {
+		return "", err
+	}
+	return point.DeviceSecret, nil
+}
+
+func (hs *HaccpService) DeletePoint(pointID uint32) error {
+	return hs.pointModel.DeletePoint(pointID)
+}
+
+// RecordReading 记录读数，超出控制点上下限时生成告警，并保留当时的上下限便于追溯
+func (hs *HaccpService) RecordReading(point *model.HaccpPoint, reading *model.HaccpReading) error {
+	if !point.Enable {
+		return fmt.Errorf("控制点%v未启用", point.Name)
+	}
+	if point.PointType != enum.HaccpPointCooking {
+		reading.DishID = 0
+	}
+	reading.ID, reading.PointID = 0, point.ID
+	reading.MinValue, reading.MaxValue = point.MinValue, point.MaxValue
+	reading.AlertStatus = enum.HaccpAlertNone
+	if point.IsOutOfRange(reading.Value) {
+		reading.AlertStatus = enum.HaccpAlertOpen
+	}
+	if reading.ReadAt.IsZero() {
+		reading.ReadAt = time.Now()
+	}
+	err := hs.readingModel.Insert(reading)
+	if err != nil {
+		return err
+	}
+	if reading.AlertStatus == enum.HaccpAlertOpen {
+		logger.Warn(haccpServiceLogTag, "Reading Out Of Range|Point:%v|Value:%v|Range:[%v,%v]", point.Name,
+			reading.Value, point.MinValue, point.MaxValue)
+	}
+	return nil
+}
+
+func (hs *HaccpService) AddManualReading(pointID uint32, reading *model.HaccpReading) error {
+	point, err := hs.pointModel.GetPoint(pointID)
+	if err != nil {
+		return err
+	}
+	if point == nil {
+		return fmt.Errorf("控制点不存在")
+	}
+	reading.Source = enum.HaccpSourceManual
+	return hs.RecordReading(point, reading)
+}
+
+// IngestReading 传感器上报读数，按设备编号匹配控制点并校验设备密钥签名，读数时间须在服务器时间前后 5 分钟内且不可重复
+func (hs *HaccpService) IngestReading(deviceKey string, value float64, readAt int64,
+	sign string) (*model.HaccpReading, error) {
+	deviceKey = strings.TrimSpace(deviceKey)
+	if deviceKey == "" {
+		return nil, fmt.Errorf("设备编号不能为空")
+	}
+	readTime := time.Unix(readAt, 0)
+	if readAt <= 0 || time.Since(readTime) > haccpIngestMaxSkew || time.Until(readTime) > haccpIngestMaxSkew {
+		return nil, fmt.Errorf("读数时间与服务器时间相差过大")
+	}
+	point, err := hs.pointModel.GetPointByDeviceKey(deviceKey)
+	if err != nil {
+		return nil, err
+	}
+	if point == nil {
+		return nil, fmt.Errorf("设备未绑定控制点")
+	}
+	if !point.VerifyIngestSign(value, readAt, sign) {
+		logger.Warn(haccpServiceLogTag, "IngestReading Sign Mismatch|DeviceKey:%v|ReadAt:%v", deviceKey, readAt)
+		return nil, fmt.Errorf("设备签名校验失败")
+	}
+	// 签名在有效时间窗内可被重放，同一读数只接受一次
+	exist, err := hs.readingModel.ExistReading(point.ID, enum.HaccpSourceSensor, readTime)
+	if err != nil {
+		return nil, err
+	}
+	if exist {
+		logger.Warn(haccpServiceLogTag, "IngestReading Duplicate|DeviceKey:%v|ReadAt:%v", deviceKey, readAt)
+		return nil, fmt.Errorf("该时间的读数已上报")
+	}
+	reading := &model.HaccpReading{Value: value, Source: enum.HaccpSourceSensor, ReadAt: readTime}
+	err = hs.RecordReading(point, reading)
+	if err != nil {
+		return nil, err
+	}
+	return reading, nil
+}
+
+func (hs *HaccpService) GetReadingList(pointID uint32, pointType uint8, alertStatus int8, startTime, endTime int64,
+	page, pageSize int32) ([]*model.HaccpReading, int32, error) {
+	var pointIDList []uint32
+	if pointID > 0 {
+		pointIDList = []uint32{pointID}
+	} else if pointType > 0 {
+		pointList, err := hs.pointModel.GetPointList(pointType)
+		if err != nil {
+			return nil, 0, err
+		}
+		if len(pointList) == 0 {
+			return []*model.HaccpReading{}, 0, nil
+		}
+		for _, point := range pointList {
+			pointIDList = append(pointIDList, point.ID)
+		}
+	}
+	readingList, err := hs.readingModel.GetReadingList(pointIDList, alertStatus, startTime, endTime, page, pageSize)
+	if err != nil {
+		return nil, 0, err
+	}
+	count, err := hs.readingModel.GetReadingCount(pointIDList, alertStatus, startTime, endTime)
+	if err != nil {
+		return nil, 0, err
+	}
+	return readingList, count, nil
+}
+
+func (hs *HaccpService) ResolveAlert(readingID, handler uint32, action string) error {
+	action = strings.TrimSpace(action)
+	if action == "" {
+		return fmt.Errorf("请填写纠偏措施")
+	}
+	reading, err := hs.readingModel.GetReading(readingID)
+	if err != nil {
+		return err
+	}
+	if reading == nil {
+		return fmt.Errorf("读数记录不存在")
+	}
+	if reading.AlertStatus != enum.HaccpAlertOpen {
+		return fmt.Errorf("该记录无待处理告警")
+	}
+	reading.AlertStatus = enum.HaccpAlertResolved
+	reading.Handler, reading.Action, reading.HandleAt = handler, action, time.Now()
+	return hs.readingModel.UpdateReading(reading, "alert_status", "handler", "action", "handle_at")
+}
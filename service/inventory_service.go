@@ -3,6 +3,8 @@ package service
 import (
 	"database/sql"
 	"fmt"
+	"math"
+	"sort"
 	"time"
 
 	"github.com/canteen_management/enum"
@@ -21,6 +23,14 @@ type InventoryService struct {
 	inventoryDetailModel *model.InventoryDetailModel
 	goodsModel           *model.GoodsModel
 	goodsHistoryModel    *model.GoodsHistoryModel
+	stockLotModel        *model.StockLotModel
+	goodsStockModel      *model.GoodsStockModel
+	costLayerModel       *model.CostLayerModel
+	stockAlertModel      *model.StockAlertModel
+	cycleCountPlanModel  *model.CycleCountPlanModel
+	documentLogModel     *model.DocumentLogModel
+
+	writeOffLimit float64
 }
 
 func NewInventoryService(sqlCli *sql.DB) *InventoryService {
@@ -28,56 +38,176 @@ func NewInventoryService(sqlCli *sql.DB) *InventoryService {
 	inventoryDetailModel := model.NewInventoryDetailModelWithDB(sqlCli)
 	goodsModel := model.NewGoodsModelWithDB(sqlCli)
 	goodsHistoryModel := model.NewGoodsHistoryModel(sqlCli)
+	stockLotModel := model.NewStockLotModelWithDB(sqlCli)
+	goodsStockModel := model.NewGoodsStockModelWithDB(sqlCli)
+	costLayerModel := model.NewCostLayerModelWithDB(sqlCli)
+	stockAlertModel := model.NewStockAlertModelWithDB(sqlCli)
+	cycleCountPlanModel := model.NewCycleCountPlanModelWithDB(sqlCli)
+	documentLogModel := model.NewDocumentLogModelWithDB(sqlCli)
 	return &InventoryService{
 		sqlCli:               sqlCli,
 		inventoryOrderModel:  inventoryOrderModel,
 		inventoryDetailModel: inventoryDetailModel,
 		goodsModel:           goodsModel,
 		goodsHistoryModel:    goodsHistoryModel,
+		stockLotModel:        stockLotModel,
+		goodsStockModel:      goodsStockModel,
+		costLayerModel:       costLayerModel,
+		stockAlertModel:      stockAlertModel,
+		cycleCountPlanModel:  cycleCountPlanModel,
+		documentLogModel:     documentLogModel,
+	}
+}
+
+func (is *InventoryService) SetCostMethod(costMethod enum.CostMethod) {
+	is.costLayerModel.SetCostMethod(costMethod)
+}
+
+func (is *InventoryService) SetWriteOffLimit(writeOffLimit float64) {
+	is.writeOffLimit = writeOffLimit
+}
+
+func (is *InventoryService) getCountingGoods() (map[uint32]bool, error) {
+	orderList, err := is.inventoryOrderModel.GetOpenOrders()
+	if err != nil {
+		logger.Warn(inventoryServiceLogTag, "GetOpenOrders Failed|Err:%v", err)
+		return nil, err
+	}
+	countingMap := make(map[uint32]bool)
+	if len(orderList) == 0 {
+		return countingMap, nil
+	}
+	orderIDList := make([]uint32, 0, len(orderList))
+	for _, order := range orderList {
+		orderIDList = append(orderIDList, order.ID)
 	}
+	details, err := is.inventoryDetailModel.GetInventoryDetailByOrderList(orderIDList, 0)
+	if err != nil {
+		return nil, err
+	}
+	for _, detail := range details {
+		countingMap[detail.GoodsID] = true
+	}
+	return countingMap, nil
 }
 
-func (is *InventoryService) StartInventory(creator uint32) (*model.InventoryOrder, []*model.InventoryDetail, error) {
-	goodsList, err := is.goodsModel.GetAllGoods()
+// StartInventory 按商品类型、库位及 ABC 分类生成盘点单，账面数量在开始盘点时冻结，
+// 盘点期间可正常出入库，审核时先扣除期间的出入库再按实盘差值调整库存
+func (is *InventoryService) StartInventory(inventory *model.InventoryOrder) ([]*model.InventoryDetail, error) {
+	allGoods, err := is.goodsModel.GetAllGoods()
 	if err != nil {
 		logger.Warn(inventoryServiceLogTag, "StartInventory GetAllGoods Failed|Err:%v", err)
-		return nil, nil, err
+		return nil, err
+	}
+	countingMap, err := is.getCountingGoods()
+	if err != nil {
+		return nil, err
+	}
+	abcMap, goodsList := model.ClassifyGoodsAbc(allGoods), make([]*model.Goods, 0, len(allGoods))
+	for _, goods := range allGoods {
+		if countingMap[goods.ID] {
+			continue
+		}
+		if inventory.GoodsTypeID > 0 && goods.GoodsTypeID != inventory.GoodsTypeID {
+			continue
+		}
+		if inventory.AbcClass != enum.AbcClassAll && abcMap[goods.ID] != inventory.AbcClass {
+			continue
+		}
+		goodsList = append(goodsList, goods)
+	}
+	lotList, err := is.stockLotModel.GetAvailableLots()
+	if err != nil {
+		logger.Warn(inventoryServiceLogTag, "StartInventory GetAvailableLots Failed|Err:%v", err)
+		return nil, err
+	}
+	stockList, err := is.goodsStockModel.GetStockList(0, 0)
+	if err != nil {
+		logger.Warn(inventoryServiceLogTag, "StartInventory GetStockList Failed|Err:%v", err)
+		return nil, err
+	}
+	lotMap, goodsStockMap := make(map[uint64][]*model.StockLot), make(map[uint32][]*model.GoodsStock)
+	for _, lot := range lotList {
+		key := model.GetStockKey(lot.GoodsID, lot.StoreTypeID)
+		lotMap[key] = append(lotMap[key], lot)
+	}
+	for _, stock := range model.BuildStockMap(goodsList, stockList) {
+		if inventory.StoreTypeID > 0 && stock.StoreTypeID != inventory.StoreTypeID {
+			continue
+		}
+		goodsStockMap[stock.GoodsID] = append(goodsStockMap[stock.GoodsID], stock)
+	}
+
+	detailList := make([]*model.InventoryDetail, 0, len(goodsList))
+	for _, goods := range goodsList {
+		stocks := goodsStockMap[goods.ID]
+		sort.Slice(stocks, func(i, j int) bool {
+			return stocks[i].StoreTypeID < stocks[j].StoreTypeID
+		})
+		for _, stock := range stocks {
+			remain, lots := stock.Quantity, lotMap[model.GetStockKey(goods.ID, stock.StoreTypeID)]
+			for _, lot := range lots {
+				detailList = append(detailList, &model.InventoryDetail{
+					InventoryID:  inventory.ID,
+					GoodsID:      goods.ID,
+					GoodsType:    goods.GoodsTypeID,
+					StoreTypeID:  stock.StoreTypeID,
+					LotID:        lot.ID,
+					BatchNo:      lot.BatchNo,
+					ExpectNumber: lot.Quantity,
+				})
+				remain -= lot.Quantity
+			}
+			if remain <= 0 && len(lots) > 0 {
+				continue
+			}
+			detail := &model.InventoryDetail{
+				InventoryID:  inventory.ID,
+				GoodsID:      goods.ID,
+				GoodsType:    goods.GoodsTypeID,
+				StoreTypeID:  stock.StoreTypeID,
+				ExpectNumber: remain,
+			}
+			detailList = append(detailList, detail)
+		}
+	}
+	if len(detailList) == 0 {
+		return nil, fmt.Errorf("盘点范围内没有可盘点的商品")
 	}
 
 	tx, err := is.sqlCli.Begin()
 	if err != nil {
 		logger.Warn(inventoryServiceLogTag, "StartInventory Begin Failed|Err:%v", err)
-		return nil, nil, err
+		return nil, err
 	}
-	defer utils.End(tx, err)
+	defer func() {
+		utils.End(tx, err)
+	}()
 
-	inventory := &model.InventoryOrder{Creator: creator}
 	err = is.inventoryOrderModel.InsertWithTx(tx, inventory)
 	if err != nil {
 		logger.Warn(inventoryServiceLogTag, "StartInventory InsertInventoryOrder Failed|Err:%v", err)
-		return nil, nil, err
+		return nil, err
 	}
-
-	detailList := make([]*model.InventoryDetail, 0, len(goodsList))
-	for _, goods := range goodsList {
-		detail := &model.InventoryDetail{
-			InventoryID:  inventory.ID,
-			GoodsID:      goods.ID,
-			GoodsType:    goods.GoodsTypeID,
-			ExpectNumber: goods.Quantity,
-		}
-		detailList = append(detailList, detail)
+	for _, detail := range detailList {
+		detail.InventoryID = inventory.ID
 	}
-	err = is.inventoryDetailModel.BatchInsert(detailList)
+	err = is.inventoryDetailModel.BatchInsertWithTx(tx, detailList)
 	if err != nil {
 		logger.Warn(inventoryServiceLogTag, "StartInventory InsertInventoryDetail Failed|Err:%v", err)
-		return nil, nil, err
+		return nil, err
 	}
-	return inventory, detailList, nil
+	return detailList, nil
 }
 
 func (is *InventoryService) UpdateInventory(detail *model.InventoryDetail) error {
-	err := is.inventoryDetailModel.UpdateDetailByCondition(detail, "id", "real_number", "tag", "status")
+	if detail.Status == enum.InventoryNeedFix && detail.Reason == enum.VarianceReasonNone {
+		return fmt.Errorf("请选择盘点差异原因")
+	}
+	if detail.Reason > enum.VarianceTransfer {
+		return fmt.Errorf("盘点差异原因错误")
+	}
+	err := is.inventoryDetailModel.UpdateDetailByCondition(detail, "id", "real_number", "reason", "tag", "status")
 	if err != nil {
 		logger.Warn(inventoryServiceLogTag, "UpdateDetailByCondition Failed|Err:%v", err)
 		return err
@@ -85,15 +215,95 @@ func (is *InventoryService) UpdateInventory(detail *model.InventoryDetail) error
 	return nil
 }
 
-func (is *InventoryService) ApplyInventory(inventoryID uint32) error {
+// ScanInventory 扫码累加实盘数量，number 为负数时用于撤销误扫
+func (is *InventoryService) ScanInventory(inventoryID, goodsID, lotID, storeTypeID uint32,
+	number float64) (target *model.InventoryDetail, err error) {
+	tx, err := is.sqlCli.Begin()
+	if err != nil {
+		logger.Warn(inventoryServiceLogTag, "ScanInventory Begin Failed|Err:%v", err)
+		return nil, err
+	}
+	defer func() {
+		utils.End(tx, err)
+	}()
+
+	// 多台设备同时扫码时锁定盘点单及明细，避免累加的实盘数量互相覆盖
+	order, err := is.inventoryOrderModel.GetInventoryOrderWithLock(tx, inventoryID)
+	if err != nil {
+		logger.Warn(inventoryServiceLogTag, "ScanInventory GetInventoryOrderWithLock Failed|Err:%v", err)
+		return nil, err
+	}
+	if order == nil || order.ID != inventoryID {
+		err = fmt.Errorf("盘点订单不存在")
+		return nil, err
+	}
+	if order.Status != enum.InventoryOrderNew {
+		err = fmt.Errorf("盘点已结束")
+		return nil, err
+	}
+	details, err := is.inventoryDetailModel.GetDetailWithLock(tx, inventoryID)
+	if err != nil {
+		logger.Warn(inventoryServiceLogTag, "ScanInventory GetDetailWithLock Failed|Err:%v", err)
+		return nil, err
+	}
+
+	for _, detail := range details {
+		if detail.GoodsID != goodsID || (storeTypeID > 0 && detail.StoreTypeID != storeTypeID) {
+			continue
+		}
+		if lotID > 0 && detail.LotID == lotID {
+			target = detail
+			break
+		}
+		if lotID == 0 && (target == nil || (target.LotID > 0 && detail.LotID == 0)) {
+			target = detail
+		}
+	}
+	if target == nil {
+		err = fmt.Errorf("该商品不在本次盘点范围内")
+		return nil, err
+	}
+	if number == 0 {
+		number = 1
+	}
+	target.RealNumber = math.Max(target.RealNumber+number, 0)
+	target.Status = enum.InventoryNeedFix
+	if math.Abs(target.RealNumber-target.ExpectNumber) < 0.000001 {
+		target.Status = enum.InventoryMatch
+	}
+	err = is.inventoryDetailModel.UpdateDetailByConditionWithTx(tx, target, "id", "real_number", "status")
+	if err != nil {
+		logger.Warn(inventoryServiceLogTag, "ScanInventory UpdateDetail Failed|Err:%v", err)
+		return nil, err
+	}
+	return target, nil
+}
+
+func (is *InventoryService) ApplyInventory(inventoryID uint32, skipUncounted bool) error {
 	details, err := is.inventoryDetailModel.GetDetail(inventoryID, enum.InventoryNew)
 	if err != nil {
 		logger.Warn(inventoryServiceLogTag, "ApplyInventory GetDetail Failed|Err:%v", err)
 		return err
 	}
-	if len(details) > 0 {
+	if len(details) > 0 && !skipUncounted {
 		return fmt.Errorf("还有未盘点的库存商品哦")
 	}
+	fixDetails, err := is.inventoryDetailModel.GetDetail(inventoryID, enum.InventoryNeedFix)
+	if err != nil {
+		logger.Warn(inventoryServiceLogTag, "ApplyInventory GetFixDetail Failed|Err:%v", err)
+		return err
+	}
+	for _, detail := range fixDetails {
+		if detail.Reason == enum.VarianceReasonNone {
+			return fmt.Errorf("还有盘点差异未填写原因")
+		}
+	}
+	if len(details) > 0 {
+		err = is.inventoryDetailModel.SkipUncountedDetail(inventoryID)
+		if err != nil {
+			return err
+		}
+	}
 
 	inventoryOrder := &model.InventoryOrder{ID: inventoryID, Status: enum.InventoryOrderFinish,
 		FinishAt: time.Now()}
@@ -127,33 +337,215 @@ func (is *InventoryService) ConfirmInventory(inventoryID, uid uint32) error {
 	return nil
 }
 
-func (is *InventoryService) ReviewInventory(inventoryID uint32) error {
+// getWriteOffValue 按当前单位成本计算盘亏金额
+func (is *InventoryService) getWriteOffValue(details []*model.InventoryDetail, goodsMap map[uint32]*model.Goods) float64 {
+	writeOff := 0.0
+	for _, detail := range details {
+		goods, ok := goodsMap[detail.GoodsID]
+		if !ok || detail.RealNumber >= detail.ExpectNumber {
+			continue
+		}
+		writeOff += (detail.ExpectNumber - detail.RealNumber) * goods.GetUnitCost(goods.Quantity)
+	}
+	return writeOff
+}
+
+// rebaseInventoryWithTx 锁定已盘商品的库位与批次并调整明细账面数量，返回调整后需要修正库存的明细
+func (is *InventoryService) rebaseInventoryWithTx(tx *sql.Tx, details []*model.InventoryDetail) ([]*model.InventoryDetail,
+	error) {
+	goodsIDList, goodsIDMap := make([]uint32, 0, len(details)), make(map[uint32]bool)
+	for _, detail := range details {
+		if detail.Status != enum.InventoryMatch && detail.Status != enum.InventoryNeedFix {
+			continue
+		}
+		if !goodsIDMap[detail.GoodsID] {
+			goodsIDMap[detail.GoodsID] = true
+			goodsIDList = append(goodsIDList, detail.GoodsID)
+		}
+	}
+	if len(goodsIDList) == 0 {
+		return make([]*model.InventoryDetail, 0), nil
+	}
+	goodsList, err := is.goodsModel.GetGoodsByIDListWithLock(tx, goodsIDList)
+	if err != nil {
+		logger.Warn(inventoryServiceLogTag, "RebaseInventory GetGoodsByIDListWithLock Failed|Err:%v", err)
+		return nil, err
+	}
+	stockList, err := is.goodsStockModel.GetStockByGoodsWithLock(tx, goodsIDList)
+	if err != nil {
+		logger.Warn(inventoryServiceLogTag, "RebaseInventory GetStockByGoodsWithLock Failed|Err:%v", err)
+		return nil, err
+	}
+	lotIDList := make([]uint32, 0)
+	for _, detail := range details {
+		if detail.LotID > 0 && goodsIDMap[detail.GoodsID] {
+			lotIDList = append(lotIDList, detail.LotID)
+		}
+	}
+	lotList, err := is.stockLotModel.GetLotsByIDListWithLock(tx, lotIDList)
+	if err != nil {
+		logger.Warn(inventoryServiceLogTag, "RebaseInventory GetLotsByIDListWithLock Failed|Err:%v", err)
+		return nil, err
+	}
+	lotMap := make(map[uint32]*model.StockLot)
+	for _, lot := range lotList {
+		lotMap[lot.ID] = lot
+	}
+
+	countedList := make([]*model.InventoryDetail, 0, len(details))
+	for _, detail := range details {
+		if goodsIDMap[detail.GoodsID] {
+			countedList = append(countedList, detail)
+		}
+	}
+	rebaseList := rebaseInventoryDetails(countedList, model.BuildStockMap(goodsList, stockList), lotMap)
+	if len(rebaseList) > 0 {
+		err = is.inventoryDetailModel.BatchUpdateExpectWithTx(tx, rebaseList)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	fixList := make([]*model.InventoryDetail, 0, len(countedList))
+	for _, detail := range countedList {
+		if detail.Status == enum.InventoryNeedFix {
+			fixList = append(fixList, detail)
+		}
+	}
+	return fixList, nil
+}
+
+func (is *InventoryService) ApproveWriteOff(inventoryID, uid uint32) error {
+	order, err := is.inventoryOrderModel.GetInventoryOrder(inventoryID)
+	if err != nil {
+		logger.Warn(inventoryServiceLogTag, "ApproveWriteOff GetInventoryOrder Failed|Err:%v", err)
+		return err
+	}
+	if order == nil {
+		return fmt.Errorf("盘点订单不存在")
+	}
+	if order.Status != enum.InventoryOrderConfirmed {
+		return fmt.Errorf("盘点单未确认")
+	}
+	if uid == order.Creator || uid == order.Partner {
+		return fmt.Errorf("二次审批人不能是盘点人或确认人")
+	}
+
+	inventoryOrder := &model.InventoryOrder{ID: inventoryID, Approver: uid}
+	err = is.inventoryOrderModel.UpdateInventoryOrderByCondition(inventoryOrder, "id", "approver")
+	if err != nil {
+		logger.Warn(inventoryServiceLogTag, "ApproveWriteOff UpdateInventoryOrder Failed|Err:%v", err)
+		return err
+	}
+	return nil
+}
+
+// rebaseInventoryDetails 实盘数量视为审核时的实际库存，将已盘明细的账面数量由开始盘点时的冻结数量
+// 调整为审核时的账面数量：批次明细按批次当前数量调整，库位的其余出入库计入该库位的非批次明细。
+// 返回账面数量有变化的明细
+func rebaseInventoryDetails(details []*model.InventoryDetail, stockMap map[uint64]*model.GoodsStock,
+	lotMap map[uint32]*model.StockLot) []*model.InventoryDetail {
+	movementMap, lotMovementMap := make(map[uint64]float64), make(map[uint32]float64)
+	for _, detail := range details {
+		key := model.GetStockKey(detail.GoodsID, detail.StoreTypeID)
+		if _, ok := movementMap[key]; !ok {
+			if stock, ok := stockMap[key]; ok {
+				movementMap[key] = stock.Quantity
+			}
+		}
+		movementMap[key] -= detail.ExpectNumber
+		if detail.LotID == 0 {
+			continue
+		}
+		lotMovement := -detail.ExpectNumber
+		if lot, ok := lotMap[detail.LotID]; ok {
+			lotMovement += lot.Quantity
+		}
+		lotMovementMap[detail.LotID] = lotMovement
+		movementMap[key] -= lotMovement
+	}
+
+	retList := make([]*model.InventoryDetail, 0)
+	for _, detail := range details {
+		if detail.Status != enum.InventoryMatch && detail.Status != enum.InventoryNeedFix {
+			continue
+		}
+		movement := movementMap[model.GetStockKey(detail.GoodsID, detail.StoreTypeID)]
+		if detail.LotID > 0 {
+			movement = lotMovementMap[detail.LotID]
+		}
+		if math.Abs(movement) < 0.000001 {
+			continue
+		}
+		detail.ExpectNumber += movement
+		detail.Status = enum.InventoryNeedFix
+		if math.Abs(detail.RealNumber-detail.ExpectNumber) < 0.000001 {
+			detail.Status = enum.InventoryMatch
+		}
+		retList = append(retList, detail)
+	}
+	return retList
+}
+
+// ReviewInventory 审核盘点单，扣除盘点期间的出入库后按实盘差值调整库存、库位和批次
+func (is *InventoryService) ReviewInventory(inventoryID uint32) (err error) {
 	tx, err := is.sqlCli.Begin()
 	if err != nil {
-		logger.Warn(inventoryServiceLogTag, "StartInventory Begin Failed|Err:%v", err)
+		logger.Warn(inventoryServiceLogTag, "ReviewInventory Begin Failed|Err:%v", err)
 		return err
 	}
-	defer utils.End(tx, err)
+	defer func() {
+		utils.End(tx, err)
+	}()
 
-	inventoryOrder := &model.InventoryOrder{ID: inventoryID, Status: enum.InventoryOrderReviewed}
-	err = is.inventoryOrderModel.UpdateInventoryOrderByConditionWithTx(tx, inventoryOrder, "id", "status")
+	// 锁定盘点单，避免重复审核
+	order, err := is.inventoryOrderModel.GetInventoryOrderWithLock(tx, inventoryID)
+	if err != nil {
+		logger.Warn(inventoryServiceLogTag, "ReviewInventory GetInventoryOrder Failed|Err:%v", err)
+		return err
+	}
+	if order == nil {
+		err = fmt.Errorf("盘点订单不存在")
+		return err
+	}
+	if order.Status != enum.InventoryOrderConfirmed {
+		err = fmt.Errorf("盘点单未确认")
+		return err
+	}
+
+	inventoryOrder := &model.InventoryOrder{ID: inventoryID, Status: enum.InventoryOrderReviewed, ReviewAt: time.Now()}
+	err = is.inventoryOrderModel.UpdateInventoryOrderByConditionWithTx(tx, inventoryOrder, "id", "status", "review_at")
 	if err != nil {
 		logger.Warn(inventoryServiceLogTag, "UpdateInventoryOrderByCondition Failed|Err:%v", err)
 		return err
 	}
 
-	details, err := is.inventoryDetailModel.GetDetail(inventoryID, enum.InventoryNeedFix)
+	allDetails, err := is.inventoryDetailModel.GetDetail(inventoryID, -1)
 	if err != nil {
 		logger.Warn(inventoryServiceLogTag, "ReviewInventory GetDetail Failed|Err:%v", err)
 		return err
 	}
+	details, err := is.rebaseInventoryWithTx(tx, allDetails)
+	if err != nil {
+		return err
+	}
 
 	if len(details) > 0 {
 		goodsIDList, goodsList := make([]uint32, 0, len(details)), make([]*model.Goods, 0, len(details))
 		updateMap, historyList := make(map[uint32]float64), make([]*model.GoodsHistory, 0, len(details))
+		lotIDList, lotList := make([]uint32, 0, len(details)), make([]*model.StockLot, 0, len(details))
+		lotUpdateMap, changeList := make(map[uint32]float64), make([]*model.GoodsStock, 0, len(details))
 		for _, detail := range details {
-			goodsIDList = append(goodsIDList, detail.GoodsID)
-			updateMap[detail.GoodsID] = detail.RealNumber - detail.ExpectNumber
+			changeList = append(changeList, &model.GoodsStock{GoodsID: detail.GoodsID, StoreTypeID: detail.StoreTypeID,
+				Quantity: detail.RealNumber - detail.ExpectNumber})
+			if _, ok := updateMap[detail.GoodsID]; !ok {
+				goodsIDList = append(goodsIDList, detail.GoodsID)
+			}
+			updateMap[detail.GoodsID] += detail.RealNumber - detail.ExpectNumber
+			if detail.LotID > 0 {
+				lotIDList = append(lotIDList, detail.LotID)
+				lotUpdateMap[detail.LotID] = detail.RealNumber - detail.ExpectNumber
+			}
 		}
 		goodsList, err = is.goodsModel.GetGoodsByIDListWithLock(tx, goodsIDList)
 		if err != nil {
@@ -161,17 +553,66 @@ func (is *InventoryService) ReviewInventory(inventoryID uint32) error {
 			return err
 		}
 
+		goodsMap := make(map[uint32]*model.Goods)
+		for _, goods := range goodsList {
+			goodsMap[goods.ID] = goods
+		}
+		writeOff := is.getWriteOffValue(details, goodsMap)
+		if is.writeOffLimit > 0 && writeOff > is.writeOffLimit && order.Approver == 0 {
+			logger.Warn(inventoryServiceLogTag, "ReviewInventory WriteOff Need Approve|ID:%v|WriteOff:%v",
+				inventoryID, writeOff)
+			err = fmt.Errorf("盘亏金额%.2f超过限额%.2f，需要二次审批", writeOff, is.writeOffLimit)
+			return err
+		}
+		for _, detail := range details {
+			if goods, ok := goodsMap[detail.GoodsID]; ok {
+				detail.VarianceValue = (detail.RealNumber - detail.ExpectNumber) * goods.GetUnitCost(goods.Quantity)
+			}
+		}
+		err = is.inventoryDetailModel.BatchUpdateVarianceWithTx(tx, details)
+		if err != nil {
+			return err
+		}
+
+		_, err = is.goodsStockModel.ChangeStockWithTx(tx, goodsList, changeList)
+		if err != nil {
+			logger.Warn(inventoryServiceLogTag, "ReviewInventory ChangeStock Failed|Err:%v", err)
+			return err
+		}
 		for _, goods := range goodsList {
 			historyList = append(historyList,
 				model.GenerateInventoryGoodsHistory(goods, updateMap[goods.ID], inventoryID))
 			goods.Quantity = goods.Quantity + updateMap[goods.ID]
 		}
+		err = is.costLayerModel.ValueHistoryWithTx(tx, goodsList, historyList, nil)
+		if err != nil {
+			logger.Warn(inventoryServiceLogTag, "ReviewInventory ValueHistory Failed|Err:%v", err)
+			return err
+		}
 
 		err = is.goodsModel.BatchUpdateQuantityWithTx(tx, goodsList)
 		if err != nil {
 			logger.Warn(inventoryServiceLogTag, "ReviewInventory BatchAddQuantity Failed|Err:%v", err)
 			return err
 		}
+		err = is.stockAlertModel.CheckAlertWithTx(tx, goodsList)
+		if err != nil {
+			return err
+		}
+
+		lotList, err = is.stockLotModel.GetLotsByIDListWithLock(tx, lotIDList)
+		if err != nil {
+			logger.Warn(inventoryServiceLogTag, "ReviewInventory GetLotsByIDListWithLock Failed|Err:%v", err)
+			return err
+		}
+		for _, lot := range lotList {
+			lot.Quantity = math.Max(lot.Quantity+lotUpdateMap[lot.ID], 0)
+		}
+		err = is.stockLotModel.BatchUpdateQuantityWithTx(tx, lotList)
+		if err != nil {
+			logger.Warn(inventoryServiceLogTag, "ReviewInventory BatchUpdateLot Failed|Err:%v", err)
+			return err
+		}
 		err = is.goodsHistoryModel.BatchInsert(tx, historyList)
 		if err != nil {
 			logger.Warn(inventoryServiceLogTag, "ReviewInventory BatchInsertHistory Failed|Err:%v", err)
@@ -181,6 +622,182 @@ func (is *InventoryService) ReviewInventory(inventoryID uint32) error {
 	return nil
 }
 
+// CancelInventoryOrder 取消未审核的盘点单，未过账不影响库存
+func (is *InventoryService) CancelInventoryOrder(inventoryID, uid uint32, reason string) (err error) {
+	tx, err := is.sqlCli.Begin()
+	if err != nil {
+		logger.Warn(inventoryServiceLogTag, "CancelInventoryOrder Begin Failed|Err:%v", err)
+		return err
+	}
+	defer func() {
+		utils.End(tx, err)
+	}()
+
+	// 锁定盘点单，避免与审核并发
+	order, err := is.inventoryOrderModel.GetInventoryOrderWithLock(tx, inventoryID)
+	if err != nil {
+		logger.Warn(inventoryServiceLogTag, "CancelInventoryOrder GetInventoryOrder Failed|Err:%v", err)
+		return err
+	}
+	if order == nil {
+		err = fmt.Errorf("盘点订单不存在")
+		return err
+	}
+	if order.Status >= enum.InventoryOrderReviewed {
+		err = fmt.Errorf("盘点单已审核或已取消，无法取消")
+		return err
+	}
+
+	inventoryOrder := &model.InventoryOrder{ID: inventoryID, Status: enum.InventoryOrderCancel}
+	err = is.inventoryOrderModel.UpdateInventoryOrderByConditionWithTx(tx, inventoryOrder, "id", "status")
+	if err != nil {
+		logger.Warn(inventoryServiceLogTag, "CancelInventoryOrder UpdateInventoryOrder Failed|Err:%v", err)
+		return err
+	}
+	docLog := &model.DocumentLog{DocType: enum.DocumentInventory, DocID: inventoryID, Action: enum.DocumentCancel,
+		Operator: uid, Reason: reason}
+	err = is.documentLogModel.InsertWithTx(tx, docLog)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// ReverseInventoryOrder 冲销已审核的盘点单，按盘点差异反向调整库存、库位和批次
+func (is *InventoryService) ReverseInventoryOrder(inventoryID, uid uint32, reason string) (err error) {
+	tx, err := is.sqlCli.Begin()
+	if err != nil {
+		logger.Warn(inventoryServiceLogTag, "ReverseInventoryOrder Begin Failed|Err:%v", err)
+		return err
+	}
+	defer func() {
+		utils.End(tx, err)
+	}()
+
+	// 锁定盘点单，避免重复冲销
+	order, err := is.inventoryOrderModel.GetInventoryOrderWithLock(tx, inventoryID)
+	if err != nil {
+		logger.Warn(inventoryServiceLogTag, "ReverseInventoryOrder GetInventoryOrder Failed|Err:%v", err)
+		return err
+	}
+	if order == nil {
+		err = fmt.Errorf("盘点订单不存在")
+		return err
+	}
+	if order.Status != enum.InventoryOrderReviewed {
+		err = fmt.Errorf("盘点单未审核，无需冲销")
+		return err
+	}
+	allDetails, err := is.inventoryDetailModel.GetDetailWithLock(tx, inventoryID)
+	if err != nil {
+		logger.Warn(inventoryServiceLogTag, "ReverseInventoryOrder GetDetail Failed|Err:%v", err)
+		return err
+	}
+	details := make([]*model.InventoryDetail, 0, len(allDetails))
+	for _, detail := range allDetails {
+		if detail.Status == enum.InventoryNeedFix {
+			details = append(details, detail)
+		}
+	}
+	historyList, err := is.goodsHistoryModel.GetHistoryByRef(enum.GoodsInventory, inventoryID)
+	if err != nil {
+		return err
+	}
+	costMap := make(map[uint32]float64)
+	for _, history := range historyList {
+		if history.ChangeQuantity < 0 {
+			costMap[history.GoodsID] = history.UnitCost
+		}
+	}
+
+	inventoryOrder := &model.InventoryOrder{ID: inventoryID, Status: enum.InventoryOrderReversed}
+	err = is.inventoryOrderModel.UpdateInventoryOrderByConditionWithTx(tx, inventoryOrder, "id", "status")
+	if err != nil {
+		logger.Warn(inventoryServiceLogTag, "ReverseInventoryOrder UpdateInventoryOrder Failed|Err:%v", err)
+		return err
+	}
+	docLog := &model.DocumentLog{DocType: enum.DocumentInventory, DocID: inventoryID, Action: enum.DocumentReverse,
+		Operator: uid, Reason: reason}
+	err = is.documentLogModel.InsertWithTx(tx, docLog)
+	if err != nil {
+		return err
+	}
+	if len(details) == 0 {
+		return nil
+	}
+
+	goodsIDList, updateMap := make([]uint32, 0, len(details)), make(map[uint32]float64)
+	lotIDList, lotUpdateMap := make([]uint32, 0, len(details)), make(map[uint32]float64)
+	changeList, reversalList := make([]*model.GoodsStock, 0, len(details)), make([]*model.GoodsHistory, 0, len(details))
+	for _, detail := range details {
+		changeList = append(changeList, &model.GoodsStock{GoodsID: detail.GoodsID, StoreTypeID: detail.StoreTypeID,
+			Quantity: detail.ExpectNumber - detail.RealNumber})
+		if _, ok := updateMap[detail.GoodsID]; !ok {
+			goodsIDList = append(goodsIDList, detail.GoodsID)
+		}
+		updateMap[detail.GoodsID] += detail.ExpectNumber - detail.RealNumber
+		if detail.LotID > 0 {
+			lotIDList = append(lotIDList, detail.LotID)
+			lotUpdateMap[detail.LotID] = detail.ExpectNumber - detail.RealNumber
+		}
+	}
+	goodsList, err := is.goodsModel.GetGoodsByIDListWithLock(tx, goodsIDList)
+	if err != nil {
+		logger.Warn(inventoryServiceLogTag, "ReverseInventoryOrder GetGoodsByIDListWithLock Failed|Err:%v", err)
+		return err
+	}
+	for _, goods := range goodsList {
+		if goods.Quantity+updateMap[goods.ID] < 0 {
+			err = fmt.Errorf("%v库存不足，无法冲销", goods.Name)
+			return err
+		}
+	}
+	_, err = is.goodsStockModel.ChangeStockWithTx(tx, goodsList, changeList)
+	if err != nil {
+		logger.Warn(inventoryServiceLogTag, "ReverseInventoryOrder ChangeStock Failed|Err:%v", err)
+		return err
+	}
+	for _, goods := range goodsList {
+		reversalList = append(reversalList,
+			model.GenerateReversalGoodsHistory(goods, updateMap[goods.ID], docLog.ID))
+		goods.Quantity = goods.Quantity + updateMap[goods.ID]
+	}
+	err = is.costLayerModel.ValueHistoryWithTx(tx, goodsList, reversalList, costMap)
+	if err != nil {
+		logger.Warn(inventoryServiceLogTag, "ReverseInventoryOrder ValueHistory Failed|Err:%v", err)
+		return err
+	}
+	err = is.goodsModel.BatchUpdateQuantityWithTx(tx, goodsList)
+	if err != nil {
+		logger.Warn(inventoryServiceLogTag, "ReverseInventoryOrder BatchUpdateQuantity Failed|Err:%v", err)
+		return err
+	}
+	err = is.stockAlertModel.CheckAlertWithTx(tx, goodsList)
+	if err != nil {
+		return err
+	}
+
+	lotList, err := is.stockLotModel.GetLotsByIDListWithLock(tx, lotIDList)
+	if err != nil {
+		logger.Warn(inventoryServiceLogTag, "ReverseInventoryOrder GetLotsByIDListWithLock Failed|Err:%v", err)
+		return err
+	}
+	for _, lot := range lotList {
+		lot.Quantity = math.Max(lot.Quantity+lotUpdateMap[lot.ID], 0)
+	}
+	err = is.stockLotModel.BatchUpdateQuantityWithTx(tx, lotList)
+	if err != nil {
+		logger.Warn(inventoryServiceLogTag, "ReverseInventoryOrder BatchUpdateLot Failed|Err:%v", err)
+		return err
+	}
+	err = is.goodsHistoryModel.BatchInsert(tx, reversalList)
+	if err != nil {
+		logger.Warn(inventoryServiceLogTag, "ReverseInventoryOrder BatchInsertHistory Failed|Err:%v", err)
+		return err
+	}
+	return nil
+}
+
 func (is *InventoryService) InventoryOrderList(inventoryID, creator uint32, status int8, startTime, endTime int64,
 	page, pageSize int32) ([]*model.InventoryOrder, int32, map[uint32][]*model.InventoryDetail, error) {
 	inventoryList, err := is.inventoryOrderModel.GetInventoryOrderList(inventoryID, creator, status, startTime, endTime, page, pageSize)
@@ -217,3 +834,119 @@ func (is *InventoryService) InventoryOrderList(inventoryID, creator uint32, stat
 	}
 	return inventoryList, inventoryCount, detailMap, nil
 }
+
+func (is *InventoryService) GetVarianceDetails(goodsTypeID uint32, startTime, endTime int64) ([]*model.InventoryDetail, error) {
+	orderList, err := is.inventoryOrderModel.GetReviewedOrders(startTime, endTime)
+	if err != nil {
+		logger.Warn(inventoryServiceLogTag, "GetVarianceDetails GetReviewedOrders Failed|Err:%v", err)
+		return nil, err
+	}
+	if len(orderList) == 0 {
+		return make([]*model.InventoryDetail, 0), nil
+	}
+	orderIDList := make([]uint32, 0, len(orderList))
+	for _, order := range orderList {
+		orderIDList = append(orderIDList, order.ID)
+	}
+	details, err := is.inventoryDetailModel.GetInventoryDetailByOrderList(orderIDList, goodsTypeID)
+	if err != nil {
+		logger.Warn(inventoryServiceLogTag, "GetVarianceDetails GetDetail Failed|Err:%v", err)
+		return nil, err
+	}
+	retList := make([]*model.InventoryDetail, 0, len(details))
+	for _, detail := range details {
+		if detail.Status == enum.InventoryNeedFix {
+			retList = append(retList, detail)
+		}
+	}
+	return retList, nil
+}
+
+func (is *InventoryService) GetCycleCountPlanList() ([]*model.CycleCountPlan, error) {
+	planList, err := is.cycleCountPlanModel.GetPlanList()
+	if err != nil {
+		logger.Warn(inventoryServiceLogTag, "GetCycleCountPlanList Failed|Err:%v", err)
+		return nil, err
+	}
+	return planList, nil
+}
+
+func (is *InventoryService) checkCycleCountPlan(plan *model.CycleCountPlan) error {
+	if plan.Name == "" {
+		return fmt.Errorf("计划名称不能为空")
+	}
+	if plan.IntervalDays == 0 {
+		return fmt.Errorf("盘点周期不能为0")
+	}
+	if plan.AbcClass > enum.AbcClassC {
+		return fmt.Errorf("ABC分类错误")
+	}
+	if plan.Counter == 0 {
+		return fmt.Errorf("请指定盘点人")
+	}
+	if plan.NextCountAt.IsZero() {
+		plan.NextCountAt = time.Now()
+	}
+	return nil
+}
+
+func (is *InventoryService) AddCycleCountPlan(plan *model.CycleCountPlan) error {
+	err := is.checkCycleCountPlan(plan)
+	if err != nil {
+		return err
+	}
+	err = is.cycleCountPlanModel.Insert(plan)
+	if err != nil {
+		logger.Warn(inventoryServiceLogTag, "AddCycleCountPlan Failed|Err:%v", err)
+		return err
+	}
+	return nil
+}
+
+func (is *InventoryService) UpdateCycleCountPlan(plan *model.CycleCountPlan) error {
+	err := is.checkCycleCountPlan(plan)
+	if err != nil {
+		return err
+	}
+	err = is.cycleCountPlanModel.UpdatePlan(plan)
+	if err != nil {
+		logger.Warn(inventoryServiceLogTag, "UpdateCycleCountPlan Failed|Err:%v", err)
+		return err
+	}
+	return nil
+}
+
+func (is *InventoryService) DeleteCycleCountPlan(planID uint32) error {
+	err := is.cycleCountPlanModel.DeletePlan(planID)
+	if err != nil {
+		logger.Warn(inventoryServiceLogTag, "DeleteCycleCountPlan Failed|Err:%v", err)
+		return err
+	}
+	return nil
+}
+
+func (is *InventoryService) StartDueCycleCount() error {
+	now := time.Now()
+	planList, err := is.cycleCountPlanModel.GetDuePlans(now)
+	if err != nil {
+		logger.Warn(inventoryServiceLogTag, "StartDueCycleCount GetDuePlans Failed|Err:%v", err)
+		return err
+	}
+	for _, plan := range planList {
+		inventory := &model.InventoryOrder{Creator: plan.Counter, PlanID: plan.ID, GoodsTypeID: plan.GoodsTypeID,
+			StoreTypeID: plan.StoreTypeID, AbcClass: plan.AbcClass}
+		_, err = is.StartInventory(inventory)
+		if err != nil {
+			logger.Warn(inventoryServiceLogTag, "StartDueCycleCount StartInventory Failed|Plan:%v|Err:%v", plan.ID, err)
+		}
+		for !plan.NextCountAt.After(now) {
+			plan.NextCountAt = plan.NextCountAt.AddDate(0, 0, int(plan.IntervalDays))
+		}
+		err = is.cycleCountPlanModel.UpdatePlan(plan, "next_count_at")
+		if err != nil {
+			logger.Warn(inventoryServiceLogTag, "StartDueCycleCount UpdatePlan Failed|Plan:%v|Err:%v", plan.ID, err)
+			return err
+		}
+	}
+	return nil
+}
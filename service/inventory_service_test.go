@@ -0,0 +1,104 @@
+package service
+
+import (
+	"math"
+	"testing"
+
+	"github.com/canteen_management/enum"
+	"github.com/canteen_management/model"
+)
+
+func TestRebaseInventoryDetails(t *testing.T) {
+	testList := []struct {
+		name       string
+		details    []*model.InventoryDetail
+		stockList  []*model.GoodsStock
+		lotList    []*model.StockLot
+		wantExpect []float64
+		wantStatus []int8
+		wantCount  int
+	}{
+		{
+			name: "no movement",
+			details: []*model.InventoryDetail{
+				{ID: 1, GoodsID: 1, StoreTypeID: 1, ExpectNumber: 10, RealNumber: 8, Status: enum.InventoryNeedFix},
+			},
+			stockList:  []*model.GoodsStock{{GoodsID: 1, StoreTypeID: 1, Quantity: 10}},
+			wantExpect: []float64{10},
+			wantStatus: []int8{enum.InventoryNeedFix},
+			wantCount:  0,
+		},
+		{
+			name: "outbound during count",
+			details: []*model.InventoryDetail{
+				{ID: 1, GoodsID: 1, StoreTypeID: 1, ExpectNumber: 10, RealNumber: 7, Status: enum.InventoryNeedFix},
+			},
+			stockList:  []*model.GoodsStock{{GoodsID: 1, StoreTypeID: 1, Quantity: 7}},
+			wantExpect: []float64{7},
+			wantStatus: []int8{enum.InventoryMatch},
+			wantCount:  1,
+		},
+		{
+			name: "inbound during count",
+			details: []*model.InventoryDetail{
+				{ID: 1, GoodsID: 1, StoreTypeID: 1, ExpectNumber: 10, RealNumber: 10, Status: enum.InventoryMatch},
+			},
+			stockList:  []*model.GoodsStock{{GoodsID: 1, StoreTypeID: 1, Quantity: 15}},
+			wantExpect: []float64{15},
+			wantStatus: []int8{enum.InventoryNeedFix},
+			wantCount:  1,
+		},
+		{
+			name: "lot movement kept on lot line",
+			details: []*model.InventoryDetail{
+				{ID: 1, GoodsID: 1, StoreTypeID: 1, LotID: 5, ExpectNumber: 6, RealNumber: 4, Status: enum.InventoryMatch},
+				{ID: 2, GoodsID: 1, StoreTypeID: 1, ExpectNumber: 4, RealNumber: 7, Status: enum.InventoryNeedFix},
+			},
+			stockList:  []*model.GoodsStock{{GoodsID: 1, StoreTypeID: 1, Quantity: 11}},
+			lotList:    []*model.StockLot{{ID: 5, Quantity: 4}},
+			wantExpect: []float64{4, 7},
+			wantStatus: []int8{enum.InventoryMatch, enum.InventoryMatch},
+			wantCount:  2,
+		},
+		{
+			name: "consumed lot",
+			details: []*model.InventoryDetail{
+				{ID: 1, GoodsID: 1, StoreTypeID: 1, LotID: 5, ExpectNumber: 3, RealNumber: 0, Status: enum.InventoryNeedFix},
+			},
+			wantExpect: []float64{0},
+			wantStatus: []int8{enum.InventoryMatch},
+			wantCount:  1,
+		},
+		{
+			name: "uncounted line untouched",
+			details: []*model.InventoryDetail{
+				{ID: 1, GoodsID: 1, StoreTypeID: 1, ExpectNumber: 10, Status: enum.InventorySkipped},
+			},
+			stockList:  []*model.GoodsStock{{GoodsID: 1, StoreTypeID: 1, Quantity: 5}},
+			wantExpect: []float64{10},
+			wantStatus: []int8{enum.InventorySkipped},
+			wantCount:  0,
+		},
+	}
+	for _, tt := range testList {
+		t.Run(tt.name, func(t *testing.T) {
+			lotMap := make(map[uint32]*model.StockLot)
+			for _, lot := range tt.lotList {
+				lotMap[lot.ID] = lot
+			}
+			got := rebaseInventoryDetails(tt.details, model.BuildStockMap(nil, tt.stockList), lotMap)
+			if len(got) != tt.wantCount {
+				t.Fatalf("rebaseInventoryDetails Count Mismatch|Got:%v|Want:%v", len(got), tt.wantCount)
+			}
+			for i, detail := range tt.details {
+				if math.Abs(detail.ExpectNumber-tt.wantExpect[i]) > 0.000001 {
+					t.Fatalf("ExpectNumber Mismatch|ID:%v|Got:%v|Want:%v", detail.ID, detail.ExpectNumber,
+						tt.wantExpect[i])
+				}
+				if detail.Status != tt.wantStatus[i] {
+					t.Fatalf("Status Mismatch|ID:%v|Got:%v|Want:%v", detail.ID, detail.Status, tt.wantStatus[i])
+				}
+			}
+		})
+	}
+}
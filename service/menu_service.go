@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/canteen_management/enum"
 	"github.com/canteen_management/logger"
 	"github.com/canteen_management/model"
 	"github.com/canteen_management/utils"
@@ -15,19 +16,27 @@ const (
 )
 
 type MenuService struct {
-	weekMenuModel *model.WeekMenuModel
-	menuModel     *model.MenuModel
-	menuTypeModel *model.MenuTypeModel
+	sqlCli               *sql.DB
+	weekMenuModel        *model.WeekMenuModel
+	weekMenuHistoryModel *model.WeekMenuHistoryModel
+	menuModel            *model.MenuModel
+	menuTypeModel        *model.MenuTypeModel
+	orderModel           *model.OrderModel
 }
 
 func NewMenuService(sqlCli *sql.DB) *MenuService {
 	weekMenuModel := model.NewWeekMenuModelWithDB(sqlCli)
+	weekMenuHistoryModel := model.NewWeekMenuHistoryModelWithDB(sqlCli)
 	menuModel := model.NewMenuModelWithDB(sqlCli)
 	menuTypeModel := model.NewMenuTypeModelWithDB(sqlCli)
+	orderModel := model.NewOrderModel(sqlCli)
 	return &MenuService{
-		weekMenuModel: weekMenuModel,
-		menuModel:     menuModel,
-		menuTypeModel: menuTypeModel,
+		sqlCli:               sqlCli,
+		weekMenuModel:        weekMenuModel,
+		weekMenuHistoryModel: weekMenuHistoryModel,
+		menuModel:            menuModel,
+		menuTypeModel:        menuTypeModel,
+		orderModel:           orderModel,
 	}
 }
 
@@ -171,7 +180,7 @@ func (ms *MenuService) GetWeekMenu(menuID uint32) (*model.WeekMenu, error) {
 	return menuList[0], nil
 }
 
-func (ms *MenuService) AddWeekMenu(weekMenu *model.WeekMenu) error {
+func (ms *MenuService) AddWeekMenu(weekMenu *model.WeekMenu, operator uint32) error {
 	preMenu, err := ms.weekMenuModel.GetWeekMenuByDate(weekMenu.MenuStartDate.Unix(), weekMenu.MenuTypeID)
 	if err != nil && err != model.ErrWeekMenuNotFound {
 		logger.Warn(menuServiceLogTag, "GetWeekMenuByDate Failed|Err:%v", err)
@@ -180,23 +189,203 @@ func (ms *MenuService) AddWeekMenu(weekMenu *model.WeekMenu) error {
 	if preMenu != nil {
 		return fmt.Errorf("重复的菜谱日期")
 	}
-	err = ms.weekMenuModel.Insert(weekMenu)
+
+	tx, err := ms.sqlCli.Begin()
+	if err != nil {
+		logger.Warn(menuServiceLogTag, "AddWeekMenu Begin Failed|Err:%v", err)
+		return err
+	}
+	defer func() {
+		utils.End(tx, err)
+	}()
+
+	weekMenu.Status = enum.WeekMenuDraft
+	weekMenu.Version = 1
+	err = ms.weekMenuModel.InsertWithTx(tx, weekMenu)
 	if err != nil {
 		logger.Warn(menuServiceLogTag, "Insert WeekMenu Failed|Err:%v", err)
 		return err
 	}
+	err = ms.weekMenuHistoryModel.InsertWithTx(tx, model.GenerateWeekMenuHistory(weekMenu, operator))
+	if err != nil {
+		logger.Warn(menuServiceLogTag, "AddWeekMenu Insert History Failed|Err:%v", err)
+		return err
+	}
 	return nil
 }
 
-func (ms *MenuService) UpdateWeekMenu(weekMenu *model.WeekMenu) error {
-	err := ms.weekMenuModel.UpdateWeekMenu(weekMenu)
+func (ms *MenuService) UpdateWeekMenu(weekMenu *model.WeekMenu, operator uint32) (err error) {
+	tx, err := ms.sqlCli.Begin()
+	if err != nil {
+		logger.Warn(menuServiceLogTag, "UpdateWeekMenu Begin Failed|Err:%v", err)
+		return err
+	}
+	defer func() {
+		utils.End(tx, err)
+	}()
+
+	// 锁定菜单，避免与审核并发时发布未经审核的内容或生成相同版本号
+	preMenu, err := ms.weekMenuModel.GetWeekMenuWithLock(tx, weekMenu.ID)
+	if err != nil {
+		logger.Warn(menuServiceLogTag, "UpdateWeekMenu GetWeekMenu Failed|ID:%v|Err:%v", weekMenu.ID, err)
+		return err
+	}
+	switch preMenu.Status {
+	case enum.WeekMenuReviewing:
+		err = fmt.Errorf("菜单审核中，无法修改")
+		return err
+	case enum.WeekMenuArchived:
+		err = fmt.Errorf("菜单已归档，无法修改")
+		return err
+	}
+	err = ms.checkNoOrderOnline(preMenu)
+	if err != nil {
+		return err
+	}
+
+	// 修改内容保存为新草稿，需重新审核；已发布的版本在 PublishedContent 中继续供点餐
+	weekMenu.Status = enum.WeekMenuDraft
+	weekMenu.Version = preMenu.Version + 1
+	err = ms.weekMenuModel.UpdateWeekMenuWithTx(tx, weekMenu)
 	if err != nil {
 		logger.Warn(menuServiceLogTag, "Update WeekMenu Failed|Err:%v", err)
 		return err
 	}
+	err = ms.weekMenuHistoryModel.InsertWithTx(tx, model.GenerateWeekMenuHistory(weekMenu, operator))
+	if err != nil {
+		logger.Warn(menuServiceLogTag, "UpdateWeekMenu Insert History Failed|Err:%v", err)
+		return err
+	}
 	return nil
 }
 
+func (ms *MenuService) ImportWeekMenu(weekMenu *model.WeekMenu, operator uint32) error {
+	preMenu, err := ms.weekMenuModel.GetWeekMenuByDate(weekMenu.MenuStartDate.Unix(), weekMenu.MenuTypeID)
+	if err != nil && err != model.ErrWeekMenuNotFound {
+		logger.Warn(menuServiceLogTag, "ImportWeekMenu GetWeekMenuByDate Failed|Err:%v", err)
+		return err
+	}
+	if preMenu == nil {
+		return ms.AddWeekMenu(weekMenu, operator)
+	}
+	weekMenu.ID = preMenu.ID
+	return ms.UpdateWeekMenu(weekMenu, operator)
+}
+
+// GetWeekMenuOrderCount 订单只来自点餐菜单类型，其他类型的周菜单不会产生订单
+func (ms *MenuService) GetWeekMenuOrderCount(weekMenu *model.WeekMenu) (int32, error) {
+	if weekMenu.MenuTypeID != enum.OrderMenuTypeID {
+		return 0, nil
+	}
+	start := weekMenu.MenuStartDate.Unix()
+	end := start + 3600*24*7 - 1
+	count, err := ms.orderModel.GetOrderListCount(nil, 0, enum.MealUnknown, -1, -1, 0, 0, "", start, end)
+	if err != nil {
+		logger.Warn(menuServiceLogTag, "GetWeekMenuOrderCount Failed|ID:%v|Err:%v", weekMenu.ID, err)
+		return 0, err
+	}
+	return count, nil
+}
+
+// checkNoOrderOnline 线上版本已有订单时不允许再替换，避免订单与菜单不一致
+func (ms *MenuService) checkNoOrderOnline(weekMenu *model.WeekMenu) error {
+	if !weekMenu.IsOnline() {
+		return nil
+	}
+	orderCount, err := ms.GetWeekMenuOrderCount(weekMenu)
+	if err != nil {
+		return err
+	}
+	if orderCount > 0 {
+		return fmt.Errorf("该周菜单已有%v个订单，无法修改", orderCount)
+	}
+	return nil
+}
+
+// changeWeekMenuStatus 在事务内锁定菜单后再校验并修改状态，避免并发的状态流转互相覆盖
+func (ms *MenuService) changeWeekMenuStatus(weekMenuID uint32,
+	changeFunc func(weekMenu *model.WeekMenu) error) (err error) {
+	tx, err := ms.sqlCli.Begin()
+	if err != nil {
+		logger.Warn(menuServiceLogTag, "ChangeWeekMenuStatus Begin Failed|Err:%v", err)
+		return err
+	}
+	defer func() {
+		utils.End(tx, err)
+	}()
+
+	weekMenu, err := ms.weekMenuModel.GetWeekMenuWithLock(tx, weekMenuID)
+	if err != nil {
+		logger.Warn(menuServiceLogTag, "ChangeWeekMenuStatus GetWeekMenu Failed|ID:%v|Err:%v", weekMenuID, err)
+		return err
+	}
+	err = changeFunc(weekMenu)
+	if err != nil {
+		return err
+	}
+	err = ms.weekMenuModel.UpdateWeekMenuStatusWithTx(tx, weekMenu)
+	return err
+}
+
+func (ms *MenuService) SubmitWeekMenu(weekMenuID uint32) error {
+	return ms.changeWeekMenuStatus(weekMenuID, func(weekMenu *model.WeekMenu) error {
+		if weekMenu.Status != enum.WeekMenuDraft {
+			logger.Warn(menuServiceLogTag, "SubmitWeekMenu Status Error|ID:%v|Status:%v", weekMenuID, weekMenu.Status)
+			return fmt.Errorf("只有草稿状态的菜单可以提交审核")
+		}
+		weekMenu.Status = enum.WeekMenuReviewing
+		return nil
+	})
+}
+
+func (ms *MenuService) ReviewWeekMenu(weekMenuID, reviewer uint32, pass bool) error {
+	return ms.changeWeekMenuStatus(weekMenuID, func(weekMenu *model.WeekMenu) error {
+		if weekMenu.Status != enum.WeekMenuReviewing {
+			logger.Warn(menuServiceLogTag, "ReviewWeekMenu Status Error|ID:%v|Status:%v", weekMenuID, weekMenu.Status)
+			return fmt.Errorf("菜单未提交审核")
+		}
+		weekMenu.Reviewer = reviewer
+		if !pass {
+			weekMenu.Status = enum.WeekMenuDraft
+			return nil
+		}
+		err := ms.checkNoOrderOnline(weekMenu)
+		if err != nil {
+			return err
+		}
+		weekMenu.Status = enum.WeekMenuPublished
+		weekMenu.PublishAt = time.Now()
+		weekMenu.PublishedContent = weekMenu.MenuContent
+		return nil
+	})
+}
+
+func (ms *MenuService) ArchiveWeekMenu(weekMenuID uint32) error {
+	return ms.changeWeekMenuStatus(weekMenuID, func(weekMenu *model.WeekMenu) error {
+		if !weekMenu.IsOnline() {
+			logger.Warn(menuServiceLogTag, "ArchiveWeekMenu Status Error|ID:%v|Status:%v", weekMenuID, weekMenu.Status)
+			return fmt.Errorf("只有已发布的菜单可以归档")
+		}
+		weekMenu.Status = enum.WeekMenuArchived
+		return nil
+	})
+}
+
+func (ms *MenuService) GetWeekMenuHistory(weekMenuID uint32, page, pageSize int32) ([]*model.WeekMenuHistory, int32, error) {
+	historyList, err := ms.weekMenuHistoryModel.GetHistoryList(weekMenuID, page, pageSize)
+	if err != nil {
+		logger.Warn(menuServiceLogTag, "GetWeekMenuHistory Failed|ID:%v|Err:%v", weekMenuID, err)
+		return nil, 0, err
+	}
+
+	count, err := ms.weekMenuHistoryModel.GetHistoryCount(weekMenuID)
+	if err != nil {
+		logger.Warn(menuServiceLogTag, "GetWeekMenuHistoryCount Failed|ID:%v|Err:%v", weekMenuID, err)
+		return nil, 0, err
+	}
+	return historyList, count, nil
+}
+
 func (ms *MenuService) GetWeekMenuByTime(menuDate int64, menuType uint32) (map[uint8][]uint32, error) {
 	start := utils.GetFirstDateOfWeek(menuDate)
 	weekMenu, err := ms.weekMenuModel.GetWeekMenuByDate(start, menuType)
@@ -204,9 +393,13 @@ func (ms *MenuService) GetWeekMenuByTime(menuDate int64, menuType uint32) (map[u
 		logger.Warn(menuServiceLogTag, "GetWeekMenuByDate Failed|Err:%v", err)
 		return nil, err
 	}
+	if !weekMenu.IsOnline() {
+		logger.Warn(menuServiceLogTag, "GetWeekMenuByTime Not Published|ID:%v|Status:%v", weekMenu.ID, weekMenu.Status)
+		return nil, fmt.Errorf("菜单未发布")
+	}
 
 	index := int(time.Unix(menuDate, 0).Weekday()+6) % 7
-	menuConf := weekMenu.ToWeekMenuConfig()
+	menuConf := weekMenu.ToPublishedWeekMenuConfig()
 	if len(menuConf) <= index {
 		logger.Warn(menuServiceLogTag, "GetWeekMenuByTime Extend Config Length|Date:%v|Index:%v", menuDate, index)
 		return nil, err
@@ -34,6 +34,7 @@ type OrderService struct {
 	orderDetailModel   *model.OrderDetailModel
 	orderDiscountModel *model.OrderDiscountModel
 	orderUserModel     *model.OrderUserModel
+	dishRatingModel    *model.DishRatingModel
 }
 
 func NewOrderService(sqlCli *sql.DB) *OrderService {
@@ -42,12 +43,14 @@ func NewOrderService(sqlCli *sql.DB) *OrderService {
 	orderDetailModel := model.NewOrderDetailModel(sqlCli)
 	orderDiscountModel := model.NewOrderDiscountModel(sqlCli)
 	orderUserModel := model.NewOrderUserModel(sqlCli)
+	dishRatingModel := model.NewDishRatingModelWithDB(sqlCli)
 	return &OrderService{
 		payOrderModel:      payOrderModel,
 		orderModel:         orderModel,
 		orderDetailModel:   orderDetailModel,
 		orderDiscountModel: orderDiscountModel,
 		orderUserModel:     orderUserModel,
+		dishRatingModel:    dishRatingModel,
 		sqlCli:             sqlCli,
 	}
 }
@@ -195,7 +198,15 @@ func (os *OrderService) ApplyOrder(tx *sql.Tx, order *model.OrderDao, items []*m
 	dishMap map[uint32]*model.Dish, discountAmount, extraPay float64) error {
 	totalAmount := float64(0)
 	for _, item := range items {
-		dish := dishMap[item.DishID]
+		dish, ok := dishMap[item.DishID]
+		if !ok || dish.IsDelete {
+			logger.Warn(orderServiceLogTag, "ApplyOrder Dish Not Found|DishID:%v", item.DishID)
+			return fmt.Errorf("菜品不存在或已删除")
+		}
+		if !dish.IsAvailable(order.OrderDate) {
+			logger.Warn(orderServiceLogTag, "ApplyOrder Dish Unavailable|DishID:%v|Date:%v", item.DishID, order.OrderDate)
+			return fmt.Errorf("%v已下架或不在供应季节", dish.DishName)
+		}
 		item.Price = dish.Price
 		item.DishType = dish.DishType
 		totalAmount += item.Price * float64(item.Quantity)
@@ -452,3 +463,77 @@ func (os *OrderService) LoginUserOrderDiscountInfo(uid uint32, discountType uint
 
 	return minPay, totalDiscount, discountAmount, nil
 }
+
+func (os *OrderService) RateOrder(uid, orderID uint32, ratingList []*model.DishRating) error {
+	order, details, err := os.GetOrder(orderID)
+	if err != nil {
+		return fmt.Errorf("订单不存在")
+	}
+	if order.Uid != uid {
+		logger.Warn(orderServiceLogTag, "RateOrder Uid Not Match|OrderID:%v|Uid:%v|OrderUid:%v",
+			orderID, uid, order.Uid)
+		return fmt.Errorf("只能评价自己的订单")
+	}
+	if order.Status != enum.OrderFinish {
+		return fmt.Errorf("订单未送达，暂不能评价")
+	}
+
+	preRatingList, err := os.dishRatingModel.GetRatingByOrder(orderID)
+	if err != nil {
+		return err
+	}
+	// 同一订单的同一菜品只能评价一次，表上 (order_id, dish_id) 唯一键兜底并发提交
+	ratedMap := make(map[uint32]bool)
+	for _, rating := range preRatingList {
+		ratedMap[rating.DishID] = true
+	}
+	detailMap := make(map[uint32]*model.OrderDetail)
+	for _, detail := range details {
+		detailMap[detail.ID] = detail
+	}
+
+	for _, rating := range ratingList {
+		detail, ok := detailMap[rating.OrderDetailID]
+		if !ok {
+			return fmt.Errorf("订单中不存在该菜品|DetailID:%v", rating.OrderDetailID)
+		}
+		if ratedMap[detail.DishID] {
+			return fmt.Errorf("菜品已评价，请勿重复评价|DetailID:%v", rating.OrderDetailID)
+		}
+		ratedMap[detail.DishID] = true
+		rating.OrderID = orderID
+		rating.DishID = detail.DishID
+		rating.Uid = uid
+	}
+
+	err = os.dishRatingModel.BatchInsert(ratingList)
+	if err != nil {
+		logger.Warn(orderServiceLogTag, "RateOrder BatchInsert Failed|OrderID:%v|Err:%v", orderID, err)
+		return err
+	}
+	return nil
+}
+
+func (os *OrderService) GetDishRatingList(dishID uint32, startTime, endTime int64, page, pageSize int32) ([]*model.DishRating, int32, error) {
+	ratingList, err := os.dishRatingModel.GetRatingList(dishID, startTime, endTime, page, pageSize)
+	if err != nil {
+		logger.Warn(orderServiceLogTag, "GetDishRatingList Failed|Err:%v", err)
+		return nil, 0, err
+	}
+
+	count, err := os.dishRatingModel.GetRatingCount(dishID, startTime, endTime)
+	if err != nil {
+		logger.Warn(orderServiceLogTag, "GetDishRatingCount Failed|Err:%v", err)
+		return nil, 0, err
+	}
+	return ratingList, count, nil
+}
+
+func (os *OrderService) GetDishRatingSummary(dishID uint32, startTime, endTime int64) ([]*model.DishRatingSummary, error) {
+	summaryList, err := os.dishRatingModel.GetRatingSummary(dishID, startTime, endTime)
+	if err != nil {
+		logger.Warn(orderServiceLogTag, "GetDishRatingSummary Failed|Err:%v", err)
+		return nil, err
+	}
+	return summaryList, nil
+}
@@ -0,0 +1,264 @@
+package service
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/canteen_management/enum"
+	"github.com/canteen_management/logger"
+	"github.com/canteen_management/model"
+	"github.com/canteen_management/utils"
+)
+
+const (
+	payableServiceLogTag = "PayableService"
+
+	amountPrecision = 0.01
+)
+
+type PayableService struct {
+	sqlCli             *sql.DB
+	purchaseOrderModel *model.PurchaseOrderModel
+	statementModel     *model.SupplierStatementModel
+	paymentModel       *model.SupplierPaymentModel
+}
+
+func NewPayableService(sqlCli *sql.DB) *PayableService {
+	purchaseOrderModel := model.NewPurchaseOrderModelWithDB(sqlCli)
+	statementModel := model.NewSupplierStatementModelWithDB(sqlCli)
+	paymentModel := model.NewSupplierPaymentModelWithDB(sqlCli)
+	return &PayableService{
+		sqlCli:             sqlCli,
+		purchaseOrderModel: purchaseOrderModel,
+		statementModel:     statementModel,
+		paymentModel:       paymentModel,
+	}
+}
+
+func (ps *PayableService) GenerateStatement(supplierID, creator uint32, startTime,
+	endTime int64) (*model.SupplierStatement, error) {
+	if supplierID == 0 {
+		return nil, fmt.Errorf("请选择供应商")
+	}
+	if endTime <= startTime {
+		return nil, fmt.Errorf("对账周期错误")
+	}
+
+	tx, err := ps.sqlCli.Begin()
+	if err != nil {
+		logger.Warn(payableServiceLogTag, "GenerateStatement Begin Failed|Err:%v", err)
+		return nil, err
+	}
+	defer func() {
+		utils.End(tx, err)
+	}()
+
+	orderList, err := ps.purchaseOrderModel.GetUnbilledOrdersWithLock(tx, supplierID, time.Unix(startTime, 0),
+		time.Unix(endTime, 0))
+	if err != nil {
+		return nil, err
+	}
+	if len(orderList) == 0 {
+		err = fmt.Errorf("该周期内无待对账的采购单")
+		return nil, err
+	}
+
+	statement := &model.SupplierStatement{SupplierID: supplierID, PeriodStart: time.Unix(startTime, 0),
+		PeriodEnd: time.Unix(endTime, 0), OrderCount: uint32(len(orderList)), Status: enum.StatementPending,
+		Creator: creator}
+	idList := make([]uint32, 0, len(orderList))
+	for _, order := range orderList {
+		statement.TotalAmount += order.PayAmount
+		idList = append(idList, order.ID)
+	}
+	err = ps.statementModel.InsertWithTx(tx, statement)
+	if err != nil {
+		return nil, err
+	}
+	err = ps.purchaseOrderModel.UpdateStatementWithTx(tx, idList, statement.ID)
+	if err != nil {
+		return nil, err
+	}
+	return statement, nil
+}
+
+func (ps *PayableService) GetStatementList(supplierID uint32, status int8, page,
+	pageSize int32) ([]*model.SupplierStatement, int32, error) {
+	statementList, err := ps.statementModel.GetStatementList(supplierID, status, page, pageSize)
+	if err != nil {
+		logger.Warn(payableServiceLogTag, "GetStatementList Failed|Err:%v", err)
+		return nil, 0, err
+	}
+	count, err := ps.statementModel.GetStatementCount(supplierID, status)
+	if err != nil {
+		logger.Warn(payableServiceLogTag, "GetStatementCount Failed|Err:%v", err)
+		return nil, 0, err
+	}
+	return statementList, count, nil
+}
+
+func (ps *PayableService) getStatement(statementID, supplierID uint32) (*model.SupplierStatement, error) {
+	statement, err := ps.statementModel.GetStatement(statementID)
+	if err != nil || statement == nil {
+		return nil, fmt.Errorf("对账单不存在")
+	}
+	if supplierID > 0 && statement.SupplierID != supplierID {
+		logger.Warn(payableServiceLogTag, "Statement Supplier Mismatch|ID:%v|Supplier:%v", statementID, supplierID)
+		return nil, fmt.Errorf("对账单不存在")
+	}
+	return statement, nil
+}
+
+func (ps *PayableService) GetStatementDetail(statementID, supplierID uint32) (*model.SupplierStatement,
+	[]*model.PurchaseOrder, []*model.SupplierPayment, error) {
+	statement, err := ps.getStatement(statementID, supplierID)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	orderList, err := ps.purchaseOrderModel.GetOrdersByStatement(statementID)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	paymentList, err := ps.paymentModel.GetPaymentList(statementID)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	return statement, orderList, paymentList, nil
+}
+
+func (ps *PayableService) ConfirmStatement(statementID, supplierID uint32, agree bool, reason string) error {
+	statement, err := ps.getStatement(statementID, supplierID)
+	if err != nil {
+		return err
+	}
+	if statement.Status != enum.StatementPending {
+		return fmt.Errorf("对账单状态错误")
+	}
+
+	statement.Status = enum.StatementConfirmed
+	if !agree {
+		if reason == "" {
+			return fmt.Errorf("请填写异议原因")
+		}
+		statement.Status = enum.StatementDisputed
+	}
+	statement.DisputeReason = reason
+	return ps.statementModel.UpdateStatementWithTx(nil, statement, "status", "dispute_reason")
+}
+
+func (ps *PayableService) RevokeStatement(statementID uint32) (err error) {
+	tx, err := ps.sqlCli.Begin()
+	if err != nil {
+		logger.Warn(payableServiceLogTag, "RevokeStatement Begin Failed|Err:%v", err)
+		return err
+	}
+	defer func() {
+		utils.End(tx, err)
+	}()
+
+	statement, err := ps.statementModel.GetStatementWithLock(tx, statementID)
+	if err != nil {
+		err = fmt.Errorf("对账单不存在")
+		return err
+	}
+	if statement.Status != enum.StatementPending && statement.Status != enum.StatementDisputed {
+		err = fmt.Errorf("对账单已确认，无法撤销")
+		return err
+	}
+	orderList, err := ps.purchaseOrderModel.GetOrdersByStatement(statementID)
+	if err != nil {
+		return err
+	}
+	idList := make([]uint32, 0, len(orderList))
+	for _, order := range orderList {
+		idList = append(idList, order.ID)
+	}
+
+	err = ps.purchaseOrderModel.UpdateStatementWithTx(tx, idList, 0)
+	if err != nil {
+		return err
+	}
+	err = ps.statementModel.DeleteStatementWithTx(tx, statementID)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func (ps *PayableService) RecordPayment(payment *model.SupplierPayment) error {
+	if payment.Amount <= 0 {
+		return fmt.Errorf("付款金额必须大于0")
+	}
+
+	tx, err := ps.sqlCli.Begin()
+	if err != nil {
+		logger.Warn(payableServiceLogTag, "RecordPayment Begin Failed|Err:%v", err)
+		return err
+	}
+	defer func() {
+		utils.End(tx, err)
+	}()
+
+	statement, err := ps.statementModel.GetStatementWithLock(tx, payment.StatementID)
+	if err != nil {
+		err = fmt.Errorf("对账单不存在")
+		return err
+	}
+	if statement.Status != enum.StatementConfirmed {
+		err = fmt.Errorf("对账单未确认或已结清")
+		return err
+	}
+	if payment.Amount > statement.GetOutstanding()+amountPrecision {
+		err = fmt.Errorf("付款金额超过未付金额|Outstanding:%.2f", statement.GetOutstanding())
+		return err
+	}
+
+	payment.SupplierID = statement.SupplierID
+	if payment.PayAt.IsZero() {
+		payment.PayAt = time.Now()
+	}
+	err = ps.paymentModel.InsertWithTx(tx, payment)
+	if err != nil {
+		return err
+	}
+	statement.PaidAmount += payment.Amount
+	if statement.GetOutstanding() < amountPrecision {
+		statement.Status = enum.StatementSettled
+	}
+	err = ps.statementModel.UpdateStatementWithTx(tx, statement, "paid_amount", "status")
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func (ps *PayableService) GetPayableBalance() (map[uint32]*model.PayableBalance, error) {
+	unbilledMap, err := ps.purchaseOrderModel.GetUnbilledAmountMap()
+	if err != nil {
+		return nil, err
+	}
+	statementList, err := ps.statementModel.GetUnsettledStatements()
+	if err != nil {
+		return nil, err
+	}
+
+	balanceMap := make(map[uint32]*model.PayableBalance)
+	for supplierID, amount := range unbilledMap {
+		balanceMap[supplierID] = &model.PayableBalance{SupplierID: supplierID, UnbilledAmount: amount}
+	}
+	for _, statement := range statementList {
+		balance, ok := balanceMap[statement.SupplierID]
+		if !ok {
+			balance = &model.PayableBalance{SupplierID: statement.SupplierID}
+			balanceMap[statement.SupplierID] = balance
+		}
+		balance.BilledAmount += statement.TotalAmount
+		balance.PaidAmount += statement.PaidAmount
+		balance.StatementCount++
+		if statement.Status == enum.StatementDisputed {
+			balance.DisputedCount++
+		}
+	}
+	return balanceMap, nil
+}
@@ -3,6 +3,7 @@ package service
 import (
 	"database/sql"
 	"fmt"
+	"sort"
 	"time"
 
 	"github.com/canteen_management/enum"
@@ -16,32 +17,67 @@ const (
 )
 
 type PurchaseService struct {
-	sqlCli              *sql.DB
-	wxUserModel         *model.WxUserModel
-	supplierModel       *model.SupplierModel
-	purchaseOrderModel  *model.PurchaseOrderModel
-	purchaseDetailModel *model.PurchaseDetailModel
-	goodsModel          *model.GoodsModel
-	goodsHistoryModel   *model.GoodsHistoryModel
+	sqlCli               *sql.DB
+	wxUserModel          *model.WxUserModel
+	supplierModel        *model.SupplierModel
+	sourcingModel        *model.SupplierSourcingModel
+	qualificationModel   *model.SupplierQualificationModel
+	approvalChainModel   *model.ApprovalChainModel
+	approvalModel        *model.PurchaseApprovalModel
+	purchaseOrderModel   *model.PurchaseOrderModel
+	purchaseDetailModel  *model.PurchaseDetailModel
+	goodsModel           *model.GoodsModel
+	goodsHistoryModel    *model.GoodsHistoryModel
+	purchaseReceiptModel *model.PurchaseReceiptModel
+	purchaseReturnModel  *model.PurchaseReturnModel
+	stockLotModel        *model.StockLotModel
+	goodsStockModel      *model.GoodsStockModel
+	costLayerModel       *model.CostLayerModel
+	stockAlertModel      *model.StockAlertModel
+	documentLogModel     *model.DocumentLogModel
 
-	menuTypeMap map[uint32]*model.MenuType
+	menuTypeMap    map[uint32]*model.MenuType
+	overTolerance  float64
+	underTolerance float64
 }
 
 func NewPurchaseService(sqlCli *sql.DB) *PurchaseService {
 	supplierModel := model.NewSupplierModelWithDB(sqlCli)
+	sourcingModel := model.NewSupplierSourcingModelWithDB(sqlCli)
+	qualificationModel := model.NewSupplierQualificationModelWithDB(sqlCli)
+	approvalChainModel := model.NewApprovalChainModelWithDB(sqlCli)
+	approvalModel := model.NewPurchaseApprovalModelWithDB(sqlCli)
 	purchaseOrderModel := model.NewPurchaseOrderModelWithDB(sqlCli)
 	purchaseDetailModel := model.NewPurchaseDetailModelWithDB(sqlCli)
 	wxUserModel := model.NewWxUserModelWithDB(sqlCli)
 	goodsModel := model.NewGoodsModelWithDB(sqlCli)
 	goodsHistoryModel := model.NewGoodsHistoryModel(sqlCli)
+	purchaseReceiptModel := model.NewPurchaseReceiptModelWithDB(sqlCli)
+	purchaseReturnModel := model.NewPurchaseReturnModelWithDB(sqlCli)
+	stockLotModel := model.NewStockLotModelWithDB(sqlCli)
+	goodsStockModel := model.NewGoodsStockModelWithDB(sqlCli)
+	costLayerModel := model.NewCostLayerModelWithDB(sqlCli)
+	stockAlertModel := model.NewStockAlertModelWithDB(sqlCli)
+	documentLogModel := model.NewDocumentLogModelWithDB(sqlCli)
 	return &PurchaseService{
-		sqlCli:              sqlCli,
-		supplierModel:       supplierModel,
-		purchaseOrderModel:  purchaseOrderModel,
-		purchaseDetailModel: purchaseDetailModel,
-		wxUserModel:         wxUserModel,
-		goodsModel:          goodsModel,
-		goodsHistoryModel:   goodsHistoryModel,
+		sqlCli:               sqlCli,
+		supplierModel:        supplierModel,
+		sourcingModel:        sourcingModel,
+		qualificationModel:   qualificationModel,
+		approvalChainModel:   approvalChainModel,
+		approvalModel:        approvalModel,
+		purchaseOrderModel:   purchaseOrderModel,
+		purchaseDetailModel:  purchaseDetailModel,
+		wxUserModel:          wxUserModel,
+		goodsModel:           goodsModel,
+		goodsHistoryModel:    goodsHistoryModel,
+		purchaseReceiptModel: purchaseReceiptModel,
+		purchaseReturnModel:  purchaseReturnModel,
+		stockLotModel:        stockLotModel,
+		goodsStockModel:      goodsStockModel,
+		costLayerModel:       costLayerModel,
+		stockAlertModel:      stockAlertModel,
+		documentLogModel:     documentLogModel,
 	}
 }
 
@@ -139,23 +175,301 @@ func (ps *PurchaseService) RenewSupplier(supplierID uint32, endTime int64) error
 		return fmt.Errorf("供应商未找到|ID:%v", supplierID)
 	}
 
-	lastValidityTime, err := ps.supplierModel.GetLastValidityTime()
+	if endTime < suppliers[0].ValidityDeadline.Unix() || endTime < time.Now().Unix() {
+		return fmt.Errorf("续期时间应该晚于当前有效期")
+	}
+
+	err = ps.supplierModel.UpdateValidityTime(supplierID, endTime)
 	if err != nil {
-		logger.Warn(purchaseServiceLogTag, "GetLastValidityTime Failed|Err:%v", err)
+		logger.Warn(purchaseServiceLogTag, "UpdateValidityTime GetSupplier Failed|Err:%v", err)
 		return err
 	}
-	if endTime < lastValidityTime {
-		return fmt.Errorf("续期时间应该晚于当前供应商")
+	return nil
+}
+
+func (ps *PurchaseService) GetSupplierByUid(uid uint32) (*model.Supplier, error) {
+	if uid == 0 {
+		return nil, nil
+	}
+	supplier, err := ps.supplierModel.GetSupplierByUid(uid)
+	if err != nil {
+		logger.Warn(purchaseServiceLogTag, "GetSupplierByUid Failed|Uid:%v|Err:%v", uid, err)
+		return nil, err
 	}
+	return supplier, nil
+}
 
-	err = ps.supplierModel.UpdateValidityTime(supplierID, endTime)
+func (ps *PurchaseService) GetSourcingList(supplierID uint32) ([]*model.SupplierSourcing, error) {
+	sourcingList, err := ps.sourcingModel.GetSourcingList(supplierID)
 	if err != nil {
-		logger.Warn(purchaseServiceLogTag, "UpdateValidityTime GetSupplier Failed|Err:%v", err)
+		logger.Warn(purchaseServiceLogTag, "GetSourcingList Failed|Err:%v", err)
+		return nil, err
+	}
+	return sourcingList, nil
+}
+
+func (ps *PurchaseService) checkSourcing(sourcing *model.SupplierSourcing) error {
+	if sourcing.SupplierID == 0 || (sourcing.GoodsType == 0 && sourcing.GoodsID == 0) {
+		return fmt.Errorf("供货规则参数错误")
+	}
+	if sourcing.GoodsID > 0 {
+		sourcing.GoodsType = 0
+	}
+	sourcingList, err := ps.sourcingModel.GetSourcingByTarget(sourcing.GoodsType, sourcing.GoodsID)
+	if err != nil {
+		logger.Warn(purchaseServiceLogTag, "CheckSourcing GetSourcingByTarget Failed|Err:%v", err)
+		return err
+	}
+	for _, preSourcing := range sourcingList {
+		if preSourcing.ID != sourcing.ID {
+			return fmt.Errorf("该商品或商品类型已有供货规则")
+		}
+	}
+	return nil
+}
+
+func (ps *PurchaseService) AddSourcing(sourcing *model.SupplierSourcing) error {
+	err := ps.checkSourcing(sourcing)
+	if err != nil {
+		return err
+	}
+	err = ps.sourcingModel.Insert(sourcing)
+	if err != nil {
+		logger.Warn(purchaseServiceLogTag, "Insert Sourcing Failed|Err:%v", err)
+		return err
+	}
+	return nil
+}
+
+func (ps *PurchaseService) UpdateSourcing(sourcing *model.SupplierSourcing) error {
+	err := ps.checkSourcing(sourcing)
+	if err != nil {
+		return err
+	}
+	err = ps.sourcingModel.UpdateSourcing(sourcing)
+	if err != nil {
+		logger.Warn(purchaseServiceLogTag, "UpdateSourcing Failed|Err:%v", err)
+		return err
+	}
+	return nil
+}
+
+func (ps *PurchaseService) DeleteSourcing(sourcingID uint32) error {
+	err := ps.sourcingModel.DeleteSourcing(sourcingID)
+	if err != nil {
+		logger.Warn(purchaseServiceLogTag, "DeleteSourcing Failed|Err:%v", err)
+		return err
+	}
+	return nil
+}
+
+func (ps *PurchaseService) GetQualificationList(supplierID uint32) ([]*model.SupplierQualification, error) {
+	qualificationList, err := ps.qualificationModel.GetQualificationList(supplierID)
+	if err != nil {
+		logger.Warn(purchaseServiceLogTag, "GetQualificationList Failed|Err:%v", err)
+		return nil, err
+	}
+	return qualificationList, nil
+}
+
+func (ps *PurchaseService) checkQualification(qualification *model.SupplierQualification) error {
+	if enum.GetQualificationName(qualification.DocType) == "" {
+		return fmt.Errorf("资质类型错误")
+	}
+	if qualification.FileUrl == "" {
+		return fmt.Errorf("请上传资质文件")
+	}
+	if !qualification.ExpireAt.After(qualification.IssueAt) {
+		return fmt.Errorf("有效期应晚于签发日期")
+	}
+	suppliers, err := ps.supplierModel.GetSupplier(qualification.SupplierID, "", "", "")
+	if err != nil || len(suppliers) == 0 {
+		return fmt.Errorf("供应商未找到|ID:%v", qualification.SupplierID)
+	}
+	return nil
+}
+
+func (ps *PurchaseService) AddQualification(qualification *model.SupplierQualification) error {
+	err := ps.checkQualification(qualification)
+	if err != nil {
+		return err
+	}
+	err = ps.qualificationModel.Insert(qualification)
+	if err != nil {
+		logger.Warn(purchaseServiceLogTag, "Insert Qualification Failed|Err:%v", err)
+		return err
+	}
+	return nil
+}
+
+// UpdateQualification 修改资质，supplierID 大于0表示供应商本人修改，只能更新证件文件与备注，不能改动类型与有效期
+func (ps *PurchaseService) UpdateQualification(qualification *model.SupplierQualification, supplierID uint32) error {
+	preQualification, err := ps.qualificationModel.GetQualification(qualification.ID)
+	if err != nil || preQualification.SupplierID != qualification.SupplierID {
+		return fmt.Errorf("资质不存在")
+	}
+	if supplierID > 0 {
+		qualification.DocType = preQualification.DocType
+		qualification.IssueAt = preQualification.IssueAt
+		qualification.ExpireAt = preQualification.ExpireAt
+	}
+	err = ps.checkQualification(qualification)
+	if err != nil {
+		return err
+	}
+	err = ps.qualificationModel.UpdateQualification(qualification)
+	if err != nil {
+		logger.Warn(purchaseServiceLogTag, "UpdateQualification Failed|Err:%v", err)
+		return err
+	}
+	return nil
+}
+
+func (ps *PurchaseService) DeleteQualification(qualificationID, supplierID uint32) error {
+	qualification, err := ps.qualificationModel.GetQualification(qualificationID)
+	if err != nil || (supplierID > 0 && qualification.SupplierID != supplierID) {
+		return fmt.Errorf("资质不存在")
+	}
+	if supplierID > 0 {
+		return fmt.Errorf("供应商不能删除资质，请联系采购人员")
+	}
+	err = ps.qualificationModel.DeleteQualification(qualificationID)
+	if err != nil {
+		logger.Warn(purchaseServiceLogTag, "DeleteQualification Failed|Err:%v", err)
 		return err
 	}
 	return nil
 }
 
+func (ps *PurchaseService) getLatestQualifications() ([]*model.SupplierQualification, error) {
+	qualificationList, err := ps.qualificationModel.GetQualificationList(0)
+	if err != nil {
+		logger.Warn(purchaseServiceLogTag, "GetLatestQualifications Failed|Err:%v", err)
+		return nil, err
+	}
+	latestMap := make(map[string]bool)
+	retList := make([]*model.SupplierQualification, 0)
+	for _, qualification := range qualificationList {
+		key := fmt.Sprintf("%v_%v", qualification.SupplierID, qualification.DocType)
+		if latestMap[key] {
+			continue
+		}
+		latestMap[key] = true
+		retList = append(retList, qualification)
+	}
+	return retList, nil
+}
+
+func (ps *PurchaseService) GetQualificationAlerts(days int) ([]*model.SupplierQualification, error) {
+	latestList, err := ps.getLatestQualifications()
+	if err != nil {
+		return nil, err
+	}
+	alertTime := time.Now().AddDate(0, 0, days)
+	retList := make([]*model.SupplierQualification, 0)
+	for _, qualification := range latestList {
+		if qualification.ExpireAt.Before(alertTime) {
+			retList = append(retList, qualification)
+		}
+	}
+	return retList, nil
+}
+
+// GetLapsedSuppliers 获取资质失效的供应商，仅必备资质已过期视为失效，缺失的资质见 GetMissingQualifications
+func (ps *PurchaseService) GetLapsedSuppliers() (map[uint32]bool, error) {
+	latestList, err := ps.getLatestQualifications()
+	if err != nil {
+		return nil, err
+	}
+	now, lapsedMap := time.Now(), make(map[uint32]bool)
+	for _, qualification := range latestList {
+		if enum.IsMandatoryQualification(qualification.DocType) && qualification.ExpireAt.Before(now) {
+			lapsedMap[qualification.SupplierID] = true
+		}
+	}
+	return lapsedMap, nil
+}
+
+// GetMissingQualifications 获取各供应商尚未上传的必备资质
+func (ps *PurchaseService) GetMissingQualifications() (map[uint32][]enum.QualificationType, error) {
+	supplierMap, err := ps.GetSupplierMap()
+	if err != nil {
+		return nil, err
+	}
+	latestList, err := ps.getLatestQualifications()
+	if err != nil {
+		return nil, err
+	}
+	existMap := make(map[string]bool)
+	for _, qualification := range latestList {
+		existMap[fmt.Sprintf("%v_%v", qualification.SupplierID, qualification.DocType)] = true
+	}
+	missingMap := make(map[uint32][]enum.QualificationType)
+	for supplierID := range supplierMap {
+		for _, docType := range enum.GetMandatoryQualificationList() {
+			if !existMap[fmt.Sprintf("%v_%v", supplierID, docType)] {
+				missingMap[supplierID] = append(missingMap[supplierID], docType)
+			}
+		}
+	}
+	return missingMap, nil
+}
+
+func (ps *PurchaseService) splitDetailBySupplier(details []*model.PurchaseDetail) (map[uint32][]*model.PurchaseDetail, error) {
+	supplierList, err := ps.supplierModel.GetValidSuppliers()
+	if err != nil {
+		logger.Warn(purchaseServiceLogTag, "SplitDetail GetValidSuppliers Failed|Err:%v", err)
+		return nil, err
+	}
+	if len(supplierList) == 0 {
+		return nil, fmt.Errorf("请续期供应商")
+	}
+	lapsedMap, err := ps.GetLapsedSuppliers()
+	if err != nil {
+		return nil, err
+	}
+	validMap, defaultSupplier := make(map[uint32]bool, len(supplierList)), uint32(0)
+	for _, supplier := range supplierList {
+		validMap[supplier.ID] = true
+		if defaultSupplier == 0 && !lapsedMap[supplier.ID] {
+			defaultSupplier = supplier.ID
+		}
+	}
+
+	sourcingList, err := ps.sourcingModel.GetSourcingList(0)
+	if err != nil {
+		logger.Warn(purchaseServiceLogTag, "SplitDetail GetSourcingList Failed|Err:%v", err)
+		return nil, err
+	}
+	goodsRule, typeRule := make(map[uint32]uint32), make(map[uint32]uint32)
+	for _, sourcing := range sourcingList {
+		if !validMap[sourcing.SupplierID] {
+			continue
+		}
+		if sourcing.GoodsID > 0 {
+			goodsRule[sourcing.GoodsID] = sourcing.SupplierID
+		} else {
+			typeRule[sourcing.GoodsType] = sourcing.SupplierID
+		}
+	}
+
+	supplierDetails := make(map[uint32][]*model.PurchaseDetail)
+	for _, detail := range details {
+		supplierID, ok := goodsRule[detail.GoodsID]
+		if !ok {
+			supplierID, ok = typeRule[detail.GoodsType]
+		}
+		if !ok {
+			supplierID = defaultSupplier
+		}
+		if supplierID == 0 || lapsedMap[supplierID] {
+			return nil, fmt.Errorf("供应商资质已过期，请更新资质|SupplierID:%v", supplierID)
+		}
+		supplierDetails[supplierID] = append(supplierDetails[supplierID], detail)
+	}
+	return supplierDetails, nil
+}
+
 func (ps *PurchaseService) GetPurchaseList(status int8, uid, purchaseID, supplierID uint32, startTime, endTime int64,
 	page, pageSize int32) ([]*model.PurchaseOrder, int32, map[uint32][]*model.PurchaseDetail, error) {
 	purchaseList, err := ps.purchaseOrderModel.GetPurchaseOrderList(purchaseID, status, supplierID, uid, startTime, endTime, page, pageSize)
@@ -193,148 +507,970 @@ func (ps *PurchaseService) GetPurchaseList(status int8, uid, purchaseID, supplie
 	return purchaseList, purchaseCount, detailMap, nil
 }
 
-func (ps *PurchaseService) ApplyPurchaseOrder(purchase *model.PurchaseOrder, details []*model.PurchaseDetail) error {
-	supplier, err := ps.supplierModel.GetCurrentSupplier()
-	if err != nil || supplier == nil {
-		logger.Warn(purchaseServiceLogTag, "ApplyPurchaseOrder GetCurrentSupplier Failed|Err:%v", err)
-		return fmt.Errorf("请续期供应商")
+func (ps *PurchaseService) ApplyPurchaseOrder(creator uint32, details []*model.PurchaseDetail) ([]*model.PurchaseOrder, error) {
+	if len(details) == 0 {
+		return nil, fmt.Errorf("采购商品不能为空")
+	}
+	supplierDetails, err := ps.splitDetailBySupplier(details)
+	if err != nil {
+		logger.Warn(purchaseServiceLogTag, "ApplyPurchaseOrder SplitDetailBySupplier Failed|Err:%v", err)
+		return nil, err
 	}
 
 	tx, err := ps.sqlCli.Begin()
 	if err != nil {
 		logger.Warn(purchaseServiceLogTag, "ApplyPurchaseOrder Begin Failed|Err:%v", err)
-		return err
+		return nil, err
 	}
-	defer utils.End(tx, err)
+	defer func() {
+		utils.End(tx, err)
+	}()
+
+	purchaseList := make([]*model.PurchaseOrder, 0, len(supplierDetails))
+	for supplierID, supplierDetailList := range supplierDetails {
+		totalAmount := 0.0
+		for _, item := range supplierDetailList {
+			totalAmount += item.Price * item.ExpectNumber
+		}
+
+		purchase := &model.PurchaseOrder{Supplier: supplierID, Creator: creator, Status: enum.PurchaseNew,
+			TotalAmount: totalAmount}
+		err = ps.purchaseOrderModel.InsertWithTx(tx, purchase)
+		if err != nil {
+			logger.Warn(purchaseServiceLogTag, "Insert Purchase Failed|Err:%v", err)
+			return nil, err
+		}
 
-	totalAmount := 0.0
-	for _, item := range details {
-		totalAmount += item.Price * item.ExpectNumber
+		for _, item := range supplierDetailList {
+			item.PurchaseID = purchase.ID
+		}
+		err = ps.purchaseDetailModel.BatchInsertWithTx(tx, supplierDetailList)
+		if err != nil {
+			logger.Warn(purchaseServiceLogTag, "BatchInsert PurchaseDetail Failed|Err:%v", err)
+			return nil, err
+		}
+		purchaseList = append(purchaseList, purchase)
 	}
+	return purchaseList, nil
+}
 
-	purchase.Supplier = supplier.ID
-	purchase.TotalAmount = totalAmount
-	err = ps.purchaseOrderModel.InsertWithTx(tx, purchase)
+func (ps *PurchaseService) getOnOrderMap() (map[uint32]float64, error) {
+	orderList, err := ps.purchaseOrderModel.GetOpenOrders()
 	if err != nil {
-		logger.Warn(purchaseServiceLogTag, "Insert Purchase Failed|Err:%v", err)
-		return err
+		return nil, err
 	}
-
-	for _, item := range details {
-		item.PurchaseID = purchase.ID
+	onOrderMap := make(map[uint32]float64)
+	if len(orderList) == 0 {
+		return onOrderMap, nil
+	}
+	idList := make([]uint32, 0, len(orderList))
+	for _, order := range orderList {
+		idList = append(idList, order.ID)
 	}
-	err = ps.purchaseDetailModel.BatchInsertWithTx(tx, details)
+	details, err := ps.purchaseDetailModel.GetPurchaseDetailByOrderList(idList, 0)
 	if err != nil {
-		logger.Warn(purchaseServiceLogTag, "BatchInsert PurchaseDetail Failed|Err:%v", err)
-		return err
+		return nil, err
 	}
-	return nil
+	for _, detail := range details {
+		if detail.ExpectNumber > detail.ReceiveNumber {
+			onOrderMap[detail.GoodsID] += detail.ExpectNumber - detail.ReceiveNumber
+		}
+	}
+	return onOrderMap, nil
 }
 
-func (ps *PurchaseService) ReviewPurchaseOrder(purchaseID uint32) error {
-	purchase, err := ps.purchaseOrderModel.GetPurchaseOrder(purchaseID)
-	if err != nil || purchase == nil {
-		logger.Warn(purchaseServiceLogTag, "GetPurchaseOrder Failed|Err:%v", err)
-		return fmt.Errorf("采购订单不存在或状态错误")
+func (ps *PurchaseService) GenerateReorderPurchase(creator uint32) ([]*model.PurchaseOrder, error) {
+	goodsList, err := ps.goodsModel.GetAllGoods()
+	if err != nil {
+		logger.Warn(purchaseServiceLogTag, "GenerateReorderPurchase GetAllGoods Failed|Err:%v", err)
+		return nil, err
 	}
-	if purchase.Status != enum.PurchaseNew {
-		logger.Warn(purchaseServiceLogTag, "ReviewPurchaseOrder Status Error|ID:%v|Status:%v",
-			purchaseID, purchase.Status)
-		return fmt.Errorf("采购订单状态错误")
+	onOrderMap, err := ps.getOnOrderMap()
+	if err != nil {
+		logger.Warn(purchaseServiceLogTag, "GenerateReorderPurchase GetOnOrder Failed|Err:%v", err)
+		return nil, err
+	}
+
+	details := make([]*model.PurchaseDetail, 0)
+	for _, goods := range goodsList {
+		if goods.IsDelete {
+			continue
+		}
+		number := goods.GetReorderNumber() - onOrderMap[goods.ID]
+		if number <= 0 {
+			continue
+		}
+		details = append(details, &model.PurchaseDetail{GoodsID: goods.ID, GoodsType: goods.GoodsTypeID,
+			ExpectNumber: number, Price: goods.Price})
+	}
+	if len(details) == 0 {
+		return nil, fmt.Errorf("暂无需要补货的商品")
 	}
+	return ps.ApplyPurchaseOrder(creator, details)
+}
 
-	dao := &model.PurchaseOrder{ID: purchaseID, Status: enum.PurchaseReviewed}
-	err = ps.purchaseOrderModel.UpdatePurchaseStatus(dao)
+func (ps *PurchaseService) GetApprovalChain() ([]*model.ApprovalChain, error) {
+	chainList, err := ps.approvalChainModel.GetChainList()
 	if err != nil {
-		logger.Warn(purchaseServiceLogTag, "ReviewPurchaseOrder Failed|Err:%v", err)
-		return err
+		logger.Warn(purchaseServiceLogTag, "GetApprovalChain Failed|Err:%v", err)
+		return nil, err
 	}
-	return nil
+	return chainList, nil
 }
 
-func (ps *PurchaseService) ConfirmPurchaseOrder(purchaseID uint32) error {
-	purchase, err := ps.purchaseOrderModel.GetPurchaseOrder(purchaseID)
-	if err != nil || purchase == nil {
-		logger.Warn(purchaseServiceLogTag, "GetPurchaseOrder Failed|Err:%v", err)
-		return fmt.Errorf("采购订单不存在")
+func (ps *PurchaseService) checkApprovalChain(chain *model.ApprovalChain) error {
+	if chain.Step == 0 || chain.Role <= enum.RoleMin || chain.Role >= enum.RoleMax || chain.MinAmount < 0 {
+		return fmt.Errorf("审批规则参数错误")
 	}
-	if purchase.Status != enum.PurchaseReviewed {
-		logger.Warn(purchaseServiceLogTag, "ConfirmPurchaseOrder Status Error|ID:%v|Status:%v",
-			purchaseID, purchase.Status)
-		return fmt.Errorf("采购订单未审核")
+	chainList, err := ps.GetApprovalChain()
+	if err != nil {
+		return err
+	}
+	for _, preChain := range chainList {
+		if preChain.Step == chain.Step && preChain.ID != chain.ID {
+			return fmt.Errorf("该审批级别已存在")
+		}
 	}
+	return nil
+}
 
-	dao := &model.PurchaseOrder{ID: purchaseID, Status: enum.PurchaseAccept}
-	err = ps.purchaseOrderModel.UpdatePurchaseStatus(dao)
+func (ps *PurchaseService) AddApprovalChain(chain *model.ApprovalChain) error {
+	err := ps.checkApprovalChain(chain)
 	if err != nil {
-		logger.Warn(purchaseServiceLogTag, "ConfirmPurchaseOrder Failed|Err:%v", err)
 		return err
 	}
-	return nil
+	return ps.approvalChainModel.Insert(chain)
 }
 
-func (ps *PurchaseService) ReceivePurchaseOrder(purchaseID, uid uint32, signPicture string, details []*model.PurchaseDetail) error {
-	purchase, err := ps.purchaseOrderModel.GetPurchaseOrder(purchaseID)
-	if err != nil || purchase == nil {
-		logger.Warn(purchaseServiceLogTag, "ReceivePurchase GetOrder Failed|Err:%v", err)
-		return fmt.Errorf("采购订单未找到|ID:%v", purchaseID)
+func (ps *PurchaseService) UpdateApprovalChain(chain *model.ApprovalChain) error {
+	err := ps.checkApprovalChain(chain)
+	if err != nil {
+		return err
 	}
+	return ps.approvalChainModel.UpdateChain(chain)
+}
+
+func (ps *PurchaseService) DeleteApprovalChain(chainID uint32) error {
+	return ps.approvalChainModel.DeleteChain(chainID)
+}
 
-	payAmount := 0.0
-	for _, item := range details {
-		payAmount += item.Price * item.ReceiveNumber
+func (ps *PurchaseService) getRequiredChain(amount float64) ([]*model.ApprovalChain, error) {
+	chainList, err := ps.GetApprovalChain()
+	if err != nil {
+		return nil, err
+	}
+	retList := make([]*model.ApprovalChain, 0, len(chainList))
+	for _, chain := range chainList {
+		if amount >= chain.MinAmount {
+			retList = append(retList, chain)
+		}
+	}
+	if len(retList) == 0 {
+		retList = append(retList, &model.ApprovalChain{Step: 1, Role: enum.RoleReviewer})
 	}
+	return retList, nil
+}
 
-	tx, err := ps.sqlCli.Begin()
+func (ps *PurchaseService) GetApprovalList(purchaseID uint32) ([]*model.PurchaseApproval, error) {
+	approvalList, err := ps.approvalModel.GetApprovalList(purchaseID)
 	if err != nil {
-		logger.Warn(purchaseServiceLogTag, "ApplyPurchaseOrder Begin Failed|Err:%v", err)
-		return err
+		logger.Warn(purchaseServiceLogTag, "GetApprovalList Failed|PurchaseID:%v|Err:%v", purchaseID, err)
+		return nil, err
+	}
+	return approvalList, nil
+}
+
+func (ps *PurchaseService) ReviewPurchaseOrder(purchaseID, approver, role uint32, pass bool, comment string) (err error) {
+	if !pass && comment == "" {
+		return fmt.Errorf("请填写驳回原因")
 	}
-	defer utils.End(tx, err)
 
-	err = ps.purchaseDetailModel.BatchUpdateDetailWithTx(tx, details)
+	tx, err := ps.sqlCli.Begin()
 	if err != nil {
-		logger.Warn(purchaseServiceLogTag, "ReceivePurchase BatchUpdateDetail Failed|Err:%v", err)
+		logger.Warn(purchaseServiceLogTag, "ReviewPurchaseOrder Begin Failed|Err:%v", err)
 		return err
 	}
+	defer func() {
+		utils.End(tx, err)
+	}()
 
-	purchase.AddReceiver(uid)
-	purchase.ReceiveAt = time.Now()
-	purchase.Status = enum.PurchaseReceived
-	purchase.SignPicture = signPicture
-	purchase.PayAmount = payAmount
-	err = ps.purchaseOrderModel.UpdatePurchaseWithTx(tx, purchase, "status", "pay_amount", "receiver", "receive_at", "sign_picture")
+	// 锁定采购单，避免同一审批级别被并发重复审批
+	purchase, err := ps.purchaseOrderModel.GetPurchaseOrderWithLock(tx, purchaseID)
 	if err != nil {
-		logger.Warn(purchaseServiceLogTag, "ReceivePurchaseOrder Failed|Err:%v", err)
+		logger.Warn(purchaseServiceLogTag, "GetPurchaseOrder Failed|Err:%v", err)
+		err = fmt.Errorf("采购订单不存在或状态错误")
 		return err
 	}
-
-	goodsIDList, goodsList := make([]uint32, 0, len(details)), make([]*model.Goods, 0, len(details))
-	updateMap, historyList := make(map[uint32]float64), make([]*model.GoodsHistory, 0, len(details))
-	for _, detail := range details {
-		goodsIDList = append(goodsIDList, detail.GoodsID)
-		updateMap[detail.GoodsID] = detail.ReceiveNumber
+	if purchase.Status != enum.PurchaseNew {
+		logger.Warn(purchaseServiceLogTag, "ReviewPurchaseOrder Status Error|ID:%v|Status:%v",
+			purchaseID, purchase.Status)
+		err = fmt.Errorf("采购订单状态错误")
+		return err
 	}
-	goodsList, err = ps.goodsModel.GetGoodsByIDListWithLock(tx, goodsIDList)
-	if err != nil {
-		logger.Warn(purchaseServiceLogTag, "ReceivePurchaseOrder GetGoodsByIDListWithLock Failed|Err:%v", err)
+	if purchase.Creator == approver {
+		err = fmt.Errorf("不能审批自己创建的采购单")
 		return err
 	}
-
-	for _, goods := range goodsList {
-		historyList = append(historyList,
-			model.GeneratePurchaseGoodsHistory(goods, updateMap[goods.ID], purchaseID))
-		goods.Quantity = goods.Quantity + updateMap[goods.ID]
+	if pass {
+		var lapsedMap map[uint32]bool
+		lapsedMap, err = ps.GetLapsedSuppliers()
+		if err != nil {
+			return err
+		}
+		if lapsedMap[purchase.Supplier] {
+			err = fmt.Errorf("供应商资质已过期，请更新资质|SupplierID:%v", purchase.Supplier)
+			return err
+		}
 	}
 
-	err = ps.goodsModel.BatchUpdateQuantityWithTx(tx, goodsList)
+	chainList, err := ps.getRequiredChain(purchase.TotalAmount)
 	if err != nil {
-		logger.Warn(purchaseServiceLogTag, "ReceivePurchaseOrder BatchAddQuantity Failed|Err:%v", err)
 		return err
 	}
-	err = ps.goodsHistoryModel.BatchInsert(tx, historyList)
-	if err != nil {
-		logger.Warn(purchaseServiceLogTag, "ReceivePurchaseOrder BatchInsertHistory Failed|Err:%v", err)
+	if int(purchase.ApproveStep) >= len(chainList) {
+		purchase.ApproveStep = uint8(len(chainList) - 1)
+	}
+	chain := chainList[purchase.ApproveStep]
+	if role&(1<<chain.Role) == 0 {
+		err = fmt.Errorf("无当前级别审批权限")
+		return err
+	}
+	approvalList, err := ps.GetApprovalList(purchaseID)
+	if err != nil {
+		return err
+	}
+	approverMap := make(map[uint32]bool)
+	for _, approval := range approvalList {
+		if !approval.Pass {
+			approverMap = make(map[uint32]bool)
+			continue
+		}
+		approverMap[approval.Approver] = true
+	}
+	if approverMap[approver] {
+		err = fmt.Errorf("同一审批人不能重复审批")
+		return err
+	}
+
+	approval := &model.PurchaseApproval{PurchaseID: purchaseID, Step: chain.Step, Approver: approver, Pass: pass,
+		Comment: comment}
+	err = ps.approvalModel.InsertWithTx(tx, approval)
+	if err != nil {
+		return err
+	}
+
+	if pass {
+		purchase.ApproveStep++
+		if int(purchase.ApproveStep) >= len(chainList) {
+			purchase.Status = enum.PurchaseReviewed
+		}
+	} else {
+		purchase.ApproveStep = 0
+		purchase.Status = enum.PurchaseRejected
+	}
+	err = ps.purchaseOrderModel.UpdatePurchaseWithTx(tx, purchase, "status", "approve_step")
+	if err != nil {
+		logger.Warn(purchaseServiceLogTag, "ReviewPurchaseOrder Failed|Err:%v", err)
+		return err
+	}
+	return nil
+}
+
+// EditPurchaseOrder 修改被驳回或未审批的采购单，修改后的商品重新按货源规则拆分，
+// 原供应商的商品保留在本单，其余供应商的商品生成新的采购单，返回本单及新生成的采购单
+func (ps *PurchaseService) EditPurchaseOrder(purchaseID, uid uint32,
+	details []*model.PurchaseDetail) (purchaseList []*model.PurchaseOrder, err error) {
+	if len(details) == 0 {
+		return nil, fmt.Errorf("采购商品不能为空")
+	}
+	supplierDetails, err := ps.splitDetailBySupplier(details)
+	if err != nil {
+		logger.Warn(purchaseServiceLogTag, "EditPurchaseOrder SplitDetailBySupplier Failed|Err:%v", err)
+		return nil, err
+	}
+
+	tx, err := ps.sqlCli.Begin()
+	if err != nil {
+		logger.Warn(purchaseServiceLogTag, "EditPurchaseOrder Begin Failed|Err:%v", err)
+		return nil, err
+	}
+	defer func() {
+		utils.End(tx, err)
+	}()
+
+	purchase, err := ps.purchaseOrderModel.GetPurchaseOrderWithLock(tx, purchaseID)
+	if err != nil {
+		logger.Warn(purchaseServiceLogTag, "EditPurchaseOrder GetOrder Failed|Err:%v", err)
+		err = fmt.Errorf("采购订单不存在")
+		return nil, err
+	}
+	if purchase.Creator != uid {
+		err = fmt.Errorf("只能修改自己的采购单")
+		return nil, err
+	}
+	if purchase.Status != enum.PurchaseRejected && !(purchase.Status == enum.PurchaseNew && purchase.ApproveStep == 0) {
+		err = fmt.Errorf("采购订单状态错误")
+		return nil, err
+	}
+
+	supplierIDList := make([]uint32, 0, len(supplierDetails))
+	for supplierID := range supplierDetails {
+		supplierIDList = append(supplierIDList, supplierID)
+	}
+	sort.Slice(supplierIDList, func(i, j int) bool {
+		return supplierIDList[i] < supplierIDList[j]
+	})
+	// 原供应商的商品优先保留在本单，原供应商无商品时本单改由第一个供应商承接
+	if _, ok := supplierDetails[purchase.Supplier]; !ok {
+		purchase.Supplier = supplierIDList[0]
+	}
+
+	err = ps.purchaseDetailModel.DeleteDetailWithTx(tx, purchaseID)
+	if err != nil {
+		return nil, err
+	}
+	purchaseList = make([]*model.PurchaseOrder, 0, len(supplierDetails))
+	for _, supplierID := range supplierIDList {
+		supplierDetailList := supplierDetails[supplierID]
+		totalAmount := 0.0
+		for _, detail := range supplierDetailList {
+			detail.ID = 0
+			detail.ReceiveNumber = 0
+			totalAmount += detail.Price * detail.ExpectNumber
+		}
+
+		if supplierID == purchase.Supplier {
+			purchase.TotalAmount = totalAmount
+			purchase.Status = enum.PurchaseNew
+			purchase.ApproveStep = 0
+			err = ps.purchaseOrderModel.UpdatePurchaseWithTx(tx, purchase, "supplier", "total_amount", "status",
+				"approve_step")
+			if err != nil {
+				logger.Warn(purchaseServiceLogTag, "EditPurchaseOrder Failed|Err:%v", err)
+				return nil, err
+			}
+			purchaseList = append(purchaseList, purchase)
+		} else {
+			newPurchase := &model.PurchaseOrder{Supplier: supplierID, Creator: uid, Status: enum.PurchaseNew,
+				TotalAmount: totalAmount}
+			err = ps.purchaseOrderModel.InsertWithTx(tx, newPurchase)
+			if err != nil {
+				logger.Warn(purchaseServiceLogTag, "EditPurchaseOrder Insert Purchase Failed|Err:%v", err)
+				return nil, err
+			}
+			purchaseList = append(purchaseList, newPurchase)
+		}
+
+		for _, detail := range supplierDetailList {
+			detail.PurchaseID = purchaseList[len(purchaseList)-1].ID
+		}
+		err = ps.purchaseDetailModel.BatchInsertWithTx(tx, supplierDetailList)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return purchaseList, nil
+}
+
+func (ps *PurchaseService) ConfirmPurchaseOrder(purchaseID, supplierID uint32) error {
+	purchase, err := ps.purchaseOrderModel.GetPurchaseOrder(purchaseID)
+	if err != nil || purchase == nil {
+		logger.Warn(purchaseServiceLogTag, "GetPurchaseOrder Failed|Err:%v", err)
+		return fmt.Errorf("采购订单不存在")
+	}
+	if supplierID > 0 && purchase.Supplier != supplierID {
+		logger.Warn(purchaseServiceLogTag, "ConfirmPurchaseOrder Supplier Mismatch|ID:%v|Supplier:%v",
+			purchaseID, supplierID)
+		return fmt.Errorf("采购订单不存在")
+	}
+	if purchase.Status != enum.PurchaseReviewed {
+		logger.Warn(purchaseServiceLogTag, "ConfirmPurchaseOrder Status Error|ID:%v|Status:%v",
+			purchaseID, purchase.Status)
+		return fmt.Errorf("采购订单未审核")
+	}
+
+	dao := &model.PurchaseOrder{ID: purchaseID, Status: enum.PurchaseAccept, AcceptAt: time.Now()}
+	err = ps.purchaseOrderModel.UpdatePurchase(dao, "status", "accept_at")
+	if err != nil {
+		logger.Warn(purchaseServiceLogTag, "ConfirmPurchaseOrder Failed|Err:%v", err)
+		return err
+	}
+	return nil
+}
+
+func (ps *PurchaseService) SetCostMethod(costMethod enum.CostMethod) {
+	ps.costLayerModel.SetCostMethod(costMethod)
+}
+
+func (ps *PurchaseService) SetReceiveTolerance(overTolerance, underTolerance float64) {
+	ps.overTolerance = overTolerance
+	ps.underTolerance = underTolerance
+}
+
+func (ps *PurchaseService) GetReceiptList(purchaseID uint32) ([]*model.PurchaseReceipt, error) {
+	receiptList, err := ps.purchaseReceiptModel.GetReceiptList(purchaseID)
+	if err != nil {
+		logger.Warn(purchaseServiceLogTag, "GetReceiptList Failed|PurchaseID:%v|Err:%v", purchaseID, err)
+		return nil, err
+	}
+	return receiptList, nil
+}
+
+func (ps *PurchaseService) isReceiveFinish(details []*model.PurchaseDetail) bool {
+	for _, detail := range details {
+		if detail.ReceiveNumber < detail.ExpectNumber*(1-ps.underTolerance/100) {
+			return false
+		}
+	}
+	return true
+}
+
+func (ps *PurchaseService) ReceivePurchaseOrder(purchaseID, uid, storeTypeID uint32, signPicture string,
+	receiveItems []*model.ReceiptItem) (err error) {
+	tx, err := ps.sqlCli.Begin()
+	if err != nil {
+		logger.Warn(purchaseServiceLogTag, "ReceivePurchaseOrder Begin Failed|Err:%v", err)
+		return err
+	}
+	defer func() {
+		utils.End(tx, err)
+	}()
+
+	// 锁定采购单与明细，避免并发收货覆盖已收数量
+	purchase, err := ps.purchaseOrderModel.GetPurchaseOrderWithLock(tx, purchaseID)
+	if err != nil {
+		logger.Warn(purchaseServiceLogTag, "ReceivePurchase GetOrder Failed|Err:%v", err)
+		err = fmt.Errorf("采购订单未找到|ID:%v", purchaseID)
+		return err
+	}
+	if purchase.Status != enum.PurchaseAccept && purchase.Status != enum.PurchaseReceived {
+		logger.Warn(purchaseServiceLogTag, "ReceivePurchaseOrder Status Error|ID:%v|Status:%v",
+			purchaseID, purchase.Status)
+		err = fmt.Errorf("采购订单状态错误")
+		return err
+	}
+	purchaseDetails, err := ps.purchaseDetailModel.GetDetailWithLock(tx, purchaseID)
+	if err != nil {
+		logger.Warn(purchaseServiceLogTag, "ReceivePurchaseOrder GetDetail Failed|ID:%v|Err:%v", purchaseID, err)
+		return err
+	}
+
+	detailMap, goodsDetailMap := make(map[uint32]*model.PurchaseDetail), make(map[uint32]*model.PurchaseDetail)
+	for _, detail := range purchaseDetails {
+		detailMap[detail.ID] = detail
+		goodsDetailMap[detail.GoodsID] = detail
+	}
+	now := time.Now()
+	items, updateDetails := make([]*model.ReceiptItem, 0, len(receiveItems)), make([]*model.PurchaseDetail, 0, len(receiveItems))
+	lotList, receiptAmount := make([]*model.StockLot, 0, len(receiveItems)), 0.0
+	for _, item := range receiveItems {
+		if item.ReceiveNumber <= 0 {
+			continue
+		}
+		detail, ok := detailMap[item.DetailID]
+		if !ok {
+			detail, ok = goodsDetailMap[item.GoodsID]
+		}
+		if !ok {
+			err = fmt.Errorf("商品不在采购单中|GoodsID:%v", item.GoodsID)
+			return err
+		}
+		if detail.ReceiveNumber+item.ReceiveNumber > detail.ExpectNumber*(1+ps.overTolerance/100) {
+			err = fmt.Errorf("收货数量超出允许范围|GoodsID:%v", detail.GoodsID)
+			return err
+		}
+		lot := &model.StockLot{GoodsID: detail.GoodsID, PurchaseID: purchaseID, BatchNo: item.BatchNo,
+			ProductionDate: now, ExpiryDate: model.NoExpiryDate, InitQuantity: item.ReceiveNumber,
+			Quantity: item.ReceiveNumber}
+		if item.ProductionDate > 0 {
+			lot.ProductionDate = time.Unix(item.ProductionDate, 0)
+		}
+		if item.ExpiryDate > 0 {
+			lot.ExpiryDate = time.Unix(item.ExpiryDate, 0)
+		}
+		if lot.ExpiryDate.Before(lot.ProductionDate) {
+			err = fmt.Errorf("保质期早于生产日期|GoodsID:%v", detail.GoodsID)
+			return err
+		}
+		detail.ReceiveNumber += item.ReceiveNumber
+		receiptAmount += detail.Price * item.ReceiveNumber
+		item.DetailID, item.GoodsID, item.Price = detail.ID, detail.GoodsID, detail.Price
+		items = append(items, item)
+		updateDetails = append(updateDetails, detail)
+		lotList = append(lotList, lot)
+	}
+	if len(items) == 0 {
+		err = fmt.Errorf("收货数量不能为空")
+		return err
+	}
+
+	receipt := &model.PurchaseReceipt{PurchaseID: purchaseID, Receiver: uid, StoreTypeID: storeTypeID,
+		SignPicture: signPicture, Amount: receiptAmount}
+	err = receipt.FromItems(items)
+	if err != nil {
+		return err
+	}
+
+	err = ps.purchaseDetailModel.BatchUpdateDetailWithTx(tx, updateDetails)
+	if err != nil {
+		logger.Warn(purchaseServiceLogTag, "ReceivePurchase BatchUpdateDetail Failed|Err:%v", err)
+		return err
+	}
+	err = ps.purchaseReceiptModel.InsertWithTx(tx, receipt)
+	if err != nil {
+		logger.Warn(purchaseServiceLogTag, "ReceivePurchase InsertReceipt Failed|Err:%v", err)
+		return err
+	}
+
+	err = purchase.AddReceiver(uid)
+	if err != nil {
+		return err
+	}
+	purchase.ReceiveAt = now
+	purchase.Status = enum.PurchaseReceived
+	if ps.isReceiveFinish(purchaseDetails) {
+		purchase.Status = enum.PurchaseFinish
+	}
+	purchase.SignPicture = signPicture
+	purchase.PayAmount += receiptAmount
+	err = ps.purchaseOrderModel.UpdatePurchaseWithTx(tx, purchase, "status", "pay_amount", "receiver", "receive_at", "sign_picture")
+	if err != nil {
+		logger.Warn(purchaseServiceLogTag, "ReceivePurchaseOrder Failed|Err:%v", err)
+		return err
+	}
+
+	goodsIDList, goodsList := make([]uint32, 0, len(items)), make([]*model.Goods, 0, len(items))
+	updateMap, historyList := make(map[uint32]float64), make([]*model.GoodsHistory, 0, len(items))
+	costMap := make(map[uint32]float64)
+	for _, item := range items {
+		if _, ok := updateMap[item.GoodsID]; !ok {
+			goodsIDList = append(goodsIDList, item.GoodsID)
+		}
+		updateMap[item.GoodsID] += item.ReceiveNumber
+		costMap[item.GoodsID] += item.Price * item.ReceiveNumber
+	}
+	for goodsID, amount := range costMap {
+		costMap[goodsID] = amount / updateMap[goodsID]
+	}
+	goodsList, err = ps.goodsModel.GetGoodsByIDListWithLock(tx, goodsIDList)
+	if err != nil {
+		logger.Warn(purchaseServiceLogTag, "ReceivePurchaseOrder GetGoodsByIDListWithLock Failed|Err:%v", err)
+		return err
+	}
+
+	storeMap, changeList := make(map[uint32]uint32), make([]*model.GoodsStock, 0, len(goodsList))
+	for _, goods := range goodsList {
+		storeMap[goods.ID] = goods.StoreTypeID
+		if storeTypeID > 0 {
+			storeMap[goods.ID] = storeTypeID
+		}
+		changeList = append(changeList, &model.GoodsStock{GoodsID: goods.ID, StoreTypeID: storeMap[goods.ID],
+			Quantity: updateMap[goods.ID]})
+	}
+	_, err = ps.goodsStockModel.ChangeStockWithTx(tx, goodsList, changeList)
+	if err != nil {
+		logger.Warn(purchaseServiceLogTag, "ReceivePurchaseOrder ChangeStock Failed|Err:%v", err)
+		return err
+	}
+	for _, lot := range lotList {
+		lot.StoreTypeID = storeMap[lot.GoodsID]
+	}
+	err = ps.stockLotModel.BatchInsertWithTx(tx, lotList)
+	if err != nil {
+		logger.Warn(purchaseServiceLogTag, "ReceivePurchase InsertStockLot Failed|Err:%v", err)
+		return err
+	}
+
+	for _, goods := range goodsList {
+		history := model.GeneratePurchaseGoodsHistory(goods, updateMap[goods.ID], purchaseID)
+		history.StoreTypeID = storeMap[goods.ID]
+		historyList = append(historyList, history)
+		goods.Quantity = goods.Quantity + updateMap[goods.ID]
+	}
+	err = ps.costLayerModel.ValueHistoryWithTx(tx, goodsList, historyList, costMap)
+	if err != nil {
+		logger.Warn(purchaseServiceLogTag, "ReceivePurchaseOrder ValueHistory Failed|Err:%v", err)
+		return err
+	}
+
+	err = ps.goodsModel.BatchUpdateQuantityWithTx(tx, goodsList)
+	if err != nil {
+		logger.Warn(purchaseServiceLogTag, "ReceivePurchaseOrder BatchAddQuantity Failed|Err:%v", err)
+		return err
+	}
+	err = ps.stockAlertModel.CheckAlertWithTx(tx, goodsList)
+	if err != nil {
+		return err
+	}
+	err = ps.goodsHistoryModel.BatchInsert(tx, historyList)
+	if err != nil {
+		logger.Warn(purchaseServiceLogTag, "ReceivePurchaseOrder BatchInsertHistory Failed|Err:%v", err)
+		return err
+	}
+	return nil
+}
+
+func (ps *PurchaseService) ClosePurchaseOrder(purchaseID, uid uint32, skipBackorder bool) (backorder *model.PurchaseOrder,
+	err error) {
+	tx, err := ps.sqlCli.Begin()
+	if err != nil {
+		logger.Warn(purchaseServiceLogTag, "ClosePurchaseOrder Begin Failed|Err:%v", err)
+		return nil, err
+	}
+	defer func() {
+		utils.End(tx, err)
+	}()
+
+	// 锁定采购单与明细后再计算补货量，避免重复关闭或与收货并发时生成重复或多余的补货单
+	purchase, err := ps.purchaseOrderModel.GetPurchaseOrderWithLock(tx, purchaseID)
+	if err != nil {
+		logger.Warn(purchaseServiceLogTag, "ClosePurchaseOrder GetOrder Failed|Err:%v", err)
+		err = fmt.Errorf("采购订单未找到|ID:%v", purchaseID)
+		return nil, err
+	}
+	if purchase.Status != enum.PurchaseAccept && purchase.Status != enum.PurchaseReceived {
+		logger.Warn(purchaseServiceLogTag, "ClosePurchaseOrder Status Error|ID:%v|Status:%v",
+			purchaseID, purchase.Status)
+		err = fmt.Errorf("采购订单状态错误")
+		return nil, err
+	}
+	details, err := ps.purchaseDetailModel.GetDetailWithLock(tx, purchaseID)
+	if err != nil {
+		logger.Warn(purchaseServiceLogTag, "ClosePurchaseOrder GetDetail Failed|ID:%v|Err:%v", purchaseID, err)
+		return nil, err
+	}
+
+	backDetails, totalAmount := make([]*model.PurchaseDetail, 0), 0.0
+	for _, detail := range details {
+		if detail.ReceiveNumber >= detail.ExpectNumber*(1-ps.underTolerance/100) {
+			continue
+		}
+		backDetail := &model.PurchaseDetail{GoodsID: detail.GoodsID, GoodsType: detail.GoodsType,
+			ExpectNumber: detail.ExpectNumber - detail.ReceiveNumber, Price: detail.Price}
+		totalAmount += backDetail.Price * backDetail.ExpectNumber
+		backDetails = append(backDetails, backDetail)
+	}
+
+	purchase.Status = enum.PurchaseFinish
+	err = ps.purchaseOrderModel.UpdatePurchaseWithTx(tx, purchase, "status")
+	if err != nil {
+		logger.Warn(purchaseServiceLogTag, "ClosePurchaseOrder Failed|ID:%v|Err:%v", purchaseID, err)
+		return nil, err
+	}
+	if skipBackorder || len(backDetails) == 0 {
+		return nil, nil
+	}
+
+	// 补货单需重新走审批流程
+	backorder = &model.PurchaseOrder{Supplier: purchase.Supplier, ParentID: purchaseID, Creator: uid,
+		Status: enum.PurchaseNew, TotalAmount: totalAmount}
+	err = ps.purchaseOrderModel.InsertWithTx(tx, backorder)
+	if err != nil {
+		logger.Warn(purchaseServiceLogTag, "ClosePurchaseOrder Insert Backorder Failed|Err:%v", err)
+		return nil, err
+	}
+	for _, detail := range backDetails {
+		detail.PurchaseID = backorder.ID
+	}
+	err = ps.purchaseDetailModel.BatchInsertWithTx(tx, backDetails)
+	if err != nil {
+		logger.Warn(purchaseServiceLogTag, "ClosePurchaseOrder Insert Backorder Detail Failed|Err:%v", err)
+		return nil, err
+	}
+	return backorder, nil
+}
+
+// CancelPurchaseOrder 取消尚未收货的采购单
+func (ps *PurchaseService) CancelPurchaseOrder(purchaseID, uid uint32, reason string) (err error) {
+	tx, err := ps.sqlCli.Begin()
+	if err != nil {
+		logger.Warn(purchaseServiceLogTag, "CancelPurchaseOrder Begin Failed|Err:%v", err)
+		return err
+	}
+	defer func() {
+		utils.End(tx, err)
+	}()
+
+	// 锁定采购单，避免与收货并发时取消已收货的订单
+	purchase, err := ps.purchaseOrderModel.GetPurchaseOrderWithLock(tx, purchaseID)
+	if err != nil {
+		logger.Warn(purchaseServiceLogTag, "CancelPurchaseOrder GetOrder Failed|Err:%v", err)
+		err = fmt.Errorf("采购订单未找到|ID:%v", purchaseID)
+		return err
+	}
+	if purchase.Status != enum.PurchaseNew && purchase.Status != enum.PurchaseReviewed &&
+		purchase.Status != enum.PurchaseAccept && purchase.Status != enum.PurchaseRejected {
+		logger.Warn(purchaseServiceLogTag, "CancelPurchaseOrder Status Error|ID:%v|Status:%v",
+			purchaseID, purchase.Status)
+		err = fmt.Errorf("采购订单已收货或已取消，无法取消")
+		return err
+	}
+
+	purchase.Status = enum.PurchaseCancel
+	err = ps.purchaseOrderModel.UpdatePurchaseWithTx(tx, purchase, "status")
+	if err != nil {
+		logger.Warn(purchaseServiceLogTag, "CancelPurchaseOrder Failed|ID:%v|Err:%v", purchaseID, err)
+		return err
+	}
+	docLog := &model.DocumentLog{DocType: enum.DocumentPurchase, DocID: purchaseID, Action: enum.DocumentCancel,
+		Operator: uid, Reason: reason}
+	err = ps.documentLogModel.InsertWithTx(tx, docLog)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// ReversePurchaseOrder 冲销已收货的采购单，扣回已收货且未退货的库存，已对账的采购单不能冲销
+func (ps *PurchaseService) ReversePurchaseOrder(purchaseID, uid uint32, reason string) (err error) {
+	tx, err := ps.sqlCli.Begin()
+	if err != nil {
+		logger.Warn(purchaseServiceLogTag, "ReversePurchaseOrder Begin Failed|Err:%v", err)
+		return err
+	}
+	defer func() {
+		utils.End(tx, err)
+	}()
+
+	// 锁定采购单与明细，避免重复冲销或与退货并发扣减库存
+	purchase, err := ps.purchaseOrderModel.GetPurchaseOrderWithLock(tx, purchaseID)
+	if err != nil {
+		logger.Warn(purchaseServiceLogTag, "ReversePurchaseOrder GetOrder Failed|Err:%v", err)
+		err = fmt.Errorf("采购订单未找到|ID:%v", purchaseID)
+		return err
+	}
+	if purchase.Status != enum.PurchaseReceived && purchase.Status != enum.PurchaseFinish {
+		logger.Warn(purchaseServiceLogTag, "ReversePurchaseOrder Status Error|ID:%v|Status:%v",
+			purchaseID, purchase.Status)
+		err = fmt.Errorf("采购订单未收货，无需冲销")
+		return err
+	}
+	if purchase.StatementID > 0 {
+		err = fmt.Errorf("采购订单已对账，无法冲销")
+		return err
+	}
+	details, err := ps.purchaseDetailModel.GetDetailWithLock(tx, purchaseID)
+	if err != nil {
+		logger.Warn(purchaseServiceLogTag, "ReversePurchaseOrder GetDetail Failed|ID:%v|Err:%v", purchaseID, err)
+		return err
+	}
+
+	purchase.Status, purchase.PayAmount = enum.PurchaseReversed, 0
+	err = ps.purchaseOrderModel.UpdatePurchaseWithTx(tx, purchase, "status", "pay_amount")
+	if err != nil {
+		logger.Warn(purchaseServiceLogTag, "ReversePurchaseOrder Failed|ID:%v|Err:%v", purchaseID, err)
+		return err
+	}
+	docLog := &model.DocumentLog{DocType: enum.DocumentPurchase, DocID: purchaseID, Action: enum.DocumentReverse,
+		Operator: uid, Reason: reason}
+	err = ps.documentLogModel.InsertWithTx(tx, docLog)
+	if err != nil {
+		return err
+	}
+
+	goodsIDList, updateMap := make([]uint32, 0, len(details)), make(map[uint32]float64)
+	for _, detail := range details {
+		if detail.ReceiveNumber <= detail.ReturnNumber {
+			continue
+		}
+		if _, ok := updateMap[detail.GoodsID]; !ok {
+			goodsIDList = append(goodsIDList, detail.GoodsID)
+		}
+		updateMap[detail.GoodsID] += detail.ReceiveNumber - detail.ReturnNumber
+	}
+	if len(goodsIDList) == 0 {
+		return nil
+	}
+	err = ps.returnStockWithTx(tx, purchaseID, goodsIDList, updateMap, enum.GoodsReversal, docLog.ID)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func (ps *PurchaseService) GetReturnList(purchaseID, supplierID uint32, startTime, endTime int64,
+	page, pageSize int32) ([]*model.PurchaseReturn, int32, error) {
+	returnList, err := ps.purchaseReturnModel.GetReturnList(purchaseID, supplierID, startTime, endTime, page, pageSize)
+	if err != nil {
+		logger.Warn(purchaseServiceLogTag, "GetReturnList Failed|Err:%v", err)
+		return nil, 0, err
+	}
+	count, err := ps.purchaseReturnModel.GetReturnCount(purchaseID, supplierID, startTime, endTime)
+	if err != nil {
+		logger.Warn(purchaseServiceLogTag, "GetReturnCount Failed|Err:%v", err)
+		return nil, 0, err
+	}
+	return returnList, count, nil
+}
+
+func (ps *PurchaseService) ApplyPurchaseReturn(purchaseReturn *model.PurchaseReturn, items []*model.ReturnItem) (err error) {
+	tx, err := ps.sqlCli.Begin()
+	if err != nil {
+		logger.Warn(purchaseServiceLogTag, "ApplyPurchaseReturn Begin Failed|Err:%v", err)
+		return err
+	}
+	defer func() {
+		utils.End(tx, err)
+	}()
+
+	// 锁定采购单与明细，避免并发退货超过已收货数量
+	purchase, err := ps.purchaseOrderModel.GetPurchaseOrderWithLock(tx, purchaseReturn.PurchaseID)
+	if err != nil {
+		logger.Warn(purchaseServiceLogTag, "ApplyPurchaseReturn GetOrder Failed|Err:%v", err)
+		err = fmt.Errorf("采购订单未找到|ID:%v", purchaseReturn.PurchaseID)
+		return err
+	}
+	if purchase.Status != enum.PurchaseReceived && purchase.Status != enum.PurchaseFinish {
+		logger.Warn(purchaseServiceLogTag, "ApplyPurchaseReturn Status Error|ID:%v|Status:%v",
+			purchase.ID, purchase.Status)
+		err = fmt.Errorf("采购订单未收货")
+		return err
+	}
+	if purchase.StatementID > 0 {
+		err = fmt.Errorf("采购订单已对账，无法退货")
+		return err
+	}
+	details, err := ps.purchaseDetailModel.GetDetailWithLock(tx, purchase.ID)
+	if err != nil {
+		logger.Warn(purchaseServiceLogTag, "ApplyPurchaseReturn GetDetail Failed|ID:%v|Err:%v", purchase.ID, err)
+		return err
+	}
+
+	detailMap, goodsDetailMap := make(map[uint32]*model.PurchaseDetail), make(map[uint32]*model.PurchaseDetail)
+	for _, detail := range details {
+		detailMap[detail.ID] = detail
+		goodsDetailMap[detail.GoodsID] = detail
+	}
+	updateDetails, returnItems := make([]*model.PurchaseDetail, 0, len(items)), make([]*model.ReturnItem, 0, len(items))
+	returnAmount := 0.0
+	for _, item := range items {
+		if item.ReturnNumber <= 0 {
+			continue
+		}
+		detail, ok := detailMap[item.DetailID]
+		if !ok {
+			detail, ok = goodsDetailMap[item.GoodsID]
+		}
+		if !ok {
+			err = fmt.Errorf("商品不在采购单中|GoodsID:%v", item.GoodsID)
+			return err
+		}
+		if detail.ReturnNumber+item.ReturnNumber > detail.ReceiveNumber {
+			err = fmt.Errorf("退货数量超过已收货数量|GoodsID:%v", detail.GoodsID)
+			return err
+		}
+		detail.ReturnNumber += item.ReturnNumber
+		returnAmount += detail.Price * item.ReturnNumber
+		returnItems = append(returnItems, &model.ReturnItem{DetailID: detail.ID, GoodsID: detail.GoodsID,
+			ReturnNumber: item.ReturnNumber, Price: detail.Price})
+		updateDetails = append(updateDetails, detail)
+	}
+	if len(returnItems) == 0 {
+		err = fmt.Errorf("退货商品不能为空")
+		return err
+	}
+	purchaseReturn.Supplier = purchase.Supplier
+	purchaseReturn.Amount = returnAmount
+	err = purchaseReturn.FromItems(returnItems)
+	if err != nil {
+		return err
+	}
+
+	err = ps.purchaseReturnModel.InsertWithTx(tx, purchaseReturn)
+	if err != nil {
+		return err
+	}
+	err = ps.purchaseDetailModel.BatchUpdateReturnWithTx(tx, updateDetails)
+	if err != nil {
+		return err
+	}
+	purchase.PayAmount -= returnAmount
+	err = ps.purchaseOrderModel.UpdatePurchaseWithTx(tx, purchase, "pay_amount")
+	if err != nil {
+		logger.Warn(purchaseServiceLogTag, "ApplyPurchaseReturn UpdatePayAmount Failed|Err:%v", err)
+		return err
+	}
+
+	goodsIDList, updateMap := make([]uint32, 0, len(returnItems)), make(map[uint32]float64)
+	for _, item := range returnItems {
+		if _, ok := updateMap[item.GoodsID]; !ok {
+			goodsIDList = append(goodsIDList, item.GoodsID)
+		}
+		updateMap[item.GoodsID] += item.ReturnNumber
+	}
+	err = ps.returnStockWithTx(tx, purchase.ID, goodsIDList, updateMap, enum.GoodsPurchaseReturn, purchaseReturn.ID)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// returnStockWithTx 按采购单批次扣减退回的库存，changeType、refID 为生成库存流水的类型与关联单据
+func (ps *PurchaseService) returnStockWithTx(tx *sql.Tx, purchaseID uint32, goodsIDList []uint32,
+	updateMap map[uint32]float64, changeType, refID uint32) (err error) {
+	goodsList, err := ps.goodsModel.GetGoodsByIDListWithLock(tx, goodsIDList)
+	if err != nil {
+		logger.Warn(purchaseServiceLogTag, "ApplyPurchaseReturn GetGoodsByIDListWithLock Failed|Err:%v", err)
+		return err
+	}
+	historyList, consumeList := make([]*model.GoodsHistory, 0, len(goodsList)), make([]*model.GoodsStock, 0, len(goodsList))
+	for _, goods := range goodsList {
+		// 已被出库单预留的库存不可退回，否则出库完成时会因库存不足失败
+		if updateMap[goods.ID] > goods.GetAvailable() {
+			err = fmt.Errorf("%v可用库存不足，无法退回，当前可用%v", goods.Name, goods.GetAvailable())
+			return err
+		}
+		consumeList = append(consumeList, &model.GoodsStock{GoodsID: goods.ID, Quantity: updateMap[goods.ID]})
+	}
+
+	consumedList, err := ps.stockLotModel.ConsumeLotsWithTx(tx, consumeList, purchaseID)
+	if err != nil {
+		logger.Warn(purchaseServiceLogTag, "ApplyPurchaseReturn ConsumeLots Failed|Err:%v", err)
+		return err
+	}
+	remainMap, changeList := make(map[uint32]float64), make([]*model.GoodsStock, 0, len(consumedList)+len(goodsList))
+	for goodsID, number := range updateMap {
+		remainMap[goodsID] = number
+	}
+	for _, lot := range consumedList {
+		remainMap[lot.GoodsID] -= lot.Quantity
+		changeList = append(changeList, &model.GoodsStock{GoodsID: lot.GoodsID, StoreTypeID: lot.StoreTypeID,
+			Quantity: -lot.Quantity})
+	}
+	for _, goods := range goodsList {
+		if remainMap[goods.ID] > 0 {
+			changeList = append(changeList, &model.GoodsStock{GoodsID: goods.ID, StoreTypeID: goods.StoreTypeID,
+				Quantity: -remainMap[goods.ID]})
+		}
+	}
+	_, err = ps.goodsStockModel.ChangeStockWithTx(tx, goodsList, changeList)
+	if err != nil {
+		logger.Warn(purchaseServiceLogTag, "ApplyPurchaseReturn ChangeStock Failed|Err:%v", err)
+		return err
+	}
+	for _, goods := range goodsList {
+		historyList = append(historyList,
+			model.GenerateGoodsHistory(goods.ID, goods.Quantity, -updateMap[goods.ID], changeType, refID))
+		goods.Quantity = goods.Quantity - updateMap[goods.ID]
+	}
+	err = ps.costLayerModel.ValueHistoryWithTx(tx, goodsList, historyList, nil)
+	if err != nil {
+		logger.Warn(purchaseServiceLogTag, "ApplyPurchaseReturn ValueHistory Failed|Err:%v", err)
+		return err
+	}
+
+	err = ps.goodsModel.BatchUpdateQuantityWithTx(tx, goodsList)
+	if err != nil {
+		logger.Warn(purchaseServiceLogTag, "ApplyPurchaseReturn BatchUpdateQuantity Failed|Err:%v", err)
+		return err
+	}
+	err = ps.stockAlertModel.CheckAlertWithTx(tx, goodsList)
+	if err != nil {
+		return err
+	}
+	err = ps.goodsHistoryModel.BatchInsert(tx, historyList)
+	if err != nil {
+		logger.Warn(purchaseServiceLogTag, "ApplyPurchaseReturn BatchInsertHistory Failed|Err:%v", err)
 		return err
 	}
 	return nil
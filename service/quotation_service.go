@@ -0,0 +1,263 @@
+package service
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/canteen_management/enum"
+	"github.com/canteen_management/logger"
+	"github.com/canteen_management/model"
+	"github.com/canteen_management/utils"
+)
+
+const (
+	quotationServiceLogTag = "QuotationService"
+)
+
+type QuotationService struct {
+	sqlCli             *sql.DB
+	quotationModel     *model.QuotationModel
+	quotationItemModel *model.QuotationItemModel
+	goodsModel         *model.GoodsModel
+	goodsTypeModel     *model.GoodsTypeModel
+	priceHistoryModel  *model.GoodsPriceHistoryModel
+}
+
+func NewQuotationService(sqlCli *sql.DB) *QuotationService {
+	quotationModel := model.NewQuotationModelWithDB(sqlCli)
+	quotationItemModel := model.NewQuotationItemModelWithDB(sqlCli)
+	goodsModel := model.NewGoodsModelWithDB(sqlCli)
+	goodsTypeModel := model.NewGoodsTypeModelWithDB(sqlCli)
+	priceHistoryModel := model.NewGoodsPriceHistoryModelWithDB(sqlCli)
+	return &QuotationService{
+		sqlCli:             sqlCli,
+		quotationModel:     quotationModel,
+		quotationItemModel: quotationItemModel,
+		goodsModel:         goodsModel,
+		goodsTypeModel:     goodsTypeModel,
+		priceHistoryModel:  priceHistoryModel,
+	}
+}
+
+func (qs *QuotationService) GetQuotationList(status int8, supplierID uint32, page, pageSize int32) ([]*model.Quotation,
+	int32, map[uint32][]*model.QuotationItem, error) {
+	quotationList, err := qs.quotationModel.GetQuotationList(status, page, pageSize)
+	if err != nil {
+		logger.Warn(quotationServiceLogTag, "GetQuotationList Failed|Err:%v", err)
+		return nil, 0, nil, err
+	}
+	count, err := qs.quotationModel.GetQuotationCount(status)
+	if err != nil {
+		logger.Warn(quotationServiceLogTag, "GetQuotationCount Failed|Err:%v", err)
+		return nil, 0, nil, err
+	}
+
+	idList := make([]uint32, 0, len(quotationList))
+	for _, quotation := range quotationList {
+		idList = append(idList, quotation.ID)
+	}
+	items, err := qs.quotationItemModel.GetItems(idList, supplierID)
+	if err != nil {
+		logger.Warn(quotationServiceLogTag, "GetQuotationList GetItems Failed|Err:%v", err)
+		return nil, 0, nil, err
+	}
+	itemMap := make(map[uint32][]*model.QuotationItem)
+	for _, item := range items {
+		itemMap[item.QuotationID] = append(itemMap[item.QuotationID], item)
+	}
+	return quotationList, count, itemMap, nil
+}
+
+func (qs *QuotationService) OpenQuotation(quotation *model.Quotation, goodsList []uint32) error {
+	if len(goodsList) == 0 {
+		return fmt.Errorf("询价商品不能为空")
+	}
+	if !quotation.Deadline.After(time.Now()) {
+		return fmt.Errorf("报价截止时间应晚于当前时间")
+	}
+	err := quotation.FromGoodsList(goodsList)
+	if err != nil {
+		return err
+	}
+	quotation.Status = enum.QuotationOpen
+	err = qs.quotationModel.Insert(quotation)
+	if err != nil {
+		logger.Warn(quotationServiceLogTag, "Insert Quotation Failed|Err:%v", err)
+		return err
+	}
+	return nil
+}
+
+func (qs *QuotationService) SubmitQuote(quotationID, supplierID uint32, items []*model.QuotationItem) error {
+	quotation, err := qs.quotationModel.GetQuotation(quotationID)
+	if err != nil {
+		logger.Warn(quotationServiceLogTag, "SubmitQuote GetQuotation Failed|ID:%v|Err:%v", quotationID, err)
+		return fmt.Errorf("询价单不存在")
+	}
+	if !quotation.IsQuoting() {
+		return fmt.Errorf("询价单已截止")
+	}
+
+	goodsMap := make(map[uint32]bool)
+	for _, goodsID := range quotation.ToGoodsList() {
+		goodsMap[goodsID] = true
+	}
+	for _, item := range items {
+		if !goodsMap[item.GoodsID] {
+			return fmt.Errorf("商品不在询价范围内|GoodsID:%v", item.GoodsID)
+		}
+		if item.Price <= 0 {
+			return fmt.Errorf("报价必须大于0|GoodsID:%v", item.GoodsID)
+		}
+		item.QuotationID = quotationID
+		item.SupplierID = supplierID
+		item.IsAward = false
+	}
+
+	tx, err := qs.sqlCli.Begin()
+	if err != nil {
+		logger.Warn(quotationServiceLogTag, "SubmitQuote Begin Failed|Err:%v", err)
+		return err
+	}
+	defer func() {
+		utils.End(tx, err)
+	}()
+
+	err = qs.quotationItemModel.DeleteSupplierItemsWithTx(tx, quotationID, supplierID)
+	if err != nil {
+		return err
+	}
+	err = qs.quotationItemModel.BatchInsertWithTx(tx, items)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func (qs *QuotationService) GetQuotationDetail(quotationID, supplierID uint32) (*model.Quotation,
+	[]*model.QuotationItem, error) {
+	quotation, err := qs.quotationModel.GetQuotation(quotationID)
+	if err != nil {
+		logger.Warn(quotationServiceLogTag, "GetQuotationDetail Failed|ID:%v|Err:%v", quotationID, err)
+		return nil, nil, fmt.Errorf("询价单不存在")
+	}
+	items, err := qs.quotationItemModel.GetItems([]uint32{quotationID}, supplierID)
+	if err != nil {
+		logger.Warn(quotationServiceLogTag, "GetQuotationDetail GetItems Failed|ID:%v|Err:%v", quotationID, err)
+		return nil, nil, err
+	}
+	return quotation, items, nil
+}
+
+func (qs *QuotationService) AwardQuotation(quotationID, operator uint32, awardMap map[uint32]uint32) error {
+	quotation, items, err := qs.GetQuotationDetail(quotationID, 0)
+	if err != nil {
+		return err
+	}
+	if quotation.Status != enum.QuotationOpen {
+		return fmt.Errorf("询价单状态错误")
+	}
+	if time.Now().Before(quotation.Deadline) {
+		return fmt.Errorf("报价尚未截止，截止时间%v", quotation.Deadline.Format("2006-01-02 15:04"))
+	}
+
+	awardItems := make(map[uint32]*model.QuotationItem)
+	for _, item := range items {
+		supplierID, ok := awardMap[item.GoodsID]
+		if ok && supplierID != item.SupplierID {
+			continue
+		}
+		preItem, ok := awardItems[item.GoodsID]
+		if !ok || item.Price < preItem.Price {
+			awardItems[item.GoodsID] = item
+		}
+	}
+	for goodsID, supplierID := range awardMap {
+		if _, ok := awardItems[goodsID]; !ok {
+			return fmt.Errorf("所选供应商未对该商品报价|GoodsID:%v|SupplierID:%v", goodsID, supplierID)
+		}
+	}
+	if len(awardItems) == 0 {
+		return fmt.Errorf("暂无供应商报价")
+	}
+
+	goodsIDList := make([]uint32, 0, len(awardItems))
+	updateItems := make([]*model.QuotationItem, 0, len(awardItems))
+	for goodsID, item := range awardItems {
+		goodsIDList = append(goodsIDList, goodsID)
+		item.IsAward = true
+		updateItems = append(updateItems, item)
+	}
+	goodsTypeList, err := qs.goodsTypeModel.GetGoodsTypes()
+	if err != nil {
+		logger.Warn(quotationServiceLogTag, "AwardQuotation GetGoodsTypes Failed|Err:%v", err)
+		return err
+	}
+	discountMap := make(map[uint32]float64)
+	for _, goodsType := range goodsTypeList {
+		discountMap[goodsType.ID] = goodsType.Discount
+	}
+
+	tx, err := qs.sqlCli.Begin()
+	if err != nil {
+		logger.Warn(quotationServiceLogTag, "AwardQuotation Begin Failed|Err:%v", err)
+		return err
+	}
+	defer func() {
+		utils.End(tx, err)
+	}()
+
+	goodsList, err := qs.goodsModel.GetGoodsByIDListWithLock(tx, goodsIDList)
+	if err != nil {
+		logger.Warn(quotationServiceLogTag, "AwardQuotation GetGoodsByIDListWithLock Failed|Err:%v", err)
+		return err
+	}
+	historyList := make([]*model.GoodsPriceHistory, 0, len(goodsList))
+	for _, goods := range goodsList {
+		item := awardItems[goods.ID]
+		discount, ok := discountMap[goods.GoodsTypeID]
+		if !ok {
+			discount = 1
+		}
+		finalPrice := item.Price * discount
+		historyList = append(historyList, model.GenerateQuotationPriceHistory(goods, item, finalPrice, operator))
+		goods.AveragePrice = item.Price
+		goods.Price = finalPrice
+	}
+
+	err = qs.goodsModel.BatchUpdatePriceWithTx(tx, goodsList)
+	if err != nil {
+		logger.Warn(quotationServiceLogTag, "AwardQuotation BatchUpdatePrice Failed|Err:%v", err)
+		return err
+	}
+	err = qs.priceHistoryModel.BatchInsert(tx, historyList)
+	if err != nil {
+		logger.Warn(quotationServiceLogTag, "AwardQuotation Insert PriceHistory Failed|Err:%v", err)
+		return err
+	}
+	err = qs.quotationItemModel.BatchUpdateAwardWithTx(tx, updateItems)
+	if err != nil {
+		return err
+	}
+	err = qs.quotationModel.UpdateStatusWithTx(tx, &model.Quotation{ID: quotationID, Status: enum.QuotationAwarded,
+		CloseAt: time.Now()})
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func (qs *QuotationService) CancelQuotation(quotationID uint32) error {
+	quotation, err := qs.quotationModel.GetQuotation(quotationID)
+	if err != nil {
+		logger.Warn(quotationServiceLogTag, "CancelQuotation GetQuotation Failed|ID:%v|Err:%v", quotationID, err)
+		return fmt.Errorf("询价单不存在")
+	}
+	if quotation.Status != enum.QuotationOpen {
+		return fmt.Errorf("询价单状态错误")
+	}
+	quotation.Status = enum.QuotationCancelled
+	quotation.CloseAt = time.Now()
+	return qs.quotationModel.UpdateStatusWithTx(nil, quotation)
+}
@@ -0,0 +1,172 @@
+package service
+
+import (
+	"database/sql"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/canteen_management/enum"
+	"github.com/canteen_management/logger"
+	"github.com/canteen_management/model"
+)
+
+const (
+	scorecardServiceLogTag = "ScorecardService"
+
+	defaultOnTimeHours = 24
+
+	onTimeWeight   = 30
+	accuracyWeight = 30
+	returnWeight   = 20
+	priceWeight    = 20
+)
+
+type ScorecardService struct {
+	sqlCli              *sql.DB
+	purchaseOrderModel  *model.PurchaseOrderModel
+	purchaseDetailModel *model.PurchaseDetailModel
+	priceHistoryModel   *model.GoodsPriceHistoryModel
+}
+
+func NewScorecardService(sqlCli *sql.DB) *ScorecardService {
+	purchaseOrderModel := model.NewPurchaseOrderModelWithDB(sqlCli)
+	purchaseDetailModel := model.NewPurchaseDetailModelWithDB(sqlCli)
+	priceHistoryModel := model.NewGoodsPriceHistoryModelWithDB(sqlCli)
+	return &ScorecardService{
+		sqlCli:              sqlCli,
+		purchaseOrderModel:  purchaseOrderModel,
+		purchaseDetailModel: purchaseDetailModel,
+		priceHistoryModel:   priceHistoryModel,
+	}
+}
+
+type scoreCounter struct {
+	acceptHours, leadHours                  float64
+	acceptCount, receiveCount, onTimeCount  uint32
+	accuracy                                float64
+	detailCount                             uint32
+	receiveAmount, returnAmount, priceTotal float64
+	priceCount                              uint32
+}
+
+func (ss *ScorecardService) GetScorecards(startTime, endTime int64, onTimeHours float64) ([]*model.SupplierScore, error) {
+	if onTimeHours <= 0 {
+		onTimeHours = defaultOnTimeHours
+	}
+	orderList, err := ss.purchaseOrderModel.GetOrdersByTime(time.Unix(startTime, 0), time.Unix(endTime, 0))
+	if err != nil {
+		logger.Warn(scorecardServiceLogTag, "GetOrdersByTime Failed|Err:%v", err)
+		return nil, err
+	}
+	priceHistory, err := ss.priceHistoryModel.GetSupplierPriceHistory(time.Unix(startTime, 0), time.Unix(endTime, 0))
+	if err != nil {
+		logger.Warn(scorecardServiceLogTag, "GetSupplierPriceHistory Failed|Err:%v", err)
+		return nil, err
+	}
+
+	scoreMap, counterMap := make(map[uint32]*model.SupplierScore), make(map[uint32]*scoreCounter)
+	getCounter := func(supplierID uint32) (*model.SupplierScore, *scoreCounter) {
+		if _, ok := scoreMap[supplierID]; !ok {
+			scoreMap[supplierID] = &model.SupplierScore{SupplierID: supplierID}
+			counterMap[supplierID] = &scoreCounter{}
+		}
+		return scoreMap[supplierID], counterMap[supplierID]
+	}
+
+	orderMap, finishIDList := make(map[uint32]*model.PurchaseOrder), make([]uint32, 0)
+	for _, order := range orderList {
+		orderMap[order.ID] = order
+		score, counter := getCounter(order.Supplier)
+		score.OrderCount++
+		startAt := order.CreateAt
+		if order.AcceptAt.After(order.CreateAt) {
+			counter.acceptHours += order.AcceptAt.Sub(order.CreateAt).Hours()
+			counter.acceptCount++
+			startAt = order.AcceptAt
+		}
+		if order.Status == enum.PurchaseReceived || order.Status == enum.PurchaseFinish {
+			leadHours := math.Max(order.ReceiveAt.Sub(startAt).Hours(), 0)
+			counter.leadHours += leadHours
+			counter.receiveCount++
+			if leadHours <= onTimeHours {
+				counter.onTimeCount++
+			}
+		}
+		if order.Status == enum.PurchaseFinish {
+			score.FinishCount++
+			finishIDList = append(finishIDList, order.ID)
+		}
+	}
+
+	if len(finishIDList) > 0 {
+		details, err := ss.purchaseDetailModel.GetPurchaseDetailByOrderList(finishIDList, 0)
+		if err != nil {
+			logger.Warn(scorecardServiceLogTag, "GetPurchaseDetail Failed|Err:%v", err)
+			return nil, err
+		}
+		for _, detail := range details {
+			order, ok := orderMap[detail.PurchaseID]
+			if !ok || detail.ExpectNumber <= 0 {
+				continue
+			}
+			_, counter := getCounter(order.Supplier)
+			counter.accuracy += math.Max(1-math.Abs(detail.ReceiveNumber-detail.ExpectNumber)/detail.ExpectNumber, 0)
+			counter.detailCount++
+			counter.receiveAmount += detail.ReceiveNumber * detail.Price
+			counter.returnAmount += detail.ReturnNumber * detail.Price
+		}
+	}
+	for _, history := range priceHistory {
+		if _, ok := scoreMap[history.SupplierID]; !ok || history.BeforePrice <= 0 {
+			continue
+		}
+		_, counter := getCounter(history.SupplierID)
+		counter.priceTotal += (history.AfterPrice - history.BeforePrice) / history.BeforePrice
+		counter.priceCount++
+	}
+
+	retList := make([]*model.SupplierScore, 0, len(scoreMap))
+	for supplierID, score := range scoreMap {
+		counter := counterMap[supplierID]
+		if counter.acceptCount > 0 {
+			score.AcceptHours = counter.acceptHours / float64(counter.acceptCount)
+		}
+		if counter.receiveCount > 0 {
+			score.LeadHours = counter.leadHours / float64(counter.receiveCount)
+			score.OnTimeRate = float64(counter.onTimeCount) / float64(counter.receiveCount)
+		}
+		if counter.detailCount > 0 {
+			score.QuantityAccuracy = counter.accuracy / float64(counter.detailCount)
+		}
+		if counter.receiveAmount > 0 {
+			score.ReturnRate = counter.returnAmount / counter.receiveAmount
+		}
+		if counter.priceCount > 0 {
+			score.PriceChangeRate = counter.priceTotal / float64(counter.priceCount)
+		}
+		score.Score = weightScore(score)
+		retList = append(retList, score)
+	}
+	rankScores(retList)
+	return retList, nil
+}
+
+// weightScore 按准时率、数量准确率、退货率、涨价幅度加权计算综合得分，满分100
+func weightScore(score *model.SupplierScore) float64 {
+	return onTimeWeight*score.OnTimeRate + accuracyWeight*score.QuantityAccuracy +
+		returnWeight*(1-math.Min(score.ReturnRate, 1)) + priceWeight*(1-math.Min(math.Max(score.PriceChangeRate, 0), 1))
+}
+
+// rankScores 按得分降序排名，得分相同时订单数多的靠前
+func rankScores(scoreList []*model.SupplierScore) {
+	sort.Slice(scoreList, func(i, j int) bool {
+		if scoreList[i].Score != scoreList[j].Score {
+			return scoreList[i].Score > scoreList[j].Score
+		}
+		return scoreList[i].OrderCount > scoreList[j].OrderCount
+	})
+	for i, score := range scoreList {
+		score.Rank = uint32(i + 1)
+	}
+}
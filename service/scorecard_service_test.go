@@ -0,0 +1,50 @@
+package service
+
+import (
+	"math"
+	"testing"
+
+	"github.com/canteen_management/model"
+)
+
+func TestWeightScore(t *testing.T) {
+	testList := []struct {
+		name  string
+		score *model.SupplierScore
+		want  float64
+	}{
+		{"perfect", &model.SupplierScore{OnTimeRate: 1, QuantityAccuracy: 1}, 100},
+		{"no delivery", &model.SupplierScore{}, 40},
+		{"half on time", &model.SupplierScore{OnTimeRate: 0.5, QuantityAccuracy: 1}, 85},
+		{"return rate", &model.SupplierScore{OnTimeRate: 1, QuantityAccuracy: 1, ReturnRate: 0.25}, 95},
+		{"return rate capped", &model.SupplierScore{OnTimeRate: 1, QuantityAccuracy: 1, ReturnRate: 3}, 80},
+		{"price rise", &model.SupplierScore{OnTimeRate: 1, QuantityAccuracy: 1, PriceChangeRate: 0.1}, 98},
+		{"price rise capped", &model.SupplierScore{OnTimeRate: 1, QuantityAccuracy: 1, PriceChangeRate: 2}, 80},
+		{"price drop not rewarded", &model.SupplierScore{OnTimeRate: 1, QuantityAccuracy: 1, PriceChangeRate: -0.5}, 100},
+	}
+	for _, tt := range testList {
+		t.Run(tt.name, func(t *testing.T) {
+			got := weightScore(tt.score)
+			if math.Abs(got-tt.want) > 0.000001 {
+				t.Fatalf("weightScore Mismatch|Got:%v|Want:%v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRankScores(t *testing.T) {
+	scoreList := []*model.SupplierScore{
+		{SupplierID: 1, Score: 80, OrderCount: 3},
+		{SupplierID: 2, Score: 95, OrderCount: 1},
+		{SupplierID: 3, Score: 80, OrderCount: 5},
+		{SupplierID: 4, Score: 60, OrderCount: 9},
+	}
+	rankScores(scoreList)
+	wantOrder := []uint32{2, 3, 1, 4}
+	for i, score := range scoreList {
+		if score.SupplierID != wantOrder[i] || score.Rank != uint32(i+1) {
+			t.Fatalf("rankScores Mismatch|Index:%v|Supplier:%v|Rank:%v|Want:%v", i, score.SupplierID, score.Rank,
+				wantOrder[i])
+		}
+	}
+}
@@ -4,6 +4,9 @@ import (
 	"database/sql"
 	"fmt"
 	"math"
+	"sort"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/canteen_management/enum"
@@ -22,10 +25,19 @@ type StoreService struct {
 	goodsModel          *model.GoodsModel
 	goodsTypeModel      *model.GoodsTypeModel
 	goodsHistoryModel   *model.GoodsHistoryModel
+	priceHistoryModel   *model.GoodsPriceHistoryModel
 	shoppingCartModel   *model.ShoppingCartModel
 	cartDetailModel     *model.CartDetailModel
 	outboundModel       *model.OutboundOrderModel
 	outboundDetailModel *model.OutboundDetailModel
+	stockLotModel       *model.StockLotModel
+	goodsStockModel     *model.GoodsStockModel
+	transferModel       *model.TransferOrderModel
+	stockAlertModel     *model.StockAlertModel
+	costLayerModel      *model.CostLayerModel
+	documentLogModel    *model.DocumentLogModel
+	goodsUnitModel      *model.GoodsUnitModel
+	outboundLotModel    *model.OutboundLotModel
 }
 
 func NewStoreService(sqlCli *sql.DB) *StoreService {
@@ -33,20 +45,38 @@ func NewStoreService(sqlCli *sql.DB) *StoreService {
 	goodsModel := model.NewGoodsModelWithDB(sqlCli)
 	goodsTypeModel := model.NewGoodsTypeModelWithDB(sqlCli)
 	goodsHistoryModel := model.NewGoodsHistoryModel(sqlCli)
+	priceHistoryModel := model.NewGoodsPriceHistoryModelWithDB(sqlCli)
 	shoppingCartModel := model.NewShoppingCartModel(sqlCli)
 	cartDetailModel := model.NewCartDetailModel(sqlCli)
 	outboundModel := model.NewOutboundOrderModelWithDB(sqlCli)
 	outboundDetailModel := model.NewOutboundDetailModelWithDB(sqlCli)
+	stockLotModel := model.NewStockLotModelWithDB(sqlCli)
+	goodsStockModel := model.NewGoodsStockModelWithDB(sqlCli)
+	transferModel := model.NewTransferOrderModelWithDB(sqlCli)
+	stockAlertModel := model.NewStockAlertModelWithDB(sqlCli)
+	costLayerModel := model.NewCostLayerModelWithDB(sqlCli)
+	documentLogModel := model.NewDocumentLogModelWithDB(sqlCli)
+	goodsUnitModel := model.NewGoodsUnitModelWithDB(sqlCli)
+	outboundLotModel := model.NewOutboundLotModelWithDB(sqlCli)
 	return &StoreService{
 		sqlCli:              sqlCli,
 		storeTypeModel:      storeTypeModel,
 		goodsModel:          goodsModel,
 		goodsTypeModel:      goodsTypeModel,
 		goodsHistoryModel:   goodsHistoryModel,
+		priceHistoryModel:   priceHistoryModel,
 		shoppingCartModel:   shoppingCartModel,
 		cartDetailModel:     cartDetailModel,
 		outboundModel:       outboundModel,
 		outboundDetailModel: outboundDetailModel,
+		stockLotModel:       stockLotModel,
+		goodsStockModel:     goodsStockModel,
+		transferModel:       transferModel,
+		stockAlertModel:     stockAlertModel,
+		costLayerModel:      costLayerModel,
+		documentLogModel:    documentLogModel,
+		goodsUnitModel:      goodsUnitModel,
+		outboundLotModel:    outboundLotModel,
 	}
 }
 
@@ -54,6 +84,10 @@ func (ss *StoreService) Init() error {
 	return nil
 }
 
+func (ss *StoreService) SetCostMethod(costMethod enum.CostMethod) {
+	ss.costLayerModel.SetCostMethod(costMethod)
+}
+
 func (ss *StoreService) GetStoreTypeList() ([]*model.StorehouseType, error) {
 	typeList, err := ss.storeTypeModel.GetStorehouseTypes()
 	if err != nil {
@@ -63,6 +97,19 @@ func (ss *StoreService) GetStoreTypeList() ([]*model.StorehouseType, error) {
 	return typeList, nil
 }
 
+func (ss *StoreService) GetStoreTypeMap() (map[uint32]*model.StorehouseType, error) {
+	typeList, err := ss.storeTypeModel.GetStorehouseTypes()
+	if err != nil {
+		logger.Warn(storeServiceLogTag, "GetStoreTypeMap Failed|Err:%v", err)
+		return nil, err
+	}
+	retMap := make(map[uint32]*model.StorehouseType)
+	for _, typeInfo := range typeList {
+		retMap[typeInfo.ID] = typeInfo
+	}
+	return retMap, nil
+}
+
 func (ss *StoreService) AddStoreType(storeType *model.StorehouseType) error {
 	err := ss.storeTypeModel.Insert(storeType)
 	if err != nil {
@@ -90,6 +137,16 @@ func (ss *StoreService) DeleteStoreType(storeTypeID uint32) error {
 	if count > 0 {
 		return fmt.Errorf("该仓库下还有商品，无法删除")
 	}
+	stockList, err := ss.goodsStockModel.GetStockList(0, storeTypeID)
+	if err != nil {
+		logger.Warn(storeServiceLogTag, "DeleteStoreType GetStockList Failed|Err:%v", err)
+		return err
+	}
+	for _, stock := range stockList {
+		if stock.Quantity > 0 {
+			return fmt.Errorf("该仓库下还有库存，无法删除")
+		}
+	}
 	err = ss.storeTypeModel.DeleteStorehouseType(storeTypeID)
 	if err != nil {
 		logger.Warn(storeServiceLogTag, "UpdateStoreType Failed|Err:%v", err)
@@ -210,21 +267,125 @@ func (ss *StoreService) GoodsList(goodsType, storeType uint32, page, pageSize in
 	return goodsList, goodsCount, nil
 }
 
+func (ss *StoreService) checkStockLevel(goods *model.Goods) error {
+	if goods.MinQuantity < 0 || goods.ReorderPoint < 0 || goods.MaxQuantity < 0 {
+		return fmt.Errorf("库存阈值不能小于0")
+	}
+	if goods.ReorderPoint > 0 && goods.MinQuantity > goods.ReorderPoint {
+		return fmt.Errorf("最低库存不能高于补货点")
+	}
+	if goods.MaxQuantity > 0 && goods.ReorderPoint > goods.MaxQuantity {
+		return fmt.Errorf("补货点不能高于最高库存")
+	}
+	return nil
+}
+
+func (ss *StoreService) checkBarcode(goods *model.Goods) error {
+	if goods.Barcode == "" {
+		return nil
+	}
+	if strings.HasPrefix(goods.Barcode, model.GoodsCodePrefix) || strings.HasPrefix(goods.Barcode, model.LotCodePrefix) {
+		return fmt.Errorf("条码不能以%v或%v开头", model.GoodsCodePrefix, model.LotCodePrefix)
+	}
+	exist, err := ss.goodsModel.GetGoodsByBarcode(goods.Barcode)
+	if err != nil {
+		return err
+	}
+	if exist != nil && exist.ID != goods.ID {
+		return fmt.Errorf("条码已被%v使用", exist.Name)
+	}
+	return nil
+}
+
+// ResolveCode 解析扫码内容，扫描批次码时同时返回批次信息
+func (ss *StoreService) ResolveCode(code string) (*model.Goods, *model.StockLot, error) {
+	code = strings.TrimSpace(code)
+	if code == "" {
+		return nil, nil, fmt.Errorf("条码不能为空")
+	}
+	goods, err := ss.goodsModel.GetGoodsByBarcode(code)
+	if err != nil {
+		return nil, nil, err
+	}
+	if goods != nil {
+		return goods, nil, nil
+	}
+
+	var lot *model.StockLot
+	goodsID := uint64(0)
+	if strings.HasPrefix(code, model.LotCodePrefix) {
+		lotID, err := strconv.ParseUint(code[len(model.LotCodePrefix):], 10, 32)
+		if err != nil {
+			return nil, nil, fmt.Errorf("未识别的条码|Code:%v", code)
+		}
+		lot, err = ss.stockLotModel.GetLotByID(uint32(lotID))
+		if err != nil {
+			return nil, nil, err
+		}
+		if lot == nil {
+			return nil, nil, fmt.Errorf("批次不存在|Code:%v", code)
+		}
+		goodsID = uint64(lot.GoodsID)
+	} else if strings.HasPrefix(code, model.GoodsCodePrefix) {
+		goodsID, err = strconv.ParseUint(code[len(model.GoodsCodePrefix):], 10, 32)
+		if err != nil {
+			return nil, nil, fmt.Errorf("未识别的条码|Code:%v", code)
+		}
+	} else {
+		return nil, nil, fmt.Errorf("未识别的条码|Code:%v", code)
+	}
+	goods, err = ss.goodsModel.GetGoodsByID(uint32(goodsID))
+	if err != nil {
+		return nil, nil, fmt.Errorf("商品不存在|Code:%v", code)
+	}
+	return goods, lot, nil
+}
+
 func (ss *StoreService) AddGoods(goods *model.Goods) error {
-	err := ss.goodsModel.Insert(goods)
+	err := ss.checkStockLevel(goods)
+	if err != nil {
+		return err
+	}
+	err = ss.checkBarcode(goods)
+	if err != nil {
+		return err
+	}
+	goods.StockValue = 0
+	err = ss.goodsModel.Insert(goods)
 	if err != nil {
 		logger.Warn(storeServiceLogTag, "Insert Goods Failed|Err:%v", err)
 		return err
 	}
 	if goods.Quantity > 0 {
-		err = ss.goodsHistoryModel.BatchInsert(nil, []*model.GoodsHistory{model.GenerateInitGoodsHistory(goods)})
-		logger.Warn(storeServiceLogTag, "Insert GoodsHistory Failed|Err:%v", err)
+		history := model.GenerateInitGoodsHistory(goods)
+		err = ss.costLayerModel.ValueHistoryWithTx(nil, []*model.Goods{goods}, []*model.GoodsHistory{history},
+			map[uint32]float64{goods.ID: goods.Price})
+		if err != nil {
+			logger.Warn(storeServiceLogTag, "Init GoodsValue Failed|Err:%v", err)
+			return err
+		}
+		err = ss.goodsModel.BatchUpdateByTagWithTx(nil, []*model.Goods{goods}, "stock_value")
+		if err != nil {
+			return err
+		}
+		err = ss.goodsHistoryModel.BatchInsert(nil, []*model.GoodsHistory{history})
+		if err != nil {
+			logger.Warn(storeServiceLogTag, "Insert GoodsHistory Failed|Err:%v", err)
+		}
 	}
 	return nil
 }
 
 func (ss *StoreService) UpdateGoods(goods *model.Goods) error {
-	err := ss.goodsModel.UpdateGoodsInfo(goods)
+	err := ss.checkStockLevel(goods)
+	if err != nil {
+		return err
+	}
+	err = ss.checkBarcode(goods)
+	if err != nil {
+		return err
+	}
+	err = ss.goodsModel.UpdateGoodsInfo(goods)
 	if err != nil {
 		logger.Warn(storeServiceLogTag, "UpdateGoods Failed|Err:%v", err)
 		return err
@@ -241,7 +402,96 @@ func (ss *StoreService) DeleteGoods(goodsID uint32) error {
 	return nil
 }
 
-func (ss *StoreService) UpdateGoodsPrice(goodsID uint32, priceMap map[uint8]float64) error {
+func (ss *StoreService) GetGoodsUnitList(goodsID uint32) ([]*model.GoodsUnit, error) {
+	unitList, err := ss.goodsUnitModel.GetUnitList(goodsID)
+	if err != nil {
+		logger.Warn(storeServiceLogTag, "GetGoodsUnitList Failed|Err:%v", err)
+		return nil, err
+	}
+	return unitList, nil
+}
+
+func (ss *StoreService) GetGoodsUnitMap() (model.GoodsUnitMap, error) {
+	goodsList, err := ss.goodsModel.GetAllGoods()
+	if err != nil {
+		logger.Warn(storeServiceLogTag, "GetGoodsUnitMap GetAllGoods Failed|Err:%v", err)
+		return nil, err
+	}
+	unitMap, err := ss.goodsUnitModel.GetUnitMap(goodsList)
+	if err != nil {
+		logger.Warn(storeServiceLogTag, "GetGoodsUnitMap Failed|Err:%v", err)
+		return nil, err
+	}
+	return unitMap, nil
+}
+
+// checkGoodsUnit 同一商品单位名称不能重复，库存单位唯一且换算系数固定为1
+func (ss *StoreService) checkGoodsUnit(unit *model.GoodsUnit) error {
+	unit.UnitName = strings.TrimSpace(unit.UnitName)
+	if unit.UnitName == "" {
+		return fmt.Errorf("请填写单位名称")
+	}
+	if unit.UnitType < enum.GoodsUnitPurchase || unit.UnitType > enum.GoodsUnitRecipe {
+		return fmt.Errorf("单位类型错误")
+	}
+	if unit.UnitType == enum.GoodsUnitStock {
+		unit.Factor = 1
+	}
+	if unit.Factor <= 0 {
+		return fmt.Errorf("换算系数必须大于0")
+	}
+	_, err := ss.goodsModel.GetGoodsByID(unit.GoodsID)
+	if err != nil {
+		return fmt.Errorf("商品不存在")
+	}
+	unitList, err := ss.goodsUnitModel.GetUnitList(unit.GoodsID)
+	if err != nil {
+		return err
+	}
+	for _, exist := range unitList {
+		if exist.ID == unit.ID {
+			continue
+		}
+		if exist.UnitName == unit.UnitName {
+			return fmt.Errorf("单位%v已存在", unit.UnitName)
+		}
+		if exist.UnitType == enum.GoodsUnitStock && unit.UnitType == enum.GoodsUnitStock {
+			return fmt.Errorf("库存单位已设置为%v", exist.UnitName)
+		}
+	}
+	return nil
+}
+
+func (ss *StoreService) AddGoodsUnit(unit *model.GoodsUnit) error {
+	unit.ID = 0
+	err := ss.checkGoodsUnit(unit)
+	if err != nil {
+		return err
+	}
+	return ss.goodsUnitModel.Insert(unit)
+}
+
+func (ss *StoreService) UpdateGoodsUnit(unit *model.GoodsUnit) error {
+	exist, err := ss.goodsUnitModel.GetUnit(unit.ID)
+	if err != nil {
+		return err
+	}
+	if exist == nil {
+		return fmt.Errorf("单位不存在")
+	}
+	unit.GoodsID = exist.GoodsID
+	err = ss.checkGoodsUnit(unit)
+	if err != nil {
+		return err
+	}
+	return ss.goodsUnitModel.UpdateUnit(unit)
+}
+
+func (ss *StoreService) DeleteGoodsUnit(unitID uint32) error {
+	return ss.goodsUnitModel.DeleteUnit(unitID)
+}
+
+func (ss *StoreService) UpdateGoodsPrice(goodsID, operator uint32, priceMap map[uint8]float64) (err error) {
 	averagePrice, count := 0.0, 0
 	for _, price := range priceMap {
 		if math.Abs(price) < 0.0000001 {
@@ -255,11 +505,26 @@ func (ss *StoreService) UpdateGoodsPrice(goodsID uint32, priceMap map[uint8]floa
 	}
 	averagePrice /= float64(count)
 
-	goods, err := ss.goodsModel.GetGoodsByID(goodsID)
+	tx, err := ss.sqlCli.Begin()
 	if err != nil {
-		logger.Warn(storeServiceLogTag, "GetGoodsByID Failed|Err:%v", err)
+		logger.Warn(storeServiceLogTag, "UpdateGoodsPrice Begin Failed|Err:%v", err)
 		return err
 	}
+	defer func() {
+		utils.End(tx, err)
+	}()
+
+	// 锁定商品，价格与价格历史在同一事务内写入
+	goodsList, err := ss.goodsModel.GetGoodsByIDListWithLock(tx, []uint32{goodsID})
+	if err != nil {
+		logger.Warn(storeServiceLogTag, "UpdateGoodsPrice GetGoodsByIDListWithLock Failed|Err:%v", err)
+		return err
+	}
+	if len(goodsList) == 0 {
+		err = fmt.Errorf("商品不存在")
+		return err
+	}
+	goods := goodsList[0]
 
 	goodsType, err := ss.goodsTypeModel.GetGoodsTypesByID(goods.GoodsTypeID)
 	if err != nil {
@@ -268,14 +533,38 @@ func (ss *StoreService) UpdateGoodsPrice(goodsID uint32, priceMap map[uint8]floa
 	}
 
 	finalPrice := averagePrice * goodsType.Discount
-	err = ss.goodsModel.UpdateGoodsPriceInfo(goodsID, averagePrice, finalPrice, priceMap)
+	err = ss.goodsModel.UpdateGoodsPriceInfoWithTx(tx, goodsID, averagePrice, finalPrice, priceMap)
 	if err != nil {
 		logger.Warn(storeServiceLogTag, "UpdateGoodsPrice Failed|Err:%v", err)
 		return err
 	}
+	if math.Abs(goods.Price-finalPrice) < 0.0000001 {
+		return nil
+	}
+	history := model.GenerateManualPriceHistory(goods, averagePrice, finalPrice, operator)
+	err = ss.priceHistoryModel.BatchInsert(tx, []*model.GoodsPriceHistory{history})
+	if err != nil {
+		logger.Warn(storeServiceLogTag, "UpdateGoodsPrice Insert PriceHistory Failed|Err:%v", err)
+		return err
+	}
 	return nil
 }
 
+func (ss *StoreService) GetGoodsPriceHistory(goodsID uint32, startTime, endTime int64,
+	page, pageSize int32) ([]*model.GoodsPriceHistory, int32, error) {
+	historyList, err := ss.priceHistoryModel.GetPriceHistory(goodsID, startTime, endTime, page, pageSize)
+	if err != nil {
+		logger.Warn(storeServiceLogTag, "GetPriceHistory Failed|Err:%v", err)
+		return nil, 0, err
+	}
+	count, err := ss.priceHistoryModel.GetPriceHistoryCount(goodsID, startTime, endTime)
+	if err != nil {
+		logger.Warn(storeServiceLogTag, "GetPriceHistoryCount Failed|Err:%v", err)
+		return nil, 0, err
+	}
+	return historyList, count, nil
+}
+
 func (ss *StoreService) GetCart(uid uint32, cartType enum.CartType) (*model.ShoppingCart, []*model.CartDetail, error) {
 	carts, err := ss.shoppingCartModel.GetCart(cartType, uid)
 	if err != nil {
@@ -312,19 +601,52 @@ func (ss *StoreService) GetCart(uid uint32, cartType enum.CartType) (*model.Shop
 	return cart, cartDetails, nil
 }
 
+// reserveStockWithTx 申请出库时预留库存，取消时释放预留
+func (ss *StoreService) reserveStockWithTx(tx *sql.Tx, details []*model.OutboundDetail, release bool) error {
+	goodsIDList, reserveMap := make([]uint32, 0, len(details)), make(map[uint32]float64)
+	for _, item := range details {
+		if _, ok := reserveMap[item.GoodsID]; !ok {
+			goodsIDList = append(goodsIDList, item.GoodsID)
+		}
+		reserveMap[item.GoodsID] += item.OutNumber
+	}
+	goodsList, err := ss.goodsModel.GetGoodsByIDListWithLock(tx, goodsIDList)
+	if err != nil {
+		logger.Warn(storeServiceLogTag, "ReserveStock GetGoodsByIDListWithLock Failed|Err:%v", err)
+		return err
+	}
+	for _, goods := range goodsList {
+		number := reserveMap[goods.ID]
+		if release {
+			goods.Reserved = math.Max(goods.Reserved-number, 0)
+			continue
+		}
+		if number > goods.GetAvailable() {
+			logger.Warn(storeServiceLogTag, "ReserveStock Not Enough|Goods:%v|Reserve:%v|Available:%v",
+				goods.ID, number, goods.GetAvailable())
+			return fmt.Errorf("%v可用库存不足，当前可用%v", goods.Name, goods.GetAvailable())
+		}
+		goods.Reserved += number
+	}
+	return ss.goodsModel.BatchUpdateByTagWithTx(tx, goodsList, "reserved")
+}
+
 func (ss *StoreService) ApplyOutboundOrder(outbound *model.OutboundOrder, details []*model.OutboundDetail) (err error) {
 	tx, err := ss.sqlCli.Begin()
 	if err != nil {
 		logger.Warn(storeServiceLogTag, "ApplyOutboundOrder Begin Failed|Err:%v", err)
 		return err
 	}
-	defer utils.End(tx, err)
+	defer func() {
+		utils.End(tx, err)
+	}()
 
 	totalAmount := 0.0
 	for _, item := range details {
 		totalAmount += item.Price * item.OutNumber
 	}
 	outbound.TotalAmount = totalAmount
+	outbound.Reserved = true
 	err = ss.outboundModel.InsertWithTx(tx, outbound)
 	if err != nil {
 		logger.Warn(storeServiceLogTag, "Insert Outbound Failed|Err:%v", err)
@@ -338,9 +660,218 @@ func (ss *StoreService) ApplyOutboundOrder(outbound *model.OutboundOrder, detail
 		logger.Warn(storeServiceLogTag, "BatchInsert OutboundDetail Failed|Err:%v", err)
 		return err
 	}
+	err = ss.reserveStockWithTx(tx, details, false)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func (ss *StoreService) CancelOutboundOrder(outboundID, uid uint32, reason string) (err error) {
+	tx, err := ss.sqlCli.Begin()
+	if err != nil {
+		logger.Warn(storeServiceLogTag, "CancelOutboundOrder Begin Failed|Err:%v", err)
+		return err
+	}
+	defer func() {
+		utils.End(tx, err)
+	}()
+
+	order, err := ss.outboundModel.GetOutboundOrderWithLock(tx, outboundID)
+	if err != nil {
+		logger.Warn(storeServiceLogTag, "CancelOutboundOrder GetOutboundOrderWithLock Failed|Err:%v", err)
+		return err
+	}
+	if order == nil {
+		err = fmt.Errorf("订单未找到")
+		return err
+	}
+	if order.Status != enum.OutboundNew && order.Status != enum.OutboundReviewed {
+		err = fmt.Errorf("订单状态异常")
+		return err
+	}
+	if order.Reserved {
+		details, err := ss.outboundDetailModel.GetDetail(outboundID)
+		if err != nil {
+			logger.Warn(storeServiceLogTag, "CancelOutboundOrder GetDetail Failed|Err:%v", err)
+			return err
+		}
+		err = ss.reserveStockWithTx(tx, details, true)
+		if err != nil {
+			return err
+		}
+	}
+	order.Status, order.Reserved = enum.OutboundCancel, false
+	err = ss.outboundModel.UpdateOutboundWithTx(tx, order, "status", "reserved")
+	if err != nil {
+		logger.Warn(storeServiceLogTag, "CancelOutboundOrder UpdateOutbound Failed|Err:%v", err)
+		return err
+	}
+	docLog := &model.DocumentLog{DocType: enum.DocumentOutbound, DocID: outboundID, Action: enum.DocumentCancel,
+		Operator: uid, Reason: reason}
+	err = ss.documentLogModel.InsertWithTx(tx, docLog)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// ReverseOutboundOrder 冲销已出库的出库单，按原出库成本回补库存，退回的商品生成新批次
+func (ss *StoreService) ReverseOutboundOrder(outboundID, uid uint32, reason string) (err error) {
+	tx, err := ss.sqlCli.Begin()
+	if err != nil {
+		logger.Warn(storeServiceLogTag, "ReverseOutboundOrder Begin Failed|Err:%v", err)
+		return err
+	}
+	defer func() {
+		utils.End(tx, err)
+	}()
+
+	order, err := ss.outboundModel.GetOutboundOrderWithLock(tx, outboundID)
+	if err != nil {
+		logger.Warn(storeServiceLogTag, "ReverseOutboundOrder GetOutboundOrderWithLock Failed|Err:%v", err)
+		return err
+	}
+	if order == nil {
+		err = fmt.Errorf("订单未找到")
+		return err
+	}
+	if order.Status != enum.OutboundFinish {
+		err = fmt.Errorf("订单未出库，无需冲销")
+		return err
+	}
+	historyList, err := ss.goodsHistoryModel.GetHistoryByRef(enum.GoodsOutbound, outboundID)
+	if err != nil {
+		return err
+	}
+	if len(historyList) == 0 {
+		err = fmt.Errorf("出库流水未找到")
+		return err
+	}
+
+	docLog := &model.DocumentLog{DocType: enum.DocumentOutbound, DocID: outboundID, Action: enum.DocumentReverse,
+		Operator: uid, Reason: reason}
+	err = ss.documentLogModel.InsertWithTx(tx, docLog)
+	if err != nil {
+		return err
+	}
+	order.Status = enum.OutboundReversed
+	err = ss.outboundModel.UpdateOutboundWithTx(tx, order, "status")
+	if err != nil {
+		logger.Warn(storeServiceLogTag, "ReverseOutboundOrder UpdateOutbound Failed|Err:%v", err)
+		return err
+	}
+
+	goodsIDList, updateMap, storeMap := make([]uint32, 0, len(historyList)), make(map[uint32]float64), make(map[uint32]uint32)
+	costMap, valueMap := make(map[uint32]float64), make(map[uint32]float64)
+	for _, history := range historyList {
+		if _, ok := updateMap[history.GoodsID]; !ok {
+			goodsIDList = append(goodsIDList, history.GoodsID)
+		}
+		updateMap[history.GoodsID] -= history.ChangeQuantity
+		valueMap[history.GoodsID] -= history.ChangeValue
+		storeMap[history.GoodsID] = history.StoreTypeID
+	}
+	for goodsID, number := range updateMap {
+		if number > 0 {
+			costMap[goodsID] = valueMap[goodsID] / number
+		}
+	}
+	goodsList, err := ss.goodsModel.GetGoodsByIDListWithLock(tx, goodsIDList)
+	if err != nil {
+		logger.Warn(storeServiceLogTag, "ReverseOutboundOrder GetGoodsByIDListWithLock Failed|Err:%v", err)
+		return err
+	}
+
+	// 退回出库时实际扣减的批次，保留原保质期以维持先到期先出
+	outboundLots, err := ss.outboundLotModel.GetOutboundLotsWithTx(tx, outboundID)
+	if err != nil {
+		return err
+	}
+	lotIDList := make([]uint32, 0, len(outboundLots))
+	for _, outboundLot := range outboundLots {
+		lotIDList = append(lotIDList, outboundLot.LotID)
+	}
+	issuedLots, err := ss.stockLotModel.GetLotsByIDListWithLock(tx, lotIDList)
+	if err != nil {
+		return err
+	}
+	lotMap := make(map[uint32]*model.StockLot, len(issuedLots))
+	for _, lot := range issuedLots {
+		lotMap[lot.ID] = lot
+	}
+	restoreList, remainMap := model.RestoreLots(outboundLots, lotMap, updateMap)
+	err = ss.stockLotModel.BatchUpdateQuantityWithTx(tx, restoreList)
+	if err != nil {
+		logger.Warn(storeServiceLogTag, "ReverseOutboundOrder RestoreLots Failed|Err:%v", err)
+		return err
+	}
+
+	now := time.Now()
+	changeList, lotList := make([]*model.GoodsStock, 0, len(goodsList)), make([]*model.StockLot, 0, len(goodsList))
+	reversalList := make([]*model.GoodsHistory, 0, len(goodsList))
+	for _, goods := range goodsList {
+		if storeMap[goods.ID] == 0 {
+			storeMap[goods.ID] = goods.StoreTypeID
+		}
+		changeList = append(changeList, &model.GoodsStock{GoodsID: goods.ID, StoreTypeID: storeMap[goods.ID],
+			Quantity: updateMap[goods.ID]})
+		// 未记录出库批次的历史单据或出库时批次不足的部分，无法确定原批次，按无保质期批次入库
+		if remainMap[goods.ID] > 0 {
+			lotList = append(lotList, &model.StockLot{GoodsID: goods.ID, StoreTypeID: storeMap[goods.ID],
+				BatchNo: fmt.Sprintf("R%v", outboundID), ProductionDate: now, ExpiryDate: model.NoExpiryDate,
+				InitQuantity: remainMap[goods.ID], Quantity: remainMap[goods.ID]})
+		}
+	}
+	_, err = ss.goodsStockModel.ChangeStockWithTx(tx, goodsList, changeList)
+	if err != nil {
+		logger.Warn(storeServiceLogTag, "ReverseOutboundOrder ChangeStock Failed|Err:%v", err)
+		return err
+	}
+	if len(lotList) > 0 {
+		err = ss.stockLotModel.BatchInsertWithTx(tx, lotList)
+		if err != nil {
+			logger.Warn(storeServiceLogTag, "ReverseOutboundOrder InsertLots Failed|Err:%v", err)
+			return err
+		}
+	}
+	for _, goods := range goodsList {
+		history := model.GenerateReversalGoodsHistory(goods, updateMap[goods.ID], docLog.ID)
+		history.StoreTypeID = storeMap[goods.ID]
+		reversalList = append(reversalList, history)
+		goods.Quantity = goods.Quantity + updateMap[goods.ID]
+	}
+	err = ss.costLayerModel.ValueHistoryWithTx(tx, goodsList, reversalList, costMap)
+	if err != nil {
+		logger.Warn(storeServiceLogTag, "ReverseOutboundOrder ValueHistory Failed|Err:%v", err)
+		return err
+	}
+	err = ss.goodsModel.BatchUpdateQuantityWithTx(tx, goodsList)
+	if err != nil {
+		logger.Warn(storeServiceLogTag, "ReverseOutboundOrder BatchUpdateQuantity Failed|Err:%v", err)
+		return err
+	}
+	err = ss.stockAlertModel.CheckAlertWithTx(tx, goodsList)
+	if err != nil {
+		return err
+	}
+	err = ss.goodsHistoryModel.BatchInsert(tx, reversalList)
+	if err != nil {
+		logger.Warn(storeServiceLogTag, "ReverseOutboundOrder BatchInsertHistory Failed|Err:%v", err)
+		return err
+	}
 	return nil
 }
 
+func (ss *StoreService) GetDocumentLogList(docType uint8, docID uint32) ([]*model.DocumentLog, error) {
+	logList, err := ss.documentLogModel.GetLogList(docType, docID)
+	if err != nil {
+		logger.Warn(storeServiceLogTag, "GetDocumentLogList Failed|Err:%v", err)
+		return nil, err
+	}
+	return logList, nil
+}
+
 func (ss *StoreService) ReviewOutboundOrder(outboundID uint32) error {
 	tx, err := ss.sqlCli.Begin()
 	if err != nil {
@@ -371,13 +902,15 @@ func (ss *StoreService) ReviewOutboundOrder(outboundID uint32) error {
 	return err
 }
 
-func (ss *StoreService) FinishOutboundOrder(outboundID uint32) error {
+func (ss *StoreService) FinishOutboundOrder(outboundID uint32) (err error) {
 	tx, err := ss.sqlCli.Begin()
 	if err != nil {
 		logger.Warn(storeServiceLogTag, "FinishOutboundOrder Begin Failed|Err:%v", err)
 		return err
 	}
-	defer utils.End(tx, err)
+	defer func() {
+		utils.End(tx, err)
+	}()
 
 	order, err := ss.outboundModel.GetOutboundOrderWithLock(tx, outboundID)
 	if err != nil {
@@ -402,7 +935,8 @@ func (ss *StoreService) FinishOutboundOrder(outboundID uint32) error {
 	if err == nil {
 		order.Status = enum.OutboundFinish
 		order.OutboundTime = time.Now()
-		err = ss.outboundModel.UpdateOutboundWithTx(tx, order, "status", "outbound_time")
+		order.Reserved = false
+		err = ss.outboundModel.UpdateOutboundWithTx(tx, order, "status", "outbound_time", "cost_amount", "reserved")
 		if err != nil {
 			logger.Warn(storeServiceLogTag, "UpdateOutboundStatus Failed|Err:%v", err)
 			return err
@@ -439,17 +973,67 @@ func (ss *StoreService) finishOutboundOrder(tx *sql.Tx, outbound *model.Outbound
 		item.OutboundID = outbound.ID
 	}
 
+	changeList, consumeList := make([]*model.GoodsStock, 0, len(goodsList)), make([]*model.GoodsStock, 0, len(goodsList))
+	storeMap := make(map[uint32]uint32)
 	for _, goods := range goodsList {
-		historyList = append(historyList,
-			model.GenerateOutboundGoodsHistory(goods, -updateMap[goods.ID], outbound.ID))
-		goods.Quantity = goods.Quantity - updateMap[goods.ID]
+		storeMap[goods.ID] = goods.StoreTypeID
+		if outbound.StoreTypeID > 0 {
+			storeMap[goods.ID] = outbound.StoreTypeID
+		}
+		changeList = append(changeList, &model.GoodsStock{GoodsID: goods.ID, StoreTypeID: storeMap[goods.ID],
+			Quantity: -updateMap[goods.ID]})
+		consumeList = append(consumeList, &model.GoodsStock{GoodsID: goods.ID, StoreTypeID: storeMap[goods.ID],
+			Quantity: updateMap[goods.ID]})
+	}
+	_, err = ss.goodsStockModel.ChangeStockWithTx(tx, goodsList, changeList)
+	if err != nil {
+		logger.Warn(storeServiceLogTag, "ApplyOutboundOrder ChangeStock Failed|Err:%v", err)
+		return err
+	}
+	consumedList, err := ss.stockLotModel.ConsumeLotsWithTx(tx, consumeList, 0)
+	if err != nil {
+		logger.Warn(storeServiceLogTag, "ApplyOutboundOrder ConsumeLots Failed|Err:%v", err)
+		return err
+	}
+	err = ss.outboundLotModel.BatchInsertWithTx(tx, model.GenerateOutboundLots(outbound.ID, consumedList))
+	if err != nil {
+		logger.Warn(storeServiceLogTag, "ApplyOutboundOrder InsertOutboundLots Failed|Err:%v", err)
+		return err
 	}
 
+	for _, goods := range goodsList {
+		history := model.GenerateOutboundGoodsHistory(goods, -updateMap[goods.ID], outbound.ID)
+		history.StoreTypeID = storeMap[goods.ID]
+		historyList = append(historyList, history)
+		goods.Quantity = goods.Quantity - updateMap[goods.ID]
+		if outbound.Reserved {
+			goods.Reserved = math.Max(goods.Reserved-updateMap[goods.ID], 0)
+		}
+	}
+	err = ss.costLayerModel.ValueHistoryWithTx(tx, goodsList, historyList, nil)
+	if err != nil {
+		logger.Warn(storeServiceLogTag, "ApplyOutboundOrder ValueHistory Failed|Err:%v", err)
+		return err
+	}
+	outbound.CostAmount = 0
+	for _, history := range historyList {
+		outbound.CostAmount -= history.ChangeValue
+	}
 	err = ss.goodsModel.BatchUpdateQuantityWithTx(tx, goodsList)
 	if err != nil {
 		logger.Warn(storeServiceLogTag, "ApplyOutboundOrder BatchAddQuantity Failed|Err:%v", err)
 		return err
 	}
+	err = ss.stockAlertModel.CheckAlertWithTx(tx, goodsList)
+	if err != nil {
+		return err
+	}
+	if outbound.Reserved {
+		err = ss.goodsModel.BatchUpdateByTagWithTx(tx, goodsList, "reserved")
+		if err != nil {
+			return err
+		}
+	}
 	err = ss.goodsHistoryModel.BatchInsert(tx, historyList)
 	if err != nil {
 		logger.Warn(storeServiceLogTag, "ApplyOutboundOrder BatchInsertHistory Failed|Err:%v", err)
@@ -495,6 +1079,232 @@ func (ss *StoreService) GetOutboundList(uid, outboundID uint32, startTime, endTi
 	return outboundList, outboundCount, detailMap, nil
 }
 
+func (ss *StoreService) GetGoodsStockList(goodsID, storeTypeID uint32) ([]*model.GoodsStock, error) {
+	goodsList, err := ss.goodsModel.GetAllGoods()
+	if err != nil {
+		logger.Warn(storeServiceLogTag, "GetGoodsStockList GetAllGoods Failed|Err:%v", err)
+		return nil, err
+	}
+	stockList, err := ss.goodsStockModel.GetStockList(goodsID, 0)
+	if err != nil {
+		logger.Warn(storeServiceLogTag, "GetGoodsStockList Failed|Err:%v", err)
+		return nil, err
+	}
+
+	retList := make([]*model.GoodsStock, 0, len(stockList))
+	for _, stock := range model.BuildStockMap(goodsList, stockList) {
+		if goodsID > 0 && stock.GoodsID != goodsID {
+			continue
+		}
+		if storeTypeID > 0 && stock.StoreTypeID != storeTypeID {
+			continue
+		}
+		retList = append(retList, stock)
+	}
+	sort.Slice(retList, func(i, j int) bool {
+		if retList[i].GoodsID != retList[j].GoodsID {
+			return retList[i].GoodsID < retList[j].GoodsID
+		}
+		return retList[i].StoreTypeID < retList[j].StoreTypeID
+	})
+	return retList, nil
+}
+
+func (ss *StoreService) TransferGoods(transfer *model.TransferOrder, items []*model.TransferItem) (err error) {
+	if transfer.FromStore == transfer.ToStore {
+		return fmt.Errorf("调出库位与调入库位不能相同")
+	}
+	storeTypeList, err := ss.storeTypeModel.GetStorehouseTypes()
+	if err != nil {
+		logger.Warn(storeServiceLogTag, "TransferGoods GetStorehouseTypes Failed|Err:%v", err)
+		return err
+	}
+	fromExist, toExist := false, false
+	for _, storeType := range storeTypeList {
+		fromExist = fromExist || storeType.ID == transfer.FromStore
+		toExist = toExist || storeType.ID == transfer.ToStore
+	}
+	if !fromExist || !toExist {
+		return fmt.Errorf("库位不存在")
+	}
+
+	goodsIDList, transferMap := make([]uint32, 0, len(items)), make(map[uint32]float64)
+	for _, item := range items {
+		if item.TransferNumber <= 0 {
+			continue
+		}
+		if _, ok := transferMap[item.GoodsID]; !ok {
+			goodsIDList = append(goodsIDList, item.GoodsID)
+		}
+		transferMap[item.GoodsID] += item.TransferNumber
+	}
+	if len(goodsIDList) == 0 {
+		return fmt.Errorf("调拨商品不能为空")
+	}
+	err = transfer.FromItems(items)
+	if err != nil {
+		return err
+	}
+
+	tx, err := ss.sqlCli.Begin()
+	if err != nil {
+		logger.Warn(storeServiceLogTag, "TransferGoods Begin Failed|Err:%v", err)
+		return err
+	}
+	defer func() {
+		utils.End(tx, err)
+	}()
+
+	err = ss.transferModel.InsertWithTx(tx, transfer)
+	if err != nil {
+		return err
+	}
+	goodsList, err := ss.goodsModel.GetGoodsByIDListWithLock(tx, goodsIDList)
+	if err != nil {
+		logger.Warn(storeServiceLogTag, "TransferGoods GetGoodsByIDListWithLock Failed|Err:%v", err)
+		return err
+	}
+	changeList, consumeList := make([]*model.GoodsStock, 0, len(goodsList)*2), make([]*model.GoodsStock, 0, len(goodsList))
+	for _, goods := range goodsList {
+		changeList = append(changeList,
+			&model.GoodsStock{GoodsID: goods.ID, StoreTypeID: transfer.FromStore, Quantity: -transferMap[goods.ID]},
+			&model.GoodsStock{GoodsID: goods.ID, StoreTypeID: transfer.ToStore, Quantity: transferMap[goods.ID]})
+		consumeList = append(consumeList,
+			&model.GoodsStock{GoodsID: goods.ID, StoreTypeID: transfer.FromStore, Quantity: transferMap[goods.ID]})
+	}
+	stockMap, err := ss.goodsStockModel.ChangeStockWithTx(tx, goodsList, changeList)
+	if err != nil {
+		logger.Warn(storeServiceLogTag, "TransferGoods ChangeStock Failed|Err:%v", err)
+		return err
+	}
+
+	consumedList, err := ss.stockLotModel.ConsumeLotsWithTx(tx, consumeList, 0)
+	if err != nil {
+		logger.Warn(storeServiceLogTag, "TransferGoods ConsumeLots Failed|Err:%v", err)
+		return err
+	}
+	lotList := make([]*model.StockLot, 0, len(consumedList))
+	for _, lot := range consumedList {
+		lot.ID, lot.StoreTypeID, lot.InitQuantity = 0, transfer.ToStore, lot.Quantity
+		lotList = append(lotList, lot)
+	}
+	if len(lotList) > 0 {
+		err = ss.stockLotModel.BatchInsertWithTx(tx, lotList)
+		if err != nil {
+			logger.Warn(storeServiceLogTag, "TransferGoods InsertStockLot Failed|Err:%v", err)
+			return err
+		}
+	}
+
+	historyList := make([]*model.GoodsHistory, 0, len(goodsList)*2)
+	for _, goods := range goodsList {
+		number := transferMap[goods.ID]
+		fromStock := stockMap[model.GetStockKey(goods.ID, transfer.FromStore)]
+		toStock := stockMap[model.GetStockKey(goods.ID, transfer.ToStore)]
+		historyList = append(historyList,
+			model.GenerateTransferGoodsHistory(goods.ID, transfer.FromStore, fromStock.Quantity+number, -number, transfer.ID),
+			model.GenerateTransferGoodsHistory(goods.ID, transfer.ToStore, toStock.Quantity-number, number, transfer.ID))
+	}
+	err = ss.costLayerModel.ValueHistoryWithTx(tx, goodsList, historyList, nil)
+	if err != nil {
+		logger.Warn(storeServiceLogTag, "TransferGoods ValueHistory Failed|Err:%v", err)
+		return err
+	}
+	err = ss.goodsHistoryModel.BatchInsert(tx, historyList)
+	if err != nil {
+		logger.Warn(storeServiceLogTag, "TransferGoods BatchInsertHistory Failed|Err:%v", err)
+		return err
+	}
+	return nil
+}
+
+func (ss *StoreService) GetTransferList(storeTypeID uint32, startTime, endTime int64,
+	page, pageSize int32) ([]*model.TransferOrder, int32, error) {
+	transferList, err := ss.transferModel.GetTransferList(storeTypeID, startTime, endTime, page, pageSize)
+	if err != nil {
+		logger.Warn(storeServiceLogTag, "GetTransferList Failed|Err:%v", err)
+		return nil, 0, err
+	}
+	count, err := ss.transferModel.GetTransferCount(storeTypeID, startTime, endTime)
+	if err != nil {
+		logger.Warn(storeServiceLogTag, "GetTransferCount Failed|Err:%v", err)
+		return nil, 0, err
+	}
+	return transferList, count, nil
+}
+
+// GetStockValuation deadline 为 0 时返回当前库存价值
+func (ss *StoreService) GetStockValuation(deadline int64, goodsTypeID uint32) ([]*model.Goods, error) {
+	goodsList, err := ss.goodsModel.GetAllGoods()
+	if err != nil {
+		logger.Warn(storeServiceLogTag, "GetStockValuation GetAllGoods Failed|Err:%v", err)
+		return nil, err
+	}
+	historyMap := make(map[uint32]*model.GoodsHistory)
+	if deadline > 0 {
+		historyList, err := ss.goodsHistoryModel.GetLatestHistoryBefore(time.Unix(deadline, 0))
+		if err != nil {
+			logger.Warn(storeServiceLogTag, "GetStockValuation GetLatestHistory Failed|Err:%v", err)
+			return nil, err
+		}
+		for _, history := range historyList {
+			historyMap[history.GoodsID] = history
+		}
+	}
+
+	retList := make([]*model.Goods, 0, len(goodsList))
+	for _, goods := range goodsList {
+		if goodsTypeID > 0 && goods.GoodsTypeID != goodsTypeID {
+			continue
+		}
+		if deadline > 0 {
+			history, ok := historyMap[goods.ID]
+			if !ok {
+				continue
+			}
+			goods.Quantity, goods.StockValue = history.AfterQuantity, history.AfterValue
+		}
+		if goods.Quantity <= 0 && goods.StockValue == 0 {
+			continue
+		}
+		retList = append(retList, goods)
+	}
+	return retList, nil
+}
+
+func (ss *StoreService) GetStockAlertList(goodsID uint32, status int8) ([]*model.StockAlert, error) {
+	alertList, err := ss.stockAlertModel.GetAlertList(goodsID, status)
+	if err != nil {
+		logger.Warn(storeServiceLogTag, "GetStockAlertList Failed|Err:%v", err)
+		return nil, err
+	}
+	return alertList, nil
+}
+
+func (ss *StoreService) CheckStockAlert() error {
+	goodsList, err := ss.goodsModel.GetAllGoods()
+	if err != nil {
+		logger.Warn(storeServiceLogTag, "CheckStockAlert GetAllGoods Failed|Err:%v", err)
+		return err
+	}
+	err = ss.stockAlertModel.CheckAlertWithTx(nil, goodsList)
+	if err != nil {
+		logger.Warn(storeServiceLogTag, "CheckStockAlert Failed|Err:%v", err)
+		return err
+	}
+	return nil
+}
+
+func (ss *StoreService) GetExpiringLots(goodsID uint32, days int64) ([]*model.StockLot, error) {
+	deadline := time.Now().Add(time.Duration(days) * 24 * time.Hour)
+	lotList, err := ss.stockLotModel.GetExpiringLots(goodsID, deadline)
+	if err != nil {
+		logger.Warn(storeServiceLogTag, "GetExpiringLots Failed|Err:%v", err)
+		return nil, err
+	}
+	return lotList, nil
+}
+
 func (ss *StoreService) GetGoodsHistoryList(goodsID, changeType uint32, startTime, endTime int64,
 	page, pageSize int32) ([]*model.GoodsHistory, int32, error) {
 	history, err := ss.goodsHistoryModel.GetGoodsHistory(goodsID, changeType, startTime, endTime, page, pageSize)
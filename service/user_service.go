@@ -204,8 +204,8 @@ func (us *UserService) bindAdminUser(tx *sql.Tx, openID, phoneNumber string) err
 		adminUser.OpenID = openID
 		err = us.adminUserModel.UpdateAdminUserByConditionWithTx(tx, adminUser, "id", "open_id")
 		if err != nil {
-			logger.Warn(userServiceLogTag, "bindAdminUser Update Failed|Uid:%v|Admin:%v|Err:%v",
-				adminUser.ID, err)
+			logger.Warn(userServiceLogTag, "bindAdminUser Update Failed|OpenID:%v|Admin:%v|Err:%v",
+				openID, adminUser.ID, err)
 			return err
 		}
 	}
@@ -0,0 +1,213 @@
+package service
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/canteen_management/enum"
+	"github.com/canteen_management/logger"
+	"github.com/canteen_management/model"
+	"github.com/canteen_management/utils"
+)
+
+const (
+	wasteServiceLogTag = "WasteService"
+)
+
+type WasteService struct {
+	sqlCli            *sql.DB
+	wasteLogModel     *model.WasteLogModel
+	goodsModel        *model.GoodsModel
+	goodsHistoryModel *model.GoodsHistoryModel
+	goodsStockModel   *model.GoodsStockModel
+	stockLotModel     *model.StockLotModel
+	costLayerModel    *model.CostLayerModel
+	stockAlertModel   *model.StockAlertModel
+}
+
+func NewWasteService(sqlCli *sql.DB) *WasteService {
+	wasteLogModel := model.NewWasteLogModelWithDB(sqlCli)
+	goodsModel := model.NewGoodsModelWithDB(sqlCli)
+	goodsHistoryModel := model.NewGoodsHistoryModel(sqlCli)
+	goodsStockModel := model.NewGoodsStockModelWithDB(sqlCli)
+	stockLotModel := model.NewStockLotModelWithDB(sqlCli)
+	costLayerModel := model.NewCostLayerModelWithDB(sqlCli)
+	stockAlertModel := model.NewStockAlertModelWithDB(sqlCli)
+	return &WasteService{
+		sqlCli:            sqlCli,
+		wasteLogModel:     wasteLogModel,
+		goodsModel:        goodsModel,
+		goodsHistoryModel: goodsHistoryModel,
+		goodsStockModel:   goodsStockModel,
+		stockLotModel:     stockLotModel,
+		costLayerModel:    costLayerModel,
+		stockAlertModel:   stockAlertModel,
+	}
+}
+
+func (ws *WasteService) SetCostMethod(costMethod enum.CostMethod) {
+	ws.costLayerModel.SetCostMethod(costMethod)
+}
+
+func (ws *WasteService) GetGoodsMap() (map[uint32]*model.Goods, error) {
+	goodsList, err := ws.goodsModel.GetAllGoods()
+	if err != nil {
+		logger.Warn(wasteServiceLogTag, "GetAllGoods Failed|Err:%v", err)
+		return nil, err
+	}
+	goodsMap := make(map[uint32]*model.Goods)
+	for _, goods := range goodsList {
+		goodsMap[goods.ID] = goods
+	}
+	return goodsMap, nil
+}
+
+func (ws *WasteService) checkWaste(wasteList []*model.WasteLog) (map[uint32]*model.WasteLog, error) {
+	prepMap := make(map[uint32]*model.WasteLog)
+	for _, waste := range wasteList {
+		if waste.Quantity < 0 || waste.Weight < 0 {
+			return nil, fmt.Errorf("浪费数量不能为负数")
+		}
+		switch waste.WasteType {
+		case enum.WastePlate, enum.WasteUnserved:
+			if waste.DishID == 0 {
+				return nil, fmt.Errorf("请选择菜品")
+			}
+			waste.GoodsID, waste.StoreTypeID = 0, 0
+		case enum.WastePrep:
+			if waste.GoodsID == 0 || waste.Quantity <= 0 {
+				return nil, fmt.Errorf("请填写损耗商品及数量")
+			}
+			if _, ok := prepMap[waste.GoodsID]; ok {
+				return nil, fmt.Errorf("同一商品请合并录入|GoodsID:%v", waste.GoodsID)
+			}
+			prepMap[waste.GoodsID] = waste
+		default:
+			return nil, fmt.Errorf("浪费类型错误")
+		}
+	}
+	return prepMap, nil
+}
+
+// RecordWaste 记录餐次浪费，就餐日期按零点归档；备餐损耗按批次先到先出扣减库存并生成损耗流水
+func (ws *WasteService) RecordWaste(creator uint32, mealDate int64, mealType uint8,
+	wasteList []*model.WasteLog) (err error) {
+	if len(wasteList) == 0 {
+		return fmt.Errorf("浪费记录不能为空")
+	}
+	if mealType < enum.MealBreakfast || mealType > enum.MealDinner {
+		return fmt.Errorf("餐次错误")
+	}
+	for _, waste := range wasteList {
+		waste.MealDate = time.Unix(utils.GetZeroTime(mealDate), 0)
+		waste.MealType, waste.Creator = mealType, creator
+	}
+	prepMap, err := ws.checkWaste(wasteList)
+	if err != nil {
+		return err
+	}
+
+	tx, err := ws.sqlCli.Begin()
+	if err != nil {
+		logger.Warn(wasteServiceLogTag, "RecordWaste Begin Failed|Err:%v", err)
+		return err
+	}
+	defer func() {
+		utils.End(tx, err)
+	}()
+
+	historyList, goodsList := make([]*model.GoodsHistory, 0, len(prepMap)), make([]*model.Goods, 0, len(prepMap))
+	if len(prepMap) > 0 {
+		goodsIDList := make([]uint32, 0, len(prepMap))
+		for goodsID := range prepMap {
+			goodsIDList = append(goodsIDList, goodsID)
+		}
+		goodsList, err = ws.goodsModel.GetGoodsByIDListWithLock(tx, goodsIDList)
+		if err != nil {
+			logger.Warn(wasteServiceLogTag, "RecordWaste GetGoodsByIDListWithLock Failed|Err:%v", err)
+			return err
+		}
+		if len(goodsList) != len(goodsIDList) {
+			err = fmt.Errorf("损耗商品不存在")
+			return err
+		}
+		changeList, consumeList := make([]*model.GoodsStock, 0, len(goodsList)), make([]*model.GoodsStock, 0, len(goodsList))
+		for _, goods := range goodsList {
+			waste := prepMap[goods.ID]
+			if waste.Quantity > goods.GetAvailable() {
+				err = fmt.Errorf("%v损耗数量超出可用库存，当前可用%v", goods.Name, goods.GetAvailable())
+				return err
+			}
+			if waste.StoreTypeID == 0 {
+				waste.StoreTypeID = goods.StoreTypeID
+			}
+			changeList = append(changeList, &model.GoodsStock{GoodsID: goods.ID, StoreTypeID: waste.StoreTypeID,
+				Quantity: -waste.Quantity})
+			consumeList = append(consumeList, &model.GoodsStock{GoodsID: goods.ID, StoreTypeID: waste.StoreTypeID,
+				Quantity: waste.Quantity})
+		}
+		_, err = ws.goodsStockModel.ChangeStockWithTx(tx, goodsList, changeList)
+		if err != nil {
+			logger.Warn(wasteServiceLogTag, "RecordWaste ChangeStock Failed|Err:%v", err)
+			return err
+		}
+		_, err = ws.stockLotModel.ConsumeLotsWithTx(tx, consumeList, 0)
+		if err != nil {
+			logger.Warn(wasteServiceLogTag, "RecordWaste ConsumeLots Failed|Err:%v", err)
+			return err
+		}
+		for _, goods := range goodsList {
+			waste := prepMap[goods.ID]
+			history := model.GenerateWasteGoodsHistory(goods, -waste.Quantity, 0)
+			history.StoreTypeID = waste.StoreTypeID
+			historyList = append(historyList, history)
+			goods.Quantity = goods.Quantity - waste.Quantity
+		}
+		err = ws.costLayerModel.ValueHistoryWithTx(tx, goodsList, historyList, nil)
+		if err != nil {
+			logger.Warn(wasteServiceLogTag, "RecordWaste ValueHistory Failed|Err:%v", err)
+			return err
+		}
+		err = ws.goodsModel.BatchUpdateQuantityWithTx(tx, goodsList)
+		if err != nil {
+			logger.Warn(wasteServiceLogTag, "RecordWaste BatchUpdateQuantity Failed|Err:%v", err)
+			return err
+		}
+		err = ws.stockAlertModel.CheckAlertWithTx(tx, goodsList)
+		if err != nil {
+			return err
+		}
+		for _, history := range historyList {
+			prepMap[history.GoodsID].Value = -history.ChangeValue
+		}
+	}
+
+	for _, waste := range wasteList {
+		err = ws.wasteLogModel.InsertWithTx(tx, waste)
+		if err != nil {
+			return err
+		}
+	}
+	for _, history := range historyList {
+		history.RefID = prepMap[history.GoodsID].ID
+	}
+	if len(historyList) > 0 {
+		err = ws.goodsHistoryModel.BatchInsert(tx, historyList)
+		if err != nil {
+			logger.Warn(wasteServiceLogTag, "RecordWaste BatchInsertHistory Failed|Err:%v", err)
+			return err
+		}
+	}
+	return nil
+}
+
+func (ws *WasteService) GetWasteList(mealType, wasteType uint8, dishID uint32, startTime,
+	endTime int64) ([]*model.WasteLog, error) {
+	wasteList, err := ws.wasteLogModel.GetWasteList(mealType, wasteType, dishID, startTime, endTime)
+	if err != nil {
+		logger.Warn(wasteServiceLogTag, "GetWasteList Failed|Err:%v", err)
+		return nil, err
+	}
+	return wasteList, nil
+}
@@ -1,6 +1,13 @@
 package utils
 
-import "golang.org/x/crypto/bcrypt"
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+
+	"golang.org/x/crypto/bcrypt"
+)
 
 func Encrypt(password string) string {
 	hashedPass, _ := bcrypt.GenerateFromPassword([]byte(password), 10)
@@ -16,3 +23,23 @@ func ComparePass(pass1, pass2 string) bool {
 	}
 
 }
+
+// GenerateSecret 生成 16 字节随机密钥的十六进制串
+func GenerateSecret() (string, error) {
+	buf := make([]byte, 16)
+	_, err := rand.Read(buf)
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+func GetHmacSha256Hex(data, secret string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(data))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func CompareHmac(sign, expected string) bool {
+	return hmac.Equal([]byte(sign), []byte(expected))
+}
@@ -0,0 +1,355 @@
+package utils
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/csv"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/canteen_management/logger"
+)
+
+const (
+	sheetLogTag = "Sheet"
+
+	defaultSheetName = "Sheet1"
+
+	SheetTypeXlsx = ".xlsx"
+	SheetTypeCsv  = ".csv"
+
+	utf8Bom = "\xef\xbb\xbf"
+
+	// 导入表格单个压缩条目解压后的上限，防止压缩炸弹耗尽内存
+	MaxXlsxEntrySize = 32 << 20
+	// 行号、列号按 Excel 上限校验，超出直接拒绝，避免按引用补齐空行空列时耗尽内存
+	MaxXlsxRows    = 1048576
+	MaxXlsxColumns = 16384
+
+	xlsxContentTypes = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">` +
+		`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>` +
+		`<Default Extension="xml" ContentType="application/xml"/>` +
+		`<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>` +
+		`<Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>` +
+		`</Types>`
+	xlsxRootRels = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+		`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>` +
+		`</Relationships>`
+	xlsxWorkbookTemplate = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">` +
+		`<sheets><sheet name="%v" sheetId="1" r:id="rId1"/></sheets></workbook>`
+	xlsxWorkbookRels = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+		`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/>` +
+		`</Relationships>`
+	xlsxSheetHead = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>`
+	xlsxSheetTail = `</sheetData></worksheet>`
+)
+
+type xlsxRichText struct {
+	Text string `xml:"t"`
+	Runs []struct {
+		Text string `xml:"t"`
+	} `xml:"r"`
+}
+
+func (rt *xlsxRichText) String() string {
+	if len(rt.Runs) == 0 {
+		return rt.Text
+	}
+	text := ""
+	for _, run := range rt.Runs {
+		text += run.Text
+	}
+	return text
+}
+
+type xlsxSharedStrings struct {
+	Items []xlsxRichText `xml:"si"`
+}
+
+type xlsxCell struct {
+	Ref    string       `xml:"r,attr"`
+	Type   string       `xml:"t,attr"`
+	Value  string       `xml:"v"`
+	Inline xlsxRichText `xml:"is"`
+}
+
+type xlsxRow struct {
+	Ref   int        `xml:"r,attr"`
+	Cells []xlsxCell `xml:"c"`
+}
+
+type xlsxWorksheet struct {
+	Rows []xlsxRow `xml:"sheetData>row"`
+}
+
+type xlsxWorkbook struct {
+	Sheets []struct {
+		RID string `xml:"http://schemas.openxmlformats.org/officeDocument/2006/relationships id,attr"`
+	} `xml:"sheets>sheet"`
+}
+
+type xlsxRelationships struct {
+	Items []struct {
+		ID     string `xml:"Id,attr"`
+		Target string `xml:"Target,attr"`
+	} `xml:"Relationship"`
+}
+
+func GetSheetType(fileName string) string {
+	return strings.ToLower(filepath.Ext(fileName))
+}
+
+func ReadSheet(fileName string, data []byte) ([][]string, error) {
+	switch GetSheetType(fileName) {
+	case SheetTypeCsv:
+		return ReadCsv(data)
+	case SheetTypeXlsx:
+		return ReadXlsx(data)
+	default:
+		return nil, fmt.Errorf("不支持的文件格式")
+	}
+}
+
+func WriteSheet(fileName string, rows [][]string) ([]byte, error) {
+	switch GetSheetType(fileName) {
+	case SheetTypeCsv:
+		return WriteCsv(rows)
+	case SheetTypeXlsx:
+		return WriteXlsx(defaultSheetName, rows)
+	default:
+		return nil, fmt.Errorf("不支持的文件格式")
+	}
+}
+
+func ReadCsv(data []byte) ([][]string, error) {
+	reader := csv.NewReader(bytes.NewReader(bytes.TrimPrefix(data, []byte(utf8Bom))))
+	reader.FieldsPerRecord = -1
+	rows, err := reader.ReadAll()
+	if err != nil {
+		logger.Warn(sheetLogTag, "ReadCsv Failed|Err:%v", err)
+		return nil, err
+	}
+	return rows, nil
+}
+
+func WriteCsv(rows [][]string) ([]byte, error) {
+	buffer := bytes.NewBufferString(utf8Bom)
+	writer := csv.NewWriter(buffer)
+	err := writer.WriteAll(rows)
+	if err != nil {
+		logger.Warn(sheetLogTag, "WriteCsv Failed|Err:%v", err)
+		return nil, err
+	}
+	return buffer.Bytes(), nil
+}
+
+func GetColumnName(column int) string {
+	name := ""
+	for column++; column > 0; column = (column - 1) / 26 {
+		name = string(rune('A'+(column-1)%26)) + name
+	}
+	return name
+}
+
+// parseColumnIndex 解析单元格引用中的列号，超过 MaxXlsxColumns 时返回 MaxXlsxColumns
+func parseColumnIndex(ref string) int {
+	index := 0
+	for _, c := range ref {
+		if c < 'A' || c > 'Z' {
+			break
+		}
+		index = index*26 + int(c-'A') + 1
+		if index > MaxXlsxColumns {
+			return MaxXlsxColumns
+		}
+	}
+	return index - 1
+}
+
+func readZipFile(reader *zip.Reader, name string) ([]byte, error) {
+	for _, file := range reader.File {
+		if file.Name != name {
+			continue
+		}
+		if file.UncompressedSize64 > MaxXlsxEntrySize {
+			return nil, fmt.Errorf("文件内容过大")
+		}
+		fileReader, err := file.Open()
+		if err != nil {
+			return nil, err
+		}
+		defer fileReader.Close()
+		// 压缩包头中的大小可被伪造，读取时仍需限制
+		data, err := ioutil.ReadAll(io.LimitReader(fileReader, MaxXlsxEntrySize+1))
+		if err != nil {
+			return nil, err
+		}
+		if len(data) > MaxXlsxEntrySize {
+			return nil, fmt.Errorf("文件内容过大")
+		}
+		return data, nil
+	}
+	return nil, nil
+}
+
+func getFirstSheetPath(reader *zip.Reader) string {
+	defaultPath := "xl/worksheets/sheet1.xml"
+	workbookData, err := readZipFile(reader, "xl/workbook.xml")
+	if err != nil || workbookData == nil {
+		return defaultPath
+	}
+	relsData, err := readZipFile(reader, "xl/_rels/workbook.xml.rels")
+	if err != nil || relsData == nil {
+		return defaultPath
+	}
+	workbook := &xlsxWorkbook{}
+	rels := &xlsxRelationships{}
+	if xml.Unmarshal(workbookData, workbook) != nil || xml.Unmarshal(relsData, rels) != nil ||
+		len(workbook.Sheets) == 0 {
+		return defaultPath
+	}
+	for _, rel := range rels.Items {
+		if rel.ID != workbook.Sheets[0].RID {
+			continue
+		}
+		if strings.HasPrefix(rel.Target, "/") {
+			return rel.Target[1:]
+		}
+		return "xl/" + rel.Target
+	}
+	return defaultPath
+}
+
+func ReadXlsx(data []byte) ([][]string, error) {
+	reader, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		logger.Warn(sheetLogTag, "ReadXlsx Open Failed|Err:%v", err)
+		return nil, fmt.Errorf("文件格式错误")
+	}
+
+	sharedStrings := &xlsxSharedStrings{}
+	stringsData, err := readZipFile(reader, "xl/sharedStrings.xml")
+	if err != nil {
+		logger.Warn(sheetLogTag, "ReadXlsx Read SharedStrings Failed|Err:%v", err)
+		return nil, err
+	}
+	if stringsData != nil {
+		err = xml.Unmarshal(stringsData, sharedStrings)
+		if err != nil {
+			logger.Warn(sheetLogTag, "ReadXlsx Parse SharedStrings Failed|Err:%v", err)
+			return nil, err
+		}
+	}
+
+	sheetData, err := readZipFile(reader, getFirstSheetPath(reader))
+	if err != nil {
+		logger.Warn(sheetLogTag, "ReadXlsx Read Sheet Failed|Err:%v", err)
+		return nil, err
+	}
+	if sheetData == nil {
+		logger.Warn(sheetLogTag, "ReadXlsx Sheet Not Found")
+		return nil, fmt.Errorf("文件格式错误")
+	}
+	worksheet := &xlsxWorksheet{}
+	err = xml.Unmarshal(sheetData, worksheet)
+	if err != nil {
+		logger.Warn(sheetLogTag, "ReadXlsx Parse Sheet Failed|Err:%v", err)
+		return nil, err
+	}
+
+	rows := make([][]string, 0, len(worksheet.Rows))
+	for _, xRow := range worksheet.Rows {
+		if xRow.Ref > MaxXlsxRows {
+			logger.Warn(sheetLogTag, "ReadXlsx Row Out Of Range|Row:%v", xRow.Ref)
+			return nil, fmt.Errorf("表格行数超出上限")
+		}
+		for xRow.Ref > len(rows)+1 {
+			rows = append(rows, make([]string, 0))
+		}
+		row := make([]string, 0, len(xRow.Cells))
+		for _, cell := range xRow.Cells {
+			if cell.Ref != "" {
+				column := parseColumnIndex(cell.Ref)
+				if column >= MaxXlsxColumns {
+					logger.Warn(sheetLogTag, "ReadXlsx Column Out Of Range|Ref:%v", cell.Ref)
+					return nil, fmt.Errorf("表格列数超出上限")
+				}
+				for len(row) < column {
+					row = append(row, "")
+				}
+			}
+			value := cell.Value
+			switch cell.Type {
+			case "s":
+				index, err := strconv.Atoi(cell.Value)
+				if err == nil && index >= 0 && index < len(sharedStrings.Items) {
+					value = sharedStrings.Items[index].String()
+				}
+			case "inlineStr":
+				value = cell.Inline.String()
+			}
+			row = append(row, value)
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+func WriteXlsx(sheetName string, rows [][]string) ([]byte, error) {
+	sheet := bytes.NewBufferString(xlsxSheetHead)
+	for rowIndex, row := range rows {
+		sheet.WriteString(fmt.Sprintf(`<row r="%v">`, rowIndex+1))
+		for column, value := range row {
+			sheet.WriteString(fmt.Sprintf(`<c r="%v%v" t="inlineStr"><is><t xml:space="preserve">`,
+				GetColumnName(column), rowIndex+1))
+			xml.EscapeText(sheet, []byte(value))
+			sheet.WriteString(`</t></is></c>`)
+		}
+		sheet.WriteString(`</row>`)
+	}
+	sheet.WriteString(xlsxSheetTail)
+
+	escapedName := bytes.NewBuffer(nil)
+	xml.EscapeText(escapedName, []byte(sheetName))
+	fileMap := []struct {
+		name    string
+		content string
+	}{
+		{"[Content_Types].xml", xlsxContentTypes},
+		{"_rels/.rels", xlsxRootRels},
+		{"xl/workbook.xml", fmt.Sprintf(xlsxWorkbookTemplate, escapedName.String())},
+		{"xl/_rels/workbook.xml.rels", xlsxWorkbookRels},
+		{"xl/worksheets/sheet1.xml", sheet.String()},
+	}
+
+	buffer := bytes.NewBuffer(nil)
+	writer := zip.NewWriter(buffer)
+	for _, file := range fileMap {
+		fileWriter, err := writer.Create(file.name)
+		if err != nil {
+			logger.Warn(sheetLogTag, "WriteXlsx Create Failed|Name:%v|Err:%v", file.name, err)
+			return nil, err
+		}
+		_, err = fileWriter.Write([]byte(file.content))
+		if err != nil {
+			logger.Warn(sheetLogTag, "WriteXlsx Write Failed|Name:%v|Err:%v", file.name, err)
+			return nil, err
+		}
+	}
+	err := writer.Close()
+	if err != nil {
+		logger.Warn(sheetLogTag, "WriteXlsx Close Failed|Err:%v", err)
+		return nil, err
+	}
+	return buffer.Bytes(), nil
+}
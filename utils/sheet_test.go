@@ -0,0 +1,119 @@
+package utils
+
+import (
+	"archive/zip"
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func TestSheetRoundTrip(t *testing.T) {
+	rows := [][]string{
+		{"餐次", "菜品类型", "星期一", "星期二"},
+		{"早餐", "主食", "包子,馒头", ""},
+		{"午餐", "荤菜", "<红烧肉> & \"鱼\"", "宫保鸡丁"},
+		{"晚餐", "", "", "  空格保留  "},
+	}
+	testList := []struct {
+		name     string
+		fileName string
+	}{
+		{"csv", "week_menu.csv"},
+		{"xlsx", "week_menu.xlsx"},
+		{"upper ext", "WEEK_MENU.XLSX"},
+	}
+	for _, tt := range testList {
+		t.Run(tt.name, func(t *testing.T) {
+			content, err := WriteSheet(tt.fileName, rows)
+			if err != nil {
+				t.Fatalf("WriteSheet Failed|Err:%v", err)
+			}
+			got, err := ReadSheet(tt.fileName, content)
+			if err != nil {
+				t.Fatalf("ReadSheet Failed|Err:%v", err)
+			}
+			if !reflect.DeepEqual(got, rows) {
+				t.Fatalf("RoundTrip Mismatch|Got:%q|Want:%q", got, rows)
+			}
+		})
+	}
+}
+
+func TestReadSheetUnsupported(t *testing.T) {
+	_, err := ReadSheet("menu.xls", []byte("data"))
+	if err == nil {
+		t.Fatal("expect error for unsupported file type")
+	}
+}
+
+func TestReadXlsxEntryLimit(t *testing.T) {
+	buffer := bytes.NewBuffer(nil)
+	writer := zip.NewWriter(buffer)
+	fileWriter, err := writer.Create("xl/worksheets/sheet1.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = fileWriter.Write(make([]byte, MaxXlsxEntrySize+1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+	_, err = ReadXlsx(buffer.Bytes())
+	if err == nil {
+		t.Fatal("expect error for oversized entry")
+	}
+}
+
+func TestReadXlsxRefLimit(t *testing.T) {
+	testList := []struct {
+		name  string
+		sheet string
+	}{
+		{"row out of range", `<row r="2000000000"><c r="A2000000000" t="inlineStr"><is><t>a</t></is></c></row>`},
+		{"column out of range", `<row r="1"><c r="ZZZZZZZ1" t="inlineStr"><is><t>a</t></is></c></row>`},
+	}
+	for _, tt := range testList {
+		t.Run(tt.name, func(t *testing.T) {
+			buffer := bytes.NewBuffer(nil)
+			writer := zip.NewWriter(buffer)
+			fileWriter, err := writer.Create("xl/worksheets/sheet1.xml")
+			if err != nil {
+				t.Fatal(err)
+			}
+			_, err = fileWriter.Write([]byte(xlsxSheetHead + tt.sheet + xlsxSheetTail))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err = writer.Close(); err != nil {
+				t.Fatal(err)
+			}
+			_, err = ReadXlsx(buffer.Bytes())
+			if err == nil {
+				t.Fatal("expect error for out of range reference")
+			}
+		})
+	}
+}
+
+func TestGetColumnName(t *testing.T) {
+	testList := []struct {
+		column int
+		want   string
+	}{
+		{0, "A"},
+		{25, "Z"},
+		{26, "AA"},
+		{701, "ZZ"},
+		{702, "AAA"},
+	}
+	for _, tt := range testList {
+		if got := GetColumnName(tt.column); got != tt.want {
+			t.Errorf("GetColumnName(%v)=%v|Want:%v", tt.column, got, tt.want)
+		}
+		if got := parseColumnIndex(tt.want + "12"); got != tt.column {
+			t.Errorf("parseColumnIndex(%v)=%v|Want:%v", tt.want, got, tt.column)
+		}
+	}
+}